@@ -9,7 +9,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var endingBlockNumber int64
+var (
+	endingBlockNumber int64
+	backFillWorkers   int
+)
 
 // backfillEventsCmd represents the backfillEvents command
 var backfillEventsCmd = &cobra.Command{
@@ -18,7 +21,13 @@ var backfillEventsCmd = &cobra.Command{
 	Long: `Fetch and persist events from configured transformers across a range
 of headers that may have already been checked for logs. Useful when adding a
 new event transformer to an instance that has already been running and marking
-headers checked as it queried for the previous (now incomplete) set of logs.`,
+headers checked as it queried for the previous (now incomplete) set of logs.
+
+Ranges of headers are back-filled concurrently by --workers workers. Completed
+ranges are recorded, so re-running an interrupted back-fill for the same
+transformers resumes where it left off. Back-filled headers are marked checked,
+so execute does not check them again. Logs watched by transformers missing from
+this back-fill are instead back-filled for those headers by execute.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		SubCommand = cmd.CalledAs()
 		LogWithCommand = *logrus.WithField("SubCommand", SubCommand)
@@ -33,6 +42,7 @@ headers checked as it queried for the previous (now incomplete) set of logs.`,
 func init() {
 	rootCmd.AddCommand(backfillEventsCmd)
 	backfillEventsCmd.Flags().Int64VarP(&endingBlockNumber, "ending-block-number", "e", -1, "last block from which to back-fill events")
+	backfillEventsCmd.Flags().IntVarP(&backFillWorkers, "workers", "w", logs.DefaultBackFillWorkers, "number of header ranges to back-fill concurrently")
 	backfillEventsCmd.MarkFlagRequired("ending-block-number")
 }
 
@@ -46,6 +56,8 @@ func backFillEvents() error {
 	db := utils.LoadPostgres(databaseConfig, blockChain.Node())
 
 	extractor := logs.NewLogExtractor(&db, blockChain)
+	extractor.Syncer = newTransactionsSyncer(&db, blockChain)
+	extractor.BackFillWorkers = backFillWorkers
 
 	for _, initializer := range ethEventInitializers {
 		transformer := initializer(&db)
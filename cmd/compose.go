@@ -89,6 +89,19 @@ of event data from an eth node (eth_event) and storage data from an eth node
 based transformers which can perform event watching provided only a contract
 address (eth_contract).
 
+An eth_event transformer can be configured with abiPath, event, table and
+contracts in place of a path, to store an event of a contract ABI without
+transformer code of its own:
+    [exporter.transfers]
+        type = "eth_event"
+        abiPath = "path/to/token_abi.json"
+        event = "Transfer"
+        table = "transfers"
+        contracts = ["0x..."]
+        repository = "github.com/account/repo"
+        migrations = "db/migrations"
+        rank = "0"
+
 Transformers of different types can be ran together in the same command using a 
 single config file or in separate command instances using different config files
 
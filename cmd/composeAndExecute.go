@@ -19,6 +19,8 @@ package cmd
 import (
 	"time"
 
+	"github.com/makerdao/vulcanizedb/libraries/shared/logs"
+	"github.com/makerdao/vulcanizedb/libraries/shared/watcher"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -90,6 +92,19 @@ of event data from an eth node (eth_event) and storage data from an eth node
 based transformers which can perform event watching provided only a conctract
 address (eth_contract).
 
+An eth_event transformer can be configured with abiPath, event, table and
+contracts in place of a path, to store an event of a contract ABI without
+transformer code of its own:
+    [exporter.transfers]
+        type = "eth_event"
+        abiPath = "path/to/token_abi.json"
+        event = "Transfer"
+        table = "transfers"
+        contracts = ["0x..."]
+        repository = "github.com/account/repo"
+        migrations = "db/migrations"
+        rank = "0"
+
 Transformers of different types can be ran together in the same command using a 
 single config file or in separate command instances using different config files
 
@@ -118,4 +133,7 @@ func init() {
 	composeAndExecuteCmd.Flags().BoolVarP(&recheckHeadersArg, "recheck-headers", "r", false, "whether to re-check headers for watched events")
 	composeAndExecuteCmd.Flags().DurationVarP(&retryInterval, "retry-interval", "i", 7*time.Second, "interval duration between retries on execution error")
 	composeAndExecuteCmd.Flags().IntVarP(&maxUnexpectedErrors, "max-unexpected-errs", "m", 5, "maximum number of unexpected errors to allow (with retries) before exiting")
+	composeAndExecuteCmd.Flags().IntVar(&maxTransformAttempts, "max-transform-attempts", logs.DefaultMaxTransformAttempts, "number of times a transformer may fail on a log before the log is skipped")
+	composeAndExecuteCmd.Flags().DurationVar(&transformRetryDelay, "transform-retry-delay", logs.DefaultTransformRetryDelay, "delay before a log that failed transformation is attempted again, doubling with each further failure")
+	composeAndExecuteCmd.Flags().IntVar(&storageWorkers, "storage-workers", watcher.DefaultStorageWorkers, "number of contracts whose storage diffs are transformed concurrently")
 }
@@ -0,0 +1,166 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deadLetterIDs             []int
+	deadLetterTransformerName string
+	allDeadLetterLogs         bool
+)
+
+// deadLetterLogsCmd represents the deadLetterLogs command
+var deadLetterLogsCmd = &cobra.Command{
+	Use:   "deadLetterLogs",
+	Short: "Lists, retries or discards event logs that transformers failed to transform",
+	Long: `When an event transformer fails on a log, the log is dead-lettered with the
+transformer's name and error, and other logs keep being transformed. The log is
+attempted again after --transform-retry-delay, doubling the delay with each
+further failure, until it has failed --max-transform-attempts times, after which
+execute skips it for that transformer.
+
+List dead-lettered logs, optionally for a single transformer:
+./vulcanizedb deadLetterLogs list --config=<config> [--transformer=<name>]
+
+Clear the failure history of logs so that they are attempted again:
+./vulcanizedb deadLetterLogs retry --config=<config> --ids=<id>,<id>
+
+Permanently skip logs, regardless of how many times they have been attempted:
+./vulcanizedb deadLetterLogs discard --config=<config> --transformer=<name>
+
+retry and discard act on the given dead letter ids, on every log dead-lettered
+for --transformer, or with --all on every dead-lettered log.`,
+}
+
+var listDeadLetterLogsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists dead-lettered event logs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		SubCommand = cmd.CalledAs()
+		LogWithCommand = *logrus.WithField("SubCommand", SubCommand)
+		return listDeadLetterLogs()
+	},
+}
+
+var retryDeadLetterLogsCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Clears the failure history of dead-lettered event logs so that they are transformed again",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		SubCommand = cmd.CalledAs()
+		LogWithCommand = *logrus.WithField("SubCommand", SubCommand)
+		return updateDeadLetterLogs(func(repository repositories.DeadLetterRepository, ids []int64) error {
+			return repository.RetryDeadLetterLogs(ids)
+		}, "retrying")
+	},
+}
+
+var discardDeadLetterLogsCmd = &cobra.Command{
+	Use:   "discard",
+	Short: "Stops transformers from attempting dead-lettered event logs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		SubCommand = cmd.CalledAs()
+		LogWithCommand = *logrus.WithField("SubCommand", SubCommand)
+		return updateDeadLetterLogs(func(repository repositories.DeadLetterRepository, ids []int64) error {
+			return repository.DiscardDeadLetterLogs(ids)
+		}, "discarding")
+	},
+}
+
+func init() {
+	deadLetterLogsCmd.PersistentFlags().StringVarP(&deadLetterTransformerName, "transformer", "t", "", "only act on logs dead-lettered for this transformer")
+	for _, subCommand := range []*cobra.Command{retryDeadLetterLogsCmd, discardDeadLetterLogsCmd} {
+		subCommand.Flags().IntSliceVar(&deadLetterIDs, "ids", nil, "ids of the dead letters to act on, as shown by list")
+		subCommand.Flags().BoolVar(&allDeadLetterLogs, "all", false, "act on every dead-lettered log")
+	}
+	deadLetterLogsCmd.AddCommand(listDeadLetterLogsCmd, retryDeadLetterLogsCmd, discardDeadLetterLogsCmd)
+	rootCmd.AddCommand(deadLetterLogsCmd)
+}
+
+func newDeadLetterRepository() repositories.DeadLetterRepository {
+	blockChain := getBlockChain()
+	db := utils.LoadPostgres(databaseConfig, blockChain.Node())
+	return repositories.NewDeadLetterRepository(&db)
+}
+
+func listDeadLetterLogs() error {
+	deadLetterLogs, err := newDeadLetterRepository().GetDeadLetterLogs(deadLetterTransformerName)
+	if err != nil {
+		return fmt.Errorf("SubCommand %v: failed to get dead-lettered logs: %w", SubCommand, err)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTRANSFORMER\tLOG ID\tBLOCK\tTX HASH\tLOG INDEX\tATTEMPTS\tDISCARDED\tUPDATED\tNEXT ATTEMPT\tERROR")
+	for _, deadLetterLog := range deadLetterLogs {
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%d\t%s\t%d\t%d\t%t\t%s\t%s\t%s\n", deadLetterLog.ID, deadLetterLog.TransformerName,
+			deadLetterLog.EventLogID, deadLetterLog.BlockNumber, deadLetterLog.TxHash, deadLetterLog.LogIndex,
+			deadLetterLog.Attempts, deadLetterLog.Discarded, deadLetterLog.Updated.Format("2006-01-02 15:04:05"),
+			deadLetterLog.NextAttemptAt.Format("2006-01-02 15:04:05"), deadLetterLog.Error)
+	}
+	return writer.Flush()
+}
+
+func updateDeadLetterLogs(update func(repositories.DeadLetterRepository, []int64) error, operation string) error {
+	if len(deadLetterIDs) == 0 && deadLetterTransformerName == "" && !allDeadLetterLogs {
+		return errors.New("pass --ids, --transformer or --all to choose which dead-lettered logs to act on")
+	}
+	repository := newDeadLetterRepository()
+	ids, idsErr := selectDeadLetterIDs(repository)
+	if idsErr != nil {
+		return fmt.Errorf("SubCommand %v: failed to get dead-lettered logs: %w", SubCommand, idsErr)
+	}
+	if len(ids) == 0 {
+		LogWithCommand.Info("no matching dead-lettered logs")
+		return nil
+	}
+	updateErr := update(repository, ids)
+	if updateErr != nil {
+		return fmt.Errorf("SubCommand %v: failed %s dead-lettered logs: %w", SubCommand, operation, updateErr)
+	}
+	LogWithCommand.Infof("%s %d dead-lettered logs", operation, len(ids))
+	return nil
+}
+
+// selectDeadLetterIDs returns the dead letter ids passed with --ids, otherwise those matching --transformer,
+// where an empty transformer name matches every dead-lettered log
+func selectDeadLetterIDs(repository repositories.DeadLetterRepository) ([]int64, error) {
+	if len(deadLetterIDs) > 0 {
+		ids := make([]int64, len(deadLetterIDs))
+		for i, id := range deadLetterIDs {
+			ids[i] = int64(id)
+		}
+		return ids, nil
+	}
+	deadLetterLogs, err := repository.GetDeadLetterLogs(deadLetterTransformerName)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(deadLetterLogs))
+	for i, deadLetterLog := range deadLetterLogs {
+		ids[i] = deadLetterLog.ID
+	}
+	return ids, nil
+}
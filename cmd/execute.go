@@ -20,10 +20,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/constants"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/logs"
+	sharedStorage "github.com/makerdao/vulcanizedb/libraries/shared/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/transformer"
 	"github.com/makerdao/vulcanizedb/libraries/shared/watcher"
 	"github.com/makerdao/vulcanizedb/pkg/fs"
@@ -77,6 +79,9 @@ func init() {
 	executeCmd.Flags().BoolVarP(&recheckHeadersArg, "recheck-headers", "r", false, "whether to re-check headers for watched events")
 	executeCmd.Flags().DurationVarP(&retryInterval, "retry-interval", "i", 7*time.Second, "interval duration between retries on execution error")
 	executeCmd.Flags().IntVarP(&maxUnexpectedErrors, "max-unexpected-errs", "m", 5, "maximum number of unexpected errors to allow (with retries) before exiting")
+	executeCmd.Flags().IntVar(&maxTransformAttempts, "max-transform-attempts", logs.DefaultMaxTransformAttempts, "number of times a transformer may fail on a log before the log is skipped")
+	executeCmd.Flags().DurationVar(&transformRetryDelay, "transform-retry-delay", logs.DefaultTransformRetryDelay, "delay before a log that failed transformation is attempted again, doubling with each further failure")
+	executeCmd.Flags().IntVar(&storageWorkers, "storage-workers", watcher.DefaultStorageWorkers, "number of contracts whose storage diffs are transformed concurrently")
 	executeCmd.Flags().Int64VarP(&diffBlockFromHeadOfChain, "diff-blocks-from-head", "d", -1, "number of blocks from head of chain to start reprocessing diffs, defaults to -1 so all diffs are processsed")
 }
 
@@ -96,7 +101,10 @@ func executeTransformers() {
 	var wg sync.WaitGroup
 	if len(ethEventInitializers) > 0 {
 		extractor := logs.NewLogExtractor(&db, blockChain)
+		extractor.Syncer = newTransactionsSyncer(&db, blockChain)
 		delegator := logs.NewLogDelegator(&db)
+		delegator.MaxTransformAttempts = maxTransformAttempts
+		delegator.TransformRetryDelay = transformRetryDelay
 		eventHealthCheckMessage := []byte("event watcher starting\n")
 		statusWriter := fs.NewStatusWriter(healthCheckFile, eventHealthCheckMessage)
 		ew := watcher.NewEventWatcher(&db, blockChain, extractor, delegator, maxUnexpectedErrors, retryInterval, statusWriter)
@@ -112,6 +120,14 @@ func executeTransformers() {
 		storageHealthCheckMessage := []byte("storage watcher starting\n")
 		statusWriter := fs.NewStatusWriter(healthCheckFile, storageHealthCheckMessage)
 		sw := watcher.NewStorageWatcher(&db, diffBlockFromHeadOfChain, statusWriter)
+		sw.Workers = storageWorkers
+		if recordPreimages {
+			recorder := sharedStorage.NewPreimageRecorder(&db)
+			for _, address := range preimageAddresses {
+				recorder.AddKnownAddresses(common.HexToAddress(address))
+			}
+			sw.PreimageRecorder = recorder
+		}
 		sw.AddTransformers(ethStorageInitializers)
 		wg.Add(1)
 		go watchEthStorage(&sw, &wg)
@@ -0,0 +1,95 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
+	"github.com/makerdao/vulcanizedb/pkg/eth"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrationAbiPath       string
+	migrationEventName     string
+	migrationSchemaName    string
+	migrationTableName     string
+	migrationDirectoryPath string
+)
+
+// generateEventMigrationCmd represents the generateEventMigration command
+var generateEventMigrationCmd = &cobra.Command{
+	Use:   "generateEventMigration",
+	Short: "Generates the migration for the table of an ABI-driven event transformer",
+	Long: `Writes a goose migration creating the table that event.NewABITransformer inserts
+the given event into, with a column per event argument typed like the contractWatcher's
+event tables.
+
+./vulcanizedb generateEventMigration --abi-path=<abi.json> --event=Transfer \
+    --schema=public --table=transfers --migrations-dir=<plugin migrations path>
+
+The migration is named with a timestamp, like those of goose create, so that it is
+run after the core vulcanizedb migrations.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		SubCommand = cmd.CalledAs()
+		LogWithCommand = *logrus.WithField("SubCommand", SubCommand)
+		return generateEventMigration()
+	},
+}
+
+func init() {
+	generateEventMigrationCmd.Flags().StringVarP(&migrationAbiPath, "abi-path", "a", "", "path to the contract's ABI file")
+	generateEventMigrationCmd.Flags().StringVarP(&migrationEventName, "event", "e", "", "name of the event in the ABI")
+	generateEventMigrationCmd.Flags().StringVarP(&migrationSchemaName, "schema", "s", "public", "schema of the event's table")
+	generateEventMigrationCmd.Flags().StringVarP(&migrationTableName, "table", "t", "", "name of the event's table, defaults to the lowercased event name")
+	generateEventMigrationCmd.Flags().StringVarP(&migrationDirectoryPath, "migrations-dir", "d", ".", "directory to write the migration to")
+	rootCmd.AddCommand(generateEventMigrationCmd)
+}
+
+func generateEventMigration() error {
+	if migrationAbiPath == "" || migrationEventName == "" {
+		return errors.New("generateEventMigration requires --abi-path and --event")
+	}
+	if migrationTableName == "" {
+		migrationTableName = strings.ToLower(migrationEventName)
+	}
+	contractAbi, abiErr := eth.ReadAbiFile(migrationAbiPath)
+	if abiErr != nil {
+		return fmt.Errorf("SubCommand %v: failed to read abi: %w", SubCommand, abiErr)
+	}
+	transformer, transformerErr := event.NewABITransformer(contractAbi, migrationEventName,
+		event.SchemaName(migrationSchemaName), event.TableName(migrationTableName))
+	if transformerErr != nil {
+		return fmt.Errorf("SubCommand %v: %w", SubCommand, transformerErr)
+	}
+
+	fileName := fmt.Sprintf("%s_create_%s_table.sql", time.Now().UTC().Format("20060102150405"), migrationTableName)
+	migrationPath := filepath.Join(migrationDirectoryPath, fileName)
+	writeErr := ioutil.WriteFile(migrationPath, []byte(transformer.CreateTableMigration()), 0644)
+	if writeErr != nil {
+		return fmt.Errorf("SubCommand %v: failed to write migration: %w", SubCommand, writeErr)
+	}
+	LogWithCommand.Infof("wrote migration %s", migrationPath)
+	return nil
+}
@@ -19,8 +19,6 @@ package cmd
 import (
 	"time"
 
-	"github.com/makerdao/vulcanizedb/pkg/core"
-	"github.com/makerdao/vulcanizedb/pkg/datastore"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
 	"github.com/makerdao/vulcanizedb/pkg/eth"
 	"github.com/makerdao/vulcanizedb/pkg/fs"
@@ -39,6 +37,17 @@ Postgres with block headers.
 
 ./vulcanizedb headerSync --starting-block-number 0 --config public.toml
 
+Missing headers are fetched in batches of --batch-size (max 100) spread across
+--workers concurrent workers, with progress (blocks/sec and ETA) logged periodically.
+
+Reorgs are detected by following parent hashes back from the most recent header
+for up to --validation-window blocks; orphaned headers are replaced, and each
+reorg is recorded in public.reorgs.
+
+With --subscribe, new headers are ingested from an eth_subscribe("newHeads")
+subscription instead of polling. Requires a websocket or IPC connection to the
+node; if the subscription drops, headers are polled until it can be restored.
+
 Expects ethereum node to be running and requires a .toml config:
 
   [database]
@@ -56,13 +65,24 @@ Expects ethereum node to be running and requires a .toml config:
 	},
 }
 
+var (
+	headerSyncBatchSize int
+	headerSyncSubscribe bool
+	headerSyncWorkers   int
+	validationWindow    int
+)
+
 func init() {
 	rootCmd.AddCommand(headerSyncCmd)
 	headerSyncCmd.Flags().Int64VarP(&startingBlockNumber, "starting-block-number", "s", 0, "Block number to start syncing from")
+	headerSyncCmd.Flags().IntVarP(&headerSyncWorkers, "workers", "w", history.DefaultHeaderWorkers, "number of concurrent workers fetching missing headers")
+	headerSyncCmd.Flags().IntVarP(&headerSyncBatchSize, "batch-size", "b", history.DefaultHeaderBatchSize, "number of headers fetched per RPC batch request")
+	headerSyncCmd.Flags().BoolVar(&headerSyncSubscribe, "subscribe", false, "ingest new headers from a newHeads subscription instead of polling")
+	headerSyncCmd.Flags().IntVarP(&validationWindow, "validation-window", "v", int(repositories.DefaultHeaderReorgWindow), "number of blocks back from the head to check for reorgs")
 }
 
-func backFillAllHeaders(blockchain core.BlockChain, headerRepository datastore.HeaderRepository, missingBlocksPopulated chan int, startingBlockNumber int64) {
-	populated, err := history.PopulateMissingHeaders(blockchain, headerRepository, startingBlockNumber)
+func backFillAllHeaders(backFiller history.HeaderBackFiller, missingBlocksPopulated chan int, startingBlockNumber int64) {
+	populated, err := backFiller.PopulateMissingHeaders(startingBlockNumber)
 	if err != nil {
 		// TODO Lots of possible errors in the call stack above. If errors occur, we still put
 		// 0 in the channel, triggering another round
@@ -78,8 +98,10 @@ func headerSync() {
 	validateHeaderSyncArgs(blockChain)
 	db := utils.LoadPostgres(databaseConfig, blockChain.Node())
 
-	headerRepository := repositories.NewHeaderRepository(&db)
+	headerRepository := repositories.NewHeaderRepository(&db, int64(validationWindow))
+	backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, headerSyncWorkers, headerSyncBatchSize)
 	validator := history.NewHeaderValidator(blockChain, headerRepository, validationWindow)
+	validator.AddReorgHandler(history.NewReorgRecorder(repositories.NewReorgRepository(&db)))
 	missingBlocksPopulated := make(chan int)
 
 	statusWriter := fs.NewStatusWriter("/tmp/header_sync_health_check", []byte("headerSync starting\n"))
@@ -88,7 +110,13 @@ func headerSync() {
 		LogWithCommand.Errorf("headerSync: Error writing health check file: %s", writeErr.Error())
 	}
 
-	go backFillAllHeaders(blockChain, headerRepository, missingBlocksPopulated, startingBlockNumber)
+	if headerSyncSubscribe {
+		subscriber := history.NewHeaderSubscriber(blockChain, headerRepository, backFiller)
+		subscriber.ResubscribeInterval = pollingInterval
+		go subscriber.SyncHeaders(startingBlockNumber, nil)
+	} else {
+		go backFillAllHeaders(backFiller, missingBlocksPopulated, startingBlockNumber)
+	}
 
 	for {
 		select {
@@ -102,7 +130,7 @@ func headerSync() {
 			if n == 0 {
 				time.Sleep(3 * time.Second)
 			}
-			go backFillAllHeaders(blockChain, headerRepository, missingBlocksPopulated, startingBlockNumber)
+			go backFillAllHeaders(backFiller, missingBlocksPopulated, startingBlockNumber)
 		}
 	}
 }
@@ -117,4 +145,13 @@ func validateHeaderSyncArgs(blockChain *eth.BlockChain) {
 		LogWithCommand.Fatalf("starting block number (%d) greater than client's most recent synced block (%d)",
 			startingBlockNumber, lastBlockNumber)
 	}
+	if headerSyncWorkers < 1 {
+		LogWithCommand.Fatalf("workers (%d) must be at least 1", headerSyncWorkers)
+	}
+	if headerSyncBatchSize < 1 || headerSyncBatchSize > eth.MAX_BATCH_SIZE {
+		LogWithCommand.Fatalf("batch size (%d) must be between 1 and %d", headerSyncBatchSize, eth.MAX_BATCH_SIZE)
+	}
+	if validationWindow < 1 {
+		LogWithCommand.Fatalf("validation window (%d) must be at least 1", validationWindow)
+	}
 }
@@ -24,12 +24,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/transactions"
 	"github.com/makerdao/vulcanizedb/libraries/shared/transformer"
 	"github.com/makerdao/vulcanizedb/pkg/config"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 	"github.com/makerdao/vulcanizedb/pkg/eth"
 	"github.com/makerdao/vulcanizedb/pkg/eth/client"
 	"github.com/makerdao/vulcanizedb/pkg/eth/converters"
@@ -43,21 +44,26 @@ var (
 	LogWithCommand           logrus.Entry
 	SubCommand               string
 	cfgFile                  string
+	clientConfig             config.Client
 	databaseConfig           config.Database
 	diffBlockFromHeadOfChain int64
 	genConfig                config.Plugin
-	ipc                      string
+	maxTransformAttempts     int
 	maxUnexpectedErrors      int
+	preimageAddresses        []string
 	recheckHeadersArg        bool
+	recordPreimages          bool
 	retryInterval            time.Duration
 	startingBlockNumber      int64
+	storageWorkers           int
 	storageDiffsPath         string
 	storageDiffsSource       string
+	syncReceipts             bool
+	transformRetryDelay      time.Duration
 )
 
 const (
-	pollingInterval  = 7 * time.Second
-	validationWindow = 15
+	pollingInterval = 7 * time.Second
 )
 
 var rootCmd = &cobra.Command{
@@ -82,9 +88,34 @@ func initFuncs(cmd *cobra.Command, args []string) {
 }
 
 func setViperConfigs() {
-	ipc = viper.GetString("client.ipcpath")
+	clientConfig = config.Client{
+		IPCPath:           viper.GetString("client.ipcPath"),
+		URL:               viper.GetString("client.url"),
+		Transport:         viper.GetString("client.transport"),
+		Headers:           viper.GetStringMapString("client.headers"),
+		BearerToken:       viper.GetString("client.bearerToken"),
+		BasicAuthUser:     viper.GetString("client.basicAuthUser"),
+		BasicAuthPassword: viper.GetString("client.basicAuthPassword"),
+		Timeout:           viper.GetDuration("client.timeout"),
+		TLS: config.ClientTLS{
+			CAFile:             viper.GetString("client.tls.caFile"),
+			CertFile:           viper.GetString("client.tls.certFile"),
+			KeyFile:            viper.GetString("client.tls.keyFile"),
+			InsecureSkipVerify: viper.GetBool("client.tls.insecureSkipVerify"),
+		},
+		NodeID:            viper.GetString("client.nodeID"),
+		RequestsPerSecond: viper.GetFloat64("client.requestsPerSecond"),
+		MaxBatchSize:      viper.GetInt("client.maxBatchSize"),
+	}
+	endpointsErr := viper.UnmarshalKey("client.endpoints", &clientConfig.Endpoints)
+	if endpointsErr != nil {
+		logrus.Fatalf("could not read client.endpoints: %s", endpointsErr.Error())
+	}
 	storageDiffsPath = viper.GetString("filesystem.storageDiffsPath")
 	storageDiffsSource = viper.GetString("storageDiffs.source")
+	recordPreimages = viper.GetBool("storageDiffs.recordPreimages")
+	preimageAddresses = viper.GetStringSlice("storageDiffs.preimageAddresses")
+	syncReceipts = viper.GetBool("transactions.syncReceipts")
 	databaseConfig = config.Database{
 		Name:     viper.GetString("database.name"),
 		Hostname: viper.GetString("database.hostname"),
@@ -121,8 +152,17 @@ func init() {
 	rootCmd.PersistentFlags().String("database-user", "", "database user")
 	rootCmd.PersistentFlags().String("database-password", "", "database password")
 	rootCmd.PersistentFlags().String("client-ipcPath", "", "location of geth.ipc file")
+	rootCmd.PersistentFlags().String("client-url", "", "url of the node's JSON-RPC endpoint (http(s)://, ws(s):// or an ipc path); overrides client-ipcPath")
+	rootCmd.PersistentFlags().String("client-transport", "", "transport used to reach the node: ipc, http or ws (inferred from the url when empty)")
+	rootCmd.PersistentFlags().Duration("client-timeout", 0, "timeout for each request to the node (e.g. 30s); 0 disables it")
+	rootCmd.PersistentFlags().String("client-nodeID", "", "stable identifier recorded for the node (defaults to the endpoint host); a node recorded under the client-ipcPath by earlier releases is renamed to it")
+	rootCmd.PersistentFlags().Float64("client-requestsPerSecond", 0, "maximum requests per second sent to the node, counting a batch as one request; 0 disables throttling")
+	rootCmd.PersistentFlags().Int("client-maxBatchSize", 0, "maximum number of calls sent to the node in one batch (default 100)")
 	rootCmd.PersistentFlags().String("filesystem-storageDiffsPath", "", "location of storage diffs csv file")
 	rootCmd.PersistentFlags().String("storageDiffs-source", "csv", "where to get the state diffs: csv or geth")
+	rootCmd.PersistentFlags().Bool("storageDiffs-recordPreimages", false, "whether to recognize mapping keys from the preimages of words in the watched contracts' event logs and transaction input data, and of known addresses")
+	rootCmd.PersistentFlags().StringSlice("storageDiffs-preimageAddresses", nil, "addresses to record preimages for besides the watched contracts', once they are in public.addresses")
+	rootCmd.PersistentFlags().Bool("transactions-syncReceipts", false, "whether to sync the receipts of transactions that emitted watched logs")
 	rootCmd.PersistentFlags().String("exporter-name", "exporter", "name of exporter plugin")
 	rootCmd.PersistentFlags().String("log-level", logrus.InfoLevel.String(), "Log level (trace, debug, info, warn, error, fatal, panic")
 
@@ -132,8 +172,17 @@ func init() {
 	viper.BindPFlag("database.user", rootCmd.PersistentFlags().Lookup("database-user"))
 	viper.BindPFlag("database.password", rootCmd.PersistentFlags().Lookup("database-password"))
 	viper.BindPFlag("client.ipcPath", rootCmd.PersistentFlags().Lookup("client-ipcPath"))
+	viper.BindPFlag("client.url", rootCmd.PersistentFlags().Lookup("client-url"))
+	viper.BindPFlag("client.transport", rootCmd.PersistentFlags().Lookup("client-transport"))
+	viper.BindPFlag("client.timeout", rootCmd.PersistentFlags().Lookup("client-timeout"))
+	viper.BindPFlag("client.nodeID", rootCmd.PersistentFlags().Lookup("client-nodeID"))
+	viper.BindPFlag("client.requestsPerSecond", rootCmd.PersistentFlags().Lookup("client-requestsPerSecond"))
+	viper.BindPFlag("client.maxBatchSize", rootCmd.PersistentFlags().Lookup("client-maxBatchSize"))
 	viper.BindPFlag("filesystem.storageDiffsPath", rootCmd.PersistentFlags().Lookup("filesystem-storageDiffsPath"))
 	viper.BindPFlag("storageDiffs.source", rootCmd.PersistentFlags().Lookup("storageDiffs-source"))
+	viper.BindPFlag("storageDiffs.recordPreimages", rootCmd.PersistentFlags().Lookup("storageDiffs-recordPreimages"))
+	viper.BindPFlag("storageDiffs.preimageAddresses", rootCmd.PersistentFlags().Lookup("storageDiffs-preimageAddresses"))
+	viper.BindPFlag("transactions.syncReceipts", rootCmd.PersistentFlags().Lookup("transactions-syncReceipts"))
 	viper.BindPFlag("exporter.fileName", rootCmd.PersistentFlags().Lookup("exporter-name"))
 	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
 }
@@ -152,24 +201,51 @@ func initConfig() {
 	}
 }
 
+// newTransactionsSyncer builds the syncer for transactions that emitted watched logs, which also syncs their receipts
+// when transactions.syncReceipts is set
+func newTransactionsSyncer(db *postgres.DB, blockChain core.BlockChain) transactions.TransactionsSyncer {
+	syncer := transactions.NewTransactionsSyncer(db, blockChain)
+	syncer.SyncReceipts = syncReceipts
+	return syncer
+}
+
 func getBlockChain() *eth.BlockChain {
 	rpcClient, ethClient := getClients()
-	vdbEthClient := client.NewEthClient(ethClient)
 	vdbNode := node.MakeNode(rpcClient)
+	vdbNode.LegacyID = clientConfig.LegacyNodeID()
 	transactionConverter := converters.NewTransactionConverter(ethClient)
-	return eth.NewBlockChain(vdbEthClient, rpcClient, vdbNode, transactionConverter)
+	return eth.NewBlockChain(ethClient, rpcClient, vdbNode, transactionConverter)
 }
 
-func getClients() (client.RpcClient, *ethclient.Client) {
-	rawRpcClient, err := rpc.Dial(ipc)
+// getClients dials the configured node, or every configured endpoint behind a failover client when
+// `client.endpoints` is set
+func getClients() (core.RpcClient, core.EthClient) {
+	validateErr := clientConfig.Validate()
+	if validateErr != nil {
+		LogWithCommand.Fatal(validateErr)
+	}
+	if len(clientConfig.Endpoints) == 0 {
+		endpoint, err := client.DialEndpoint(clientConfig)
+		if err != nil {
+			LogWithCommand.Fatal(err)
+		}
+		return endpoint.RpcClient, endpoint.EthClient
+	}
 
+	var endpoints []client.Endpoint
+	for _, endpointConfig := range clientConfig.Endpoints {
+		endpoint, err := client.DialEndpoint(endpointConfig)
+		if err != nil {
+			LogWithCommand.Fatalf("error dialing rpc endpoint %s: %s", endpointConfig.StableNodeID(), err.Error())
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	multiClient, err := client.NewMultiEndpointClient(endpoints, clientConfig.NodeID)
 	if err != nil {
 		LogWithCommand.Fatal(err)
 	}
-	rpcClient := client.NewRpcClient(rawRpcClient, ipc)
-	ethClient := ethclient.NewClient(rawRpcClient)
-
-	return rpcClient, ethClient
+	go multiClient.MonitorHealth(nil)
+	return multiClient, multiClient
 }
 
 func prepConfig() error {
@@ -178,8 +254,12 @@ func prepConfig() error {
 	transformers := make(map[string]config.Transformer)
 	for _, name := range names {
 		transformer := viper.GetStringMapString("exporter." + name)
+		abiEvent, abiEventErr := prepABIEventConfig(name, transformer)
+		if abiEventErr != nil {
+			return abiEventErr
+		}
 		p, pOK := transformer["path"]
-		if !pOK || p == "" {
+		if (!pOK || p == "") && abiEvent.AbiPath == "" {
 			return fmt.Errorf("transformer config is missing `path` value: %s", name)
 		}
 		r, rOK := transformer["repository"]
@@ -206,6 +286,9 @@ func prepConfig() error {
 		if transformerType == config.UnknownTransformerType {
 			return errors.New(`unknown transformer type in exporter config accepted types are "eth_event", "eth_storage"`)
 		}
+		if abiEvent.AbiPath != "" && transformerType != config.EthEvent {
+			return fmt.Errorf("transformer config with an `abiPath` must be of type eth_event: %s", name)
+		}
 
 		transformers[name] = config.Transformer{
 			Path:           p,
@@ -213,6 +296,7 @@ func prepConfig() error {
 			RepositoryPath: r,
 			MigrationPath:  m,
 			MigrationRank:  rank,
+			ABIEvent:       abiEvent,
 		}
 	}
 
@@ -226,6 +310,52 @@ func prepConfig() error {
 	return nil
 }
 
+// prepABIEventConfig reads the config of an eth_event transformer built from an event of a contract ABI, which is
+// configured with an `abiPath` instead of a `path` to transformer code
+func prepABIEventConfig(name string, transformer map[string]string) (config.ABIEvent, error) {
+	abiPath := transformer["abipath"]
+	if abiPath == "" {
+		return config.ABIEvent{}, nil
+	}
+	eventName := transformer["event"]
+	if eventName == "" {
+		return config.ABIEvent{}, fmt.Errorf("transformer config with an `abiPath` is missing `event` value: %s", name)
+	}
+	contracts := viper.GetStringSlice("exporter." + name + ".contracts")
+	if len(contracts) == 0 {
+		return config.ABIEvent{}, fmt.Errorf("transformer config with an `abiPath` is missing `contracts` value: %s", name)
+	}
+	abiEvent := config.ABIEvent{
+		AbiPath:           abiPath,
+		EventName:         eventName,
+		SchemaName:        transformer["schema"],
+		TableName:         transformer["table"],
+		ContractAddresses: contracts,
+		EndingBlock:       -1,
+	}
+	if abiEvent.SchemaName == "" {
+		abiEvent.SchemaName = "public"
+	}
+	if abiEvent.TableName == "" {
+		abiEvent.TableName = strings.ToLower(eventName)
+	}
+	if startingBlock := transformer["startingblock"]; startingBlock != "" {
+		var parseErr error
+		abiEvent.StartingBlock, parseErr = strconv.ParseInt(startingBlock, 10, 64)
+		if parseErr != nil {
+			return config.ABIEvent{}, fmt.Errorf("`startingBlock` can't be converted to an integer: %s", name)
+		}
+	}
+	if endingBlock := transformer["endingblock"]; endingBlock != "" {
+		var parseErr error
+		abiEvent.EndingBlock, parseErr = strconv.ParseInt(endingBlock, 10, 64)
+		if parseErr != nil {
+			return config.ABIEvent{}, fmt.Errorf("`endingBlock` can't be converted to an integer: %s", name)
+		}
+	}
+	return abiEvent, nil
+}
+
 func exportTransformers() ([]event.TransformerInitializer, []storage.TransformerInitializer, []transformer.ContractTransformerInitializer, error) {
 	// Build plugin generator config
 	configErr := prepConfig()
@@ -0,0 +1,119 @@
+-- +goose Up
+ALTER TABLE public.headers
+    ADD COLUMN parent_hash VARCHAR(66);
+
+CREATE INDEX headers_parent_hash
+    ON public.headers (parent_hash);
+
+DROP FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR, raw JSONB, block_timestamp NUMERIC, eth_node_id INTEGER);
+
+-- +goose StatementBegin
+CREATE FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR(66), raw JSONB,
+                                            block_timestamp NUMERIC, eth_node_id INTEGER,
+                                            parent_hash VARCHAR(66), reorg_window INTEGER) RETURNS INTEGER AS
+$$
+DECLARE
+    matching_header_id    INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash = get_or_create_header.hash
+    );
+    nonmatching_header_id INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash != get_or_create_header.hash
+    );
+    max_block_number      BIGINT  := (
+        SELECT MAX(headers.block_number)
+        FROM public.headers
+    );
+    inserted_header_id    INTEGER;
+BEGIN
+    IF matching_header_id != 0 THEN
+        UPDATE public.headers
+        SET parent_hash = get_or_create_header.parent_hash
+        WHERE id = matching_header_id
+          AND headers.parent_hash IS NULL;
+        RETURN matching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number <= max_block_number - reorg_window THEN
+        RETURN nonmatching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number > max_block_number - reorg_window THEN
+        DELETE FROM public.headers WHERE id = nonmatching_header_id;
+    END IF;
+
+    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id, parent_hash)
+    VALUES (get_or_create_header.hash, get_or_create_header.block_number, get_or_create_header.raw,
+            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id, get_or_create_header.parent_hash)
+    RETURNING id INTO inserted_header_id;
+
+    RETURN inserted_header_id;
+END
+$$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+COMMENT ON FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR, raw JSONB, block_timestamp NUMERIC, eth_node_id INTEGER, parent_hash VARCHAR, reorg_window INTEGER)
+    IS E'@omit';
+
+-- +goose Down
+DROP FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR, raw JSONB, block_timestamp NUMERIC, eth_node_id INTEGER, parent_hash VARCHAR, reorg_window INTEGER);
+
+-- +goose StatementBegin
+CREATE FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR(66), raw JSONB,
+                                            block_timestamp NUMERIC, eth_node_id INTEGER) RETURNS INTEGER AS
+$$
+DECLARE
+    matching_header_id    INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash = get_or_create_header.hash
+    );
+    nonmatching_header_id INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash != get_or_create_header.hash
+    );
+    max_block_number      BIGINT  := (
+        SELECT MAX(headers.block_number)
+        FROM public.headers
+    );
+    inserted_header_id    INTEGER;
+BEGIN
+    IF matching_header_id != 0 THEN
+        RETURN matching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number <= max_block_number - 15 THEN
+        RETURN nonmatching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number > max_block_number - 15 THEN
+        DELETE FROM public.headers WHERE id = nonmatching_header_id;
+    END IF;
+
+    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id)
+    VALUES (get_or_create_header.hash, get_or_create_header.block_number, get_or_create_header.raw,
+            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id)
+    RETURNING id INTO inserted_header_id;
+
+    RETURN inserted_header_id;
+END
+$$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+COMMENT ON FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR, raw JSONB, block_timestamp NUMERIC, eth_node_id INTEGER)
+    IS E'@omit';
+
+DROP INDEX public.headers_parent_hash;
+
+ALTER TABLE public.headers
+    DROP COLUMN parent_hash;
@@ -0,0 +1,127 @@
+-- +goose Up
+-- Headers deleted by get_or_create_header when a reorg replaces them. header_id is the id the deleted header had.
+CREATE TABLE public.orphaned_headers
+(
+    id           SERIAL PRIMARY KEY,
+    header_id    INTEGER     NOT NULL,
+    block_number BIGINT      NOT NULL,
+    hash         VARCHAR(66) NOT NULL,
+    handled      BOOLEAN     NOT NULL DEFAULT FALSE,
+    created      TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX orphaned_headers_header
+    ON public.orphaned_headers (header_id);
+CREATE INDEX orphaned_headers_handled
+    ON public.orphaned_headers (handled);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR(66), raw JSONB,
+                                                       block_timestamp NUMERIC, eth_node_id INTEGER,
+                                                       parent_hash VARCHAR(66), reorg_window INTEGER) RETURNS INTEGER AS
+$$
+DECLARE
+    matching_header_id    INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash = get_or_create_header.hash
+    );
+    nonmatching_header_id INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash != get_or_create_header.hash
+    );
+    max_block_number      BIGINT  := (
+        SELECT MAX(headers.block_number)
+        FROM public.headers
+    );
+    inserted_header_id    INTEGER;
+BEGIN
+    IF matching_header_id != 0 THEN
+        UPDATE public.headers
+        SET parent_hash = get_or_create_header.parent_hash
+        WHERE id = matching_header_id
+          AND headers.parent_hash IS NULL;
+        RETURN matching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number <= max_block_number - reorg_window THEN
+        RETURN nonmatching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number > max_block_number - reorg_window THEN
+        INSERT INTO public.orphaned_headers (header_id, block_number, hash)
+        SELECT id, headers.block_number, headers.hash
+        FROM public.headers
+        WHERE id = nonmatching_header_id;
+
+        -- cascades to everything derived from the orphaned header, including transformer output keyed by header_id
+        DELETE FROM public.headers WHERE id = nonmatching_header_id;
+    END IF;
+
+    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id, parent_hash)
+    VALUES (get_or_create_header.hash, get_or_create_header.block_number, get_or_create_header.raw,
+            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id, get_or_create_header.parent_hash)
+    RETURNING id INTO inserted_header_id;
+
+    RETURN inserted_header_id;
+END
+$$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.get_or_create_header(block_number BIGINT, hash VARCHAR(66), raw JSONB,
+                                                       block_timestamp NUMERIC, eth_node_id INTEGER,
+                                                       parent_hash VARCHAR(66), reorg_window INTEGER) RETURNS INTEGER AS
+$$
+DECLARE
+    matching_header_id    INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash = get_or_create_header.hash
+    );
+    nonmatching_header_id INTEGER := (
+        SELECT id
+        FROM public.headers
+        WHERE headers.block_number = get_or_create_header.block_number
+          AND headers.hash != get_or_create_header.hash
+    );
+    max_block_number      BIGINT  := (
+        SELECT MAX(headers.block_number)
+        FROM public.headers
+    );
+    inserted_header_id    INTEGER;
+BEGIN
+    IF matching_header_id != 0 THEN
+        UPDATE public.headers
+        SET parent_hash = get_or_create_header.parent_hash
+        WHERE id = matching_header_id
+          AND headers.parent_hash IS NULL;
+        RETURN matching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number <= max_block_number - reorg_window THEN
+        RETURN nonmatching_header_id;
+    END IF;
+
+    IF nonmatching_header_id != 0 AND block_number > max_block_number - reorg_window THEN
+        DELETE FROM public.headers WHERE id = nonmatching_header_id;
+    END IF;
+
+    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id, parent_hash)
+    VALUES (get_or_create_header.hash, get_or_create_header.block_number, get_or_create_header.raw,
+            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id, get_or_create_header.parent_hash)
+    RETURNING id INTO inserted_header_id;
+
+    RETURN inserted_header_id;
+END
+$$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TABLE public.orphaned_headers;
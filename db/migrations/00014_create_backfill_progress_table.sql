@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE public.backfill_progress
+(
+    id                SERIAL PRIMARY KEY,
+    watched_logs_hash VARCHAR(66) NOT NULL,
+    starting_block    BIGINT      NOT NULL,
+    ending_block      BIGINT      NOT NULL,
+    created           TIMESTAMP   NOT NULL DEFAULT NOW(),
+    CONSTRAINT backfill_progress_range_key UNIQUE (watched_logs_hash, starting_block, ending_block)
+);
+
+-- +goose Down
+DROP TABLE public.backfill_progress;
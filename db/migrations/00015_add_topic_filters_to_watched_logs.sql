@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE public.watched_logs
+    ADD COLUMN topic_one VARCHAR(66),
+    ADD COLUMN topic_two VARCHAR(66),
+    ADD COLUMN topic_three VARCHAR(66);
+
+-- +goose Down
+ALTER TABLE public.watched_logs
+    DROP COLUMN topic_one,
+    DROP COLUMN topic_two,
+    DROP COLUMN topic_three;
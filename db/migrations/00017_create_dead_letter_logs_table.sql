@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE public.dead_letter_logs
+(
+    id               SERIAL PRIMARY KEY,
+    event_log_id     BIGINT    NOT NULL REFERENCES public.event_logs (id) ON DELETE CASCADE,
+    transformer_name TEXT      NOT NULL,
+    error            TEXT      NOT NULL,
+    attempts         INTEGER   NOT NULL DEFAULT 1,
+    discarded        BOOLEAN   NOT NULL DEFAULT FALSE,
+    created          TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated          TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT dead_letter_logs_event_log_transformer_key UNIQUE (event_log_id, transformer_name)
+);
+
+CREATE INDEX dead_letter_logs_transformer
+    ON public.dead_letter_logs (transformer_name);
+
+-- +goose Down
+DROP TABLE public.dead_letter_logs;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Logs already marked transformed in event_logs.transformed are treated as transformed by every transformer,
+-- since it is unknown which transformers handled them
+CREATE TABLE public.transformed_event_logs
+(
+    event_log_id     BIGINT    NOT NULL REFERENCES public.event_logs (id) ON DELETE CASCADE,
+    transformer_name TEXT      NOT NULL,
+    created          TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_log_id, transformer_name)
+);
+
+-- +goose Down
+DROP TABLE public.transformed_event_logs;
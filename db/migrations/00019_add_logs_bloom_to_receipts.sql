@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE public.receipts
+    ADD COLUMN logs_bloom BYTEA;
+
+-- +goose Down
+ALTER TABLE public.receipts
+    DROP COLUMN logs_bloom;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE public.transactions
+    ADD COLUMN tx_type                  SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN chain_id                 NUMERIC,
+    ADD COLUMN max_fee_per_gas          NUMERIC,
+    ADD COLUMN max_priority_fee_per_gas NUMERIC,
+    ADD COLUMN access_list              JSONB,
+    ADD COLUMN effective_gas_price      NUMERIC;
+
+-- +goose Down
+ALTER TABLE public.transactions
+    DROP COLUMN tx_type,
+    DROP COLUMN chain_id,
+    DROP COLUMN max_fee_per_gas,
+    DROP COLUMN max_priority_fee_per_gas,
+    DROP COLUMN access_list,
+    DROP COLUMN effective_gas_price;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Preimages of mapping slots, keccak256(key . slot), for keys observed in event logs, transaction input data and
+-- known addresses. Used to recognize storage diffs for mapping entries whose keys haven't been loaded otherwise.
+CREATE TABLE public.storage_preimage
+(
+    id        BIGSERIAL PRIMARY KEY,
+    hash      BYTEA     NOT NULL UNIQUE,
+    key       BYTEA     NOT NULL,
+    slot      BYTEA     NOT NULL,
+    source    TEXT      NOT NULL,
+    source_id BIGINT    NOT NULL,
+    created   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX storage_preimage_source_slot_index
+    ON public.storage_preimage (source, slot, source_id);
+
+-- +goose Down
+DROP TABLE public.storage_preimage;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Reorgs found by headerSync, with the hashes of the orphaned branch and the canonical headers that replaced it
+CREATE TABLE public.reorgs
+(
+    id                           SERIAL PRIMARY KEY,
+    common_ancestor_block_number BIGINT        NOT NULL,
+    depth                        INTEGER       NOT NULL,
+    old_hashes                   VARCHAR(66)[] NOT NULL,
+    new_hashes                   VARCHAR(66)[] NOT NULL,
+    created                      TIMESTAMP     NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX reorgs_common_ancestor_block_number
+    ON public.reorgs (common_ancestor_block_number);
+
+-- +goose Down
+DROP TABLE public.reorgs;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE public.dead_letter_logs
+    ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- +goose Down
+ALTER TABLE public.dead_letter_logs
+    DROP COLUMN next_attempt_at;
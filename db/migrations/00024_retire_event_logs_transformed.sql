@@ -0,0 +1,15 @@
+-- +goose Up
+-- Transformations are recorded per transformer in transformed_event_logs, so event_logs.transformed is no longer
+-- written. It is kept so that logs transformed before then aren't delivered to any transformer again, but nearly
+-- every log is now untransformed by this flag, so its partial index no longer narrows lookups.
+DROP INDEX public.event_logs_untransformed;
+
+COMMENT ON COLUMN public.event_logs.transformed
+    IS 'Legacy: set for logs transformed before transformations were recorded in transformed_event_logs; no longer written';
+
+-- +goose Down
+COMMENT ON COLUMN public.event_logs.transformed IS NULL;
+
+CREATE INDEX event_logs_untransformed
+    ON public.event_logs (transformed)
+    WHERE transformed = false;
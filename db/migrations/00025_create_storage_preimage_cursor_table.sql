@@ -0,0 +1,20 @@
+-- +goose Up
+-- How far the event logs and transactions of each watched contract have been scanned for the preimages of its mapping
+-- entries, per mapping slot and as deep as its preimages were recorded
+CREATE TABLE public.storage_preimage_cursor
+(
+    address   VARCHAR(42) NOT NULL,
+    source    TEXT        NOT NULL,
+    slot      BYTEA       NOT NULL,
+    depth     INTEGER     NOT NULL,
+    source_id BIGINT      NOT NULL,
+    PRIMARY KEY (address, source, slot)
+);
+
+DROP INDEX public.storage_preimage_source_slot_index;
+
+-- +goose Down
+CREATE INDEX storage_preimage_source_slot_index
+    ON public.storage_preimage (source, slot, source_id);
+
+DROP TABLE public.storage_preimage_cursor;
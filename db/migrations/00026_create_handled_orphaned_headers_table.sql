@@ -0,0 +1,23 @@
+-- +goose Up
+-- Orphaned headers already marked handled in orphaned_headers.handled are treated as handled by every transformer,
+-- since it is unknown which transformers were running when they were cleaned up
+CREATE TABLE public.handled_orphaned_headers
+(
+    orphaned_header_id INTEGER   NOT NULL REFERENCES public.orphaned_headers (id) ON DELETE CASCADE,
+    transformer_name   TEXT      NOT NULL,
+    created            TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (orphaned_header_id, transformer_name)
+);
+
+DROP INDEX public.orphaned_headers_handled;
+
+COMMENT ON COLUMN public.orphaned_headers.handled
+    IS 'Legacy: set for headers cleaned up before cleanup was recorded in handled_orphaned_headers; no longer written';
+
+-- +goose Down
+COMMENT ON COLUMN public.orphaned_headers.handled IS NULL;
+
+CREATE INDEX orphaned_headers_handled
+    ON public.orphaned_headers (handled);
+
+DROP TABLE public.handled_orphaned_headers;
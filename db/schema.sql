@@ -78,10 +78,10 @@ COMMENT ON FUNCTION public.create_back_filled_diff(block_height bigint, block_ha
 
 
 --
--- Name: get_or_create_header(bigint, character varying, jsonb, numeric, integer); Type: FUNCTION; Schema: public; Owner: -
+-- Name: get_or_create_header(bigint, character varying, jsonb, numeric, integer, character varying, integer); Type: FUNCTION; Schema: public; Owner: -
 --
 
-CREATE FUNCTION public.get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer) RETURNS integer
+CREATE FUNCTION public.get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer, parent_hash character varying, reorg_window integer) RETURNS integer
     LANGUAGE plpgsql
     AS $$
 DECLARE
@@ -104,20 +104,30 @@ DECLARE
     inserted_header_id    INTEGER;
 BEGIN
     IF matching_header_id != 0 THEN
+        UPDATE public.headers
+        SET parent_hash = get_or_create_header.parent_hash
+        WHERE id = matching_header_id
+          AND headers.parent_hash IS NULL;
         RETURN matching_header_id;
     END IF;
 
-    IF nonmatching_header_id != 0 AND block_number <= max_block_number - 15 THEN
+    IF nonmatching_header_id != 0 AND block_number <= max_block_number - reorg_window THEN
         RETURN nonmatching_header_id;
     END IF;
 
-    IF nonmatching_header_id != 0 AND block_number > max_block_number - 15 THEN
+    IF nonmatching_header_id != 0 AND block_number > max_block_number - reorg_window THEN
+        INSERT INTO public.orphaned_headers (header_id, block_number, hash)
+        SELECT id, headers.block_number, headers.hash
+        FROM public.headers
+        WHERE id = nonmatching_header_id;
+
+        -- cascades to everything derived from the orphaned header, including transformer output keyed by header_id
         DELETE FROM public.headers WHERE id = nonmatching_header_id;
     END IF;
 
-    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id)
+    INSERT INTO public.headers (hash, block_number, raw, block_timestamp, eth_node_id, parent_hash)
     VALUES (get_or_create_header.hash, get_or_create_header.block_number, get_or_create_header.raw,
-            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id)
+            get_or_create_header.block_timestamp, get_or_create_header.eth_node_id, get_or_create_header.parent_hash)
     RETURNING id INTO inserted_header_id;
 
     RETURN inserted_header_id;
@@ -126,10 +136,10 @@ $$;
 
 
 --
--- Name: FUNCTION get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer); Type: COMMENT; Schema: public; Owner: -
+-- Name: FUNCTION get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer, parent_hash character varying, reorg_window integer); Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON FUNCTION public.get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer) IS '@omit';
+COMMENT ON FUNCTION public.get_or_create_header(block_number bigint, hash character varying, raw jsonb, block_timestamp numeric, eth_node_id integer, parent_hash character varying, reorg_window integer) IS '@omit';
 
 
 --
@@ -180,6 +190,39 @@ CREATE SEQUENCE public.addresses_id_seq
 ALTER SEQUENCE public.addresses_id_seq OWNED BY public.addresses.id;
 
 
+--
+-- Name: backfill_progress; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.backfill_progress (
+    id integer NOT NULL,
+    watched_logs_hash character varying(66) NOT NULL,
+    starting_block bigint NOT NULL,
+    ending_block bigint NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: backfill_progress_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.backfill_progress_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: backfill_progress_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.backfill_progress_id_seq OWNED BY public.backfill_progress.id;
+
+
 --
 -- Name: checked_headers; Type: TABLE; Schema: public; Owner: -
 --
@@ -210,6 +253,43 @@ CREATE SEQUENCE public.checked_headers_id_seq
 ALTER SEQUENCE public.checked_headers_id_seq OWNED BY public.checked_headers.id;
 
 
+--
+-- Name: dead_letter_logs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.dead_letter_logs (
+    id integer NOT NULL,
+    event_log_id bigint NOT NULL,
+    transformer_name text NOT NULL,
+    error text NOT NULL,
+    attempts integer DEFAULT 1 NOT NULL,
+    discarded boolean DEFAULT false NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL,
+    updated timestamp without time zone DEFAULT now() NOT NULL,
+    next_attempt_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: dead_letter_logs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.dead_letter_logs_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: dead_letter_logs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.dead_letter_logs_id_seq OWNED BY public.dead_letter_logs.id;
+
+
 --
 -- Name: eth_nodes; Type: TABLE; Schema: public; Owner: -
 --
@@ -263,6 +343,13 @@ CREATE TABLE public.event_logs (
 );
 
 
+--
+-- Name: COLUMN event_logs.transformed; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.event_logs.transformed IS 'Legacy: set for logs transformed before transformations were recorded in transformed_event_logs; no longer written';
+
+
 --
 -- Name: event_logs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -314,6 +401,17 @@ CREATE SEQUENCE public.goose_db_version_id_seq
 ALTER SEQUENCE public.goose_db_version_id_seq OWNED BY public.goose_db_version.id;
 
 
+--
+-- Name: handled_orphaned_headers; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.handled_orphaned_headers (
+    orphaned_header_id integer NOT NULL,
+    transformer_name text NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
 --
 -- Name: headers; Type: TABLE; Schema: public; Owner: -
 --
@@ -327,7 +425,8 @@ CREATE TABLE public.headers (
     check_count integer DEFAULT 0 NOT NULL,
     eth_node_id integer NOT NULL,
     created timestamp without time zone DEFAULT now() NOT NULL,
-    updated timestamp without time zone DEFAULT now() NOT NULL
+    updated timestamp without time zone DEFAULT now() NOT NULL,
+    parent_hash character varying(66)
 );
 
 
@@ -351,6 +450,47 @@ CREATE SEQUENCE public.headers_id_seq
 ALTER SEQUENCE public.headers_id_seq OWNED BY public.headers.id;
 
 
+--
+-- Name: orphaned_headers; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.orphaned_headers (
+    id integer NOT NULL,
+    header_id integer NOT NULL,
+    block_number bigint NOT NULL,
+    hash character varying(66) NOT NULL,
+    handled boolean DEFAULT false NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN orphaned_headers.handled; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.orphaned_headers.handled IS 'Legacy: set for headers cleaned up before cleanup was recorded in handled_orphaned_headers; no longer written';
+
+
+--
+-- Name: orphaned_headers_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.orphaned_headers_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: orphaned_headers_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.orphaned_headers_id_seq OWNED BY public.orphaned_headers.id;
+
+
 --
 -- Name: receipts; Type: TABLE; Schema: public; Owner: -
 --
@@ -365,7 +505,8 @@ CREATE TABLE public.receipts (
     state_root character varying(66),
     status integer,
     tx_hash character varying(66),
-    rlp bytea
+    rlp bytea,
+    logs_bloom bytea
 );
 
 
@@ -389,6 +530,40 @@ CREATE SEQUENCE public.receipts_id_seq
 ALTER SEQUENCE public.receipts_id_seq OWNED BY public.receipts.id;
 
 
+--
+-- Name: reorgs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.reorgs (
+    id integer NOT NULL,
+    common_ancestor_block_number bigint NOT NULL,
+    depth integer NOT NULL,
+    old_hashes character varying(66)[] NOT NULL,
+    new_hashes character varying(66)[] NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: reorgs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.reorgs_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: reorgs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.reorgs_id_seq OWNED BY public.reorgs.id;
+
+
 --
 -- Name: storage_diff; Type: TABLE; Schema: public; Owner: -
 --
@@ -425,6 +600,53 @@ CREATE SEQUENCE public.storage_diff_id_seq
 ALTER SEQUENCE public.storage_diff_id_seq OWNED BY public.storage_diff.id;
 
 
+--
+-- Name: storage_preimage; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.storage_preimage (
+    id bigint NOT NULL,
+    hash bytea NOT NULL,
+    key bytea NOT NULL,
+    slot bytea NOT NULL,
+    source text NOT NULL,
+    source_id bigint NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: storage_preimage_cursor; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.storage_preimage_cursor (
+    address character varying(42) NOT NULL,
+    source text NOT NULL,
+    slot bytea NOT NULL,
+    depth integer NOT NULL,
+    source_id bigint NOT NULL
+);
+
+
+--
+-- Name: storage_preimage_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.storage_preimage_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: storage_preimage_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.storage_preimage_id_seq OWNED BY public.storage_preimage.id;
+
+
 --
 -- Name: transactions; Type: TABLE; Schema: public; Owner: -
 --
@@ -441,7 +663,13 @@ CREATE TABLE public.transactions (
     tx_from character varying(44),
     tx_index integer,
     tx_to character varying(44),
-    value numeric
+    value numeric,
+    tx_type smallint DEFAULT 0 NOT NULL,
+    chain_id numeric,
+    max_fee_per_gas numeric,
+    max_priority_fee_per_gas numeric,
+    access_list jsonb,
+    effective_gas_price numeric
 );
 
 
@@ -465,6 +693,17 @@ CREATE SEQUENCE public.transactions_id_seq
 ALTER SEQUENCE public.transactions_id_seq OWNED BY public.transactions.id;
 
 
+--
+-- Name: transformed_event_logs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.transformed_event_logs (
+    event_log_id bigint NOT NULL,
+    transformer_name text NOT NULL,
+    created timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
 --
 -- Name: watched_logs; Type: TABLE; Schema: public; Owner: -
 --
@@ -472,7 +711,12 @@ ALTER SEQUENCE public.transactions_id_seq OWNED BY public.transactions.id;
 CREATE TABLE public.watched_logs (
     id integer NOT NULL,
     contract_address character varying(42),
-    topic_zero character varying(66)
+    topic_zero character varying(66),
+    topic_one character varying(66),
+    topic_two character varying(66),
+    topic_three character varying(66),
+    next_backfill_block bigint,
+    backfill_ending_block bigint
 );
 
 
@@ -503,6 +747,13 @@ ALTER SEQUENCE public.watched_logs_id_seq OWNED BY public.watched_logs.id;
 ALTER TABLE ONLY public.addresses ALTER COLUMN id SET DEFAULT nextval('public.addresses_id_seq'::regclass);
 
 
+--
+-- Name: backfill_progress id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.backfill_progress ALTER COLUMN id SET DEFAULT nextval('public.backfill_progress_id_seq'::regclass);
+
+
 --
 -- Name: checked_headers id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -510,6 +761,13 @@ ALTER TABLE ONLY public.addresses ALTER COLUMN id SET DEFAULT nextval('public.ad
 ALTER TABLE ONLY public.checked_headers ALTER COLUMN id SET DEFAULT nextval('public.checked_headers_id_seq'::regclass);
 
 
+--
+-- Name: dead_letter_logs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letter_logs ALTER COLUMN id SET DEFAULT nextval('public.dead_letter_logs_id_seq'::regclass);
+
+
 --
 -- Name: eth_nodes id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -538,6 +796,13 @@ ALTER TABLE ONLY public.goose_db_version ALTER COLUMN id SET DEFAULT nextval('pu
 ALTER TABLE ONLY public.headers ALTER COLUMN id SET DEFAULT nextval('public.headers_id_seq'::regclass);
 
 
+--
+-- Name: orphaned_headers id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orphaned_headers ALTER COLUMN id SET DEFAULT nextval('public.orphaned_headers_id_seq'::regclass);
+
+
 --
 -- Name: receipts id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -545,6 +810,13 @@ ALTER TABLE ONLY public.headers ALTER COLUMN id SET DEFAULT nextval('public.head
 ALTER TABLE ONLY public.receipts ALTER COLUMN id SET DEFAULT nextval('public.receipts_id_seq'::regclass);
 
 
+--
+-- Name: reorgs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.reorgs ALTER COLUMN id SET DEFAULT nextval('public.reorgs_id_seq'::regclass);
+
+
 --
 -- Name: storage_diff id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -552,6 +824,13 @@ ALTER TABLE ONLY public.receipts ALTER COLUMN id SET DEFAULT nextval('public.rec
 ALTER TABLE ONLY public.storage_diff ALTER COLUMN id SET DEFAULT nextval('public.storage_diff_id_seq'::regclass);
 
 
+--
+-- Name: storage_preimage id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.storage_preimage ALTER COLUMN id SET DEFAULT nextval('public.storage_preimage_id_seq'::regclass);
+
+
 --
 -- Name: transactions id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -582,6 +861,22 @@ ALTER TABLE ONLY public.addresses
     ADD CONSTRAINT addresses_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: backfill_progress backfill_progress_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.backfill_progress
+    ADD CONSTRAINT backfill_progress_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: backfill_progress backfill_progress_range_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.backfill_progress
+    ADD CONSTRAINT backfill_progress_range_key UNIQUE (watched_logs_hash, starting_block, ending_block);
+
+
 --
 -- Name: checked_headers checked_headers_header_id_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -598,6 +893,22 @@ ALTER TABLE ONLY public.checked_headers
     ADD CONSTRAINT checked_headers_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: dead_letter_logs dead_letter_logs_event_log_transformer_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letter_logs
+    ADD CONSTRAINT dead_letter_logs_event_log_transformer_key UNIQUE (event_log_id, transformer_name);
+
+
+--
+-- Name: dead_letter_logs dead_letter_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letter_logs
+    ADD CONSTRAINT dead_letter_logs_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: eth_nodes eth_nodes_genesis_block_network_id_eth_node_id_client_name_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -638,6 +949,14 @@ ALTER TABLE ONLY public.goose_db_version
     ADD CONSTRAINT goose_db_version_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: handled_orphaned_headers handled_orphaned_headers_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.handled_orphaned_headers
+    ADD CONSTRAINT handled_orphaned_headers_pkey PRIMARY KEY (orphaned_header_id, transformer_name);
+
+
 --
 -- Name: headers headers_block_number_eth_node_id_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -654,6 +973,14 @@ ALTER TABLE ONLY public.headers
     ADD CONSTRAINT headers_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: orphaned_headers orphaned_headers_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orphaned_headers
+    ADD CONSTRAINT orphaned_headers_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: receipts receipts_header_id_transaction_id_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -670,6 +997,14 @@ ALTER TABLE ONLY public.receipts
     ADD CONSTRAINT receipts_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: reorgs reorgs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.reorgs
+    ADD CONSTRAINT reorgs_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: storage_diff storage_diff_block_height_block_hash_hashed_address_storage_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -686,6 +1021,30 @@ ALTER TABLE ONLY public.storage_diff
     ADD CONSTRAINT storage_diff_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: storage_preimage_cursor storage_preimage_cursor_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.storage_preimage_cursor
+    ADD CONSTRAINT storage_preimage_cursor_pkey PRIMARY KEY (address, source, slot);
+
+
+--
+-- Name: storage_preimage storage_preimage_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.storage_preimage
+    ADD CONSTRAINT storage_preimage_hash_key UNIQUE (hash);
+
+
+--
+-- Name: storage_preimage storage_preimage_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.storage_preimage
+    ADD CONSTRAINT storage_preimage_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: transactions transactions_hash_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -702,6 +1061,14 @@ ALTER TABLE ONLY public.transactions
     ADD CONSTRAINT transactions_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: transformed_event_logs transformed_event_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.transformed_event_logs
+    ADD CONSTRAINT transformed_event_logs_pkey PRIMARY KEY (event_log_id, transformer_name);
+
+
 --
 -- Name: watched_logs watched_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -711,24 +1078,24 @@ ALTER TABLE ONLY public.watched_logs
 
 
 --
--- Name: event_logs_address; Type: INDEX; Schema: public; Owner: -
+-- Name: dead_letter_logs_transformer; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX event_logs_address ON public.event_logs USING btree (address);
+CREATE INDEX dead_letter_logs_transformer ON public.dead_letter_logs USING btree (transformer_name);
 
 
 --
--- Name: event_logs_transaction; Type: INDEX; Schema: public; Owner: -
+-- Name: event_logs_address; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX event_logs_transaction ON public.event_logs USING btree (tx_hash);
+CREATE INDEX event_logs_address ON public.event_logs USING btree (address);
 
 
 --
--- Name: event_logs_untransformed; Type: INDEX; Schema: public; Owner: -
+-- Name: event_logs_transaction; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX event_logs_untransformed ON public.event_logs USING btree (transformed) WHERE (transformed = false);
+CREATE INDEX event_logs_transaction ON public.event_logs USING btree (tx_hash);
 
 
 --
@@ -759,6 +1126,20 @@ CREATE INDEX headers_check_count ON public.headers USING btree (check_count);
 CREATE INDEX headers_eth_node ON public.headers USING btree (eth_node_id);
 
 
+--
+-- Name: headers_parent_hash; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX headers_parent_hash ON public.headers USING btree (parent_hash);
+
+
+--
+-- Name: orphaned_headers_header; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX orphaned_headers_header ON public.orphaned_headers USING btree (header_id);
+
+
 --
 -- Name: receipts_contract_address; Type: INDEX; Schema: public; Owner: -
 --
@@ -773,6 +1154,13 @@ CREATE INDEX receipts_contract_address ON public.receipts USING btree (contract_
 CREATE INDEX receipts_transaction ON public.receipts USING btree (transaction_id);
 
 
+--
+-- Name: reorgs_common_ancestor_block_number; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX reorgs_common_ancestor_block_number ON public.reorgs USING btree (common_ancestor_block_number);
+
+
 --
 -- Name: storage_diff_eth_node; Type: INDEX; Schema: public; Owner: -
 --
@@ -816,6 +1204,14 @@ ALTER TABLE ONLY public.checked_headers
     ADD CONSTRAINT checked_headers_header_id_fkey FOREIGN KEY (header_id) REFERENCES public.headers(id) ON DELETE CASCADE;
 
 
+--
+-- Name: dead_letter_logs dead_letter_logs_event_log_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letter_logs
+    ADD CONSTRAINT dead_letter_logs_event_log_id_fkey FOREIGN KEY (event_log_id) REFERENCES public.event_logs(id) ON DELETE CASCADE;
+
+
 --
 -- Name: event_logs event_logs_address_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -840,6 +1236,14 @@ ALTER TABLE ONLY public.event_logs
     ADD CONSTRAINT event_logs_tx_hash_fkey FOREIGN KEY (tx_hash) REFERENCES public.transactions(hash) ON DELETE CASCADE;
 
 
+--
+-- Name: handled_orphaned_headers handled_orphaned_headers_orphaned_header_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.handled_orphaned_headers
+    ADD CONSTRAINT handled_orphaned_headers_orphaned_header_id_fkey FOREIGN KEY (orphaned_header_id) REFERENCES public.orphaned_headers(id) ON DELETE CASCADE;
+
+
 --
 -- Name: headers headers_eth_node_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -888,6 +1292,14 @@ ALTER TABLE ONLY public.transactions
     ADD CONSTRAINT transactions_header_id_fkey FOREIGN KEY (header_id) REFERENCES public.headers(id) ON DELETE CASCADE;
 
 
+--
+-- Name: transformed_event_logs transformed_event_logs_event_log_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.transformed_event_logs
+    ADD CONSTRAINT transformed_event_logs_event_log_id_fkey FOREIGN KEY (event_log_id) REFERENCES public.event_logs(id) ON DELETE CASCADE;
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -46,7 +46,7 @@ var _ = Describe("contractWatcher transformer", func() {
 
 	BeforeEach(func() {
 		db, blockChain = test_helpers.SetupDBandBC()
-		headerRepository = repositories.NewHeaderRepository(db)
+		headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 	})
 
 	AfterEach(func() {
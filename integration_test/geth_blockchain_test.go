@@ -19,7 +19,6 @@ package integration_test
 import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/eth"
 	"github.com/makerdao/vulcanizedb/pkg/eth/client"
@@ -34,9 +33,9 @@ var _ = Describe("Reading from the Geth blockchain", func() {
 	var blockChain *eth.BlockChain
 
 	BeforeEach(func() {
-		rawRpcClient, err := rpc.Dial(test_config.TestClient.IPCPath)
+		rawRpcClient, err := client.Dial(test_config.TestClient)
 		Expect(err).NotTo(HaveOccurred())
-		rpcClient := client.NewRpcClient(rawRpcClient, test_config.TestClient.IPCPath)
+		rpcClient := client.NewRpcClient(rawRpcClient, test_config.TestClient)
 		ethClient := ethclient.NewClient(rawRpcClient)
 		blockChainClient := client.NewEthClient(ethClient)
 		node := node.MakeNode(rpcClient)
@@ -76,17 +75,20 @@ var _ = Describe("Reading from the Geth blockchain", func() {
 			101, 160, 36, 226, 116, 43, 147, 236, 124, 76, 227, 250, 228, 168, 22, 19, 248, 155, 248, 151, 219, 14, 1, 186,
 			159, 35, 154, 22, 222, 123, 254, 147, 63, 221}
 		expectedModel := core.TransactionModel{
-			Data:     expectedData,
-			From:     "0x3b08b99441086edd66f36f9f9aee733280698378",
-			GasLimit: 91741,
-			GasPrice: 1000000000,
-			Hash:     "0x44d462f2a19ad267e276b234a62c542fc91c974d2e4754a325ca405f95440255",
-			Nonce:    9,
-			Raw:      expectedRaw,
-			Receipt:  core.Receipt{},
-			To:       "0x2c4bd064b998838076fa341a83d007fc2fa50957",
-			TxIndex:  30,
-			Value:    "0",
+			ChainID:           "1",
+			Data:              expectedData,
+			EffectiveGasPrice: "1000000000",
+			From:              "0x3b08b99441086edd66f36f9f9aee733280698378",
+			GasLimit:          91741,
+			GasPrice:          "1000000000",
+			Hash:              "0x44d462f2a19ad267e276b234a62c542fc91c974d2e4754a325ca405f95440255",
+			Nonce:             9,
+			Raw:               expectedRaw,
+			Receipt:           core.Receipt{},
+			To:                "0x2c4bd064b998838076fa341a83d007fc2fa50957",
+			TxIndex:           30,
+			Type:              core.LegacyTxType,
+			Value:             "0",
 		}
 		Expect(transactions[0]).To(Equal(expectedModel))
 	})
@@ -30,16 +30,18 @@ type Chunker interface {
 }
 
 type LogChunker struct {
-	AddressToNames map[string][]string
-	NameToTopic0   map[string]common.Hash
+	AddressToNames     map[string][]string
+	NameToTopic0       map[string]common.Hash
+	NameToTopicFilters map[string][][]common.Hash
 }
 
 // Returns a new log chunker with initialised maps.
 // Needs to have configs added with `AddConfigs` to consider logs for the respective transformer.
 func NewLogChunker() *LogChunker {
 	return &LogChunker{
-		AddressToNames: map[string][]string{},
-		NameToTopic0:   map[string]common.Hash{},
+		AddressToNames:     map[string][]string{},
+		NameToTopic0:       map[string]common.Hash{},
+		NameToTopicFilters: map[string][][]common.Hash{},
 	}
 }
 
@@ -49,10 +51,11 @@ func (chunker *LogChunker) AddConfig(transformerConfig event.TransformerConfig)
 		var lowerCaseAddress = strings.ToLower(address)
 		chunker.AddressToNames[lowerCaseAddress] = append(chunker.AddressToNames[lowerCaseAddress], transformerConfig.TransformerName)
 		chunker.NameToTopic0[transformerConfig.TransformerName] = common.HexToHash(transformerConfig.Topic)
+		chunker.NameToTopicFilters[transformerConfig.TransformerName] = transformerConfig.TopicFilters()
 	}
 }
 
-// Goes through a slice of logs, associating relevant logs (matching addresses and topic) with transformers
+// Goes through a slice of logs, associating relevant logs (matching addresses and topics) with transformers
 func (chunker *LogChunker) ChunkLogs(logs []core.EventLog) map[string][]core.EventLog {
 	chunks := map[string][]core.EventLog{}
 	for _, log := range logs {
@@ -60,7 +63,7 @@ func (chunker *LogChunker) ChunkLogs(logs []core.EventLog) map[string][]core.Eve
 		relevantTransformers := chunker.AddressToNames[strings.ToLower(log.Log.Address.Hex())]
 
 		for _, t := range relevantTransformers {
-			if chunker.NameToTopic0[t] == log.Log.Topics[0] {
+			if chunker.NameToTopic0[t] == log.Log.Topics[0] && event.TopicsMatch(chunker.NameToTopicFilters[t], log.Log.Topics) {
 				chunks[t] = append(chunks[t], log)
 			}
 		}
@@ -106,6 +106,32 @@ var _ = Describe("Log chunker", func() {
 			Expect(chunks["TransformerB"]).To(BeEmpty())
 			Expect(chunks["TransformerC"]).To(ContainElement(log5))
 		})
+
+		It("only associates logs matching a transformer's topic filters", func() {
+			configD := event.TransformerConfig{
+				TransformerName:   "TransformerD",
+				ContractAddresses: []string{"0x00000000000000000000000000000000000000D1"},
+				Topic:             "0xD",
+				Topic2:            "0xD2",
+			}
+			chunker.AddConfig(configD)
+			matchingLog := core.EventLog{Log: types.Log{
+				Address: common.HexToAddress("0xD1"),
+				Topics:  []common.Hash{common.HexToHash("0xD"), common.HexToHash("0x1"), common.HexToHash("0xD2")},
+			}}
+			otherTopic2Log := core.EventLog{Log: types.Log{
+				Address: common.HexToAddress("0xD1"),
+				Topics:  []common.Hash{common.HexToHash("0xD"), common.HexToHash("0x1"), common.HexToHash("0x2")},
+			}}
+			missingTopic2Log := core.EventLog{Log: types.Log{
+				Address: common.HexToAddress("0xD1"),
+				Topics:  []common.Hash{common.HexToHash("0xD")},
+			}}
+
+			chunks := chunker.ChunkLogs([]core.EventLog{matchingLog, otherTopic2Log, missingTopic2Log})
+
+			Expect(chunks["TransformerD"]).To(Equal([]core.EventLog{matchingLog}))
+		})
 	})
 })
 
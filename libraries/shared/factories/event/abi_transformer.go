@@ -0,0 +1,284 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/contract_watcher/types"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+	"github.com/makerdao/vulcanizedb/pkg/eth"
+)
+
+// ABITransformer is a Transformer for any event of a contract ABI. It decodes the indexed and non-indexed arguments of
+// each log into a row of a table with a column per argument, as created by CreateTableMigration.
+type ABITransformer struct {
+	SchemaName SchemaName
+	TableName  TableName
+	Event      types.Event
+	abiEvent   abi.Event
+	columns    []ColumnName
+}
+
+// NewABITransformer builds a transformer for the named event of the contract ABI, inserting into the given table
+func NewABITransformer(contractAbi, eventName string, schemaName SchemaName, tableName TableName) (ABITransformer, error) {
+	parsedAbi, abiErr := eth.ParseAbi(contractAbi)
+	if abiErr != nil {
+		return ABITransformer{}, abiErr
+	}
+	abiEvent, found := parsedAbi.Events[eventName]
+	if !found {
+		return ABITransformer{}, fmt.Errorf("event %s not found in contract abi", eventName)
+	}
+	event := types.NewEvent(abiEvent)
+	columns := make([]ColumnName, len(event.Fields))
+	for i := range event.Fields {
+		field := &event.Fields[i]
+		if field.Indexed && isDynamicType(field.Type) {
+			// Only the hash of an indexed dynamic value is available from the log's topics
+			field.PgType = "CHARACTER VARYING(66)"
+		}
+		if field.Type.T == abi.FixedPointTy {
+			return ABITransformer{}, fmt.Errorf("argument %s of event %s has unsupported fixed point type", field.Name, eventName)
+		}
+		// Add an underscore, like contract watcher tables, to avoid collisions with reserved pg words
+		columns[i] = ColumnName(strings.ToLower(field.Name) + "_")
+	}
+	return ABITransformer{
+		SchemaName: schemaName,
+		TableName:  tableName,
+		Event:      event,
+		abiEvent:   abiEvent,
+		columns:    columns,
+	}, nil
+}
+
+// NewABITransformerInitializer builds an initializer for the configured event transformer, so that an eth_event
+// transformer only needs a config, event name and table rather than its own Transformer. The config's topic0 defaults
+// to the event's signature.
+func NewABITransformerInitializer(config TransformerConfig, eventName string, schemaName SchemaName, tableName TableName) (TransformerInitializer, error) {
+	transformer, err := NewABITransformer(config.ContractAbi, eventName, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if config.Topic == "" {
+		config.Topic = transformer.abiEvent.ID.Hex()
+	}
+	return ConfiguredTransformer{Config: config, Transformer: transformer}.NewTransformer, nil
+}
+
+// MustNewABITransformerInitializer is like NewABITransformerInitializer, but panics if the transformer can't be built
+// so that a plugin exporting a misconfigured transformer fails when it is loaded
+func MustNewABITransformerInitializer(config TransformerConfig, eventName string, schemaName SchemaName, tableName TableName) TransformerInitializer {
+	initializer, err := NewABITransformerInitializer(config, eventName, schemaName, tableName)
+	if err != nil {
+		panic(fmt.Sprintf("error building %s transformer: %s", config.TransformerName, err.Error()))
+	}
+	return initializer
+}
+
+// Columns returns the table's columns for the event's arguments, in the order of the arguments
+func (transformer ABITransformer) Columns() []ColumnName {
+	return transformer.columns
+}
+
+// ToModels decodes the logs with the transformer's event; the contract abi passed in is not used again
+func (transformer ABITransformer) ToModels(_ string, logs []core.EventLog, _ *postgres.DB) ([]InsertionModel, error) {
+	orderedColumns := append([]ColumnName{HeaderFK, LogFK}, transformer.columns...)
+	models := make([]InsertionModel, 0, len(logs))
+	for _, log := range logs {
+		values, decodeErr := transformer.decode(log)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("error decoding %s log %d: %w", transformer.Event.Name, log.ID, decodeErr)
+		}
+		columnValues := ColumnValues{
+			HeaderFK: log.HeaderID,
+			LogFK:    log.ID,
+		}
+		for i, field := range transformer.Event.Fields {
+			value, valueErr := columnValue(field, values[field.Name])
+			if valueErr != nil {
+				return nil, fmt.Errorf("error converting argument %s of %s log %d: %w", field.Name, transformer.Event.Name, log.ID, valueErr)
+			}
+			columnValues[transformer.columns[i]] = value
+		}
+		models = append(models, InsertionModel{
+			SchemaName:     transformer.SchemaName,
+			TableName:      transformer.TableName,
+			OrderedColumns: orderedColumns,
+			ColumnValues:   columnValues,
+		})
+	}
+	return models, nil
+}
+
+// CreateTableMigration returns a goose migration creating the table the transformer inserts into, with a column per
+// event argument and the header_id and log_id columns identifying each row
+func (transformer ABITransformer) CreateTableMigration() string {
+	table := fmt.Sprintf("%s.%s", transformer.SchemaName, transformer.TableName)
+	columnDefinitions := []string{
+		"id        SERIAL PRIMARY KEY",
+		"header_id INTEGER NOT NULL REFERENCES public.headers (id) ON DELETE CASCADE",
+		"log_id    BIGINT  NOT NULL REFERENCES public.event_logs (id) ON DELETE CASCADE",
+	}
+	for i, field := range transformer.Event.Fields {
+		columnDefinitions = append(columnDefinitions, fmt.Sprintf("%s %s", transformer.columns[i], field.PgType))
+	}
+	columnDefinitions = append(columnDefinitions, "UNIQUE (header_id, log_id)")
+
+	var migration strings.Builder
+	migration.WriteString("-- +goose Up\n")
+	if transformer.SchemaName != "public" {
+		fmt.Fprintf(&migration, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", transformer.SchemaName)
+	}
+	fmt.Fprintf(&migration, "CREATE TABLE %s\n(\n    %s\n);\n\n", table, strings.Join(columnDefinitions, ",\n    "))
+	fmt.Fprintf(&migration, "CREATE INDEX %s_header_index ON %s (header_id);\n", transformer.TableName, table)
+	fmt.Fprintf(&migration, "CREATE INDEX %s_log_index ON %s (log_id);\n\n", transformer.TableName, table)
+	fmt.Fprintf(&migration, "-- +goose Down\nDROP TABLE %s;\n", table)
+	return migration.String()
+}
+
+func (transformer ABITransformer) decode(log core.EventLog) (map[string]interface{}, error) {
+	topics := log.Log.Topics
+	if !transformer.abiEvent.Anonymous {
+		if len(topics) == 0 || topics[0] != transformer.abiEvent.ID {
+			return nil, errors.New("log topic0 does not match the event signature")
+		}
+		topics = topics[1:]
+	}
+
+	values := make(map[string]interface{})
+	unpackErr := transformer.abiEvent.Inputs.NonIndexed().UnpackIntoMap(values, log.Log.Data)
+	if unpackErr != nil {
+		return nil, unpackErr
+	}
+	var indexed abi.Arguments
+	for _, input := range transformer.abiEvent.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	parseErr := abi.ParseTopicsIntoMap(values, indexed, topics)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return values, nil
+}
+
+// columnValue converts a decoded argument to a value for the column type that types.NewEvent gives the argument
+func columnValue(field types.Field, value interface{}) (interface{}, error) {
+	if field.Indexed && isDynamicType(field.Type) {
+		return value, nil
+	}
+	switch field.Type.T {
+	case abi.IntTy, abi.UintTy:
+		return toBigInt(value)
+	case abi.BytesTy, abi.FixedBytesTy:
+		return toBytes(value)
+	case abi.ArrayTy:
+		return pq.StringArray(elementTexts(value)), nil
+	case abi.SliceTy:
+		jsonValue, err := json.Marshal(elementTexts(value))
+		return string(jsonValue), err
+	case abi.TupleTy:
+		jsonValue, err := json.Marshal(value)
+		return string(jsonValue), err
+	case abi.FunctionTy:
+		return textValue(value), nil
+	default:
+		return value, nil
+	}
+}
+
+func isDynamicType(argumentType abi.Type) bool {
+	switch argumentType.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	default:
+		return false
+	}
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	if number, ok := value.(*big.Int); ok {
+		return number, nil
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(reflected.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(reflected.Uint()), nil
+	default:
+		return nil, fmt.Errorf("unexpected integer value of type %T", value)
+	}
+}
+
+func toBytes(value interface{}) ([]byte, error) {
+	if bytes, ok := value.([]byte); ok {
+		return bytes, nil
+	}
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Array || reflected.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, fmt.Errorf("unexpected bytes value of type %T", value)
+	}
+	bytes := make([]byte, reflected.Len())
+	reflect.Copy(reflect.ValueOf(bytes), reflected)
+	return bytes, nil
+}
+
+// elementTexts renders each element of an array or slice argument as text
+func elementTexts(value interface{}) []string {
+	reflected := reflect.ValueOf(value)
+	texts := make([]string, reflected.Len())
+	for i := range texts {
+		texts[i] = textValue(reflected.Index(i).Interface())
+	}
+	return texts
+}
+
+func textValue(value interface{}) string {
+	switch typedValue := value.(type) {
+	case *big.Int:
+		return typedValue.String()
+	case common.Address:
+		return typedValue.Hex()
+	case common.Hash:
+		return typedValue.Hex()
+	case []byte:
+		return hexutil.Encode(typedValue)
+	case bool:
+		return strconv.FormatBool(typedValue)
+	case string:
+		return typedValue
+	}
+	if bytes, err := toBytes(value); err == nil {
+		return hexutil.Encode(bytes)
+	}
+	return fmt.Sprint(value)
+}
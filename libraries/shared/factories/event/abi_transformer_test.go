@@ -0,0 +1,171 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package event_test
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
+	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const exampleEventAbi = `[{"anonymous": false, "name": "Example", "type": "event", "inputs": [
+	{"indexed": true, "name": "sender", "type": "address"},
+	{"indexed": true, "name": "id", "type": "bytes32"},
+	{"indexed": true, "name": "note", "type": "string"},
+	{"indexed": false, "name": "small", "type": "uint8"},
+	{"indexed": false, "name": "amount", "type": "uint256"},
+	{"indexed": false, "name": "flag", "type": "bool"},
+	{"indexed": false, "name": "data", "type": "bytes"},
+	{"indexed": false, "name": "pair", "type": "uint256[2]"},
+	{"indexed": false, "name": "text", "type": "string"}
+]}]`
+
+var _ = Describe("ABITransformer", func() {
+	var (
+		transformer event.ABITransformer
+		sender      = test_data.FakeAddress()
+		id          = common.HexToHash("0x1234")
+		eventLog    core.EventLog
+	)
+
+	BeforeEach(func() {
+		var err error
+		transformer, err = event.NewABITransformer(exampleEventAbi, "Example", "public", "example_event")
+		Expect(err).NotTo(HaveOccurred())
+
+		parsedAbi, abiErr := abi.JSON(strings.NewReader(exampleEventAbi))
+		Expect(abiErr).NotTo(HaveOccurred())
+		data, packErr := parsedAbi.Events["Example"].Inputs.NonIndexed().Pack(uint8(7), big.NewInt(123), true,
+			[]byte{1, 2, 3}, [2]*big.Int{big.NewInt(1), big.NewInt(2)}, "hello")
+		Expect(packErr).NotTo(HaveOccurred())
+		eventLog = core.EventLog{
+			ID:       5,
+			HeaderID: 6,
+			Log: types.Log{
+				Topics: []common.Hash{
+					parsedAbi.Events["Example"].ID,
+					common.BytesToHash(sender.Bytes()),
+					id,
+					crypto.Keccak256Hash([]byte("note")),
+				},
+				Data: data,
+			},
+		}
+	})
+
+	It("returns an error if the event isn't in the abi", func() {
+		_, err := event.NewABITransformer(exampleEventAbi, "Missing", "public", "missing_event")
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("names a column per argument", func() {
+		Expect(transformer.Columns()).To(Equal([]event.ColumnName{
+			"sender_", "id_", "note_", "small_", "amount_", "flag_", "data_", "pair_", "text_",
+		}))
+	})
+
+	It("converts indexed and non-indexed arguments to column values", func() {
+		models, err := transformer.ToModels("", []core.EventLog{eventLog}, nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models).To(HaveLen(1))
+		Expect(models[0].SchemaName).To(Equal(event.SchemaName("public")))
+		Expect(models[0].TableName).To(Equal(event.TableName("example_event")))
+		Expect(models[0].OrderedColumns).To(Equal(append([]event.ColumnName{event.HeaderFK, event.LogFK},
+			transformer.Columns()...)))
+		Expect(models[0].ColumnValues).To(Equal(event.ColumnValues{
+			event.HeaderFK: eventLog.HeaderID,
+			event.LogFK:    eventLog.ID,
+			"sender_":      sender,
+			"id_":          id.Bytes(),
+			"note_":        crypto.Keccak256Hash([]byte("note")),
+			"small_":       big.NewInt(7),
+			"amount_":      big.NewInt(123),
+			"flag_":        true,
+			"data_":        []byte{1, 2, 3},
+			"pair_":        pq.StringArray{"1", "2"},
+			"text_":        "hello",
+		}))
+	})
+
+	It("returns an error if a log isn't of the event", func() {
+		eventLog.Log.Topics[0] = common.HexToHash("0xabc")
+
+		_, err := transformer.ToModels("", []core.EventLog{eventLog}, nil)
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("generates a migration for the event's table", func() {
+		Expect(transformer.CreateTableMigration()).To(Equal(`-- +goose Up
+CREATE TABLE public.example_event
+(
+    id        SERIAL PRIMARY KEY,
+    header_id INTEGER NOT NULL REFERENCES public.headers (id) ON DELETE CASCADE,
+    log_id    BIGINT  NOT NULL REFERENCES public.event_logs (id) ON DELETE CASCADE,
+    sender_ CHARACTER VARYING(66),
+    id_ BYTEA,
+    note_ CHARACTER VARYING(66),
+    small_ NUMERIC,
+    amount_ NUMERIC,
+    flag_ BOOLEAN,
+    data_ BYTEA,
+    pair_ TEXT[],
+    text_ TEXT,
+    UNIQUE (header_id, log_id)
+);
+
+CREATE INDEX example_event_header_index ON public.example_event (header_id);
+CREATE INDEX example_event_log_index ON public.example_event (log_id);
+
+-- +goose Down
+DROP TABLE public.example_event;
+`))
+	})
+
+	It("defaults the transformer's topic0 to the event signature", func() {
+		initializer, err := event.NewABITransformerInitializer(event.TransformerConfig{
+			TransformerName: "example",
+			ContractAbi:     exampleEventAbi,
+		}, "Example", "public", "example_event")
+		Expect(err).NotTo(HaveOccurred())
+
+		config := initializer(nil).GetConfig()
+
+		Expect(config.Topic).To(Equal(eventLog.Log.Topics[0].Hex()))
+	})
+
+	It("panics when building an initializer for an event missing from the abi", func() {
+		Expect(func() {
+			event.MustNewABITransformerInitializer(event.TransformerConfig{
+				TransformerName: "example",
+				ContractAbi:     exampleEventAbi,
+			}, "Missing", "public", "missing_event")
+		}).To(Panic())
+	})
+})
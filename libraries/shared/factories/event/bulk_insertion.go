@@ -0,0 +1,207 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxInsertionParameters caps the bind parameters in one multi-row insertion, within Postgres' limit of 65535
+var MaxInsertionParameters = 65535
+
+// modelBatch holds the rows of models sharing an insertion query shape, so that they can be inserted together
+type modelBatch struct {
+	model    InsertionModel // The first model of the batch, which defines its schema, table and columns
+	rows     [][]interface{}
+	rowByKey map[string]int
+}
+
+// batchModels groups models by their insertion query shape, in the order each group first appears. Models sharing a
+// conflict key are collapsed into the row the conflict strategy would leave, since a single upsert cannot affect the
+// same row twice; with ErrorOnConflict they are kept so that the insertion fails.
+func batchModels(models []InsertionModel) ([]*modelBatch, error) {
+	var batches []*modelBatch
+	batchByShape := make(map[string]*modelBatch)
+	for _, model := range models {
+		validationErr := validateConflictStrategy(model)
+		if validationErr != nil {
+			return nil, validationErr
+		}
+		row := make([]interface{}, len(model.OrderedColumns))
+		for i, col := range model.OrderedColumns {
+			value := model.ColumnValues[col]
+			// Check whether or not PG can accept the type of value in the model
+			dbValue, ok := toDatabaseValue(value)
+			if !ok {
+				logrus.WithField("model", model).Errorf("PG cannot handle value of this type in column %s: %T", col, value)
+				return nil, ErrUnsupportedColumnValue(col, value)
+			}
+			row[i] = dbValue
+		}
+
+		shape := model.queryShape()
+		batch, exists := batchByShape[shape]
+		if !exists {
+			batch = &modelBatch{model: model, rowByKey: make(map[string]int)}
+			batchByShape[shape] = batch
+			batches = append(batches, batch)
+		}
+		key, conflicts, keyErr := conflictKey(model)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		if !conflicts {
+			batch.rows = append(batch.rows, row)
+			continue
+		}
+		if existingRow, duplicate := batch.rowByKey[key]; duplicate && model.ConflictStrategy != ErrorOnConflict {
+			if model.ConflictStrategy != DoNothingOnConflict {
+				mergeUpdatedColumns(model, batch.rows[existingRow], row)
+			}
+			continue
+		}
+		batch.rowByKey[key] = len(batch.rows)
+		batch.rows = append(batch.rows, row)
+	}
+	return batches, nil
+}
+
+// mergeUpdatedColumns overwrites the columns of an earlier row that the later row's conflict strategy updates, as
+// upserting the rows one after the other would
+func mergeUpdatedColumns(model InsertionModel, existingRow, row []interface{}) {
+	for i, column := range model.OrderedColumns {
+		if containsColumn(model.updatedColumns(), column) {
+			existingRow[i] = row[i]
+		}
+	}
+}
+
+func validateConflictStrategy(model InsertionModel) error {
+	if model.ConflictStrategy != UpdateColumnsOnConflict {
+		return nil
+	}
+	if len(model.UpdateColumns) == 0 {
+		return ErrNoUpdateColumns
+	}
+	for _, updateColumn := range model.UpdateColumns {
+		if !containsColumn(model.OrderedColumns, updateColumn) {
+			return fmt.Errorf("insertion model updates column %s on conflict but doesn't insert it", updateColumn)
+		}
+	}
+	return nil
+}
+
+func containsColumn(columns []ColumnName, column ColumnName) bool {
+	for _, candidate := range columns {
+		if candidate == column {
+			return true
+		}
+	}
+	return false
+}
+
+// insert upserts the batch's rows with multi-row statements of powers of two rows, as large as the parameter limit
+// allows, so that few distinct queries are memoised for the batch's query shape
+func (batch *modelBatch) insert(tx *sqlx.Tx) error {
+	columnCount := len(batch.model.OrderedColumns)
+	if columnCount == 0 {
+		return ErrNoColumns
+	}
+	maxRowsPerStatement := MaxInsertionParameters / columnCount
+	if maxRowsPerStatement == 0 {
+		return fmt.Errorf("insertion model has %d columns, more than the %d parameters of an insertion", columnCount,
+			MaxInsertionParameters)
+	}
+	for start := 0; start < len(batch.rows); {
+		rowsPerStatement := powerOfTwoAtMost(len(batch.rows) - start)
+		if rowsPerStatement > maxRowsPerStatement {
+			rowsPerStatement = powerOfTwoAtMost(maxRowsPerStatement)
+		}
+		rows := batch.rows[start : start+rowsPerStatement]
+		start += rowsPerStatement
+		args := make([]interface{}, 0, len(rows)*len(batch.model.OrderedColumns))
+		for _, row := range rows {
+			args = append(args, row...)
+		}
+		_, execErr := tx.Exec(getMemoizedBulkQuery(batch.model, len(rows)), args...)
+		if execErr != nil {
+			return execErr
+		}
+	}
+	return nil
+}
+
+func powerOfTwoAtMost(n int) int {
+	power := 1
+	for power*2 <= n {
+		power *= 2
+	}
+	return power
+}
+
+// GenerateBulkInsertionQuery creates an SQL query upserting rowCount rows shaped like the insertion model, with the
+// values of each row following the previous row's in the query's parameters
+func GenerateBulkInsertionQuery(model InsertionModel, rowCount int) string {
+	columnCount := len(model.OrderedColumns)
+	rowPlaceholders := make([]string, rowCount)
+	for row := 0; row < rowCount; row++ {
+		valuePlaceholders := make([]string, columnCount)
+		for col := 0; col < columnCount; col++ {
+			valuePlaceholders[col] = fmt.Sprintf("$%d", 1+row*columnCount+col)
+		}
+		rowPlaceholders[row] = "(" + strings.Join(valuePlaceholders, ", ") + ")"
+	}
+	conflictClause := model.conflictClause()
+
+	baseQuery := `INSERT INTO %v.%v (%v) VALUES %v%v;`
+
+	return fmt.Sprintf(baseQuery,
+		model.SchemaName,
+		model.TableName,
+		joinOrderedColumns(model.OrderedColumns),
+		strings.Join(rowPlaceholders, ", "),
+		conflictClause)
+}
+
+// conflictKey identifies the row a model upserts by the values inserted for its conflict columns, so that models
+// supplying the same value as different types, such as a *big.Int and its decimal string, share a key. A model with
+// a NULL conflict column never conflicts, so it has no key.
+func conflictKey(model InsertionModel) (string, bool, error) {
+	conflictColumns := model.conflictColumns()
+	values := make([]string, len(conflictColumns))
+	for i, column := range conflictColumns {
+		value := model.ColumnValues[column]
+		dbValue, ok := toDatabaseValue(value)
+		if !ok {
+			return "", false, ErrUnsupportedColumnValue(column, value)
+		}
+		driverValue, convertErr := driver.DefaultParameterConverter.ConvertValue(dbValue)
+		if convertErr != nil {
+			return "", false, fmt.Errorf("error converting value for conflict column %s: %w", column, convertErr)
+		}
+		if driverValue == nil {
+			return "", false, nil
+		}
+		values[i] = fmt.Sprintf("%v", driverValue)
+	}
+	return strings.Join(values, "|"), true, nil
+}
@@ -0,0 +1,103 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lib/pq"
+)
+
+// toDatabaseValue converts a column value to one the postgres driver accepts. Besides driver values and
+// driver.Valuers, it accepts:
+//   - *big.Int, inserted as its decimal representation so it can populate a NUMERIC column
+//   - []*big.Int, inserted as an array for a NUMERIC[] column, with nil elements as NULL
+//   - common.Address and common.Hash, inserted as hex text; pass their Bytes() to populate a BYTEA column
+//   - JSON-marshallable structs and maps, inserted as JSON for a JSONB column
+//   - nil and nil pointers as NULL, and pointers to any of the above
+func toDatabaseValue(value interface{}) (interface{}, bool) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, true
+	// Addresses and hashes are driver.Valuers encoding as bytes, so they need to be matched first to insert hex text
+	case common.Address:
+		return typedValue.Hex(), true
+	case *common.Address:
+		if typedValue == nil {
+			return nil, true
+		}
+		return typedValue.Hex(), true
+	case common.Hash:
+		return typedValue.Hex(), true
+	case *common.Hash:
+		if typedValue == nil {
+			return nil, true
+		}
+		return typedValue.Hex(), true
+	case driver.Valuer:
+		return typedValue, true
+	case *big.Int:
+		if typedValue == nil {
+			return nil, true
+		}
+		return typedValue.String(), true
+	case big.Int:
+		return typedValue.String(), true
+	case []*big.Int:
+		numbers := make([]sql.NullString, len(typedValue))
+		for i, number := range typedValue {
+			if number != nil {
+				numbers[i] = sql.NullString{String: number.String(), Valid: true}
+			}
+		}
+		return pq.Array(numbers), true
+	}
+	if driver.IsValue(value) {
+		return value, true
+	}
+
+	reflectedValue := reflect.ValueOf(value)
+	switch reflectedValue.Kind() {
+	case reflect.Ptr:
+		if reflectedValue.IsNil() {
+			return nil, true
+		}
+		return toDatabaseValue(reflectedValue.Elem().Interface())
+	case reflect.Map:
+		if reflectedValue.IsNil() {
+			return nil, true
+		}
+		return toJSON(value)
+	case reflect.Struct:
+		return toJSON(value)
+	default:
+		return nil, false
+	}
+}
+
+func toJSON(value interface{}) (interface{}, bool) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return string(jsonValue), true
+}
@@ -25,3 +25,10 @@ import (
 type Transformer interface {
 	ToModels(contractAbi string, ethLog []core.EventLog, db *postgres.DB) ([]InsertionModel, error)
 }
+
+// OrphanedHeaderHandler can optionally be implemented by a Transformer (or an ITransformer) whose output doesn't
+// cascade on deletion of headers, so that derived data for a header replaced by a reorg can be removed before logs are
+// extracted for its replacement
+type OrphanedHeaderHandler interface {
+	CleanUpOrphanedHeader(header core.OrphanedHeader, db *postgres.DB) error
+}
@@ -17,17 +17,25 @@
 package event
 
 import (
-	"database/sql/driver"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 	"github.com/makerdao/vulcanizedb/utils"
 	"github.com/sirupsen/logrus"
 )
 
-// SetLogTransformedQuery marks the log as transformed in the database
+// RecordLogsTransformedQuery records that a transformer has transformed the logs with the given ids. It takes a
+// pq.Array of log ids and the transformer name.
+const RecordLogsTransformedQuery = `INSERT INTO public.transformed_event_logs (event_log_id, transformer_name)
+	SELECT UNNEST($1::BIGINT[]), $2 ON CONFLICT DO NOTHING`
+
+// SetLogTransformedQuery marks the log as transformed in the database, for every transformer
+//
+// Deprecated: transformed logs are recorded per transformer; use RecordLogsTransformedQuery.
 const SetLogTransformedQuery = `UPDATE public.event_logs SET transformed = true WHERE id = $1`
 
 // ErrEmptyModelSlice is returned when PersistModel gets 0 InsertionModels
@@ -51,7 +59,9 @@ type TableName string
 // ColumnName identifies columns on the given table
 type ColumnName string
 
-// ColumnValues maps a column to the value for insertion. This is restricted to []byte, bool, float64, int64, string, time.Time
+// ColumnValues maps a column to the value for insertion. Besides []byte, bool, float64, int64, string, time.Time and
+// driver.Valuers, values may be *big.Int, []*big.Int, common.Address, common.Hash, JSON-marshallable structs and maps,
+// nil, or pointers to any of these.
 type ColumnValues map[ColumnName]interface{}
 
 // ErrUnsupportedValue is thrown when a model supplies a type of value the postgres driver cannot handle.
@@ -59,56 +69,125 @@ var ErrUnsupportedValue = func(value interface{}) error {
 	return fmt.Errorf("unsupported type of value supplied in model: %v (%T)", value, value)
 }
 
+// ErrUnsupportedColumnValue is thrown when a model supplies a type of value the postgres driver cannot handle,
+// naming the column it was supplied for.
+var ErrUnsupportedColumnValue = func(column ColumnName, value interface{}) error {
+	return fmt.Errorf("unsupported type of value supplied in model for column %s: %v (%T)", column, value, value)
+}
+
+// ConflictStrategy determines what an insertion does when a row with the same conflict columns already exists
+type ConflictStrategy int
+
+const (
+	// UpdateAllOnConflict overwrites every column of the existing row, and is the default strategy
+	UpdateAllOnConflict ConflictStrategy = iota
+	// UpdateColumnsOnConflict overwrites only the model's UpdateColumns of the existing row
+	UpdateColumnsOnConflict
+	// DoNothingOnConflict keeps the existing row
+	DoNothingOnConflict
+	// ErrorOnConflict fails the insertion
+	ErrorOnConflict
+)
+
+// DefaultConflictColumns identify the row of an insertion model that doesn't declare its ConflictColumns
+var DefaultConflictColumns = []ColumnName{HeaderFK, LogFK}
+
+// ErrNoColumns is returned when a model has no columns to insert
+var ErrNoColumns = fmt.Errorf("insertion model has no columns")
+
+// ErrNoUpdateColumns is returned when a model updates a subset of columns on conflict but doesn't name any
+var ErrNoUpdateColumns = fmt.Errorf("insertion model updates columns on conflict but has no UpdateColumns")
+
 // InsertionModel is the generalised data structure a converter returns, and contains everything the repository needs to
 // persist the converted data.
 type InsertionModel struct {
-	SchemaName     SchemaName
-	TableName      TableName
-	OrderedColumns []ColumnName // Defines the fields to insert, and in which order the table expects them
-	ColumnValues   ColumnValues // Associated values for columns, restricted to []byte, bool, float64, int64, string, time.Time
+	SchemaName       SchemaName
+	TableName        TableName
+	OrderedColumns   []ColumnName     // Defines the fields to insert, and in which order the table expects them
+	ColumnValues     ColumnValues     // Associated values for columns, restricted to the types listed on ColumnValues
+	ConflictColumns  []ColumnName     // Optional; the unique columns identifying a row, defaults to DefaultConflictColumns
+	ConflictStrategy ConflictStrategy // Optional; defaults to UpdateAllOnConflict
+	UpdateColumns    []ColumnName     // The columns overwritten on conflict when using UpdateColumnsOnConflict
+}
+
+func (model InsertionModel) conflictColumns() []ColumnName {
+	if len(model.ConflictColumns) == 0 {
+		return DefaultConflictColumns
+	}
+	return model.ConflictColumns
 }
 
-// ModelToQuery stores memoised insertion queries to minimise computation
-var ModelToQuery = map[string]string{}
+func (model InsertionModel) updatedColumns() []ColumnName {
+	if model.ConflictStrategy == UpdateColumnsOnConflict {
+		return model.UpdateColumns
+	}
+	return model.OrderedColumns
+}
+
+// conflictClause generates the ON CONFLICT clause of the model's insertion query
+func (model InsertionModel) conflictClause() string {
+	switch model.ConflictStrategy {
+	case ErrorOnConflict:
+		return ""
+	case DoNothingOnConflict:
+		return fmt.Sprintf("\n\t\tON CONFLICT (%v) DO NOTHING", joinOrderedColumns(model.conflictColumns()))
+	default:
+		var updateOnConflict []string
+		for _, column := range model.updatedColumns() {
+			updateOnConflict = append(updateOnConflict, fmt.Sprintf("%s = excluded.%s", column, column))
+		}
+		return fmt.Sprintf("\n\t\tON CONFLICT (%v) DO UPDATE SET %v", joinOrderedColumns(model.conflictColumns()),
+			strings.Join(updateOnConflict, ", "))
+	}
+}
+
+// queryShape identifies everything that determines a model's insertion query
+func (model InsertionModel) queryShape() string {
+	return fmt.Sprintf("%s.%s(%s) conflict(%s) strategy(%d) update(%s)", model.SchemaName, model.TableName,
+		joinOrderedColumns(model.OrderedColumns), joinOrderedColumns(model.conflictColumns()), model.ConflictStrategy,
+		joinOrderedColumns(model.UpdateColumns))
+}
+
+var (
+	// ModelToQuery stores memoised insertion queries to minimise computation. Multi-row queries are only generated
+	// for powers of two rows, so there are at most a handful per query shape.
+	ModelToQuery      = map[string]string{}
+	modelToQueryMutex sync.RWMutex
+)
 
-// GetMemoizedQuery gets/creates a DB insertion query for the model
+// GetMemoizedQuery gets/creates a DB query inserting a single row of the model. It is safe for concurrent use.
 func GetMemoizedQuery(model InsertionModel) string {
-	// The schema and table name uniquely determines the insertion query, use that for memoization
-	queryKey := string(model.SchemaName) + string(model.TableName)
+	return getMemoizedBulkQuery(model, 1)
+}
+
+// getMemoizedBulkQuery gets/creates a DB query upserting rowCount rows of the model, which should be a power of two.
+// It is safe for concurrent use.
+func getMemoizedBulkQuery(model InsertionModel, rowCount int) string {
+	queryKey := model.queryShape()
+	if rowCount > 1 {
+		queryKey = fmt.Sprintf("%s rows(%d)", queryKey, rowCount)
+	}
+	modelToQueryMutex.RLock()
 	query, queryMemoized := ModelToQuery[queryKey]
+	modelToQueryMutex.RUnlock()
 	if !queryMemoized {
-		query = GenerateInsertionQuery(model)
+		query = GenerateBulkInsertionQuery(model, rowCount)
+		modelToQueryMutex.Lock()
 		ModelToQuery[queryKey] = query
+		modelToQueryMutex.Unlock()
 	}
 	return query
 }
 
-// GenerateInsertionQuery creates an SQL insertion query from an insertion model.
-// Should be called through GetMemoizedQuery, so the query is not generated on each call to PersistModels.
+// GenerateInsertionQuery creates an SQL query inserting a single row of the insertion model.
+// Should be called through GetMemoizedQuery, so the query is not generated on each insertion.
 func GenerateInsertionQuery(model InsertionModel) string {
-	var valuePlaceholders []string
-	var updateOnConflict []string
-	for i := 0; i < len(model.OrderedColumns); i++ {
-		valuePlaceholder := fmt.Sprintf("$%d", 1+i)
-		valuePlaceholders = append(valuePlaceholders, valuePlaceholder)
-		updateOnConflict = append(updateOnConflict,
-			fmt.Sprintf("%s = %s", model.OrderedColumns[i], valuePlaceholder))
-	}
-
-	baseQuery := `INSERT INTO %v.%v (%v) VALUES(%v)
-		ON CONFLICT (header_id, log_id) DO UPDATE SET %v;`
-
-	return fmt.Sprintf(baseQuery,
-		model.SchemaName,
-		model.TableName,
-		joinOrderedColumns(model.OrderedColumns),
-		strings.Join(valuePlaceholders, ", "),
-		strings.Join(updateOnConflict, ", "))
+	return GenerateBulkInsertionQuery(model, 1)
 }
 
 /*
-PersistModels generates an insertion query and persists to the DB, given a slice of InsertionModels.
-ColumnValues are restricted to []byte, bool, float64, int64, string, time.Time.
+PersistModels persists a slice of InsertionModels to the DB. Models for the same table are upserted together with
+multi-row insertions. ColumnValues are restricted to the types listed on ColumnValues.
 
 testModel = shared.InsertionModel{
 	SchemaName:     "public"
@@ -122,33 +201,28 @@ testModel = shared.InsertionModel{
 }
 */
 func PersistModels(models []InsertionModel, db *postgres.DB) error {
+	return PersistTransformedModels("", nil, models, db)
+}
+
+// PersistTransformedModels persists models like PersistModels, and in the same transaction records that the named
+// transformer has transformed the logs, so that the logs are not delivered to it again
+func PersistTransformedModels(transformerName string, logs []core.EventLog, models []InsertionModel, db *postgres.DB) error {
 	if len(models) == 0 {
 		return ErrEmptyModelSlice
 	}
 
+	batches, batchErr := batchModels(models)
+	if batchErr != nil {
+		return batchErr
+	}
+
 	tx, dbErr := db.Beginx()
 	if dbErr != nil {
 		return dbErr
 	}
 
-	for _, model := range models {
-		// Maps can't be iterated over in a reliable manner, so we rely on OrderedColumns to define the order to insert
-		// tx.Exec is variadically typed in the args, so if we wrap in []interface{} we can apply them all automatically
-		var args []interface{}
-		for _, col := range model.OrderedColumns {
-			value := model.ColumnValues[col]
-			// Check whether or not PG can accept the type of value in the model
-			okPgValue := isValidValue(value)
-			if !okPgValue {
-				logrus.WithField("model", model).Errorf("PG cannot handle value of this type: %T", value)
-				return ErrUnsupportedValue(value)
-			}
-			args = append(args, value)
-		}
-
-		insertionQuery := GetMemoizedQuery(model)
-		_, execErr := tx.Exec(insertionQuery, args...) // couldn't pass varying types in bulk with args :: []string
-
+	for _, batch := range batches {
+		execErr := batch.insert(tx)
 		if execErr != nil {
 			rollbackErr := tx.Rollback()
 			if rollbackErr != nil {
@@ -156,11 +230,16 @@ func PersistModels(models []InsertionModel, db *postgres.DB) error {
 			}
 			return execErr
 		}
+	}
 
-		_, logErr := tx.Exec(SetLogTransformedQuery, model.ColumnValues[LogFK])
-
+	if len(logs) > 0 {
+		logIDs := make([]int64, len(logs))
+		for i, log := range logs {
+			logIDs[i] = log.ID
+		}
+		_, logErr := tx.Exec(RecordLogsTransformedQuery, pq.Array(logIDs), transformerName)
 		if logErr != nil {
-			utils.RollbackAndLogFailure(tx, logErr, "event_logs.transformed")
+			utils.RollbackAndLogFailure(tx, logErr, "transformed_event_logs")
 			return logErr
 		}
 	}
@@ -168,15 +247,6 @@ func PersistModels(models []InsertionModel, db *postgres.DB) error {
 	return tx.Commit()
 }
 
-func isValidValue(value interface{}) bool {
-	switch value.(type) {
-	case *pq.StringArray:
-		return true
-	default:
-		return driver.IsValue(value)
-	}
-}
-
 func joinOrderedColumns(columns []ColumnName) string {
 	var stringColumns []string
 	for _, columnName := range columns {
@@ -19,9 +19,13 @@ package event_test
 import (
 	"fmt"
 	"math/big"
+	"strings"
+	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
 	"github.com/makerdao/vulcanizedb/pkg/fakes"
@@ -55,7 +59,7 @@ var _ = Describe("Repository", func() {
 		BeforeEach(func() {
 			_, tableErr := db.Exec(createTestEventTableQuery)
 			Expect(tableErr).NotTo(HaveOccurred())
-			headerRepository = repositories.NewHeaderRepository(db)
+			headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 			var insertHeaderErr error
 			headerID, insertHeaderErr = headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
 			Expect(insertHeaderErr).NotTo(HaveOccurred())
@@ -80,13 +84,11 @@ var _ = Describe("Repository", func() {
 			db.MustExec(`DROP TABLE public.testEvent;`)
 		})
 
-		// Needs to run before the other tests, since those insert keys in map
 		It("memoizes queries", func() {
-			Expect(len(event.ModelToQuery)).To(Equal(0))
-			event.GetMemoizedQuery(testModel)
-			Expect(len(event.ModelToQuery)).To(Equal(1))
-			event.GetMemoizedQuery(testModel)
-			Expect(len(event.ModelToQuery)).To(Equal(1))
+			firstQuery := event.GetMemoizedQuery(testModel)
+
+			Expect(event.GetMemoizedQuery(testModel)).To(Equal(firstQuery))
+			Expect(firstQuery).To(Equal(event.GenerateInsertionQuery(testModel)))
 		})
 
 		It("persists a model to postgres", func() {
@@ -126,18 +128,13 @@ var _ = Describe("Repository", func() {
 					},
 				}
 
-				// Remove cached queries, or we won't generate a new (incorrect) one
-				delete(event.ModelToQuery, "publictestEvent")
-
 				createErr := event.PersistModels([]event.InsertionModel{brokenModel}, db)
-				// Remove incorrect query, so other tests won't get it
-				delete(event.ModelToQuery, "publictestEvent")
 
 				Expect(createErr).To(HaveOccurred())
 			})
 
 			It("for unsupported types in ColumnValue", func() {
-				unsupportedValue := big.NewInt(5)
+				unsupportedValue := []int{5}
 				testModel = event.InsertionModel{
 					SchemaName: "public",
 					TableName:  "testEvent",
@@ -152,7 +149,7 @@ var _ = Describe("Repository", func() {
 				}
 
 				createErr := event.PersistModels([]event.InsertionModel{testModel}, db)
-				Expect(createErr).To(MatchError(event.ErrUnsupportedValue(unsupportedValue)))
+				Expect(createErr).To(MatchError(event.ErrUnsupportedColumnValue("variable1", unsupportedValue)))
 			})
 		})
 
@@ -181,19 +178,368 @@ var _ = Describe("Repository", func() {
 
 		It("generates correct queries", func() {
 			actualQuery := event.GenerateInsertionQuery(testModel)
-			expectedQuery := `INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES($1, $2, $3)
-		ON CONFLICT (header_id, log_id) DO UPDATE SET header_id = $1, log_id = $2, variable1 = $3;`
+			expectedQuery := `INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES ($1, $2, $3)
+		ON CONFLICT (header_id, log_id) DO UPDATE SET header_id = excluded.header_id, log_id = excluded.log_id, variable1 = excluded.variable1;`
 			Expect(actualQuery).To(Equal(expectedQuery))
 		})
 
-		It("marks log transformed", func() {
-			createErr := event.PersistModels([]event.InsertionModel{testModel}, db)
+		It("generates correct bulk queries", func() {
+			actualQuery := event.GenerateBulkInsertionQuery(testModel, 2)
+			expectedQuery := `INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES ($1, $2, $3), ($4, $5, $6)
+		ON CONFLICT (header_id, log_id) DO UPDATE SET header_id = excluded.header_id, log_id = excluded.log_id, variable1 = excluded.variable1;`
+			Expect(actualQuery).To(Equal(expectedQuery))
+		})
+
+		Describe("conflict strategies", func() {
+			It("updates only the given columns on conflict", func() {
+				testModel.ConflictStrategy = event.UpdateColumnsOnConflict
+				testModel.UpdateColumns = []event.ColumnName{"variable1"}
+
+				actualQuery := event.GenerateInsertionQuery(testModel)
+
+				expectedQuery := `INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES ($1, $2, $3)
+		ON CONFLICT (header_id, log_id) DO UPDATE SET variable1 = excluded.variable1;`
+				Expect(actualQuery).To(Equal(expectedQuery))
+			})
+
+			It("uses the model's conflict columns", func() {
+				testModel.ConflictColumns = []event.ColumnName{event.LogFK}
+				testModel.ConflictStrategy = event.DoNothingOnConflict
+
+				actualQuery := event.GenerateBulkInsertionQuery(testModel, 1)
+
+				expectedQuery := `INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES ($1, $2, $3)
+		ON CONFLICT (log_id) DO NOTHING;`
+				Expect(actualQuery).To(Equal(expectedQuery))
+			})
+
+			It("omits the conflict clause when conflicts are errors", func() {
+				testModel.ConflictStrategy = event.ErrorOnConflict
+
+				actualQuery := event.GenerateInsertionQuery(testModel)
+
+				Expect(actualQuery).To(Equal(`INSERT INTO public.testEvent (header_id, log_id, variable1) VALUES ($1, $2, $3);`))
+			})
+
+			It("memoizes queries separately per conflict strategy", func() {
+				doNothingModel := testModel
+				doNothingModel.ConflictStrategy = event.DoNothingOnConflict
+
+				Expect(event.GetMemoizedQuery(doNothingModel)).NotTo(Equal(event.GetMemoizedQuery(testModel)))
+			})
+
+			It("memoizes queries safely from concurrent goroutines", func() {
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						model := testModel
+						model.TableName = event.TableName(fmt.Sprintf("table%d", i%3))
+						event.GetMemoizedQuery(model)
+					}(i)
+				}
+				wg.Wait()
+			})
+
+			It("keeps the existing row when conflicts are ignored", func() {
+				createErr := event.PersistModels([]event.InsertionModel{testModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+				ignoredModel := testModel
+				ignoredModel.ConflictStrategy = event.DoNothingOnConflict
+				ignoredModel.ColumnValues = event.ColumnValues{
+					event.HeaderFK: headerID,
+					event.LogFK:    logID,
+					"variable1":    "ignoredValue",
+				}
+
+				ignoreErr := event.PersistModels([]event.InsertionModel{ignoredModel}, db)
+				Expect(ignoreErr).NotTo(HaveOccurred())
+
+				var res FakeEvent
+				dbErr := db.Get(&res, `SELECT log_id, variable1 FROM public.testEvent;`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(res.Variable1).To(Equal(testModel.ColumnValues["variable1"]))
+			})
+
+			It("keeps columns that aren't updated when models in one batch conflict", func() {
+				db.MustExec(`ALTER TABLE public.testEvent ADD COLUMN variable2 TEXT`)
+				orderedColumns := []event.ColumnName{event.HeaderFK, event.LogFK, "variable1", "variable2"}
+				firstModel := event.InsertionModel{
+					SchemaName:       testModel.SchemaName,
+					TableName:        testModel.TableName,
+					OrderedColumns:   orderedColumns,
+					ConflictStrategy: event.UpdateColumnsOnConflict,
+					UpdateColumns:    []event.ColumnName{"variable1"},
+					ColumnValues: event.ColumnValues{
+						event.HeaderFK: headerID,
+						event.LogFK:    logID,
+						"variable1":    "first1",
+						"variable2":    "first2",
+					},
+				}
+				secondModel := firstModel
+				secondModel.ColumnValues = event.ColumnValues{
+					event.HeaderFK: headerID,
+					event.LogFK:    logID,
+					"variable1":    "second1",
+					"variable2":    "second2",
+				}
+
+				createErr := event.PersistModels([]event.InsertionModel{firstModel, secondModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var variables struct {
+					Variable1 string
+					Variable2 string
+				}
+				dbErr := db.Get(&variables, `SELECT variable1, variable2 FROM public.testEvent`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(variables.Variable1).To(Equal("second1"))
+				Expect(variables.Variable2).To(Equal("first2"))
+			})
+
+			It("fails when conflicts are errors", func() {
+				createErr := event.PersistModels([]event.InsertionModel{testModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+				conflictingModel := testModel
+				conflictingModel.ConflictStrategy = event.ErrorOnConflict
+
+				err := event.PersistModels([]event.InsertionModel{conflictingModel}, db)
+
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("returns an error if no columns are updated on conflict", func() {
+				testModel.ConflictStrategy = event.UpdateColumnsOnConflict
+
+				err := event.PersistModels([]event.InsertionModel{testModel}, db)
+
+				Expect(err).To(MatchError(event.ErrNoUpdateColumns))
+			})
+
+			It("for models without columns", func() {
+				testModel.OrderedColumns = nil
+
+				err := event.PersistModels([]event.InsertionModel{testModel}, db)
+
+				Expect(err).To(MatchError(event.ErrNoColumns))
+			})
+		})
+
+		It("persists more models than fit in one insertion", func() {
+			originalMaxParameters := event.MaxInsertionParameters
+			defer func() { event.MaxInsertionParameters = originalMaxParameters }()
+			event.MaxInsertionParameters = len(testModel.OrderedColumns) * 2
+			models := []event.InsertionModel{testModel}
+			for i := 0; i < 2; i++ {
+				otherLog := test_data.CreateTestLog(headerID, db)
+				models = append(models, event.InsertionModel{
+					SchemaName:     testModel.SchemaName,
+					TableName:      testModel.TableName,
+					OrderedColumns: testModel.OrderedColumns,
+					ColumnValues: event.ColumnValues{
+						event.HeaderFK: headerID,
+						event.LogFK:    otherLog.ID,
+						"variable1":    fmt.Sprintf("value%d", i+2),
+					},
+				})
+			}
+
+			createErr := event.PersistModels(models, db)
+			Expect(createErr).NotTo(HaveOccurred())
+
+			var variables []string
+			dbErr := db.Select(&variables, `SELECT variable1 FROM public.testEvent`)
+			Expect(dbErr).NotTo(HaveOccurred())
+			Expect(variables).To(ConsistOf("value1", "value2", "value3"))
+		})
+
+		It("memoizes multi-row queries only for powers of two rows", func() {
+			models := []event.InsertionModel{testModel}
+			for i := 0; i < 6; i++ {
+				otherLog := test_data.CreateTestLog(headerID, db)
+				models = append(models, event.InsertionModel{
+					SchemaName:     testModel.SchemaName,
+					TableName:      testModel.TableName,
+					OrderedColumns: testModel.OrderedColumns,
+					ColumnValues: event.ColumnValues{
+						event.HeaderFK: headerID,
+						event.LogFK:    otherLog.ID,
+						"variable1":    fmt.Sprintf("value%d", i+2),
+					},
+				})
+			}
+
+			createErr := event.PersistModels(models, db)
 			Expect(createErr).NotTo(HaveOccurred())
 
-			var logTransformed bool
-			getErr := db.Get(&logTransformed, `SELECT transformed FROM public.event_logs WHERE id = $1`, logID)
-			Expect(getErr).NotTo(HaveOccurred())
-			Expect(logTransformed).To(BeTrue())
+			var rowCounts []int
+			for key := range event.ModelToQuery {
+				var rowCount int
+				if _, scanErr := fmt.Sscanf(key[strings.LastIndex(key, " ")+1:], "rows(%d)", &rowCount); scanErr == nil &&
+					strings.HasPrefix(key, "public.testEvent(") {
+					rowCounts = append(rowCounts, rowCount)
+				}
+			}
+			Expect(rowCounts).To(ContainElement(4))
+			Expect(rowCounts).To(ContainElement(2))
+			for _, rowCount := range rowCounts {
+				Expect(rowCount & (rowCount - 1)).To(BeZero())
+			}
+			var count int
+			countErr := db.Get(&count, `SELECT COUNT(*) FROM public.testEvent`)
+			Expect(countErr).NotTo(HaveOccurred())
+			Expect(count).To(Equal(len(models)))
+		})
+
+		Describe("PersistTransformedModels", func() {
+			It("records the logs transformed by the transformer", func() {
+				createErr := event.PersistTransformedModels("transformer", []core.EventLog{{ID: logID}},
+					[]event.InsertionModel{testModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var transformerNames []string
+				getErr := db.Select(&transformerNames,
+					`SELECT transformer_name FROM public.transformed_event_logs WHERE event_log_id = $1`, logID)
+				Expect(getErr).NotTo(HaveOccurred())
+				Expect(transformerNames).To(ConsistOf("transformer"))
+			})
+
+			It("does not record the logs transformed if persisting models fails", func() {
+				unsupportedValue := []int{5}
+				testModel.ColumnValues["variable1"] = unsupportedValue
+
+				createErr := event.PersistTransformedModels("transformer", []core.EventLog{{ID: logID}},
+					[]event.InsertionModel{testModel}, db)
+				Expect(createErr).To(MatchError(event.ErrUnsupportedColumnValue("variable1", unsupportedValue)))
+
+				var transformedCount int
+				getErr := db.Get(&transformedCount, `SELECT COUNT(*) FROM public.transformed_event_logs`)
+				Expect(getErr).NotTo(HaveOccurred())
+				Expect(transformedCount).To(BeZero())
+			})
+		})
+
+		Describe("column value types", func() {
+			const createTypedEventTableQuery = `CREATE TABLE public.typedEvent(
+			id          SERIAL PRIMARY KEY,
+			header_id   INTEGER NOT NULL REFERENCES public.headers (id) ON DELETE CASCADE,
+			log_id      BIGINT  NOT NULL REFERENCES public.event_logs (id) ON DELETE CASCADE,
+			amount      NUMERIC,
+			amounts     NUMERIC[],
+			address     TEXT,
+			hash        BYTEA,
+			details     JSONB,
+			UNIQUE (header_id, log_id)
+			);`
+
+			var (
+				typedModel event.InsertionModel
+				address    = test_data.FakeAddress()
+			)
+
+			BeforeEach(func() {
+				db.MustExec(createTypedEventTableQuery)
+				typedModel = event.InsertionModel{
+					SchemaName: "public",
+					TableName:  "typedEvent",
+					OrderedColumns: []event.ColumnName{
+						event.HeaderFK, event.LogFK, "amount", "amounts", "address", "hash", "details",
+					},
+					ColumnValues: event.ColumnValues{
+						event.HeaderFK: headerID,
+						event.LogFK:    logID,
+						"amount":       big.NewInt(123),
+						"amounts":      []*big.Int{big.NewInt(1), nil, big.NewInt(3)},
+						"address":      address,
+						"hash":         common.HexToHash("0x12").Bytes(),
+						"details":      typedEventDetails{Name: "name", Count: 2},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				db.MustExec(`DROP TABLE public.typedEvent;`)
+			})
+
+			It("persists numbers, arrays, addresses, hashes and JSON", func() {
+				createErr := event.PersistModels([]event.InsertionModel{typedModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var res typedEvent
+				dbErr := db.Get(&res, `SELECT amount, amounts::TEXT, address, hash, details FROM public.typedEvent`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(res.Amount).To(Equal("123"))
+				Expect(res.Amounts).To(Equal("{1,NULL,3}"))
+				Expect(res.Address).To(Equal(address.Hex()))
+				Expect(res.Hash).To(Equal(common.HexToHash("0x12").Bytes()))
+				Expect(res.Details).To(MatchJSON(`{"name": "name", "count": 2}`))
+			})
+
+			It("persists nil values and nil pointers as NULL", func() {
+				var nilAmount *big.Int
+				var nilDetails *typedEventDetails
+				typedModel.ColumnValues["amount"] = nilAmount
+				typedModel.ColumnValues["amounts"] = nil
+				typedModel.ColumnValues["details"] = nilDetails
+
+				createErr := event.PersistModels([]event.InsertionModel{typedModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var nullCount int
+				dbErr := db.Get(&nullCount, `SELECT COUNT(*) FROM public.typedEvent
+					WHERE amount IS NULL AND amounts IS NULL AND details IS NULL`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(nullCount).To(Equal(1))
+			})
+
+			It("upserts models supplying the same conflict value as different types", func() {
+				conflictingModel := typedModel
+				conflictingModel.ColumnValues = event.ColumnValues{
+					event.HeaderFK: headerID,
+					event.LogFK:    &logID,
+					"amount":       big.NewInt(456),
+				}
+
+				createErr := event.PersistModels([]event.InsertionModel{typedModel, conflictingModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var amounts []string
+				dbErr := db.Select(&amounts, `SELECT amount FROM public.typedEvent`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(amounts).To(ConsistOf("456"))
+			})
+
+			It("does not collapse models whose conflict values are NULL", func() {
+				db.MustExec(`CREATE UNIQUE INDEX typed_event_address ON public.typedEvent (address)`)
+				typedModel.ConflictColumns = []event.ColumnName{"address"}
+				typedModel.ColumnValues["address"] = nil
+				otherModel := typedModel
+				otherModel.ColumnValues = event.ColumnValues{
+					event.HeaderFK: headerID,
+					event.LogFK:    test_data.CreateTestLog(headerID, db).ID,
+					"address":      nil,
+				}
+
+				createErr := event.PersistModels([]event.InsertionModel{typedModel, otherModel}, db)
+				Expect(createErr).NotTo(HaveOccurred())
+
+				var rowCount int
+				dbErr := db.Get(&rowCount, `SELECT COUNT(*) FROM public.typedEvent`)
+				Expect(dbErr).NotTo(HaveOccurred())
+				Expect(rowCount).To(Equal(2))
+			})
+
+			It("names the column of a value that can't be marshalled", func() {
+				unsupportedValue := unmarshallableDetails{Callback: func() {}}
+				typedModel.ColumnValues["details"] = unsupportedValue
+
+				createErr := event.PersistModels([]event.InsertionModel{typedModel}, db)
+
+				Expect(createErr).To(HaveOccurred())
+				Expect(createErr.Error()).To(ContainSubstring("column details"))
+			})
 		})
 	})
 })
@@ -202,3 +548,20 @@ type FakeEvent struct {
 	LogID     string `db:"log_id"`
 	Variable1 string
 }
+
+type typedEvent struct {
+	Amount  string
+	Amounts string
+	Address string
+	Hash    []byte
+	Details string
+}
+
+type typedEventDetails struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type unmarshallableDetails struct {
+	Callback func()
+}
@@ -35,10 +35,64 @@ type TransformerConfig struct {
 	ContractAddresses   []string
 	ContractAbi         string
 	Topic               string
+	Topic1              string // Optional; restricts logs to those with this topic1. Likewise Topic2 and Topic3.
+	Topic2              string
+	Topic3              string
 	StartingBlockNumber int64
 	EndingBlockNumber   int64 // Set -1 for indefinite transformer
 }
 
+// Topics returns topic0 through topic3, with unset topic filters empty
+func (config TransformerConfig) Topics() core.Topics {
+	return core.Topics{config.Topic, config.Topic1, config.Topic2, config.Topic3}
+}
+
+// TopicFilters returns the config's topics in the positional format of an ethereum.FilterQuery:
+// each position lists the accepted topics, and an empty position accepts any topic.
+func (config TransformerConfig) TopicFilters() [][]common.Hash {
+	return TopicFilters(config.Topics())
+}
+
+// TopicFilters converts topic0 through topic3, with unset topic filters empty, to positional topic filters
+func TopicFilters(topics core.Topics) [][]common.Hash {
+	lastFiltered := 0
+	for i, topic := range topics {
+		if topic != "" {
+			lastFiltered = i
+		}
+	}
+	filters := make([][]common.Hash, lastFiltered+1)
+	for i := 0; i <= lastFiltered; i++ {
+		if i == 0 || topics[i] != "" {
+			filters[i] = []common.Hash{common.HexToHash(topics[i])}
+		}
+	}
+	return filters
+}
+
+// TopicsMatch reports whether a log's topics satisfy positional topic filters
+func TopicsMatch(filters [][]common.Hash, topics []common.Hash) bool {
+	for i, accepted := range filters {
+		if len(accepted) == 0 {
+			continue
+		}
+		if i >= len(topics) || !ContainsHash(accepted, topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsHash reports whether hash is among hashes
+func ContainsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, candidate := range hashes {
+		if candidate == hash {
+			return true
+		}
+	}
+	return false
+}
+
 func HexStringsToAddresses(strings []string) (addresses []common.Address) {
 	for _, hexString := range strings {
 		addresses = append(addresses, common.HexToAddress(hexString))
@@ -74,7 +128,7 @@ func (ct ConfiguredTransformer) Execute(logs []core.EventLog) error {
 		return err
 	}
 
-	err = PersistModels(models, ct.DB)
+	err = PersistTransformedModels(transformerName, logs, models, ct.DB)
 	if err != nil {
 		logrus.Errorf("error persisting %v record: %v", transformerName, err)
 		return err
@@ -87,3 +141,12 @@ func (ct ConfiguredTransformer) Execute(logs []core.EventLog) error {
 func (ct ConfiguredTransformer) GetConfig() TransformerConfig {
 	return ct.Config
 }
+
+// CleanUpOrphanedHeader delegates to the underlying Transformer if it handles orphaned headers
+func (ct ConfiguredTransformer) CleanUpOrphanedHeader(header core.OrphanedHeader, db *postgres.DB) error {
+	handler, ok := ct.Transformer.(OrphanedHeaderHandler)
+	if !ok {
+		return nil
+	}
+	return handler.CleanUpOrphanedHeader(header, db)
+}
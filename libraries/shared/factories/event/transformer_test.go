@@ -19,6 +19,7 @@ package event_test
 import (
 	"math/rand"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/libraries/shared/mocks"
 	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
@@ -71,6 +72,37 @@ var _ = Describe("Transformer", func() {
 		Expect(converter.LogsToConvert).To(Equal(logs))
 	})
 
+	Describe("cleaning up an orphaned header", func() {
+		orphanedHeader := core.OrphanedHeader{ID: rand.Int63(), HeaderID: rand.Int63(), Hash: fakes.FakeHash.Hex()}
+
+		It("passes the orphaned header to the converter if it handles orphaned headers", func() {
+			handlingConverter := mocks.MockOrphanedHeaderHandlingConverter{}
+			cleaner := event.ConfiguredTransformer{Transformer: &handlingConverter, Config: config}
+
+			err := cleaner.CleanUpOrphanedHeader(orphanedHeader, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handlingConverter.PassedOrphanedHeaders).To(ConsistOf(orphanedHeader))
+		})
+
+		It("returns an error if the converter fails to clean up", func() {
+			handlingConverter := mocks.MockOrphanedHeaderHandlingConverter{CleanUpError: fakes.FakeError}
+			cleaner := event.ConfiguredTransformer{Transformer: &handlingConverter, Config: config}
+
+			err := cleaner.CleanUpOrphanedHeader(orphanedHeader, nil)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+		})
+
+		It("does nothing if the converter doesn't handle orphaned headers", func() {
+			cleaner := event.ConfiguredTransformer{Transformer: &converter, Config: config}
+
+			err := cleaner.CleanUpOrphanedHeader(orphanedHeader, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
 	It("returns an error if converting to models fails", func() {
 		converter.ToModelsError = fakes.FakeError
 
@@ -79,4 +111,38 @@ var _ = Describe("Transformer", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError(fakes.FakeError))
 	})
+
+	Describe("TopicFilters", func() {
+		It("returns only topic0 when no topic filters are set", func() {
+			filters := event.TransformerConfig{Topic: "0xA"}.TopicFilters()
+
+			Expect(filters).To(Equal([][]common.Hash{{common.HexToHash("0xA")}}))
+		})
+
+		It("leaves unset positions before a topic filter empty", func() {
+			filters := event.TransformerConfig{Topic: "0xA", Topic2: "0xB"}.TopicFilters()
+
+			Expect(filters).To(Equal([][]common.Hash{{common.HexToHash("0xA")}, nil, {common.HexToHash("0xB")}}))
+		})
+	})
+
+	Describe("TopicsMatch", func() {
+		filters := [][]common.Hash{{common.HexToHash("0xA")}, nil, {common.HexToHash("0xB")}}
+
+		It("matches topics satisfying every filtered position", func() {
+			topics := []common.Hash{common.HexToHash("0xA"), common.HexToHash("0x1"), common.HexToHash("0xB")}
+
+			Expect(event.TopicsMatch(filters, topics)).To(BeTrue())
+		})
+
+		It("does not match topics differing in a filtered position", func() {
+			topics := []common.Hash{common.HexToHash("0xA"), common.HexToHash("0x1"), common.HexToHash("0xC")}
+
+			Expect(event.TopicsMatch(filters, topics)).To(BeFalse())
+		})
+
+		It("does not match logs with too few topics", func() {
+			Expect(event.TopicsMatch(filters, []common.Hash{common.HexToHash("0xA")})).To(BeFalse())
+		})
+	})
 })
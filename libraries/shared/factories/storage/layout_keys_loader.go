@@ -0,0 +1,107 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+// layoutKeysLoader loads metadata from a contract's solc storage layout instead of hand-written slot indexes.
+// Static variables are always loaded. Entries of a mapping or dynamic array are loaded for the keys returned by its
+// query in keyQueries, which is indexed by the variable's path and selects one text column per level of nesting.
+type layoutKeysLoader struct {
+	db         *postgres.DB
+	keyQueries map[string]string
+	layout     storage.Layout
+}
+
+func NewLayoutKeysLoader(layout storage.Layout, keyQueries map[string]string) KeysLoader {
+	return &layoutKeysLoader{keyQueries: keyQueries, layout: layout}
+}
+
+func (loader *layoutKeysLoader) LoadMappings() (map[common.Hash]types.ValueMetadata, error) {
+	mappings, staticErr := loader.layout.StaticMappings()
+	if staticErr != nil {
+		return nil, fmt.Errorf("error loading static storage keys: %w", staticErr)
+	}
+	if len(loader.keyQueries) == 0 {
+		return mappings, nil
+	}
+	templates, templatesErr := loader.layout.KeyTemplates()
+	if templatesErr != nil {
+		return nil, fmt.Errorf("error loading storage key templates: %w", templatesErr)
+	}
+	for path, query := range loader.keyQueries {
+		template, ok := templates[path]
+		if !ok {
+			return nil, fmt.Errorf("storage layout has no mapping or dynamic array %s", path)
+		}
+		entries, entriesErr := loader.loadEntries(template, query)
+		if entriesErr != nil {
+			return nil, fmt.Errorf("error loading storage keys for %s: %w", path, entriesErr)
+		}
+		for key, metadata := range entries {
+			mappings[key] = metadata
+		}
+	}
+	return mappings, nil
+}
+
+func (loader *layoutKeysLoader) loadEntries(template storage.KeyTemplate, query string) (map[common.Hash]types.ValueMetadata, error) {
+	rows, queryErr := loader.db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+	columns, columnsErr := rows.Columns()
+	if columnsErr != nil {
+		return nil, columnsErr
+	}
+	entries := make(map[common.Hash]types.ValueMetadata)
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		destinations := make([]interface{}, len(columns))
+		for i := range values {
+			destinations[i] = &values[i]
+		}
+		if scanErr := rows.Scan(destinations...); scanErr != nil {
+			return nil, scanErr
+		}
+		keys := make([]string, len(values))
+		for i, value := range values {
+			keys[i] = value.String
+		}
+		metadata, metadataErr := template.Metadata(keys...)
+		if metadataErr != nil {
+			return nil, metadataErr
+		}
+		for key, entry := range metadata {
+			entries[key] = entry
+		}
+	}
+	return entries, rows.Err()
+}
+
+func (loader *layoutKeysLoader) SetDB(db *postgres.DB) {
+	loader.db = db
+}
@@ -0,0 +1,108 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
+	sharedStorage "github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const allowanceLayout = `{
+	"storage": [
+		{"label": "supply", "offset": 0, "slot": "0", "type": "t_uint256"},
+		{"label": "allowance", "offset": 0, "slot": "1", "type": "t_mapping(t_address,t_mapping(t_address,t_uint256))"}
+	],
+	"types": {
+		"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+		"t_mapping(t_address,t_mapping(t_address,t_uint256))": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => mapping(address => uint256))", "numberOfBytes": "32", "value": "t_mapping(t_address,t_uint256)"},
+		"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+		"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"}
+	}
+}`
+
+var _ = Describe("Layout keys loader", func() {
+	var layout sharedStorage.Layout
+
+	BeforeEach(func() {
+		var parseErr error
+		layout, parseErr = sharedStorage.ParseLayout([]byte(allowanceLayout))
+		Expect(parseErr).NotTo(HaveOccurred())
+	})
+
+	It("loads static variables from the layout", func() {
+		loader := storage.NewLayoutKeysLoader(layout, nil)
+
+		mappings, err := loader.LoadMappings()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mappings).To(Equal(map[common.Hash]types.ValueMetadata{
+			common.HexToHash(sharedStorage.IndexZero): {Name: "supply", Type: types.Uint256},
+		}))
+	})
+
+	It("returns error if a query is configured for an unknown variable", func() {
+		loader := storage.NewLayoutKeysLoader(layout, map[string]string{"balances": "SELECT 1"})
+
+		_, err := loader.LoadMappings()
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no mapping or dynamic array balances"))
+	})
+
+	Describe("when keys are queried from the database", func() {
+		var (
+			owner   = common.HexToAddress("0x1234")
+			spender = common.HexToAddress("0x5678")
+		)
+
+		It("loads the entries for each row's keys", func() {
+			query := `SELECT '` + owner.Hex() + `' AS owner, '` + spender.Hex() + `' AS spender`
+			loader := storage.NewLayoutKeysLoader(layout, map[string]string{"allowance": query})
+			loader.SetDB(test_config.NewTestDB(test_config.NewTestNode()))
+
+			mappings, err := loader.LoadMappings()
+
+			Expect(err).NotTo(HaveOccurred())
+			key := sharedStorage.GetKeyForNestedMapping(sharedStorage.IndexOne, owner.Hash().Hex(), spender.Hash().Hex())
+			Expect(mappings).To(HaveLen(2))
+			Expect(mappings[key]).To(Equal(types.ValueMetadata{
+				Name: "allowance",
+				Keys: map[types.Key]string{
+					"key0":     owner.Hex(),
+					"key1":     spender.Hex(),
+					types.Path: "allowance[" + owner.Hex() + "][" + spender.Hex() + "]",
+				},
+				Type: types.Uint256,
+			}))
+		})
+
+		It("returns error if the query fails", func() {
+			loader := storage.NewLayoutKeysLoader(layout, map[string]string{"allowance": "SELECT FROM nowhere"})
+			loader.SetDB(test_config.NewTestDB(test_config.NewTestNode()))
+
+			_, err := loader.LoadMappings()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error loading storage keys for allowance"))
+		})
+	})
+})
@@ -0,0 +1,154 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+// MaxPreimageOffset is how many slots past a mapping entry's hash its value may span, e.g. the members of a struct
+var MaxPreimageOffset int64 = 256
+
+// PreimageKeysLookup is a KeysLookup that recognizes mapping entries from recorded preimages. Its mapping positions
+// are recorded by the storage watcher when preimage recording is enabled.
+type PreimageKeysLookup interface {
+	KeysLookup
+	MappingPositions() ([]storage.MappingPosition, error)
+}
+
+// preimageKeysLookup falls back to the preimages in public.storage_preimage for keys the wrapped lookup doesn't
+// recognize, deriving their metadata from the contract's storage layout
+type preimageKeysLookup struct {
+	lookup     KeysLookup
+	layout     storage.Layout
+	repository storage.PreimageRepository
+	templates  map[common.Hash]storage.KeyTemplate
+	maxDepth   int
+	resolved   map[common.Hash]types.ValueMetadata
+}
+
+func NewPreimageKeysLookup(lookup KeysLookup, layout storage.Layout) PreimageKeysLookup {
+	return &preimageKeysLookup{
+		lookup:   lookup,
+		layout:   layout,
+		resolved: make(map[common.Hash]types.ValueMetadata),
+	}
+}
+
+func (lookup *preimageKeysLookup) Lookup(key common.Hash) (types.ValueMetadata, error) {
+	if metadata, ok := lookup.resolved[key]; ok {
+		return metadata, nil
+	}
+	metadata, lookupErr := lookup.lookup.Lookup(key)
+	if !errors.Is(lookupErr, types.ErrKeyNotFound) {
+		return metadata, lookupErr
+	}
+	resolveErr := lookup.resolve(key)
+	if resolveErr != nil {
+		return metadata, fmt.Errorf("error resolving storage key %s from preimages: %w", key.Hex(), resolveErr)
+	}
+	if resolved, ok := lookup.resolved[key]; ok {
+		return resolved, nil
+	}
+	return metadata, lookupErr
+}
+
+func (lookup *preimageKeysLookup) GetKeys() ([]common.Hash, error) {
+	keys, keysErr := lookup.lookup.GetKeys()
+	if keysErr != nil {
+		return keys, keysErr
+	}
+	for key := range lookup.resolved {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (lookup *preimageKeysLookup) MappingPositions() ([]storage.MappingPosition, error) {
+	return lookup.layout.MappingPositions()
+}
+
+func (lookup *preimageKeysLookup) SetDB(db *postgres.DB) {
+	lookup.lookup.SetDB(db)
+	lookup.repository = storage.NewPreimageRepository(db)
+}
+
+// resolve follows preimages from the key back to a mapping's slot, collecting the keys of each level of nesting,
+// and caches the metadata of the entry they lead to
+func (lookup *preimageKeysLookup) resolve(key common.Hash) error {
+	templatesErr := lookup.loadTemplates()
+	if templatesErr != nil {
+		return templatesErr
+	}
+	var keys []common.Hash
+	hash, maxOffset := key, MaxPreimageOffset
+	for depth := 0; depth < lookup.maxDepth; depth++ {
+		preimage, preimageErr := lookup.repository.GetPreimage(hash, maxOffset)
+		if errors.Is(preimageErr, sql.ErrNoRows) {
+			return nil
+		}
+		if preimageErr != nil {
+			return preimageErr
+		}
+		keys = append([]common.Hash{preimage.Key}, keys...)
+		template, ok := lookup.templates[preimage.Slot]
+		if !ok {
+			// the slot of a nested mapping is itself the hash of an entry of the outer mapping
+			hash, maxOffset = preimage.Slot, 0
+			continue
+		}
+		mappings, metadataErr := template.MetadataForEncodedKeys(keys...)
+		if metadataErr != nil {
+			// the keys weren't recorded for this contract's mapping and don't fit its key types
+			return nil
+		}
+		for mappingKey, metadata := range mappings {
+			lookup.resolved[mappingKey] = metadata
+		}
+		return nil
+	}
+	return nil
+}
+
+func (lookup *preimageKeysLookup) loadTemplates() error {
+	if lookup.templates != nil {
+		return nil
+	}
+	templates, templatesErr := lookup.layout.KeyTemplates()
+	if templatesErr != nil {
+		return fmt.Errorf("error loading storage key templates: %w", templatesErr)
+	}
+	lookup.templates = make(map[common.Hash]storage.KeyTemplate)
+	for _, template := range templates {
+		depth := template.MappingDepth()
+		if depth == 0 {
+			continue
+		}
+		lookup.templates[template.Slot] = template
+		if depth > lookup.maxDepth {
+			lookup.maxDepth = depth
+		}
+	}
+	return nil
+}
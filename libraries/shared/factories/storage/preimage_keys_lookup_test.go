@@ -0,0 +1,171 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/mocks"
+	sharedStorage "github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const ilksLayout = `{
+	"storage": [
+		{"label": "ilks", "offset": 0, "slot": "2", "type": "t_mapping(t_bytes32,t_struct(Ilk)10_storage)"}
+	],
+	"types": {
+		"t_bytes32": {"encoding": "inplace", "label": "bytes32", "numberOfBytes": "32"},
+		"t_mapping(t_bytes32,t_struct(Ilk)10_storage)": {"encoding": "mapping", "key": "t_bytes32", "label": "mapping(bytes32 => struct Example.Ilk)", "numberOfBytes": "32", "value": "t_struct(Ilk)10_storage"},
+		"t_struct(Ilk)10_storage": {"encoding": "inplace", "label": "struct Example.Ilk", "numberOfBytes": "64", "members": [
+			{"label": "Art", "offset": 0, "slot": "0", "type": "t_uint256"},
+			{"label": "rate", "offset": 0, "slot": "1", "type": "t_uint256"}
+		]},
+		"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"}
+	}
+}`
+
+var _ = Describe("Preimage keys lookup", func() {
+	var (
+		wrappedLookup *mocks.MockStorageKeysLookup
+		layout        sharedStorage.Layout
+		lookup        storage.PreimageKeysLookup
+	)
+
+	BeforeEach(func() {
+		var parseErr error
+		layout, parseErr = sharedStorage.ParseLayout([]byte(allowanceLayout))
+		Expect(parseErr).NotTo(HaveOccurred())
+		wrappedLookup = &mocks.MockStorageKeysLookup{}
+		lookup = storage.NewPreimageKeysLookup(wrappedLookup, layout)
+	})
+
+	It("returns metadata recognized by the wrapped lookup", func() {
+		wrappedLookup.Metadata = types.GetValueMetadata("supply", nil, types.Uint256)
+
+		metadata, err := lookup.Lookup(common.HexToHash(sharedStorage.IndexZero))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metadata).To(Equal(wrappedLookup.Metadata))
+	})
+
+	It("returns errors from the wrapped lookup other than the key not being found", func() {
+		wrappedLookup.LookupErr = fakes.FakeError
+
+		_, err := lookup.Lookup(common.HexToHash(sharedStorage.IndexZero))
+
+		Expect(err).To(MatchError(fakes.FakeError))
+	})
+
+	It("returns the positions of the layout's mappings", func() {
+		positions, err := lookup.MappingPositions()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(positions).To(ConsistOf(sharedStorage.MappingPosition{Slot: common.HexToHash(sharedStorage.IndexOne), Depth: 2}))
+	})
+
+	Describe("when the wrapped lookup doesn't recognize a key", func() {
+		var (
+			db         = test_config.NewTestDB(test_config.NewTestNode())
+			owner      = common.HexToAddress("0x1234")
+			spender    = common.HexToAddress("0x5678")
+			repository sharedStorage.PreimageRepository
+		)
+
+		BeforeEach(func() {
+			test_config.CleanTestDB(db)
+			wrappedLookup.LookupErr = types.ErrKeyNotFound
+			lookup.SetDB(db)
+			repository = sharedStorage.NewPreimageRepository(db)
+		})
+
+		recordKeys := func(slot common.Hash, keys ...common.Hash) {
+			position := sharedStorage.MappingPosition{Slot: slot, Depth: len(keys)}
+			// record every ordering of the keys, as they would be for a log with these arguments
+			preimages := sharedStorage.MappingPreimages(keys, position, sharedStorage.EventLogPreimageSource, 1)
+			_, createErr := repository.CreatePreimages(preimages)
+			Expect(createErr).NotTo(HaveOccurred())
+		}
+
+		It("resolves entries of nested mappings from their preimages", func() {
+			recordKeys(common.HexToHash(sharedStorage.IndexOne), owner.Hash(), spender.Hash())
+			key := sharedStorage.GetKeyForNestedMapping(sharedStorage.IndexOne, owner.Hash().Hex(), spender.Hash().Hex())
+
+			metadata, err := lookup.Lookup(key)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(Equal(types.ValueMetadata{
+				Name: "allowance",
+				Keys: map[types.Key]string{
+					"key0":     owner.Hex(),
+					"key1":     spender.Hex(),
+					types.Path: "allowance[" + owner.Hex() + "][" + spender.Hex() + "]",
+				},
+				Type: types.Uint256,
+			}))
+		})
+
+		It("resolves members of structs in a mapping", func() {
+			structLayout, parseErr := sharedStorage.ParseLayout([]byte(ilksLayout))
+			Expect(parseErr).NotTo(HaveOccurred())
+			lookup = storage.NewPreimageKeysLookup(wrappedLookup, structLayout)
+			lookup.SetDB(db)
+			ilk := common.RightPadBytes([]byte("ETH-A"), 32)
+			recordKeys(common.HexToHash(sharedStorage.IndexTwo), common.BytesToHash(ilk))
+			rateKey := sharedStorage.GetIncrementedKey(sharedStorage.GetKeyForMapping(sharedStorage.IndexTwo, common.Bytes2Hex(ilk)), 1)
+
+			metadata, err := lookup.Lookup(rateKey)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata.Name).To(Equal("ilks.rate"))
+			Expect(metadata.Keys["key0"]).To(Equal(common.ToHex(ilk)))
+		})
+
+		It("includes resolved keys in the lookup's keys", func() {
+			recordKeys(common.HexToHash(sharedStorage.IndexOne), owner.Hash(), spender.Hash())
+			key := sharedStorage.GetKeyForNestedMapping(sharedStorage.IndexOne, owner.Hash().Hex(), spender.Hash().Hex())
+			_, lookupErr := lookup.Lookup(key)
+			Expect(lookupErr).NotTo(HaveOccurred())
+
+			keys, err := lookup.GetKeys()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(ContainElement(key))
+		})
+
+		It("returns ErrKeyNotFound if the key has no preimage", func() {
+			_, err := lookup.Lookup(fakes.FakeHash)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(types.ErrKeyNotFound))
+		})
+
+		It("returns ErrKeyNotFound if the preimage's keys don't fit the mapping", func() {
+			notAnAddress := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+			recordKeys(common.HexToHash(sharedStorage.IndexOne), notAnAddress, spender.Hash())
+			key := sharedStorage.GetKeyForNestedMapping(sharedStorage.IndexOne, notAnAddress.Hex(), spender.Hash().Hex())
+
+			_, err := lookup.Lookup(key)
+
+			Expect(err).To(MatchError(types.ErrKeyNotFound))
+		})
+	})
+})
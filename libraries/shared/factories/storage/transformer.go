@@ -17,6 +17,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -38,7 +39,9 @@ type Transformer struct {
 	Address           common.Address
 	StorageKeysLookup KeysLookup
 	Repository        Repository
-	hashedAddress     common.Hash
+	// DiffRepository supplies the data slots of string and bytes values too long to fit in their own slot
+	DiffRepository storage.DiffRepository
+	hashedAddress  common.Hash
 }
 
 func (transformer Transformer) GetStorageKeysLookup() KeysLookup {
@@ -52,6 +55,7 @@ func (transformer Transformer) GetContractAddress() common.Address {
 func (transformer Transformer) NewTransformer(db *postgres.DB) ITransformer {
 	transformer.StorageKeysLookup.SetDB(db)
 	transformer.Repository.SetDB(db)
+	transformer.DiffRepository = storage.NewDiffRepository(db)
 	return &transformer
 }
 
@@ -70,6 +74,26 @@ func (transformer Transformer) Execute(diff types.PersistedDiff) error {
 	if lookupErr != nil {
 		return fmt.Errorf("error getting metadata for storage key: %w", lookupErr)
 	}
-	value := storage.Decode(diff, metadata)
+	value, decodeErr := storage.Decode(diff, metadata)
+	if errors.As(decodeErr, &types.ErrValueSpansSlots{}) {
+		value, decodeErr = transformer.decodeDynamicValue(diff, metadata)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("error decoding storage value for %s: %w", metadata.Name, decodeErr)
+	}
 	return transformer.Repository.Create(diff.ID, diff.HeaderID, metadata, value)
 }
+
+// decodeDynamicValue decodes a string or bytes value spanning several slots from the diff of its length slot and
+// the values of its data slots as of the diff's block
+func (transformer Transformer) decodeDynamicValue(diff types.PersistedDiff, metadata types.ValueMetadata) (string, error) {
+	dataSlots, slotsErr := storage.DynamicValueDataSlots(diff.StorageKey, diff.StorageValue)
+	if slotsErr != nil {
+		return "", slotsErr
+	}
+	dataSlotValues, valuesErr := transformer.DiffRepository.GetStorageValues(diff.HashedAddress, dataSlots, diff.BlockHeight)
+	if valuesErr != nil {
+		return "", fmt.Errorf("error getting data slots: %w", valuesErr)
+	}
+	return storage.DecodeDynamicValue(diff.StorageValue, dataSlotValues, metadata.Type)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/mocks"
+	sharedStorage "github.com/makerdao/vulcanizedb/libraries/shared/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	"github.com/makerdao/vulcanizedb/pkg/fakes"
 	. "github.com/onsi/ginkgo"
@@ -32,16 +33,19 @@ var _ = Describe("Storage transformer", func() {
 	var (
 		storageKeysLookup *mocks.MockStorageKeysLookup
 		repository        *mocks.MockStorageRepository
+		diffRepository    *mocks.MockStorageDiffRepository
 		t                 storage.Transformer
 	)
 
 	BeforeEach(func() {
 		storageKeysLookup = &mocks.MockStorageKeysLookup{}
 		repository = &mocks.MockStorageRepository{}
+		diffRepository = &mocks.MockStorageDiffRepository{}
 		t = storage.Transformer{
 			Address:           common.Address{},
 			StorageKeysLookup: storageKeysLookup,
 			Repository:        repository,
+			DiffRepository:    diffRepository,
 		}
 	})
 
@@ -103,6 +107,16 @@ var _ = Describe("Storage transformer", func() {
 		Expect(repository.PassedValue.(string)).To(Equal(rawValue.Hex()))
 	})
 
+	It("returns error if decoding the value fails", func() {
+		storageKeysLookup.Metadata = types.ValueMetadata{Name: "fake_name", Type: types.ValueType(-1)}
+
+		err := t.Execute(types.PersistedDiff{})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("error decoding storage value for fake_name"))
+		Expect(repository.PassedMetadata).To(Equal(types.ValueMetadata{}))
+	})
+
 	It("returns error if creating row fails", func() {
 		rawValue := common.HexToAddress("0x12345")
 		fakeMetadata := types.ValueMetadata{Type: types.Address}
@@ -116,6 +130,68 @@ var _ = Describe("Storage transformer", func() {
 		Expect(err).To(MatchError(fakes.FakeError))
 	})
 
+	Describe("when a string is too long to fit in its slot", func() {
+		var (
+			fakeMetadata = types.ValueMetadata{Name: "name", Type: types.String}
+			lengthSlot   = common.HexToHash("0x3")
+			// twice the length of the 40 byte string plus one
+			lengthSlotValue = common.HexToHash("0x51")
+			dataSlotValues  = []common.Hash{
+				common.BytesToHash([]byte("abcdefghijklmnopqrstuvwxyz012345")),
+				common.BytesToHash(append([]byte("6789ABCD"), make([]byte, 24)...)),
+			}
+			diff types.PersistedDiff
+		)
+
+		BeforeEach(func() {
+			storageKeysLookup.Metadata = fakeMetadata
+			diff = types.PersistedDiff{
+				ID:       rand.Int63(),
+				HeaderID: rand.Int63(),
+				RawDiff: types.RawDiff{
+					HashedAddress: fakes.FakeHash,
+					BlockHeight:   rand.Int(),
+					StorageKey:    lengthSlot,
+					StorageValue:  lengthSlotValue,
+				},
+			}
+		})
+
+		It("gets the values of its data slots as of the diff's block", func() {
+			diffRepository.GetStorageValuesToReturn = dataSlotValues
+
+			err := t.Execute(diff)
+
+			Expect(err).NotTo(HaveOccurred())
+			expectedSlots, slotsErr := sharedStorage.DynamicValueDataSlots(lengthSlot, lengthSlotValue)
+			Expect(slotsErr).NotTo(HaveOccurred())
+			Expect(diffRepository.GetStorageValuesPassedHashedAddress).To(Equal(diff.HashedAddress))
+			Expect(diffRepository.GetStorageValuesPassedKeys).To(Equal(expectedSlots))
+			Expect(diffRepository.GetStorageValuesPassedBlockHeight).To(Equal(diff.BlockHeight))
+		})
+
+		It("creates storage row with the value decoded from its data slots", func() {
+			diffRepository.GetStorageValuesToReturn = dataSlotValues
+
+			err := t.Execute(diff)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repository.PassedDiffID).To(Equal(diff.ID))
+			Expect(repository.PassedMetadata).To(Equal(fakeMetadata))
+			Expect(repository.PassedValue).To(Equal("abcdefghijklmnopqrstuvwxyz0123456789ABCD"))
+		})
+
+		It("returns error if getting its data slots fails", func() {
+			diffRepository.GetStorageValuesErr = types.ErrKeyNotFound
+
+			err := t.Execute(diff)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(types.ErrKeyNotFound))
+			Expect(repository.PassedMetadata).To(Equal(types.ValueMetadata{}))
+		})
+	})
+
 	Describe("when a storage row contains more than one item packed in storage", func() {
 		var (
 			rawValue     = common.HexToAddress("000000000000000000000000000000000000000000000002a300000000002a30")
@@ -17,16 +17,32 @@
 package fetcher
 
 import (
+	"math/big"
+	"sort"
+	"strings"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
 
 	"github.com/makerdao/vulcanizedb/pkg/core"
 )
 
+// Error messages nodes and hosted providers return when a log query matches too many results
+var tooManyResultsMessages = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response size should not",
+	"log response size",
+	"too many results",
+	"limit exceeded",
+	"query timeout exceeded",
+}
+
 type ILogFetcher interface {
-	FetchLogs(contractAddresses []common.Address, topics []common.Hash, missingHeader core.Header) ([]types.Log, error)
-	// TODO Extend FetchLogs for doing several blocks at a time
+	FetchLogs(contractAddresses []common.Address, topics [][]common.Hash, missingHeader core.Header) ([]types.Log, error)
+	FetchLogsForRange(contractAddresses []common.Address, topics [][]common.Hash, headers []core.Header) ([]types.Log, error)
 }
 
 type LogFetcher struct {
@@ -39,14 +55,14 @@ func NewLogFetcher(blockchain core.BlockChain) *LogFetcher {
 	}
 }
 
-// Checks all topic0s, on all addresses, fetching matching logs for the given header
-func (logFetcher LogFetcher) FetchLogs(addresses []common.Address, topic0s []common.Hash, header core.Header) ([]types.Log, error) {
+// Checks all topics, on all addresses, fetching matching logs for the given header
+func (logFetcher LogFetcher) FetchLogs(addresses []common.Address, topics [][]common.Hash, header core.Header) ([]types.Log, error) {
 	blockHash := common.HexToHash(header.Hash)
 	query := ethereum.FilterQuery{
 		BlockHash: &blockHash,
 		Addresses: addresses,
-		// Search for _any_ of the topics in topic0 position; see docs on `FilterQuery`
-		Topics: [][]common.Hash{topic0s},
+		// Search for _any_ of the topics in each position; see docs on `FilterQuery`
+		Topics: topics,
 	}
 
 	logs, err := logFetcher.blockChain.GetEthLogsWithCustomQuery(query)
@@ -57,3 +73,115 @@ func (logFetcher LogFetcher) FetchLogs(addresses []common.Address, topic0s []com
 
 	return logs, nil
 }
+
+// FetchLogsForRange fetches matching logs for all the given headers using block range queries.
+// Every returned log belongs to one of the headers: logs whose block hash doesn't match the stored header are
+// discarded and that header's logs are re-fetched by its hash. Ranges are split in half when the node reports
+// that a query matched too many results.
+func (logFetcher LogFetcher) FetchLogsForRange(addresses []common.Address, topics [][]common.Hash, headers []core.Header) ([]types.Log, error) {
+	var result []types.Log
+	for _, run := range contiguousRuns(headers) {
+		logs, err := logFetcher.fetchRun(addresses, topics, run)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, logs...)
+	}
+	return result, nil
+}
+
+func (logFetcher LogFetcher) fetchRun(addresses []common.Address, topics [][]common.Hash, run []core.Header) ([]types.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(run[0].BlockNumber),
+		ToBlock:   big.NewInt(run[len(run)-1].BlockNumber),
+		Addresses: addresses,
+		Topics:    topics,
+	}
+	logs, err := logFetcher.blockChain.GetEthLogsWithCustomQuery(query)
+	if err != nil {
+		if !isTooManyResults(err) || len(run) < 2 {
+			return nil, err
+		}
+		logrus.Debugf("log query for blocks %d to %d returned too many results, splitting range", run[0].BlockNumber, run[len(run)-1].BlockNumber)
+		middle := len(run) / 2
+		firstHalf, firstErr := logFetcher.fetchRun(addresses, topics, run[:middle])
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		secondHalf, secondErr := logFetcher.fetchRun(addresses, topics, run[middle:])
+		if secondErr != nil {
+			return nil, secondErr
+		}
+		return append(firstHalf, secondHalf...), nil
+	}
+	return logFetcher.matchLogsToHeaders(addresses, topics, run, logs)
+}
+
+// matchLogsToHeaders keeps the logs whose block hash matches a stored header. If the node returned logs for a
+// different block at a header's height, the stored header may have been reorged out, so that header's logs are
+// fetched by its hash instead.
+func (logFetcher LogFetcher) matchLogsToHeaders(addresses []common.Address, topics [][]common.Hash, headers []core.Header, logs []types.Log) ([]types.Log, error) {
+	logsByBlockNumber := make(map[uint64][]types.Log)
+	for _, log := range logs {
+		logsByBlockNumber[log.BlockNumber] = append(logsByBlockNumber[log.BlockNumber], log)
+	}
+
+	var result []types.Log
+	for _, header := range headers {
+		headerHash := common.HexToHash(header.Hash)
+		logsAtHeight := logsByBlockNumber[uint64(header.BlockNumber)]
+		if allLogsInBlock(logsAtHeight, headerHash) {
+			result = append(result, logsAtHeight...)
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"blockNumber": header.BlockNumber,
+			"headerHash":  header.Hash,
+		}).Warn("logs returned for block don't match stored header, fetching logs by block hash")
+		headerLogs, err := logFetcher.FetchLogs(addresses, topics, header)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, headerLogs...)
+	}
+	return result, nil
+}
+
+func allLogsInBlock(logs []types.Log, blockHash common.Hash) bool {
+	for _, log := range logs {
+		if log.BlockHash != blockHash {
+			return false
+		}
+	}
+	return true
+}
+
+// contiguousRuns sorts headers by block number and groups them into runs without gaps, so range queries
+// don't cover blocks that weren't requested
+func contiguousRuns(headers []core.Header) [][]core.Header {
+	sorted := make([]core.Header, len(headers))
+	copy(sorted, headers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].BlockNumber < sorted[j].BlockNumber
+	})
+
+	var runs [][]core.Header
+	for i, header := range sorted {
+		if i == 0 || header.BlockNumber > sorted[i-1].BlockNumber+1 {
+			runs = append(runs, []core.Header{header})
+			continue
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], header)
+	}
+	return runs
+}
+
+func isTooManyResults(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, candidate := range tooManyResultsMessages {
+		if strings.Contains(message, candidate) {
+			return true
+		}
+	}
+	return false
+}
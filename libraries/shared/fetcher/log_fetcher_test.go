@@ -17,8 +17,12 @@
 package fetcher_test
 
 import (
+	"errors"
+	"math/big"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -41,7 +45,7 @@ var _ = Describe("LogFetcher", func() {
 
 			topicZeros := []common.Hash{common.BytesToHash([]byte{1, 2, 3, 4, 5})}
 
-			_, err := logFetcher.FetchLogs(addresses, topicZeros, header)
+			_, err := logFetcher.FetchLogs(addresses, [][]common.Hash{topicZeros}, header)
 
 			address1 := common.HexToAddress("0xfakeAddress")
 			address2 := common.HexToAddress("0xanotherFakeAddress")
@@ -61,10 +65,127 @@ var _ = Describe("LogFetcher", func() {
 			blockChain.SetGetEthLogsWithCustomQueryErr(fakes.FakeError)
 			logFetcher := fetcher.NewLogFetcher(blockChain)
 
-			_, err := logFetcher.FetchLogs([]common.Address{}, []common.Hash{}, core.Header{})
+			_, err := logFetcher.FetchLogs([]common.Address{}, [][]common.Hash{}, core.Header{})
 
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError(fakes.FakeError))
 		})
 	})
+
+	Describe("FetchLogsForRange", func() {
+		var (
+			blockChain *fakes.MockBlockChain
+			logFetcher *fetcher.LogFetcher
+			addresses  = []common.Address{common.HexToAddress("0xfakeAddress")}
+			topicZeros = []common.Hash{common.BytesToHash([]byte{1, 2, 3, 4, 5})}
+			headers    []core.Header
+		)
+
+		BeforeEach(func() {
+			blockChain = fakes.NewMockBlockChain()
+			logFetcher = fetcher.NewLogFetcher(blockChain)
+			headers = []core.Header{
+				{BlockNumber: 10, Hash: common.HexToHash("0x10").Hex()},
+				{BlockNumber: 11, Hash: common.HexToHash("0x11").Hex()},
+				{BlockNumber: 12, Hash: common.HexToHash("0x12").Hex()},
+				{BlockNumber: 13, Hash: common.HexToHash("0x13").Hex()},
+			}
+		})
+
+		It("fetches logs for contiguous headers with a single block range query", func() {
+			_, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blockChain.LogQueries).To(ConsistOf(ethereum.FilterQuery{
+				FromBlock: big.NewInt(10),
+				ToBlock:   big.NewInt(13),
+				Addresses: addresses,
+				Topics:    [][]common.Hash{topicZeros},
+			}))
+		})
+
+		It("queries each contiguous run of headers separately", func() {
+			gappedHeaders := []core.Header{headers[3], headers[0], headers[1]}
+
+			_, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, gappedHeaders)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blockChain.LogQueries).To(HaveLen(2))
+			Expect(blockChain.LogQueries[0].FromBlock).To(Equal(big.NewInt(10)))
+			Expect(blockChain.LogQueries[0].ToBlock).To(Equal(big.NewInt(11)))
+			Expect(blockChain.LogQueries[1].FromBlock).To(Equal(big.NewInt(13)))
+			Expect(blockChain.LogQueries[1].ToBlock).To(Equal(big.NewInt(13)))
+		})
+
+		It("returns logs matching the stored headers", func() {
+			logs := []types.Log{
+				{BlockNumber: 10, BlockHash: common.HexToHash(headers[0].Hash), Index: 1},
+				{BlockNumber: 12, BlockHash: common.HexToHash(headers[2].Hash), Index: 2},
+			}
+			blockChain.SetGetEthLogsWithCustomQueryReturnLogs(logs)
+
+			result, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(logs))
+		})
+
+		It("splits the range when the node reports too many results", func() {
+			blockChain.SetGetEthLogsWithCustomQueryFunc(func(query ethereum.FilterQuery) ([]types.Log, error) {
+				if query.BlockHash == nil && query.ToBlock.Int64()-query.FromBlock.Int64() > 1 {
+					return nil, errors.New("query returned more than 10000 results")
+				}
+				return nil, nil
+			})
+
+			_, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blockChain.LogQueries).To(HaveLen(3))
+			Expect(blockChain.LogQueries[1].FromBlock).To(Equal(big.NewInt(10)))
+			Expect(blockChain.LogQueries[1].ToBlock).To(Equal(big.NewInt(11)))
+			Expect(blockChain.LogQueries[2].FromBlock).To(Equal(big.NewInt(12)))
+			Expect(blockChain.LogQueries[2].ToBlock).To(Equal(big.NewInt(13)))
+		})
+
+		It("returns the error if a single block query returns too many results", func() {
+			tooManyResultsErr := errors.New("query returned more than 10000 results")
+			blockChain.SetGetEthLogsWithCustomQueryErr(tooManyResultsErr)
+
+			_, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).To(MatchError(tooManyResultsErr))
+			Expect(blockChain.LogQueries).To(HaveLen(3))
+		})
+
+		It("fetches logs by block hash when returned logs don't match a stored header", func() {
+			reorgedLog := types.Log{BlockNumber: 11, BlockHash: common.HexToHash("0xabc"), Index: 1}
+			canonicalLog := types.Log{BlockNumber: 11, BlockHash: common.HexToHash(headers[1].Hash), Index: 2}
+			blockChain.SetGetEthLogsWithCustomQueryFunc(func(query ethereum.FilterQuery) ([]types.Log, error) {
+				if query.BlockHash != nil {
+					return []types.Log{canonicalLog}, nil
+				}
+				return []types.Log{reorgedLog}, nil
+			})
+
+			result, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ConsistOf(canonicalLog))
+			blockHash := common.HexToHash(headers[1].Hash)
+			blockChain.AssertGetEthLogsWithCustomQueryCalledWith(ethereum.FilterQuery{
+				BlockHash: &blockHash,
+				Addresses: addresses,
+				Topics:    [][]common.Hash{topicZeros},
+			})
+		})
+
+		It("returns an error if fetching the logs fails", func() {
+			blockChain.SetGetEthLogsWithCustomQueryErr(fakes.FakeError)
+
+			_, err := logFetcher.FetchLogsForRange(addresses, [][]common.Hash{topicZeros}, headers)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+		})
+	})
 })
@@ -18,6 +18,8 @@ package logs
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/makerdao/vulcanizedb/libraries/shared/chunker"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
@@ -31,23 +33,39 @@ import (
 var (
 	ErrNoLogs         = errors.New("no logs available for transforming")
 	ErrNoTransformers = errors.New("no event transformers configured in the log delegator")
+	// DefaultMaxTransformAttempts is how many times a transformer may fail on a log before the log is skipped
+	DefaultMaxTransformAttempts = 3
+	// DefaultTransformRetryDelay is how long a log that failed transformation waits before its next attempt, doubling
+	// with each further failure
+	DefaultTransformRetryDelay = time.Minute
 )
 
 type ILogDelegator interface {
 	AddTransformer(t event.ITransformer)
+	CleanUpOrphanedHeaders() error
 	DelegateLogs(limit int) error
 }
 
 type LogDelegator struct {
-	Chunker       chunker.Chunker
-	LogRepository datastore.EventLogRepository
-	Transformers  []event.ITransformer
+	Chunker                   chunker.Chunker
+	DeadLetterRepository      datastore.DeadLetterRepository
+	LogRepository             datastore.EventLogRepository
+	MaxTransformAttempts      int
+	OrphanedHeadersRepository datastore.OrphanedHeadersRepository
+	TransformRetryDelay       time.Duration
+	Transformers              []event.ITransformer
+	db                        *postgres.DB
 }
 
 func NewLogDelegator(db *postgres.DB) *LogDelegator {
 	return &LogDelegator{
-		Chunker:       chunker.NewLogChunker(),
-		LogRepository: repositories.NewEventLogRepository(db),
+		Chunker:                   chunker.NewLogChunker(),
+		DeadLetterRepository:      repositories.NewDeadLetterRepository(db),
+		LogRepository:             repositories.NewEventLogRepository(db),
+		MaxTransformAttempts:      DefaultMaxTransformAttempts,
+		OrphanedHeadersRepository: repositories.NewOrphanedHeadersRepository(db),
+		TransformRetryDelay:       DefaultTransformRetryDelay,
+		db:                        db,
 	}
 }
 
@@ -56,48 +74,201 @@ func (delegator *LogDelegator) AddTransformer(t event.ITransformer) {
 	delegator.Chunker.AddConfig(t.GetConfig())
 }
 
+// DelegateLogs pages through each transformer's untransformed logs separately, so that every transformer receives
+// each of its logs once regardless of how the others fare. It returns ErrNoLogs when no transformer has logs left to
+// transform.
 func (delegator *LogDelegator) DelegateLogs(limit int) error {
 	if len(delegator.Transformers) < 1 {
 		return ErrNoTransformers
 	}
 
+	caughtUp := true
+	for _, t := range delegator.Transformers {
+		transformerCaughtUp, err := delegator.delegateTransformerLogs(t, limit)
+		if err != nil {
+			return err
+		}
+		caughtUp = caughtUp && transformerCaughtUp
+	}
+	if caughtUp {
+		return ErrNoLogs
+	}
+	return nil
+}
+
+// delegateTransformerLogs reports whether the transformer is caught up: either its last page of logs was empty, or
+// every log it was given is being skipped as dead-lettered or waiting to be attempted again
+func (delegator *LogDelegator) delegateTransformerLogs(t event.ITransformer, limit int) (bool, error) {
+	config := t.GetConfig()
 	minID := 0
+	fetchedLogs, skippedLogs := 0, 0
 	for {
-		persistedLogs, fetchErr := delegator.LogRepository.GetUntransformedEventLogs(minID, limit)
+		persistedLogs, fetchErr := delegator.LogRepository.GetUntransformedEventLogs(config.TransformerName,
+			config.ContractAddresses, config.Topics(), minID, limit)
 		if fetchErr != nil {
 			logrus.Errorf("error loading logs from db: %s", fetchErr.Error())
-			return fetchErr
+			return false, fetchErr
 		}
 
 		lenPersistedLogs := len(persistedLogs)
 
 		if lenPersistedLogs < 1 {
-			return ErrNoLogs
+			return true, nil
 		} else {
 			minID = int(persistedLogs[lenPersistedLogs-1].ID)
 		}
 
-		transformErr := delegator.delegateLogs(persistedLogs)
+		skipped, transformErr := delegator.delegateLogs(t, persistedLogs)
 		if transformErr != nil {
 			logrus.Errorf("error transforming logs: %s", transformErr)
-			return transformErr
+			return false, transformErr
 		}
+		fetchedLogs += lenPersistedLogs
+		skippedLogs += skipped
 
 		if lenPersistedLogs < limit {
-			return nil
+			return fetchedLogs == skippedLogs, nil
 		}
 	}
 }
 
-func (delegator *LogDelegator) delegateLogs(logs []core.EventLog) error {
-	chunkedLogs := delegator.Chunker.ChunkLogs(logs)
+// CleanUpOrphanedHeaders gives each transformer a chance to remove data derived from headers replaced by a reorg.
+// Headers are marked handled per transformer, so a transformer added later still cleans up earlier orphans.
+func (delegator *LogDelegator) CleanUpOrphanedHeaders() error {
 	for _, t := range delegator.Transformers {
-		transformerName := t.GetConfig().TransformerName
-		logChunk := chunkedLogs[transformerName]
-		err := t.Execute(logChunk)
-		if err != nil {
-			logrus.Errorf("%v transformer failed to execute in watcher: %v", transformerName, err)
-			return err
+		handler, ok := t.(event.OrphanedHeaderHandler)
+		if !ok {
+			continue
+		}
+		cleanUpErr := delegator.cleanUpOrphanedHeaders(t.GetConfig().TransformerName, handler)
+		if cleanUpErr != nil {
+			return cleanUpErr
+		}
+	}
+	return nil
+}
+
+func (delegator *LogDelegator) cleanUpOrphanedHeaders(transformerName string, handler event.OrphanedHeaderHandler) error {
+	orphanedHeaders, fetchErr := delegator.OrphanedHeadersRepository.GetUnhandledOrphanedHeaders(transformerName)
+	if fetchErr != nil {
+		return fmt.Errorf("error getting orphaned headers for %s transformer: %w", transformerName, fetchErr)
+	}
+
+	for _, orphanedHeader := range orphanedHeaders {
+		cleanUpErr := handler.CleanUpOrphanedHeader(orphanedHeader, delegator.db)
+		if cleanUpErr != nil {
+			return fmt.Errorf("%s transformer failed to clean up orphaned header %d: %w",
+				transformerName, orphanedHeader.HeaderID, cleanUpErr)
+		}
+
+		markHandledErr := delegator.OrphanedHeadersRepository.MarkOrphanedHeaderHandled(orphanedHeader.ID, transformerName)
+		if markHandledErr != nil {
+			return fmt.Errorf("error marking orphaned header %d handled by %s transformer: %w",
+				orphanedHeader.HeaderID, transformerName, markHandledErr)
+		}
+		logrus.WithFields(logrus.Fields{
+			"headerId":    orphanedHeader.HeaderID,
+			"blockNumber": orphanedHeader.BlockNumber,
+			"orphanHash":  orphanedHeader.Hash,
+			"transformer": transformerName,
+		}).Info("cleaned up data derived from orphaned header")
+	}
+	return nil
+}
+
+// delegateLogs passes the transformer its logs and returns how many were skipped as dead-lettered. When the
+// transformer fails, the logs it cannot transform are dead-lettered instead of blocking the rest.
+func (delegator *LogDelegator) delegateLogs(t event.ITransformer, logs []core.EventLog) (int, error) {
+	transformerName := t.GetConfig().TransformerName
+	matchingLogs := delegator.Chunker.ChunkLogs(logs)[transformerName]
+	logChunk, skipErr := delegator.withoutSkippedLogs(transformerName, matchingLogs)
+	if skipErr != nil {
+		return 0, fmt.Errorf("error getting dead-lettered logs for %s transformer: %w", transformerName, skipErr)
+	}
+	skipped := len(matchingLogs) - len(logChunk)
+
+	err := t.Execute(logChunk)
+	if err == nil {
+		return skipped, delegator.markLogsTransformed(transformerName, logChunk)
+	}
+	logrus.Errorf("%v transformer failed to execute in watcher: %v", transformerName, err)
+	// a failure without logs can't be pinned on any of them, so it's left to the watcher's retries
+	if len(logChunk) == 0 {
+		return skipped, err
+	}
+	return skipped, delegator.deadLetterFailingLogs(t, logChunk, err)
+}
+
+// markLogsTransformed records that the transformer has transformed the logs, so that they are not delivered to it
+// again. Transformers persisting through event.PersistTransformedModels have already recorded them.
+func (delegator *LogDelegator) markLogsTransformed(transformerName string, logs []core.EventLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	logIDs := make([]int64, len(logs))
+	for i, log := range logs {
+		logIDs[i] = log.ID
+	}
+	err := delegator.LogRepository.MarkLogsTransformed(transformerName, logIDs)
+	if err != nil {
+		return fmt.Errorf("error marking logs transformed by %s transformer: %w", transformerName, err)
+	}
+	return nil
+}
+
+// withoutSkippedLogs drops logs that were discarded, that the transformer has already failed on MaxTransformAttempts
+// times, or whose next attempt isn't due yet
+func (delegator *LogDelegator) withoutSkippedLogs(transformerName string, logs []core.EventLog) ([]core.EventLog, error) {
+	if len(logs) == 0 {
+		return logs, nil
+	}
+	logIDs := make([]int64, len(logs))
+	for i, log := range logs {
+		logIDs[i] = log.ID
+	}
+	skippedIDs, err := delegator.DeadLetterRepository.GetSkippedLogIDs(transformerName, logIDs, delegator.MaxTransformAttempts)
+	if err != nil || len(skippedIDs) == 0 {
+		return logs, err
+	}
+	skipped := make(map[int64]bool, len(skippedIDs))
+	for _, id := range skippedIDs {
+		skipped[id] = true
+	}
+	var remaining []core.EventLog
+	for _, log := range logs {
+		if !skipped[log.ID] {
+			remaining = append(remaining, log)
+		}
+	}
+	return remaining, nil
+}
+
+// deadLetterFailingLogs executes the transformer on each log of a failed chunk on its own, so that logs it can
+// transform are persisted and only the ones it fails on are dead-lettered
+func (delegator *LogDelegator) deadLetterFailingLogs(t event.ITransformer, logs []core.EventLog, chunkErr error) error {
+	transformerName := t.GetConfig().TransformerName
+	for _, log := range logs {
+		err := chunkErr
+		if len(logs) > 1 {
+			err = t.Execute([]core.EventLog{log})
+			if err == nil {
+				markErr := delegator.markLogsTransformed(transformerName, []core.EventLog{log})
+				if markErr != nil {
+					return markErr
+				}
+				continue
+			}
+		}
+		attempts, recordErr := delegator.DeadLetterRepository.RecordFailure(log.ID, transformerName, err.Error(),
+			delegator.TransformRetryDelay)
+		if recordErr != nil {
+			return fmt.Errorf("error dead-lettering log %d for %s transformer: %w", log.ID, transformerName, recordErr)
+		}
+		logEntry := logrus.WithFields(logrus.Fields{"transformer": transformerName, "logId": log.ID, "attempts": attempts})
+		if attempts >= delegator.MaxTransformAttempts {
+			logEntry.Errorf("skipping log that failed every transformation attempt: %s", err.Error())
+		} else {
+			logEntry.Warnf("dead-lettered log that failed transformation: %s", err.Error())
 		}
 	}
 	return nil
@@ -18,6 +18,7 @@ package logs_test
 
 import (
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -59,6 +60,84 @@ var _ = Describe("Log delegator", func() {
 		})
 	})
 
+	Describe("CleanUpOrphanedHeaders", func() {
+		var (
+			orphanedHeader       = core.OrphanedHeader{ID: 1, HeaderID: 2, BlockNumber: 3, Hash: fakes.FakeHash.Hex()}
+			orphanedHeadersRepo  *fakes.MockOrphanedHeadersRepository
+			delegator            *logs.LogDelegator
+			fakeTransformer      *mocks.MockEventTransformer
+			nonCleaningConverter mocks.MockConverter
+			nonCleaningConfig    = event.TransformerConfig{TransformerName: "NonCleaningTransformer"}
+		)
+
+		BeforeEach(func() {
+			orphanedHeadersRepo = &fakes.MockOrphanedHeadersRepository{
+				ReturnOrphanHeaders: []core.OrphanedHeader{orphanedHeader},
+			}
+			delegator = newDelegator(&fakes.MockEventLogRepository{})
+			delegator.OrphanedHeadersRepository = orphanedHeadersRepo
+			fakeTransformer = &mocks.MockEventTransformer{}
+			fakeTransformer.SetTransformerConfig(mocks.FakeTransformerConfig)
+			delegator.AddTransformer(fakeTransformer)
+			delegator.AddTransformer(event.ConfiguredTransformer{
+				Transformer: &nonCleaningConverter,
+				Config:      nonCleaningConfig,
+			})
+		})
+
+		It("gets unhandled orphaned headers for each transformer that handles them", func() {
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphanedHeadersRepo.GetPassedTransformerNames).To(ConsistOf(
+				mocks.FakeTransformerConfig.TransformerName, nonCleaningConfig.TransformerName))
+		})
+
+		It("passes unhandled orphaned headers to transformers", func() {
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeTransformer.PassedOrphanedHeaders).To(ConsistOf(orphanedHeader))
+		})
+
+		It("marks orphaned headers handled by each transformer after it cleans up", func() {
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphanedHeadersRepo.MarkedHandledIDs).To(ConsistOf(orphanedHeader.ID, orphanedHeader.ID))
+			Expect(orphanedHeadersRepo.MarkedHandledTransformerNames).To(ConsistOf(
+				mocks.FakeTransformerConfig.TransformerName, nonCleaningConfig.TransformerName))
+		})
+
+		It("returns error if getting orphaned headers fails", func() {
+			orphanedHeadersRepo.GetError = fakes.FakeError
+
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+		})
+
+		It("does not mark orphaned header handled if a transformer fails to clean up", func() {
+			fakeTransformer.CleanUpError = fakes.FakeError
+
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+			Expect(orphanedHeadersRepo.MarkedHandledIDs).To(BeEmpty())
+		})
+
+		It("returns error if marking orphaned header handled fails", func() {
+			orphanedHeadersRepo.MarkHandledError = fakes.FakeError
+
+			err := delegator.CleanUpOrphanedHeaders()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+		})
+	})
+
 	Describe("DelegateLogs", func() {
 		It("returns error if no transformers configured", func() {
 			delegator := newDelegator(&fakes.MockEventLogRepository{})
@@ -150,6 +229,63 @@ var _ = Describe("Log delegator", func() {
 			Expect(fakeTransformer.PassedLogs).To(Equal(fakeEventLogs))
 		})
 
+		It("gets each transformer's untransformed logs by its name, addresses and topics", func() {
+			mockLogRepository := &fakes.MockEventLogRepository{}
+			delegator := newDelegator(mockLogRepository)
+			config := mocks.FakeTransformerConfig
+			config.Topic1 = fakes.FakeHash.Hex()
+			fakeTransformer := &mocks.MockEventTransformer{}
+			fakeTransformer.SetTransformerConfig(config)
+			delegator.AddTransformer(fakeTransformer)
+
+			err := delegator.DelegateLogs(1)
+
+			Expect(err).To(MatchError(logs.ErrNoLogs))
+			Expect(mockLogRepository.PassedTransformerNames).To(Equal([]string{config.TransformerName}))
+			Expect(mockLogRepository.PassedAddresses).To(Equal([][]string{config.ContractAddresses}))
+			Expect(mockLogRepository.PassedTopics).To(Equal([]core.Topics{config.Topics()}))
+		})
+
+		It("marks delegated logs transformed for the transformer", func() {
+			fakeTransformer := &mocks.MockEventTransformer{}
+			config := mocks.FakeTransformerConfig
+			fakeTransformer.SetTransformerConfig(config)
+			fakeGethLog := types.Log{
+				Address: common.HexToAddress(config.ContractAddresses[0]),
+				Topics:  []common.Hash{common.HexToHash(config.Topic)},
+			}
+			mockLogRepository := &fakes.MockEventLogRepository{}
+			mockLogRepository.ReturnLogs = []core.EventLog{{ID: 1, Log: fakeGethLog}, {ID: 2, Log: fakeGethLog}}
+			delegator := newDelegator(mockLogRepository)
+			delegator.AddTransformer(fakeTransformer)
+
+			err := delegator.DelegateLogs(3)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockLogRepository.MarkedTransformedLogIDs).To(Equal(map[string][]int64{
+				config.TransformerName: {1, 2},
+			}))
+		})
+
+		It("returns error if marking logs transformed fails", func() {
+			fakeTransformer := &mocks.MockEventTransformer{}
+			config := mocks.FakeTransformerConfig
+			fakeTransformer.SetTransformerConfig(config)
+			fakeGethLog := types.Log{
+				Address: common.HexToAddress(config.ContractAddresses[0]),
+				Topics:  []common.Hash{common.HexToHash(config.Topic)},
+			}
+			mockLogRepository := &fakes.MockEventLogRepository{MarkTransformedError: fakes.FakeError}
+			mockLogRepository.ReturnLogs = []core.EventLog{{ID: 1, Log: fakeGethLog}}
+			delegator := newDelegator(mockLogRepository)
+			delegator.AddTransformer(fakeTransformer)
+
+			err := delegator.DelegateLogs(2)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+		})
+
 		It("returns error if transformer returns an error", func() {
 			mockLogRepository := &fakes.MockEventLogRepository{}
 			mockLogRepository.ReturnLogs = []core.EventLog{{}}
@@ -162,12 +298,137 @@ var _ = Describe("Log delegator", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err).To(MatchError(fakes.FakeError))
 		})
+
+		Describe("when a transformer fails on some logs", func() {
+			var (
+				deadLetterRepository   *fakes.MockDeadLetterRepository
+				delegator              *logs.LogDelegator
+				delegatorLogRepository *fakes.MockEventLogRepository
+				failingTransformer     *mocks.MockEventTransformer
+				otherTransformer       *mocks.MockEventTransformer
+				eventLogs              []core.EventLog
+			)
+
+			BeforeEach(func() {
+				config := mocks.FakeTransformerConfig
+				otherConfig := config
+				otherConfig.TransformerName = "other-transformer"
+				failingTransformer = &mocks.MockEventTransformer{FailingLogIDs: []int64{2}}
+				failingTransformer.SetTransformerConfig(config)
+				otherTransformer = &mocks.MockEventTransformer{}
+				otherTransformer.SetTransformerConfig(otherConfig)
+				gethLog := types.Log{
+					Address: common.HexToAddress(config.ContractAddresses[0]),
+					Topics:  []common.Hash{common.HexToHash(config.Topic)},
+				}
+				eventLogs = []core.EventLog{{ID: 1, Log: gethLog}, {ID: 2, Log: gethLog}, {ID: 3, Log: gethLog}}
+				delegatorLogRepository = &fakes.MockEventLogRepository{ReturnLogs: eventLogs}
+				deadLetterRepository = &fakes.MockDeadLetterRepository{}
+				delegator = newDelegator(delegatorLogRepository)
+				delegator.DeadLetterRepository = deadLetterRepository
+				delegator.AddTransformer(failingTransformer)
+				delegator.AddTransformer(otherTransformer)
+			})
+
+			It("executes the failing transformer on each log separately", func() {
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(failingTransformer.PassedLogChunks).To(Equal([][]core.EventLog{
+					eventLogs, {eventLogs[0]}, {eventLogs[1]}, {eventLogs[2]},
+				}))
+			})
+
+			It("dead-letters only the logs the transformer fails on", func() {
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deadLetterRepository.RecordedFailures).To(ConsistOf(core.DeadLetterLog{
+					EventLogID:      2,
+					TransformerName: mocks.FakeTransformerConfig.TransformerName,
+					Error:           fakes.FakeError.Error(),
+				}))
+			})
+
+			It("delays the next attempt of dead-lettered logs by the transform retry delay", func() {
+				delegator.TransformRetryDelay = time.Hour
+
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deadLetterRepository.RecordFailurePassedRetryDelay).To(Equal(time.Hour))
+			})
+
+			It("keeps delegating logs to other transformers", func() {
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(otherTransformer.PassedLogs).To(Equal(eventLogs))
+			})
+
+			It("does not execute the transformer on logs it should skip", func() {
+				deadLetterRepository.SkippedLogIDs = map[string][]int64{
+					mocks.FakeTransformerConfig.TransformerName: {2},
+				}
+
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deadLetterRepository.GetSkippedLogIDsPassedAttempts).To(Equal(logs.DefaultMaxTransformAttempts))
+				Expect(failingTransformer.PassedLogChunks).To(Equal([][]core.EventLog{{eventLogs[0], eventLogs[2]}}))
+				Expect(otherTransformer.PassedLogs).To(Equal(eventLogs))
+				Expect(deadLetterRepository.RecordedFailures).To(BeEmpty())
+			})
+
+			It("marks only the logs each transformer transformed", func() {
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(delegatorLogRepository.MarkedTransformedLogIDs).To(Equal(map[string][]int64{
+					mocks.FakeTransformerConfig.TransformerName: {1, 3},
+					"other-transformer":                         {1, 2, 3},
+				}))
+			})
+
+			It("returns logs.ErrNoLogs if every log is skipped", func() {
+				deadLetterRepository.SkippedLogIDs = map[string][]int64{
+					mocks.FakeTransformerConfig.TransformerName: {1, 2, 3},
+					"other-transformer":                         {1, 2, 3},
+				}
+
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).To(MatchError(logs.ErrNoLogs))
+			})
+
+			It("returns error if getting skipped logs fails", func() {
+				deadLetterRepository.GetSkippedLogIDsError = fakes.FakeError
+
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+				Expect(failingTransformer.PassedLogChunks).To(BeEmpty())
+			})
+
+			It("returns error if dead-lettering a log fails", func() {
+				deadLetterRepository.RecordFailureError = fakes.FakeError
+
+				err := delegator.DelegateLogs(len(eventLogs) + 1)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+			})
+		})
 	})
 })
 
 func newDelegator(eventLogRepository *fakes.MockEventLogRepository) *logs.LogDelegator {
 	return &logs.LogDelegator{
-		Chunker:       chunker.NewLogChunker(),
-		LogRepository: eventLogRepository,
+		Chunker:              chunker.NewLogChunker(),
+		DeadLetterRepository: &fakes.MockDeadLetterRepository{},
+		LogRepository:        eventLogRepository,
+		MaxTransformAttempts: logs.DefaultMaxTransformAttempts,
+		TransformRetryDelay:  logs.DefaultTransformRetryDelay,
 	}
 }
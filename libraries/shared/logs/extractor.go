@@ -19,8 +19,13 @@ package logs
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/makerdao/vulcanizedb/libraries/shared/constants"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/libraries/shared/fetcher"
@@ -40,6 +45,10 @@ var (
 	ErrNoUncheckedHeaders                 = errors.New("no unchecked headers available for log fetching")
 	ErrNoWatchedAddresses                 = errors.New("no watched addresses configured in the log extractor")
 	HeaderChunkSize       int64           = 1000
+	// LogFetchChunkSize is the number of headers whose logs are requested with a single block range query
+	LogFetchChunkSize = 100
+	// DefaultBackFillWorkers is the number of header ranges back-filled concurrently when no worker count is configured
+	DefaultBackFillWorkers = 1
 )
 
 type ILogExtractor interface {
@@ -48,28 +57,41 @@ type ILogExtractor interface {
 	ExtractLogs(recheckHeaders constants.TransformerExecution) error
 }
 
+// LogFilter selects logs emitted by any of Addresses whose topics match Topics, in the positional format of an
+// ethereum.FilterQuery
+type LogFilter struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
 type LogExtractor struct {
-	Addresses                []common.Address
-	CheckedHeadersRepository datastore.CheckedHeadersRepository
-	CheckedLogsRepository    datastore.CheckedLogsRepository
-	Fetcher                  fetcher.ILogFetcher
-	HeaderRepository         datastore.HeaderRepository
-	LogRepository            datastore.EventLogRepository
-	StartingBlock            *int64
-	EndingBlock              *int64
-	Syncer                   transactions.ITransactionsSyncer
-	Topics                   []common.Hash
-	RecheckHeaderCap         int64
+	Addresses                  []common.Address
+	BackFillProgressRepository datastore.BackFillProgressRepository
+	BackFillWorkers            int
+	CheckedHeadersRepository   datastore.CheckedHeadersRepository
+	CheckedLogsRepository      datastore.CheckedLogsRepository
+	Fetcher                    fetcher.ILogFetcher
+	Filters                    []LogFilter
+	HeaderRepository           datastore.HeaderRepository
+	LogRepository              datastore.EventLogRepository
+	StartingBlock              *int64
+	EndingBlock                *int64
+	Syncer                     transactions.ITransactionsSyncer
+	Topics                     []common.Hash
+	RecheckHeaderCap           int64
 }
 
 func NewLogExtractor(db *postgres.DB, bc core.BlockChain) *LogExtractor {
 	return &LogExtractor{
-		CheckedHeadersRepository: repositories.NewCheckedHeadersRepository(db),
-		CheckedLogsRepository:    repositories.NewCheckedLogsRepository(db),
-		Fetcher:                  fetcher.NewLogFetcher(bc),
-		LogRepository:            repositories.NewEventLogRepository(db),
-		Syncer:                   transactions.NewTransactionsSyncer(db, bc),
-		RecheckHeaderCap:         constants.RecheckHeaderCap,
+		BackFillProgressRepository: repositories.NewBackFillProgressRepository(db),
+		BackFillWorkers:            DefaultBackFillWorkers,
+		CheckedHeadersRepository:   repositories.NewCheckedHeadersRepository(db),
+		CheckedLogsRepository:      repositories.NewCheckedLogsRepository(db),
+		Fetcher:                    fetcher.NewLogFetcher(bc),
+		HeaderRepository:           repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow),
+		LogRepository:              repositories.NewEventLogRepository(db),
+		Syncer:                     transactions.NewTransactionsSyncer(db, bc),
+		RecheckHeaderCap:           constants.RecheckHeaderCap,
 	}
 }
 
@@ -91,9 +113,59 @@ func (extractor *LogExtractor) AddTransformerConfig(config event.TransformerConf
 	addresses := event.HexStringsToAddresses(config.ContractAddresses)
 	extractor.Addresses = append(extractor.Addresses, addresses...)
 	extractor.Topics = append(extractor.Topics, common.HexToHash(config.Topic))
+	extractor.addFilter(addresses, config.TopicFilters())
 	return nil
 }
 
+// addFilter merges the config into the filter with the same topic1-3 filters, so that configs differing only in
+// address or topic0 are fetched with a single query
+func (extractor *LogExtractor) addFilter(addresses []common.Address, topicFilters [][]common.Hash) {
+	for i, filter := range extractor.Filters {
+		if !sameTopicFilters(filter.Topics[1:], topicFilters[1:]) {
+			continue
+		}
+		for _, address := range addresses {
+			if !containsAddress(filter.Addresses, address) {
+				extractor.Filters[i].Addresses = append(extractor.Filters[i].Addresses, address)
+			}
+		}
+		if !event.ContainsHash(filter.Topics[0], topicFilters[0][0]) {
+			extractor.Filters[i].Topics[0] = append(extractor.Filters[i].Topics[0], topicFilters[0][0])
+		}
+		return
+	}
+	extractor.Filters = append(extractor.Filters, LogFilter{
+		Addresses: append([]common.Address{}, addresses...),
+		Topics:    topicFilters,
+	})
+}
+
+func sameTopicFilters(a, b [][]common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, candidate := range addresses {
+		if candidate == address {
+			return true
+		}
+	}
+	return false
+}
+
 func shouldResetStartingBlockToEarlierTransformerBlock(currentTransformerBlock int64, extractorBlock *int64) bool {
 	isExtractorBlockNil := extractorBlock == nil
 	if isExtractorBlockNil {
@@ -131,26 +203,117 @@ func (extractor LogExtractor) ExtractLogs(recheckHeaders constants.TransformerEx
 		return fmt.Errorf("error getting unchecked headers to check for logs: %w", uncheckedHeadersErr)
 	}
 
-	if len(uncheckedHeaders) < 1 {
+	for _, chunk := range chunkHeaders(uncheckedHeaders, LogFetchChunkSize) {
+		logsByBlockHash, fetchErr := extractor.fetchLogsForHeaders(extractor.Filters, chunk)
+		if fetchErr != nil {
+			return fetchErr
+		}
+
+		for _, header := range chunk {
+			err := extractor.persistLogsForHeader(header, logsByBlockHash[common.HexToHash(header.Hash)])
+			if err != nil {
+				return fmt.Errorf("error fetching and persisting logs for header with id %d: %w", header.Id, err)
+			}
+
+			markHeaderCheckedErr := extractor.CheckedHeadersRepository.MarkHeaderChecked(header.Id)
+			if markHeaderCheckedErr != nil {
+				logError("error marking header checked: %s", markHeaderCheckedErr, header)
+				return markHeaderCheckedErr
+			}
+		}
+	}
+
+	backFilled, backFillErr := extractor.backFillNewlyWatchedLogs()
+	if backFillErr != nil {
+		return backFillErr
+	}
+
+	if len(uncheckedHeaders) < 1 && !backFilled {
 		return ErrNoUncheckedHeaders
 	}
+	return nil
+}
+
+// backFillNewlyWatchedLogs checks the next HeaderChunkSize headers that were checked before a log was watched, for
+// each watched log still needing a back-fill. It reports whether there was anything to back-fill.
+func (extractor LogExtractor) backFillNewlyWatchedLogs() (bool, error) {
+	watchedLogs, getLogsErr := extractor.CheckedLogsRepository.GetLogsToBackFill()
+	if getLogsErr != nil {
+		return false, fmt.Errorf("error getting watched logs to back-fill: %w", getLogsErr)
+	}
 
-	for _, header := range uncheckedHeaders {
-		err := extractor.fetchAndPersistLogsForHeader(header)
-		if err != nil {
-			return fmt.Errorf("error fetching and persisting logs for header with id %d: %w", header.Id, err)
+	for _, backFill := range groupLogBackFills(watchedLogs) {
+		endingBlock := backFill.nextBlock + HeaderChunkSize - 1
+		if endingBlock > backFill.endingBlock {
+			endingBlock = backFill.endingBlock
+		}
+		headers, headersErr := extractor.HeaderRepository.GetHeadersInRange(backFill.nextBlock, endingBlock)
+		if headersErr != nil {
+			return false, fmt.Errorf("error getting headers to back-fill watched logs: %w", headersErr)
 		}
 
-		markHeaderCheckedErr := extractor.CheckedHeadersRepository.MarkHeaderChecked(header.Id)
-		if markHeaderCheckedErr != nil {
-			logError("error marking header checked: %s", markHeaderCheckedErr, header)
-			return markHeaderCheckedErr
+		for _, chunk := range chunkHeaders(headers, LogFetchChunkSize) {
+			logsByBlockHash, fetchErr := extractor.fetchLogsForHeaders([]LogFilter{backFill.filter}, chunk)
+			if fetchErr != nil {
+				return false, fetchErr
+			}
+			for _, header := range chunk {
+				err := extractor.persistLogsForHeader(header, logsByBlockHash[common.HexToHash(header.Hash)])
+				if err != nil {
+					return false, fmt.Errorf("error back-filling logs for header with id %d: %w", header.Id, err)
+				}
+			}
+		}
+
+		markErr := extractor.CheckedLogsRepository.MarkLogsBackFilledThrough(backFill.watchedLogIDs, backFill.nextBlock, endingBlock)
+		if markErr != nil {
+			return false, fmt.Errorf("error recording back-fill of watched logs through block %d: %w", endingBlock, markErr)
 		}
+		logrus.WithFields(logrus.Fields{
+			"topic0":      backFill.filter.Topics[0][0].Hex(),
+			"blockNumber": endingBlock,
+		}).Infof("back-filled newly watched logs through block %d of %d", endingBlock, backFill.endingBlock)
 	}
-	return nil
+	return len(watchedLogs) > 0, nil
+}
+
+type logBackFill struct {
+	filter        LogFilter
+	watchedLogIDs []int64
+	nextBlock     int64
+	endingBlock   int64
+}
+
+// groupLogBackFills merges watched logs registered together, which differ only by address, into one back-fill
+func groupLogBackFills(watchedLogs []core.WatchedLog) []logBackFill {
+	type backFillKey struct {
+		topics                 core.Topics
+		nextBlock, endingBlock int64
+	}
+	var backFills []logBackFill
+	indexes := make(map[backFillKey]int)
+	for _, watchedLog := range watchedLogs {
+		key := backFillKey{topics: watchedLog.Topics(), nextBlock: watchedLog.NextBackFillBlock, endingBlock: watchedLog.BackFillEndingBlock}
+		i, ok := indexes[key]
+		if !ok {
+			i = len(backFills)
+			indexes[key] = i
+			backFills = append(backFills, logBackFill{
+				filter:      LogFilter{Topics: event.TopicFilters(key.topics)},
+				nextBlock:   key.nextBlock,
+				endingBlock: key.endingBlock,
+			})
+		}
+		backFills[i].filter.Addresses = append(backFills[i].filter.Addresses, common.HexToAddress(watchedLog.ContractAddress))
+		backFills[i].watchedLogIDs = append(backFills[i].watchedLogIDs, watchedLog.ID)
+	}
+	return backFills
 }
 
-// BackFillLogs fetches and persists watched logs from provided range of headers
+// BackFillLogs fetches and persists watched logs from provided range of headers.
+// Ranges of HeaderChunkSize headers are processed by BackFillWorkers concurrent workers. Completed ranges are
+// recorded, so an interrupted back-fill of the same watched logs resumes where it left off. Logs watched by
+// transformers outside this back-fill are recorded as still needing a back-fill of the headers it marks checked.
 func (extractor LogExtractor) BackFillLogs(endingBlock int64) error {
 	if len(extractor.Addresses) < 1 {
 		logrus.Errorf("error extracting logs: %s", ErrNoWatchedAddresses.Error())
@@ -162,24 +325,216 @@ func (extractor LogExtractor) BackFillLogs(endingBlock int64) error {
 		return fmt.Errorf("error chunking headers to lookup in logs backfill: %w", chunkErr)
 	}
 
-	for _, r := range ranges {
-		headers, headersErr := extractor.HeaderRepository.GetHeadersInRange(r[StartInterval], r[EndInterval])
-		if headersErr != nil {
-			logrus.Errorf("error fetching missing headers: %s", headersErr)
-			return fmt.Errorf("error getting unchecked headers to check for logs: %w", headersErr)
+	watchedLogsHash := extractor.watchedLogsHash()
+	completedRanges, completedErr := extractor.BackFillProgressRepository.GetCompletedRanges(watchedLogsHash)
+	if completedErr != nil {
+		return fmt.Errorf("error getting completed back-fill ranges: %w", completedErr)
+	}
+	pendingRanges := excludeCompletedRanges(ranges, completedRanges)
+	if len(pendingRanges) < len(ranges) {
+		logrus.Infof("resuming logs backfill: %d of %d header ranges already completed", len(ranges)-len(pendingRanges), len(ranges))
+	}
+
+	unfilteredLogIDs, unfilteredErr := extractor.unfilteredWatchedLogIDs()
+	if unfilteredErr != nil {
+		return unfilteredErr
+	}
+
+	workers := extractor.BackFillWorkers
+	if workers < 1 {
+		workers = DefaultBackFillWorkers
+	}
+
+	var (
+		rangesToFill = make(chan core.BlockRange)
+		quit         = make(chan struct{})
+		wg           sync.WaitGroup
+		errOnce      sync.Once
+		backFillErr  error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for blockRange := range rangesToFill {
+				err := extractor.backFillRange(watchedLogsHash, unfilteredLogIDs, blockRange)
+				if err != nil {
+					errOnce.Do(func() {
+						backFillErr = err
+						close(quit)
+					})
+					return
+				}
+			}
+		}()
+	}
+
+SendRanges:
+	for _, blockRange := range pendingRanges {
+		select {
+		case rangesToFill <- blockRange:
+		case <-quit:
+			break SendRanges
+		}
+	}
+	close(rangesToFill)
+	wg.Wait()
+
+	return backFillErr
+}
+
+// backFillRange fetches and persists logs for headers in the range and records its headers as checked so that
+// they're not checked again for the same logs. Watched logs the back-fill doesn't fetch are first recorded as needing
+// a back-fill of the range, since checked headers are otherwise assumed to have been checked for every watched log.
+// The range is only recorded as complete once every block in it has a header, so a range with gaps is revisited on the
+// next back-fill.
+func (extractor LogExtractor) backFillRange(watchedLogsHash string, unfilteredLogIDs []int64, blockRange core.BlockRange) error {
+	headers, headersErr := extractor.HeaderRepository.GetHeadersInRange(blockRange.StartingBlock, blockRange.EndingBlock)
+	if headersErr != nil {
+		logrus.Errorf("error fetching missing headers: %s", headersErr)
+		return fmt.Errorf("error getting unchecked headers to check for logs: %w", headersErr)
+	}
+
+	headerIDs := make([]int64, 0, len(headers))
+	for _, chunk := range chunkHeaders(headers, LogFetchChunkSize) {
+		logsByBlockHash, fetchErr := extractor.fetchLogsForHeaders(extractor.Filters, chunk)
+		if fetchErr != nil {
+			return fetchErr
 		}
 
-		for _, header := range headers {
-			err := extractor.fetchAndPersistLogsForHeader(header)
+		for _, header := range chunk {
+			err := extractor.persistLogsForHeader(header, logsByBlockHash[common.HexToHash(header.Hash)])
 			if err != nil {
 				return fmt.Errorf("error fetching and persisting logs for header with id %d: %w", header.Id, err)
 			}
+			headerIDs = append(headerIDs, header.Id)
 		}
 	}
 
+	if len(headerIDs) > 0 && len(unfilteredLogIDs) > 0 {
+		markBackFillErr := extractor.CheckedLogsRepository.MarkLogsForBackFill(unfilteredLogIDs, blockRange)
+		if markBackFillErr != nil {
+			return fmt.Errorf("error recording blocks %d to %d as needing a back-fill for other watched logs: %w",
+				blockRange.StartingBlock, blockRange.EndingBlock, markBackFillErr)
+		}
+	}
+
+	if len(headerIDs) > 0 {
+		markCheckedErr := extractor.CheckedHeadersRepository.MarkHeadersChecked(headerIDs)
+		if markCheckedErr != nil {
+			return fmt.Errorf("error marking headers in blocks %d to %d checked: %w", blockRange.StartingBlock, blockRange.EndingBlock, markCheckedErr)
+		}
+	}
+
+	expectedHeaders := blockRange.EndingBlock - blockRange.StartingBlock + 1
+	if foundHeaders := countBlockNumbers(headers); foundHeaders < expectedHeaders {
+		logrus.Warnf("leaving back-fill of blocks %d to %d open: found headers for %d of %d blocks",
+			blockRange.StartingBlock, blockRange.EndingBlock, foundHeaders, expectedHeaders)
+		return nil
+	}
+
+	markCompleteErr := extractor.BackFillProgressRepository.MarkRangeComplete(watchedLogsHash, blockRange)
+	if markCompleteErr != nil {
+		return fmt.Errorf("error recording back-fill of blocks %d to %d: %w", blockRange.StartingBlock, blockRange.EndingBlock, markCompleteErr)
+	}
 	return nil
 }
 
+// unfilteredWatchedLogIDs returns the watched logs that none of the extractor's filters fetch in full, e.g. those
+// registered by transformers that aren't part of this run
+func (extractor LogExtractor) unfilteredWatchedLogIDs() ([]int64, error) {
+	watchedLogs, getLogsErr := extractor.CheckedLogsRepository.GetWatchedLogs()
+	if getLogsErr != nil {
+		return nil, fmt.Errorf("error getting watched logs: %w", getLogsErr)
+	}
+	var unfilteredLogIDs []int64
+	for _, watchedLog := range watchedLogs {
+		if !filtersFetch(extractor.Filters, watchedLog) {
+			unfilteredLogIDs = append(unfilteredLogIDs, watchedLog.ID)
+		}
+	}
+	return unfilteredLogIDs, nil
+}
+
+// filtersFetch reports whether any of the filters fetches every log matching the watched log
+func filtersFetch(filters []LogFilter, watchedLog core.WatchedLog) bool {
+	address := common.HexToAddress(watchedLog.ContractAddress)
+	watchedTopics := event.TopicFilters(watchedLog.Topics())
+	for _, filter := range filters {
+		if containsAddress(filter.Addresses, address) && topicFiltersInclude(filter.Topics, watchedTopics) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicFiltersInclude reports whether every log matching the narrower topic filters also matches the broader ones
+func topicFiltersInclude(broader, narrower [][]common.Hash) bool {
+	for i, accepted := range broader {
+		if len(accepted) == 0 {
+			continue
+		}
+		if i >= len(narrower) || len(narrower[i]) == 0 {
+			return false
+		}
+		for _, topic := range narrower[i] {
+			if !event.ContainsHash(accepted, topic) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func countBlockNumbers(headers []core.Header) int64 {
+	blockNumbers := make(map[int64]bool)
+	for _, header := range headers {
+		blockNumbers[header.BlockNumber] = true
+	}
+	return int64(len(blockNumbers))
+}
+
+// watchedLogsHash identifies the set of watched addresses and topics, so back-fill progress recorded for one set
+// isn't reused after transformers are added
+func (extractor LogExtractor) watchedLogsHash() string {
+	unique := make(map[string]bool)
+	for _, address := range extractor.Addresses {
+		unique["address:"+address.Hex()] = true
+	}
+	for _, topic := range extractor.Topics {
+		unique["topic:"+topic.Hex()] = true
+	}
+	for _, filter := range extractor.Filters {
+		for position, accepted := range filter.Topics[1:] {
+			for _, topic := range accepted {
+				unique[fmt.Sprintf("topic%d:%s", position+1, topic.Hex())] = true
+			}
+		}
+	}
+	watched := make([]string, 0, len(unique))
+	for item := range unique {
+		watched = append(watched, item)
+	}
+	sort.Strings(watched)
+	return crypto.Keccak256Hash([]byte(strings.Join(watched, ","))).Hex()
+}
+
+func excludeCompletedRanges(ranges []map[BlockIdentifier]int64, completedRanges []core.BlockRange) []core.BlockRange {
+	completed := make(map[core.BlockRange]bool, len(completedRanges))
+	for _, blockRange := range completedRanges {
+		completed[blockRange] = true
+	}
+
+	var pending []core.BlockRange
+	for _, r := range ranges {
+		blockRange := core.BlockRange{StartingBlock: r[StartInterval], EndingBlock: r[EndInterval]}
+		if !completed[blockRange] {
+			pending = append(pending, blockRange)
+		}
+	}
+	return pending
+}
+
 func ChunkRanges(startingBlock, endingBlock, interval int64) ([]map[BlockIdentifier]int64, error) {
 	if endingBlock <= startingBlock {
 		return nil, errors.New("ending block for backfill not > starting block")
@@ -223,13 +578,15 @@ func (extractor *LogExtractor) getCheckCount(recheckHeaders constants.Transforme
 }
 
 func (extractor *LogExtractor) updateCheckedHeaders(config event.TransformerConfig) error {
-	alreadyWatchingLog, watchingLogErr := extractor.CheckedLogsRepository.AlreadyWatchingLog(config.ContractAddresses, config.Topic)
+	alreadyWatchingLog, watchingLogErr := extractor.CheckedLogsRepository.AlreadyWatchingLog(config.ContractAddresses, config.Topics())
 	if watchingLogErr != nil {
 		return watchingLogErr
 	}
 	if !alreadyWatchingLog {
-		logrus.Warnf("new event log for topic 0 %s detected, back-fill may be required for already-checked headers", config.Topic)
-		markLogWatchedErr := extractor.CheckedLogsRepository.MarkLogWatched(config.ContractAddresses, config.Topic)
+		logrus.Infof("new event log for topic 0 %s detected, already-checked headers from block %d will be back-filled",
+			config.Topic, config.StartingBlockNumber)
+		markLogWatchedErr := extractor.CheckedLogsRepository.MarkLogWatched(config.ContractAddresses, config.Topics(),
+			config.StartingBlockNumber, config.EndingBlockNumber)
 		if markLogWatchedErr != nil {
 			return markLogWatchedErr
 		}
@@ -237,13 +594,39 @@ func (extractor *LogExtractor) updateCheckedHeaders(config event.TransformerConf
 	return nil
 }
 
-func (extractor *LogExtractor) fetchAndPersistLogsForHeader(header core.Header) error {
-	logs, fetchLogsErr := extractor.Fetcher.FetchLogs(extractor.Addresses, extractor.Topics, header)
-	if fetchLogsErr != nil {
-		logError("error fetching logs for header: %s", fetchLogsErr, header)
-		return fmt.Errorf("error fetching logs for block %d: %w", header.BlockNumber, fetchLogsErr)
+// fetchLogsForHeaders fetches watched logs for a chunk of headers with a range query per filter, grouped by block hash
+func (extractor *LogExtractor) fetchLogsForHeaders(filters []LogFilter, headers []core.Header) (map[common.Hash][]types.Log, error) {
+	type logID struct {
+		blockHash common.Hash
+		index     uint
 	}
+	fetched := make(map[logID]bool)
+	logsByBlockHash := make(map[common.Hash][]types.Log)
+	for _, filter := range filters {
+		logs, fetchLogsErr := extractor.Fetcher.FetchLogsForRange(filter.Addresses, filter.Topics, headers)
+		if fetchLogsErr != nil {
+			firstBlock, lastBlock := headers[0].BlockNumber, headers[len(headers)-1].BlockNumber
+			logrus.WithFields(logrus.Fields{
+				"firstBlockNumber": firstBlock,
+				"lastBlockNumber":  lastBlock,
+			}).Errorf("error fetching logs for headers: %s", fetchLogsErr.Error())
+			return nil, fmt.Errorf("error fetching logs for blocks %d to %d: %w", firstBlock, lastBlock, fetchLogsErr)
+		}
 
+		// a log can match more than one filter
+		for _, log := range logs {
+			id := logID{blockHash: log.BlockHash, index: log.Index}
+			if fetched[id] {
+				continue
+			}
+			fetched[id] = true
+			logsByBlockHash[log.BlockHash] = append(logsByBlockHash[log.BlockHash], log)
+		}
+	}
+	return logsByBlockHash, nil
+}
+
+func (extractor *LogExtractor) persistLogsForHeader(header core.Header, logs []types.Log) error {
 	if len(logs) > 0 {
 		transactionsSyncErr := extractor.Syncer.SyncTransactions(header.Id, logs)
 		if transactionsSyncErr != nil {
@@ -259,3 +642,15 @@ func (extractor *LogExtractor) fetchAndPersistLogsForHeader(header core.Header)
 	}
 	return nil
 }
+
+func chunkHeaders(headers []core.Header, chunkSize int) [][]core.Header {
+	var chunks [][]core.Header
+	for start := 0; start < len(headers); start += chunkSize {
+		end := start + chunkSize
+		if end > len(headers) {
+			end = len(headers)
+		}
+		chunks = append(chunks, headers[start:end])
+	}
+	return chunks
+}
@@ -43,12 +43,13 @@ var _ = Describe("Log extractor", func() {
 		checkedHeadersRepository = &fakes.MockCheckedHeadersRepository{}
 		checkedLogsRepository = &fakes.MockCheckedLogsRepository{}
 		extractor = &logs.LogExtractor{
-			CheckedHeadersRepository: checkedHeadersRepository,
-			CheckedLogsRepository:    checkedLogsRepository,
-			Fetcher:                  &mocks.MockLogFetcher{},
-			LogRepository:            &fakes.MockEventLogRepository{},
-			Syncer:                   &fakes.MockTransactionSyncer{},
-			RecheckHeaderCap:         constants.RecheckHeaderCap,
+			BackFillProgressRepository: &fakes.MockBackFillProgressRepository{},
+			CheckedHeadersRepository:   checkedHeadersRepository,
+			CheckedLogsRepository:      checkedLogsRepository,
+			Fetcher:                    &mocks.MockLogFetcher{},
+			LogRepository:              &fakes.MockEventLogRepository{},
+			Syncer:                     &fakes.MockTransactionSyncer{},
+			RecheckHeaderCap:           constants.RecheckHeaderCap,
 		}
 	})
 
@@ -121,6 +122,58 @@ var _ = Describe("Log extractor", func() {
 			Expect(extractor.Topics).To(Equal([]common.Hash{common.HexToHash(topic)}))
 		})
 
+		It("merges configs without topic filters into one log filter", func() {
+			configA := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+			configB := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+			configB.ContractAddresses = []string{fakes.AnotherFakeAddress.Hex()}
+			configB.Topic = fakes.AnotherFakeHash.Hex()
+
+			errA := extractor.AddTransformerConfig(configA)
+			Expect(errA).NotTo(HaveOccurred())
+			errB := extractor.AddTransformerConfig(configB)
+			Expect(errB).NotTo(HaveOccurred())
+
+			Expect(extractor.Filters).To(Equal([]logs.LogFilter{{
+				Addresses: []common.Address{fakes.FakeAddress, fakes.AnotherFakeAddress},
+				Topics:    [][]common.Hash{{fakes.FakeHash, fakes.AnotherFakeHash}},
+			}}))
+		})
+
+		It("adds a separate log filter for configs with different topic filters", func() {
+			unfilteredConfig := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+			filteredConfig := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+			vault := common.HexToHash(fakes.AnotherFakeAddress.Hex())
+			filteredConfig.Topic2 = vault.Hex()
+			anotherFilteredConfig := filteredConfig
+			anotherFilteredConfig.Topic = fakes.AnotherFakeHash.Hex()
+
+			for _, config := range []event.TransformerConfig{unfilteredConfig, filteredConfig, anotherFilteredConfig} {
+				err := extractor.AddTransformerConfig(config)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(extractor.Filters).To(Equal([]logs.LogFilter{
+				{
+					Addresses: []common.Address{fakes.FakeAddress},
+					Topics:    [][]common.Hash{{fakes.FakeHash}},
+				},
+				{
+					Addresses: []common.Address{fakes.FakeAddress},
+					Topics:    [][]common.Hash{{fakes.FakeHash, fakes.AnotherFakeHash}, nil, {vault}},
+				},
+			}))
+		})
+
+		It("checks whether the log with all of its topics has been watched", func() {
+			config := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+			config.Topic1 = fakes.AnotherFakeHash.Hex()
+
+			err := extractor.AddTransformerConfig(config)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checkedLogsRepository.AlreadyWatchingLogTopics).To(Equal(config.Topics()))
+		})
+
 		It("returns error if checking whether log has been checked returns error", func() {
 			checkedLogsRepository.AlreadyWatchingLogError = fakes.FakeError
 
@@ -142,7 +195,9 @@ var _ = Describe("Log extractor", func() {
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(checkedLogsRepository.MarkLogWatchedAddresses).To(Equal(config.ContractAddresses))
-				Expect(checkedLogsRepository.MarkLogWatchedTopicZero).To(Equal(config.Topic))
+				Expect(checkedLogsRepository.MarkLogWatchedTopics).To(Equal(config.Topics()))
+				Expect(checkedLogsRepository.MarkLogWatchedStartingBlock).To(Equal(config.StartingBlockNumber))
+				Expect(checkedLogsRepository.MarkLogWatchedEndingBlock).To(Equal(config.EndingBlockNumber))
 			})
 
 			It("returns error if marking logs watched returns error", func() {
@@ -232,6 +287,95 @@ var _ = Describe("Log extractor", func() {
 			})
 		})
 
+		Describe("when newly watched logs need a back-fill", func() {
+			var (
+				mockHeaderRepository *fakes.MockHeaderRepository
+				watchedLogs          []core.WatchedLog
+			)
+
+			BeforeEach(func() {
+				addTransformerConfig(extractor)
+				mockHeaderRepository = &fakes.MockHeaderRepository{AllHeaders: []core.Header{{Id: rand.Int63()}}}
+				extractor.HeaderRepository = mockHeaderRepository
+				watchedLog := core.WatchedLog{
+					ID:                  rand.Int63(),
+					ContractAddress:     fakes.FakeAddress.Hex(),
+					TopicZero:           fakes.AnotherFakeHash.Hex(),
+					NextBackFillBlock:   100,
+					BackFillEndingBlock: 100 + logs.HeaderChunkSize*2,
+				}
+				anotherWatchedLog := watchedLog
+				anotherWatchedLog.ID = rand.Int63()
+				anotherWatchedLog.ContractAddress = fakes.AnotherFakeAddress.Hex()
+				watchedLogs = []core.WatchedLog{watchedLog, anotherWatchedLog}
+				checkedLogsRepository.LogsToBackFill = watchedLogs
+			})
+
+			It("fetches the newly watched logs for the next chunk of already-checked headers", func() {
+				mockLogFetcher := &mocks.MockLogFetcher{}
+				extractor.Fetcher = mockLogFetcher
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockHeaderRepository.GetHeadersInRangeStartingBlocks).To(Equal([]int64{100}))
+				Expect(mockHeaderRepository.GetHeadersInRangeEndingBlocks).To(Equal([]int64{100 + logs.HeaderChunkSize - 1}))
+				Expect(mockLogFetcher.ContractAddresses).To(Equal([]common.Address{fakes.FakeAddress, fakes.AnotherFakeAddress}))
+				Expect(mockLogFetcher.Topics).To(Equal([][]common.Hash{{fakes.AnotherFakeHash}}))
+			})
+
+			It("persists back-filled logs without marking headers checked", func() {
+				fakeLogs := []types.Log{{Index: 1}}
+				extractor.Fetcher = &mocks.MockLogFetcher{ReturnLogs: fakeLogs}
+				mockLogRepository := &fakes.MockEventLogRepository{}
+				extractor.LogRepository = mockLogRepository
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockLogRepository.PassedLogs).To(Equal(fakeLogs))
+				Expect(checkedHeadersRepository.MarkHeaderCheckedHeaderID).To(BeZero())
+			})
+
+			It("records the back-fill progress of the watched logs", func() {
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(checkedLogsRepository.MarkLogsBackFilledThroughIDs).To(Equal([]int64{watchedLogs[0].ID, watchedLogs[1].ID}))
+				Expect(checkedLogsRepository.MarkLogsBackFilledThroughFrom).To(Equal(int64(100)))
+				Expect(checkedLogsRepository.MarkLogsBackFilledThroughBlock).To(Equal(100 + logs.HeaderChunkSize - 1))
+			})
+
+			It("does not back-fill past the watched logs' ending block", func() {
+				for i := range checkedLogsRepository.LogsToBackFill {
+					checkedLogsRepository.LogsToBackFill[i].BackFillEndingBlock = 110
+				}
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockHeaderRepository.GetHeadersInRangeEndingBlocks).To(Equal([]int64{110}))
+				Expect(checkedLogsRepository.MarkLogsBackFilledThroughBlock).To(Equal(int64(110)))
+			})
+
+			It("does not record progress if back-filling fails", func() {
+				extractor.Fetcher = &mocks.MockLogFetcher{ReturnError: fakes.FakeError}
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(checkedLogsRepository.MarkLogsBackFilledThroughIDs).To(BeNil())
+			})
+
+			It("returns error if getting watched logs to back-fill fails", func() {
+				checkedLogsRepository.GetLogsToBackFillError = fakes.FakeError
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).To(MatchError(fakes.FakeError))
+			})
+		})
+
 		Describe("when there are unchecked headers", func() {
 			It("fetches logs for unchecked headers", func() {
 				addUncheckedHeader(extractor)
@@ -249,12 +393,84 @@ var _ = Describe("Log extractor", func() {
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(mockLogFetcher.FetchCalled).To(BeTrue())
-				expectedTopics := []common.Hash{common.HexToHash(config.Topic)}
+				expectedTopics := [][]common.Hash{{common.HexToHash(config.Topic)}}
 				Expect(mockLogFetcher.Topics).To(Equal(expectedTopics))
 				expectedAddresses := event.HexStringsToAddresses(config.ContractAddresses)
 				Expect(mockLogFetcher.ContractAddresses).To(Equal(expectedAddresses))
 			})
 
+			It("fetches logs for unchecked headers in chunks of LogFetchChunkSize", func() {
+				addTransformerConfig(extractor)
+				mockCheckedHeadersRepository := &fakes.MockCheckedHeadersRepository{}
+				uncheckedHeaders := make([]core.Header, logs.LogFetchChunkSize+1)
+				mockCheckedHeadersRepository.UncheckedHeadersReturnHeaders = uncheckedHeaders
+				extractor.CheckedHeadersRepository = mockCheckedHeadersRepository
+				mockLogFetcher := &mocks.MockLogFetcher{}
+				extractor.Fetcher = mockLogFetcher
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockLogFetcher.PassedHeaders).To(Equal([][]core.Header{
+					uncheckedHeaders[:logs.LogFetchChunkSize],
+					uncheckedHeaders[logs.LogFetchChunkSize:],
+				}))
+			})
+
+			It("persists fetched logs with the header matching their block hash", func() {
+				addTransformerConfig(extractor)
+				headerWithoutLogs := core.Header{Id: rand.Int63(), Hash: common.HexToHash("0x1").Hex()}
+				headerWithLogs := core.Header{Id: rand.Int63(), Hash: common.HexToHash("0x2").Hex()}
+				mockCheckedHeadersRepository := &fakes.MockCheckedHeadersRepository{}
+				mockCheckedHeadersRepository.UncheckedHeadersReturnHeaders = []core.Header{headerWithoutLogs, headerWithLogs}
+				extractor.CheckedHeadersRepository = mockCheckedHeadersRepository
+				fakeLogs := []types.Log{{BlockHash: common.HexToHash(headerWithLogs.Hash)}}
+				extractor.Fetcher = &mocks.MockLogFetcher{ReturnLogs: fakeLogs}
+				mockLogRepository := &fakes.MockEventLogRepository{}
+				extractor.LogRepository = mockLogRepository
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockLogRepository.PassedHeaderID).To(Equal(headerWithLogs.Id))
+				Expect(mockLogRepository.PassedLogs).To(Equal(fakeLogs))
+			})
+
+			It("fetches logs once per log filter", func() {
+				addUncheckedHeader(extractor)
+				addTransformerConfig(extractor)
+				filteredConfig := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+				filteredConfig.Topic1 = fakes.AnotherFakeHash.Hex()
+				addTransformerErr := extractor.AddTransformerConfig(filteredConfig)
+				Expect(addTransformerErr).NotTo(HaveOccurred())
+				mockLogFetcher := &mocks.MockLogFetcher{}
+				extractor.Fetcher = mockLogFetcher
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockLogFetcher.PassedHeaders).To(HaveLen(2))
+				Expect(mockLogFetcher.Topics).To(Equal(filteredConfig.TopicFilters()))
+			})
+
+			It("persists logs matching more than one log filter once", func() {
+				addUncheckedHeader(extractor)
+				addTransformerConfig(extractor)
+				filteredConfig := getTransformerConfig(rand.Int63(), defaultEndingBlockNumber)
+				filteredConfig.Topic1 = fakes.AnotherFakeHash.Hex()
+				addTransformerErr := extractor.AddTransformerConfig(filteredConfig)
+				Expect(addTransformerErr).NotTo(HaveOccurred())
+				fakeLogs := []types.Log{{Index: 1}}
+				extractor.Fetcher = &mocks.MockLogFetcher{ReturnLogs: fakeLogs}
+				mockLogRepository := &fakes.MockEventLogRepository{}
+				extractor.LogRepository = mockLogRepository
+
+				err := extractor.ExtractLogs(constants.HeaderUnchecked)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mockLogRepository.PassedLogs).To(Equal(fakeLogs))
+			})
+
 			It("returns error if fetching logs fails", func() {
 				addUncheckedHeader(extractor)
 				addTransformerConfig(extractor)
@@ -463,7 +679,7 @@ var _ = Describe("Log extractor", func() {
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mockLogFetcher.FetchCalled).To(BeTrue())
-			expectedTopics := []common.Hash{common.HexToHash(config.Topic)}
+			expectedTopics := [][]common.Hash{{common.HexToHash(config.Topic)}}
 			Expect(mockLogFetcher.Topics).To(Equal(expectedTopics))
 			expectedAddresses := event.HexStringsToAddresses(config.ContractAddresses)
 			Expect(mockLogFetcher.ContractAddresses).To(Equal(expectedAddresses))
@@ -558,6 +774,202 @@ var _ = Describe("Log extractor", func() {
 		})
 	})
 
+	Describe("BackFillLogs progress", func() {
+		var (
+			mockProgressRepository *fakes.MockBackFillProgressRepository
+			mockHeaderRepository   *fakes.MockHeaderRepository
+			startingBlock          int64
+		)
+
+		BeforeEach(func() {
+			mockProgressRepository = &fakes.MockBackFillProgressRepository{}
+			extractor.BackFillProgressRepository = mockProgressRepository
+			mockHeaderRepository = &fakes.MockHeaderRepository{}
+			extractor.HeaderRepository = mockHeaderRepository
+			startingBlock = addTransformerConfig(extractor)
+		})
+
+		It("records each back-filled range as complete for the watched logs", func() {
+			endingBlock := startingBlock + logs.HeaderChunkSize*2 - 1
+			mockHeaderRepository.AllHeaders = headersForBlocks(startingBlock, logs.HeaderChunkSize)
+
+			err := extractor.BackFillLogs(endingBlock)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockProgressRepository.MarkRangeCompletePassedRanges).To(ConsistOf(
+				core.BlockRange{StartingBlock: startingBlock, EndingBlock: startingBlock + logs.HeaderChunkSize - 1},
+				core.BlockRange{StartingBlock: startingBlock + logs.HeaderChunkSize, EndingBlock: endingBlock},
+			))
+			for _, hash := range mockProgressRepository.MarkRangeCompletePassedHashes {
+				Expect(hash).To(Equal(mockProgressRepository.GetCompletedRangesPassedHash))
+			}
+		})
+
+		It("identifies progress by the watched addresses and topics", func() {
+			_ = extractor.BackFillLogs(startingBlock + 1)
+			firstHash := mockProgressRepository.GetCompletedRangesPassedHash
+
+			addTransformerErr := extractor.AddTransformerConfig(event.TransformerConfig{
+				ContractAddresses:   []string{fakes.AnotherFakeAddress.Hex()},
+				Topic:               fakes.FakeHash.Hex(),
+				StartingBlockNumber: startingBlock,
+			})
+			Expect(addTransformerErr).NotTo(HaveOccurred())
+			_ = extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(firstHash).NotTo(BeEmpty())
+			Expect(mockProgressRepository.GetCompletedRangesPassedHash).NotTo(Equal(firstHash))
+		})
+
+		It("skips ranges already back-filled", func() {
+			endingBlock := startingBlock + logs.HeaderChunkSize*2 - 1
+			mockProgressRepository.CompletedRanges = []core.BlockRange{
+				{StartingBlock: startingBlock, EndingBlock: startingBlock + logs.HeaderChunkSize - 1},
+			}
+
+			err := extractor.BackFillLogs(endingBlock)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHeaderRepository.GetHeadersInRangeStartingBlocks).To(ConsistOf(startingBlock + logs.HeaderChunkSize))
+		})
+
+		It("back-fills ranges concurrently across workers", func() {
+			extractor.BackFillWorkers = 3
+			endingBlock := startingBlock + logs.HeaderChunkSize*5 - 1
+			mockHeaderRepository.AllHeaders = headersForBlocks(startingBlock, logs.HeaderChunkSize)
+
+			err := extractor.BackFillLogs(endingBlock)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHeaderRepository.GetHeadersInRangeStartingBlocks).To(HaveLen(5))
+			Expect(mockProgressRepository.MarkRangeCompletePassedRanges).To(HaveLen(5))
+		})
+
+		It("marks back-filled headers checked", func() {
+			headerIDs := []int64{rand.Int63(), rand.Int63()}
+			mockHeaderRepository.AllHeaders = []core.Header{{Id: headerIDs[0]}, {Id: headerIDs[1]}}
+			mockCheckedHeadersRepository := &fakes.MockCheckedHeadersRepository{}
+			extractor.CheckedHeadersRepository = mockCheckedHeadersRepository
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockCheckedHeadersRepository.MarkHeadersCheckedHeaderIDs).To(Equal(headerIDs))
+		})
+
+		Describe("when other watched logs aren't fetched by the back-fill", func() {
+			var (
+				fetchedLogs   []core.WatchedLog
+				unfetchedLogs []core.WatchedLog
+			)
+
+			BeforeEach(func() {
+				mockHeaderRepository.AllHeaders = []core.Header{{Id: rand.Int63()}}
+				fetchedLogs = []core.WatchedLog{
+					{ID: rand.Int63(), ContractAddress: fakes.FakeAddress.Hex(), TopicZero: fakes.FakeHash.Hex()},
+					{ID: rand.Int63(), ContractAddress: fakes.FakeAddress.Hex(), TopicZero: fakes.FakeHash.Hex(),
+						TopicOne: fakes.AnotherFakeHash.Hex()},
+				}
+				unfetchedLogs = []core.WatchedLog{
+					{ID: rand.Int63(), ContractAddress: fakes.AnotherFakeAddress.Hex(), TopicZero: fakes.FakeHash.Hex()},
+					{ID: rand.Int63(), ContractAddress: fakes.FakeAddress.Hex(), TopicZero: fakes.AnotherFakeHash.Hex()},
+				}
+				checkedLogsRepository.WatchedLogs = append(append([]core.WatchedLog{}, fetchedLogs...), unfetchedLogs...)
+			})
+
+			It("records the back-filled range as needing a back-fill for them", func() {
+				err := extractor.BackFillLogs(startingBlock + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(checkedLogsRepository.MarkLogsForBackFillIDs).To(Equal([]int64{unfetchedLogs[0].ID, unfetchedLogs[1].ID}))
+				Expect(checkedLogsRepository.MarkLogsForBackFillRanges).To(ConsistOf(
+					core.BlockRange{StartingBlock: startingBlock, EndingBlock: startingBlock + 1}))
+				Expect(checkedHeadersRepository.MarkHeadersCheckedHeaderIDs).To(HaveLen(1))
+			})
+
+			It("does not record a back-fill when the filters fetch every watched log", func() {
+				checkedLogsRepository.WatchedLogs = fetchedLogs
+
+				err := extractor.BackFillLogs(startingBlock + 1)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(checkedLogsRepository.MarkLogsForBackFillRanges).To(BeEmpty())
+				Expect(checkedHeadersRepository.MarkHeadersCheckedHeaderIDs).To(HaveLen(1))
+			})
+
+			It("does not mark headers checked if recording the back-fill fails", func() {
+				checkedLogsRepository.MarkLogsForBackFillError = fakes.FakeError
+
+				err := extractor.BackFillLogs(startingBlock + 1)
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(checkedHeadersRepository.MarkHeadersCheckedHeaderIDs).To(BeEmpty())
+			})
+
+			It("returns error if getting watched logs fails", func() {
+				checkedLogsRepository.GetWatchedLogsError = fakes.FakeError
+
+				err := extractor.BackFillLogs(startingBlock + 1)
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(mockHeaderRepository.GetHeadersInRangeStartingBlocks).To(BeEmpty())
+			})
+		})
+
+		It("returns error if marking headers checked fails", func() {
+			mockHeaderRepository.AllHeaders = []core.Header{{Id: rand.Int63()}}
+			mockCheckedHeadersRepository := &fakes.MockCheckedHeadersRepository{}
+			mockCheckedHeadersRepository.MarkHeadersCheckedReturnError = fakes.FakeError
+			extractor.CheckedHeadersRepository = mockCheckedHeadersRepository
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(mockProgressRepository.MarkRangeCompletePassedRanges).To(BeEmpty())
+		})
+
+		It("does not record a range complete if back-filling it fails", func() {
+			mockHeaderRepository.AllHeaders = []core.Header{{}}
+			extractor.Fetcher = &mocks.MockLogFetcher{ReturnError: fakes.FakeError}
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(mockProgressRepository.MarkRangeCompletePassedRanges).To(BeEmpty())
+		})
+
+		It("returns error if getting completed ranges fails", func() {
+			mockProgressRepository.GetCompletedRangesReturnError = fakes.FakeError
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(mockHeaderRepository.GetHeadersInRangeStartingBlocks).To(BeEmpty())
+		})
+
+		It("does not record a range complete if headers are missing from it", func() {
+			headerID := rand.Int63()
+			mockHeaderRepository.AllHeaders = []core.Header{{Id: headerID, BlockNumber: startingBlock}}
+			mockCheckedHeadersRepository := &fakes.MockCheckedHeadersRepository{}
+			extractor.CheckedHeadersRepository = mockCheckedHeadersRepository
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockCheckedHeadersRepository.MarkHeadersCheckedHeaderIDs).To(ConsistOf(headerID))
+			Expect(mockProgressRepository.MarkRangeCompletePassedRanges).To(BeEmpty())
+		})
+
+		It("returns error if recording a completed range fails", func() {
+			mockHeaderRepository.AllHeaders = headersForBlocks(startingBlock, 2)
+			mockProgressRepository.MarkRangeCompleteReturnError = fakes.FakeError
+
+			err := extractor.BackFillLogs(startingBlock + 1)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+		})
+	})
+
 	Describe("ChunkRanges", func() {
 		It("returns error if upper bound <= lower bound", func() {
 			_, err := logs.ChunkRanges(10, 10, 1)
@@ -610,6 +1022,14 @@ func addHeaderInRange(extractor *logs.LogExtractor) {
 	extractor.HeaderRepository = mockHeadersRepository
 }
 
+func headersForBlocks(startingBlock, count int64) []core.Header {
+	headers := make([]core.Header, 0, count)
+	for blockNumber := startingBlock; blockNumber < startingBlock+count; blockNumber++ {
+		headers = append(headers, core.Header{Id: rand.Int63(), BlockNumber: blockNumber})
+	}
+	return headers
+}
+
 func addFetchedLog(extractor *logs.LogExtractor) {
 	mockLogFetcher := &mocks.MockLogFetcher{}
 	mockLogFetcher.ReturnLogs = []types.Log{{}}
@@ -36,3 +36,14 @@ func (converter *MockConverter) ToModels(abi string, logs []core.EventLog, _ *po
 	converter.ToModelsCalledCounter = converter.ToModelsCalledCounter + 1
 	return nil, converter.ToModelsError
 }
+
+type MockOrphanedHeaderHandlingConverter struct {
+	MockConverter
+	CleanUpError          error
+	PassedOrphanedHeaders []core.OrphanedHeader
+}
+
+func (converter *MockOrphanedHeaderHandlingConverter) CleanUpOrphanedHeader(header core.OrphanedHeader, _ *postgres.DB) error {
+	converter.PassedOrphanedHeaders = append(converter.PassedOrphanedHeaders, header)
+	return converter.CleanUpError
+}
@@ -24,21 +24,38 @@ import (
 )
 
 type MockEventTransformer struct {
-	ExecuteWasCalled bool
-	ExecuteError     error
-	PassedLogs       []core.EventLog
-	config           event.TransformerConfig
+	ExecuteWasCalled      bool
+	ExecuteError          error
+	FailingLogIDs         []int64
+	PassedLogs            []core.EventLog
+	PassedLogChunks       [][]core.EventLog
+	CleanUpError          error
+	PassedOrphanedHeaders []core.OrphanedHeader
+	config                event.TransformerConfig
 }
 
 func (t *MockEventTransformer) Execute(logs []core.EventLog) error {
+	t.PassedLogChunks = append(t.PassedLogChunks, logs)
 	if t.ExecuteError != nil {
 		return t.ExecuteError
 	}
+	for _, log := range logs {
+		for _, failingID := range t.FailingLogIDs {
+			if log.ID == failingID {
+				return fakes.FakeError
+			}
+		}
+	}
 	t.ExecuteWasCalled = true
 	t.PassedLogs = logs
 	return nil
 }
 
+func (t *MockEventTransformer) CleanUpOrphanedHeader(header core.OrphanedHeader, _ *postgres.DB) error {
+	t.PassedOrphanedHeaders = append(t.PassedOrphanedHeaders, header)
+	return t.CleanUpError
+}
+
 func (t *MockEventTransformer) GetConfig() event.TransformerConfig {
 	return t.config
 }
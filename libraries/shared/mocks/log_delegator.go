@@ -23,6 +23,8 @@ import (
 
 type MockLogDelegator struct {
 	AddedTransformers   []event.ITransformer
+	CleanUpCallCount    int
+	CleanUpError        error
 	DelegateCallCount   int
 	DelegateErrors      []error
 	DelegatePassedLimit int
@@ -32,6 +34,11 @@ func (delegator *MockLogDelegator) AddTransformer(t event.ITransformer) {
 	delegator.AddedTransformers = append(delegator.AddedTransformers, t)
 }
 
+func (delegator *MockLogDelegator) CleanUpOrphanedHeaders() error {
+	delegator.CleanUpCallCount++
+	return delegator.CleanUpError
+}
+
 func (delegator *MockLogDelegator) DelegateLogs(limit int) error {
 	delegator.DelegateCallCount++
 	delegator.DelegatePassedLimit = limit
@@ -17,6 +17,8 @@
 package mocks
 
 import (
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/makerdao/vulcanizedb/pkg/core"
@@ -26,15 +28,27 @@ type MockLogFetcher struct {
 	ContractAddresses []common.Address
 	FetchCalled       bool
 	MissingHeader     core.Header
+	PassedHeaders     [][]core.Header
 	ReturnError       error
 	ReturnLogs        []types.Log
-	Topics            []common.Hash
+	Topics            [][]common.Hash
+	mutex             sync.Mutex
 }
 
-func (fetcher *MockLogFetcher) FetchLogs(contractAddresses []common.Address, topics []common.Hash, missingHeader core.Header) ([]types.Log, error) {
+func (fetcher *MockLogFetcher) FetchLogs(contractAddresses []common.Address, topics [][]common.Hash, missingHeader core.Header) ([]types.Log, error) {
 	fetcher.FetchCalled = true
 	fetcher.ContractAddresses = contractAddresses
 	fetcher.Topics = topics
 	fetcher.MissingHeader = missingHeader
 	return fetcher.ReturnLogs, fetcher.ReturnError
 }
+
+func (fetcher *MockLogFetcher) FetchLogsForRange(contractAddresses []common.Address, topics [][]common.Hash, headers []core.Header) ([]types.Log, error) {
+	fetcher.mutex.Lock()
+	defer fetcher.mutex.Unlock()
+	fetcher.FetchCalled = true
+	fetcher.ContractAddresses = contractAddresses
+	fetcher.Topics = topics
+	fetcher.PassedHeaders = append(fetcher.PassedHeaders, headers)
+	return fetcher.ReturnLogs, fetcher.ReturnError
+}
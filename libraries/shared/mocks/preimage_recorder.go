@@ -0,0 +1,48 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+)
+
+type MockPreimageRecorder struct {
+	AddPositionsPassedAddresses []common.Address
+	AddPositionsPassedPositions []storage.MappingPosition
+	AddKnownAddressesPassed     []common.Address
+	RecordPreimagesCalled       bool
+	RecordPreimagesReturn       int64
+	RecordPreimagesErr          error
+}
+
+func (recorder *MockPreimageRecorder) AddPositions(address common.Address, positions ...storage.MappingPosition) {
+	recorder.AddPositionsPassedAddresses = append(recorder.AddPositionsPassedAddresses, address)
+	recorder.AddPositionsPassedPositions = append(recorder.AddPositionsPassedPositions, positions...)
+}
+
+func (recorder *MockPreimageRecorder) AddKnownAddresses(addresses ...common.Address) {
+	recorder.AddKnownAddressesPassed = append(recorder.AddKnownAddressesPassed, addresses...)
+}
+
+func (recorder *MockPreimageRecorder) RecordPreimages() (int64, error) {
+	recorder.RecordPreimagesCalled = true
+	recorded := recorder.RecordPreimagesReturn
+	// only the first pass finds new preimages
+	recorder.RecordPreimagesReturn = 0
+	return recorded, recorder.RecordPreimagesErr
+}
@@ -17,6 +17,7 @@
 package mocks
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 )
 
@@ -28,6 +29,9 @@ type MockStorageDiffRepository struct {
 	GetNewDiffsErrors                          []error
 	GetNewDiffsPassedMinIDs                    []int
 	GetNewDiffsPassedLimits                    []int
+	GetUnrecognizedDiffsDiffs                  []types.PersistedDiff
+	GetUnrecognizedDiffsErr                    error
+	GetUnrecognizedDiffsPassedMinIDs           []int
 	MarkCheckedPassedID                        int64
 	MarkUnrecognizedPassedID                   int64
 	MarkNoncanonicalPassedID                   int64
@@ -35,6 +39,11 @@ type MockStorageDiffRepository struct {
 	GetFirstDiffIDToReturn                     int64
 	GetFirstDiffIDErr                          error
 	GetFirstDiffBlockHeightPassed              int64
+	GetStorageValuesPassedHashedAddress        common.Hash
+	GetStorageValuesPassedKeys                 []common.Hash
+	GetStorageValuesPassedBlockHeight          int
+	GetStorageValuesToReturn                   []common.Hash
+	GetStorageValuesErr                        error
 }
 
 func (repository *MockStorageDiffRepository) CreateStorageDiff(rawDiff types.RawDiff) (int64, error) {
@@ -57,6 +66,17 @@ func (repository *MockStorageDiffRepository) GetNewDiffs(minID, limit int) ([]ty
 	return repository.GetNewDiffsDiffs, err
 }
 
+func (repository *MockStorageDiffRepository) GetUnrecognizedDiffs(minID, limit int) ([]types.PersistedDiff, error) {
+	repository.GetUnrecognizedDiffsPassedMinIDs = append(repository.GetUnrecognizedDiffsPassedMinIDs, minID)
+	var diffs []types.PersistedDiff
+	for _, diff := range repository.GetUnrecognizedDiffsDiffs {
+		if int(diff.ID) > minID && len(diffs) < limit {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, repository.GetUnrecognizedDiffsErr
+}
+
 func (repository *MockStorageDiffRepository) MarkTransformed(id int64) error {
 	repository.MarkCheckedPassedID = id
 	return nil
@@ -81,3 +101,10 @@ func (repository *MockStorageDiffRepository) GetFirstDiffIDForBlockHeight(blockH
 	repository.GetFirstDiffBlockHeightPassed = blockHeight
 	return repository.GetFirstDiffIDToReturn, repository.GetFirstDiffIDErr
 }
+
+func (repository *MockStorageDiffRepository) GetStorageValues(hashedAddress common.Hash, keys []common.Hash, blockHeight int) ([]common.Hash, error) {
+	repository.GetStorageValuesPassedHashedAddress = hashedAddress
+	repository.GetStorageValuesPassedKeys = keys
+	repository.GetStorageValuesPassedBlockHeight = blockHeight
+	return repository.GetStorageValuesToReturn, repository.GetStorageValuesErr
+}
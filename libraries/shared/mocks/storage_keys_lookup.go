@@ -18,6 +18,7 @@ package mocks
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 )
@@ -43,3 +44,13 @@ func (lookup *MockStorageKeysLookup) GetKeys() ([]common.Hash, error) {
 }
 
 func (lookup *MockStorageKeysLookup) SetDB(db *postgres.DB) {}
+
+type MockPreimageKeysLookup struct {
+	MockStorageKeysLookup
+	Positions    []storage.MappingPosition
+	PositionsErr error
+}
+
+func (lookup *MockPreimageKeysLookup) MappingPositions() ([]storage.MappingPosition, error) {
+	return lookup.Positions, lookup.PositionsErr
+}
@@ -29,10 +29,12 @@ type MockStorageTransformer struct {
 	KeccakOfAddress   common.Hash
 	ExecuteErr        error
 	PassedDiff        types.PersistedDiff
+	PassedDiffs       []types.PersistedDiff
 }
 
 func (transformer *MockStorageTransformer) Execute(diff types.PersistedDiff) error {
 	transformer.PassedDiff = diff
+	transformer.PassedDiffs = append(transformer.PassedDiffs, diff)
 	return transformer.ExecuteErr
 }
 
@@ -24,7 +24,7 @@ var _ = Describe("header updated trigger", func() {
 
 	It("updates time updated when record is changed", func() {
 		blockNumber := rand.Int63()
-		headerRepo := repositories.NewHeaderRepository(db)
+		headerRepo := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 		header := fakes.GetFakeHeader(blockNumber)
 		headerID, insertErr := headerRepo.CreateOrUpdateHeader(header)
 		Expect(insertErr).NotTo(HaveOccurred())
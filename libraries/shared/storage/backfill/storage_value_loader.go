@@ -26,7 +26,7 @@ func NewStorageValueLoader(bc core.BlockChain, db *postgres.DB, initializers []s
 	return StorageValueLoader{
 		bc:               bc,
 		db:               db,
-		HeaderRepo:       repositories.NewHeaderRepository(db),
+		HeaderRepo:       repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow),
 		StorageDiffRepo:  storage2.NewDiffRepository(db),
 		storageByAddress: make(map[common.Address]chunksOfKeysToValues, len(initializers)),
 		initializers:     initializers,
@@ -21,33 +21,114 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 )
 
 const (
-	bitsPerByte = 8
+	slotSize = 32
+	// longest string or bytes value decoded from storage; longer lengths are taken to be corrupt data
+	maxDynamicValueLength = 1 << 24
 )
 
-func Decode(diff types.PersistedDiff, metadata types.ValueMetadata) interface{} {
+// Decode returns the value of a storage slot as a string, or a map of position to string for packed slots.
+// Integers are formatted as decimals, addresses and fixed-size bytes as hex, and booleans as "true" or "false".
+// A string or bytes value too long to fit in its slot returns types.ErrValueSpansSlots; decode it with
+// DecodeDynamicValue once the slots holding its data are available.
+func Decode(diff types.PersistedDiff, metadata types.ValueMetadata) (interface{}, error) {
 	switch metadata.Type {
-	case types.Uint256:
-		return decodeInteger(diff.StorageValue.Bytes())
-	case types.Uint8:
-		return decodeInteger(diff.StorageValue.Bytes())
-	case types.Uint32:
-		return decodeInteger(diff.StorageValue.Bytes())
-	case types.Uint48:
-		return decodeInteger(diff.StorageValue.Bytes())
-	case types.Uint128:
-		return decodeInteger(diff.StorageValue.Bytes())
-	case types.Address:
-		return decodeAddress(diff.StorageValue.Bytes())
-	case types.Bytes32:
-		return diff.StorageValue.Hex()
 	case types.PackedSlot:
 		return decodePackedSlot(diff.StorageValue.Bytes(), metadata.PackedTypes)
+	case types.String, types.Bytes:
+		return decodeShortDynamicValue(diff.StorageValue, metadata.Type)
 	default:
-		panic(fmt.Sprintf("can't decode unknown type: %d", metadata.Type))
+		return decodeItem(diff.StorageValue.Bytes(), metadata.Type)
+	}
+}
+
+// DynamicValueDataSlots returns the keys of the slots holding the data of a long string or bytes value, given the
+// key and value of the slot holding its length. It returns no keys for values short enough to fit in that slot.
+func DynamicValueDataSlots(lengthSlotKey, lengthSlotValue common.Hash) ([]common.Hash, error) {
+	length, isLong, lengthErr := decodeDynamicValueLength(lengthSlotValue)
+	if lengthErr != nil {
+		return nil, lengthErr
+	}
+	if !isLong {
+		return nil, nil
+	}
+	firstDataSlot := crypto.Keccak256Hash(lengthSlotKey.Bytes()).Big()
+	numberOfSlots := (length + slotSize - 1) / slotSize
+	slots := make([]common.Hash, numberOfSlots)
+	for i := range slots {
+		slot := new(big.Int).Add(firstDataSlot, big.NewInt(int64(i)))
+		slots[i] = common.BigToHash(slot)
+	}
+	return slots, nil
+}
+
+// DecodeDynamicValue decodes a string or bytes value of any length from the value of the slot holding its length
+// and the values of the slots returned by DynamicValueDataSlots, in order
+func DecodeDynamicValue(lengthSlotValue common.Hash, dataSlotValues []common.Hash, valueType types.ValueType) (string, error) {
+	length, isLong, lengthErr := decodeDynamicValueLength(lengthSlotValue)
+	if lengthErr != nil {
+		return "", lengthErr
+	}
+	if !isLong {
+		return decodeShortDynamicValue(lengthSlotValue, valueType)
+	}
+	numberOfSlots := (length + slotSize - 1) / slotSize
+	if len(dataSlotValues) < numberOfSlots {
+		return "", fmt.Errorf("value of length %d needs %d data slots, got %d", length, numberOfSlots, len(dataSlotValues))
+	}
+	data := make([]byte, 0, numberOfSlots*slotSize)
+	for _, slotValue := range dataSlotValues[:numberOfSlots] {
+		data = append(data, slotValue.Bytes()...)
+	}
+	return formatDynamicValue(data[:length], valueType)
+}
+
+// decodeDynamicValueLength reads the length slot of a string or bytes value. Values shorter than 32 bytes are
+// stored in the slot itself, with twice their length in the lowest byte; longer values store twice their length
+// plus one, and keep their data in consecutive slots starting at the keccak256 of the slot's key.
+func decodeDynamicValueLength(lengthSlotValue common.Hash) (int, bool, error) {
+	if lengthSlotValue[slotSize-1]&1 == 0 {
+		length := int(lengthSlotValue[slotSize-1] / 2)
+		if length >= slotSize {
+			return 0, false, fmt.Errorf("malformed short value length: %d", length)
+		}
+		return length, false, nil
+	}
+	encodedLength := lengthSlotValue.Big()
+	length := encodedLength.Rsh(encodedLength, 1)
+	if !length.IsInt64() || length.Int64() > maxDynamicValueLength {
+		return 0, true, fmt.Errorf("malformed long value length: %s", length.String())
+	}
+	if length.Int64() < slotSize {
+		return 0, true, fmt.Errorf("long value encoded with short length: %d", length.Int64())
+	}
+	return int(length.Int64()), true, nil
+}
+
+func decodeShortDynamicValue(slotValue common.Hash, valueType types.ValueType) (string, error) {
+	length, isLong, lengthErr := decodeDynamicValueLength(slotValue)
+	if lengthErr != nil {
+		return "", lengthErr
+	}
+	if isLong {
+		return "", types.ErrValueSpansSlots{Length: length}
+	}
+	return formatDynamicValue(slotValue.Bytes()[:length], valueType)
+}
+
+func formatDynamicValue(data []byte, valueType types.ValueType) (string, error) {
+	switch valueType {
+	case types.String:
+		return string(data), nil
+	case types.Bytes:
+		return hexutil.Encode(data), nil
+	default:
+		return "", fmt.Errorf("value type %d is not a string or bytes", valueType)
 	}
 }
 
@@ -56,11 +137,38 @@ func decodeInteger(raw []byte) string {
 	return n.String()
 }
 
+// decodeSignedInteger reads the two's complement integer in the lowest size bytes of raw
+func decodeSignedInteger(raw []byte, size int) string {
+	valueBytes := lowestBytes(raw, size)
+	n := big.NewInt(0).SetBytes(valueBytes)
+	if len(valueBytes) > 0 && valueBytes[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(valueBytes)*8)))
+	}
+	return n.String()
+}
+
 func decodeAddress(raw []byte) string {
 	return common.BytesToAddress(raw).Hex()
 }
 
-func decodePackedSlot(raw []byte, packedTypes map[int]types.ValueType) map[int]string {
+func decodeBool(raw []byte) string {
+	for _, b := range raw {
+		if b != 0 {
+			return "true"
+		}
+	}
+	return "false"
+}
+
+// lowestBytes returns the size lower-order bytes of raw, where Solidity aligns values in a storage slot
+func lowestBytes(raw []byte, size int) []byte {
+	if size >= len(raw) {
+		return raw
+	}
+	return raw[len(raw)-size:]
+}
+
+func decodePackedSlot(raw []byte, packedTypes map[int]types.ValueType) (map[int]string, error) {
 	storageSlotData := raw
 	decodedStorageSlotItems := map[int]string{}
 	numberOfTypes := len(packedTypes)
@@ -70,44 +178,50 @@ func decodePackedSlot(raw []byte, packedTypes map[int]types.ValueType) map[int]s
 		lengthOfStorageData := len(storageSlotData)
 
 		//get item details (type, length, starting index, value bytes)
-		itemType := packedTypes[position]
-		lengthOfItem := getNumberOfBytes(itemType)
+		itemType, ok := packedTypes[position]
+		if !ok {
+			return nil, fmt.Errorf("packed slot is missing a type for position %d", position)
+		}
+		lengthOfItem, sizeErr := itemType.Size()
+		if sizeErr != nil {
+			return nil, fmt.Errorf("can't decode item %d of packed slot: %w", position, sizeErr)
+		}
+		if lengthOfItem > lengthOfStorageData {
+			return nil, fmt.Errorf("item %d of packed slot needs %d bytes, %d remaining", position, lengthOfItem, lengthOfStorageData)
+		}
 		itemStartingIndex := lengthOfStorageData - lengthOfItem
 		itemValueBytes := storageSlotData[itemStartingIndex:]
 
 		//decode item's bytes and set in results map
-		decodedValue := decodeIndividualItem(itemValueBytes, itemType)
+		decodedValue, decodeErr := decodeItem(itemValueBytes, itemType)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("can't decode item %d of packed slot: %w", position, decodeErr)
+		}
 		decodedStorageSlotItems[position] = decodedValue
 
 		//pop last item off raw slot data before moving on
 		storageSlotData = storageSlotData[0:itemStartingIndex]
 	}
 
-	return decodedStorageSlotItems
-}
-
-func decodeIndividualItem(itemBytes []byte, valueType types.ValueType) string {
-	switch valueType {
-	case types.Uint32, types.Uint48, types.Uint128:
-		return decodeInteger(itemBytes)
-	case types.Address:
-		return decodeAddress(itemBytes)
-	default:
-		panic(fmt.Sprintf("can't decode unknown type: %d", valueType))
-	}
+	return decodedStorageSlotItems, nil
 }
 
-func getNumberOfBytes(valueType types.ValueType) int {
-	switch valueType {
-	case types.Uint32:
-		return 32 / bitsPerByte
-	case types.Uint48:
-		return 48 / bitsPerByte
-	case types.Uint128:
-		return 128 / bitsPerByte
-	case types.Address:
-		return 20
+// decodeItem decodes a value from raw, which is either the item's own bytes in a packed slot or a whole slot
+func decodeItem(raw []byte, valueType types.ValueType) (string, error) {
+	switch {
+	case valueType.IsUnsignedInteger(), valueType == types.Enum:
+		return decodeInteger(raw), nil
+	case valueType.IsSignedInteger():
+		size, _ := valueType.Size()
+		return decodeSignedInteger(raw, size), nil
+	case valueType.IsFixedBytes():
+		size, _ := valueType.Size()
+		return hexutil.Encode(lowestBytes(raw, size)), nil
+	case valueType == types.Address:
+		return decodeAddress(raw), nil
+	case valueType == types.Bool:
+		return decodeBool(raw), nil
 	default:
-		panic(fmt.Sprintf("ValueType %d not recognized", valueType))
+		return "", fmt.Errorf("can't decode unknown type: %d", valueType)
 	}
 }
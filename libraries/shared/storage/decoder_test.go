@@ -20,6 +20,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	. "github.com/onsi/ginkgo"
@@ -32,8 +33,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeInt}}
 		metadata := types.ValueMetadata{Type: types.Uint256}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(big.NewInt(0).SetBytes(fakeInt.Bytes()).String()))
 	})
 
@@ -42,8 +44,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeInt}}
 		metadata := types.ValueMetadata{Type: types.Uint8}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(big.NewInt(0).SetBytes(fakeInt.Bytes()).String()))
 	})
 
@@ -52,8 +55,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeInt}}
 		metadata := types.ValueMetadata{Type: types.Uint128}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(big.NewInt(0).SetBytes(fakeInt.Bytes()).String()))
 	})
 
@@ -62,8 +66,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeInt}}
 		metadata := types.ValueMetadata{Type: types.Uint32}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(big.NewInt(0).SetBytes(fakeInt.Bytes()).String()))
 	})
 
@@ -72,8 +77,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeInt}}
 		metadata := types.ValueMetadata{Type: types.Uint48}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(big.NewInt(0).SetBytes(fakeInt.Bytes()).String()))
 	})
 
@@ -82,8 +88,9 @@ var _ = Describe("Storage decoder", func() {
 		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeAddress.Hash()}}
 		metadata := types.ValueMetadata{Type: types.Address}
 
-		result := storage.Decode(diff, metadata)
+		result, err := storage.Decode(diff, metadata)
 
+		Expect(err).NotTo(HaveOccurred())
 		Expect(result).To(Equal(fakeAddress.Hex()))
 	})
 
@@ -102,7 +109,8 @@ var _ = Describe("Storage decoder", func() {
 				PackedTypes: packedTypes,
 			}
 
-			result := storage.Decode(diff, metadata)
+			result, err := storage.Decode(diff, metadata)
+			Expect(err).NotTo(HaveOccurred())
 			decodedValues := result.(map[int]string)
 
 			Expect(decodedValues[0]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("01").Bytes()).String()))
@@ -122,7 +130,8 @@ var _ = Describe("Storage decoder", func() {
 				PackedTypes: packedTypes,
 			}
 
-			result := storage.Decode(diff, metadata)
+			result, err := storage.Decode(diff, metadata)
+			Expect(err).NotTo(HaveOccurred())
 			decodedValues := result.(map[int]string)
 
 			Expect(decodedValues[0]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("2a30").Bytes()).String()))
@@ -148,7 +157,8 @@ var _ = Describe("Storage decoder", func() {
 				PackedTypes: packedTypes,
 			}
 
-			result := storage.Decode(diff, metadata)
+			result, err := storage.Decode(diff, metadata)
+			Expect(err).NotTo(HaveOccurred())
 			decodedValues := result.(map[int]string)
 
 			Expect(decodedValues[0]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("2a30").Bytes()).String()))
@@ -174,7 +184,8 @@ var _ = Describe("Storage decoder", func() {
 				PackedTypes: packedTypes,
 			}
 
-			result := storage.Decode(diff, metadata)
+			result, err := storage.Decode(diff, metadata)
+			Expect(err).NotTo(HaveOccurred())
 			decodedValues := result.(map[int]string)
 
 			Expect(decodedValues[0]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("AB54A98CEB1F0AD2").Bytes()).String()))
@@ -196,12 +207,253 @@ var _ = Describe("Storage decoder", func() {
 				PackedTypes: packedTypes,
 			}
 
-			result := storage.Decode(row, metadata)
+			result, err := storage.Decode(row, metadata)
+			Expect(err).NotTo(HaveOccurred())
 			decodedValues := result.(map[int]string)
 
 			Expect(decodedValues[0]).To(Equal("0x" + addressHex))
 			Expect(decodedValues[1]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("2a30").Bytes()).String()))
 			Expect(decodedValues[2]).To(Equal(big.NewInt(0).SetBytes(common.HexToHash("2a300").Bytes()).String()))
 		})
+
+		It("decodes uint8, bool, signed integer, fixed-size bytes and address items", func() {
+			addressHex := "1234567890abcdef1234567890abcdef12345678"
+			packedStorage := common.HexToHash("00000000" + addressHex + "deadbeef" + "fffe" + "01" + "2a")
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: packedStorage}}
+			packedTypes := map[int]types.ValueType{
+				0: types.Uint8,
+				1: types.Bool,
+				2: types.Int16,
+				3: fixedBytes(4),
+				4: types.Address,
+			}
+			metadata := types.ValueMetadata{
+				Type:        types.PackedSlot,
+				PackedTypes: packedTypes,
+			}
+
+			result, err := storage.Decode(diff, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			decodedValues := result.(map[int]string)
+
+			Expect(decodedValues[0]).To(Equal("42"))
+			Expect(decodedValues[1]).To(Equal("true"))
+			Expect(decodedValues[2]).To(Equal("-2"))
+			Expect(decodedValues[3]).To(Equal("0xdeadbeef"))
+			Expect(decodedValues[4]).To(Equal(common.HexToAddress(addressHex).Hex()))
+		})
+
+		It("returns error if the items don't fit in the slot", func() {
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("01")}}
+			metadata := types.ValueMetadata{
+				Type:        types.PackedSlot,
+				PackedTypes: map[int]types.ValueType{0: types.Uint128, 1: types.Uint128, 2: types.Uint8},
+			}
+
+			_, err := storage.Decode(diff, metadata)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("item 2 of packed slot needs 1 bytes, 0 remaining"))
+		})
+
+		It("returns error if an item has no fixed size", func() {
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("01")}}
+			metadata := types.ValueMetadata{
+				Type:        types.PackedSlot,
+				PackedTypes: map[int]types.ValueType{0: types.String},
+			}
+
+			_, err := storage.Decode(diff, metadata)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("signed integers", func() {
+		It("decodes negative values from their two's complement", func() {
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("ff")}}
+			metadata := types.ValueMetadata{Type: types.Int8}
+
+			result, err := storage.Decode(diff, metadata)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("-1"))
+		})
+
+		It("decodes int256", func() {
+			minusTwo := common.HexToHash("fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe")
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: minusTwo}}
+			metadata := types.ValueMetadata{Type: types.Int256}
+
+			result, err := storage.Decode(diff, metadata)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("-2"))
+		})
+
+		It("decodes positive values", func() {
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("7f")}}
+			metadata := types.ValueMetadata{Type: types.Int8}
+
+			result, err := storage.Decode(diff, metadata)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("127"))
+		})
+	})
+
+	It("decodes uint16 and uint64", func() {
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("ffff")}}
+
+		uint16Result, uint16Err := storage.Decode(diff, types.ValueMetadata{Type: types.Uint16})
+		uint64Result, uint64Err := storage.Decode(diff, types.ValueMetadata{Type: types.Uint64})
+
+		Expect(uint16Err).NotTo(HaveOccurred())
+		Expect(uint16Result).To(Equal("65535"))
+		Expect(uint64Err).NotTo(HaveOccurred())
+		Expect(uint64Result).To(Equal("65535"))
+	})
+
+	It("decodes bool", func() {
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("01")}}
+		metadata := types.ValueMetadata{Type: types.Bool}
+
+		result, err := storage.Decode(diff, metadata)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("true"))
+	})
+
+	It("decodes enum", func() {
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("03")}}
+		metadata := types.ValueMetadata{Type: types.Enum}
+
+		result, err := storage.Decode(diff, metadata)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("3"))
+	})
+
+	It("decodes fixed-size bytes", func() {
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("deadbeef")}}
+		metadata := types.ValueMetadata{Type: fixedBytes(4)}
+
+		result, err := storage.Decode(diff, metadata)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("0xdeadbeef"))
+	})
+
+	It("decodes bytes32", func() {
+		fakeHash := common.HexToHash("0x12345")
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: fakeHash}}
+		metadata := types.ValueMetadata{Type: types.Bytes32}
+
+		result, err := storage.Decode(diff, metadata)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(fakeHash.Hex()))
+	})
+
+	It("returns error for an unknown type", func() {
+		diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: common.HexToHash("01")}}
+		metadata := types.ValueMetadata{Type: types.ValueType(-1)}
+
+		_, err := storage.Decode(diff, metadata)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("can't decode unknown type"))
+	})
+
+	Describe("strings and bytes", func() {
+		var (
+			slotKey    = common.HexToHash("05")
+			longString = "a string too long to fit in a single slot"
+		)
+
+		It("decodes short strings stored in their slot", func() {
+			shortString := "hello"
+			slotValue := common.BytesToHash(append(common.RightPadBytes([]byte(shortString), 31), byte(len(shortString)*2)))
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: slotValue}}
+			metadata := types.ValueMetadata{Type: types.String}
+
+			result, err := storage.Decode(diff, metadata)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(shortString))
+		})
+
+		It("decodes short bytes stored in their slot", func() {
+			slotValue := common.BytesToHash(append(common.RightPadBytes([]byte{0x12, 0x34}, 31), 4))
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: slotValue}}
+			metadata := types.ValueMetadata{Type: types.Bytes}
+
+			result, err := storage.Decode(diff, metadata)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("0x1234"))
+		})
+
+		It("returns the length of long values spanning multiple slots", func() {
+			lengthSlotValue := common.BigToHash(big.NewInt(int64(len(longString)*2 + 1)))
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: lengthSlotValue}}
+			metadata := types.ValueMetadata{Type: types.String}
+
+			_, err := storage.Decode(diff, metadata)
+
+			Expect(err).To(MatchError(types.ErrValueSpansSlots{Length: len(longString)}))
+		})
+
+		It("returns the slots holding the data of long values", func() {
+			lengthSlotValue := common.BigToHash(big.NewInt(int64(len(longString)*2 + 1)))
+
+			slots, err := storage.DynamicValueDataSlots(slotKey, lengthSlotValue)
+
+			Expect(err).NotTo(HaveOccurred())
+			firstSlot := crypto.Keccak256Hash(slotKey.Bytes())
+			secondSlot := common.BigToHash(new(big.Int).Add(firstSlot.Big(), big.NewInt(1)))
+			Expect(slots).To(Equal([]common.Hash{firstSlot, secondSlot}))
+		})
+
+		It("returns no data slots for short values", func() {
+			slots, err := storage.DynamicValueDataSlots(slotKey, common.HexToHash("0a"))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(slots).To(BeEmpty())
+		})
+
+		It("decodes long values from their data slots", func() {
+			lengthSlotValue := common.BigToHash(big.NewInt(int64(len(longString)*2 + 1)))
+			data := common.RightPadBytes([]byte(longString), 64)
+			dataSlotValues := []common.Hash{common.BytesToHash(data[:32]), common.BytesToHash(data[32:])}
+
+			result, err := storage.DecodeDynamicValue(lengthSlotValue, dataSlotValues, types.String)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(longString))
+		})
+
+		It("returns error if data slots are missing", func() {
+			lengthSlotValue := common.BigToHash(big.NewInt(int64(len(longString)*2 + 1)))
+
+			_, err := storage.DecodeDynamicValue(lengthSlotValue, []common.Hash{{}}, types.String)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("needs 2 data slots, got 1"))
+		})
+
+		It("returns error if a long value's length is implausible", func() {
+			lengthSlotValue := common.HexToHash("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+			_, err := storage.DynamicValueDataSlots(slotKey, lengthSlotValue)
+
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
+
+func fixedBytes(size int) types.ValueType {
+	valueType, err := types.FixedBytes(size)
+	Expect(err).NotTo(HaveOccurred())
+	return valueType
+}
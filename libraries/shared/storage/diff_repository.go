@@ -19,6 +19,8 @@ package storage
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lib/pq"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 )
@@ -27,11 +29,13 @@ type DiffRepository interface {
 	CreateStorageDiff(rawDiff types.RawDiff) (int64, error)
 	CreateBackFilledStorageValue(rawDiff types.RawDiff) error
 	GetNewDiffs(minID, limit int) ([]types.PersistedDiff, error)
+	GetUnrecognizedDiffs(minID, limit int) ([]types.PersistedDiff, error)
 	MarkTransformed(id int64) error
 	MarkNoncanonical(id int64) error
 	MarkUnrecognized(id int64) error
 	MarkUnwatched(id int64) error
 	GetFirstDiffIDForBlockHeight(blockHeight int64) (int64, error)
+	GetStorageValues(hashedAddress common.Hash, keys []common.Hash, blockHeight int) ([]common.Hash, error)
 }
 
 var (
@@ -87,6 +91,20 @@ func (repository diffRepository) GetNewDiffs(minID, limit int) ([]types.Persiste
 	return result, nil
 }
 
+// GetUnrecognizedDiffs returns diffs whose keys weren't recognized, so that they can be retried once their keys are known
+func (repository diffRepository) GetUnrecognizedDiffs(minID, limit int) ([]types.PersistedDiff, error) {
+	var result []types.PersistedDiff
+	err := repository.db.Select(
+		&result,
+		`SELECT * FROM public.storage_diff WHERE status = $1 AND id > $2 ORDER BY id ASC LIMIT $3`,
+		Unrecognized, minID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unrecognized storage diffs with id greater than %d: %w", minID, err)
+	}
+	return result, nil
+}
+
 func (repository diffRepository) MarkTransformed(id int64) error {
 	_, err := repository.db.Exec(`UPDATE public.storage_diff SET status = $1 WHERE id = $2`, Transformed, id)
 	if err != nil {
@@ -128,3 +146,37 @@ func (repository diffRepository) GetFirstDiffIDForBlockHeight(blockHeight int64)
 	}
 	return diffID, nil
 }
+
+// GetStorageValues returns the value of each key of a contract's storage as of the given block, taken from its latest
+// canonical diff at or before that block. Keys without such a diff return types.ErrKeyNotFound.
+func (repository diffRepository) GetStorageValues(hashedAddress common.Hash, keys []common.Hash, blockHeight int) ([]common.Hash, error) {
+	var storageKeys pq.ByteaArray
+	for _, key := range keys {
+		storageKeys = append(storageKeys, key.Bytes())
+	}
+	var latestValues []struct {
+		StorageKey   common.Hash `db:"storage_key"`
+		StorageValue common.Hash `db:"storage_value"`
+	}
+	err := repository.db.Select(&latestValues,
+		`SELECT DISTINCT ON (storage_key) storage_key, storage_value FROM public.storage_diff
+		WHERE hashed_address = $1 AND storage_key = ANY($2) AND block_height <= $3 AND status != $4
+		ORDER BY storage_key, block_height DESC, id DESC`,
+		hashedAddress.Bytes(), storageKeys, blockHeight, Noncanonical)
+	if err != nil {
+		return nil, fmt.Errorf("error getting storage values as of block %d: %w", blockHeight, err)
+	}
+	valuesByKey := make(map[common.Hash]common.Hash, len(latestValues))
+	for _, latestValue := range latestValues {
+		valuesByKey[latestValue.StorageKey] = latestValue.StorageValue
+	}
+	values := make([]common.Hash, len(keys))
+	for i, key := range keys {
+		value, ok := valuesByKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: no diff for %s at or before block %d", types.ErrKeyNotFound, key.Hex(), blockHeight)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
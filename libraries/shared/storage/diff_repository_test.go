@@ -286,6 +286,53 @@ var _ = Describe("Storage diffs repository", func() {
 		})
 	})
 
+	Describe("GetUnrecognizedDiffs", func() {
+		It("sends diffs that are marked as 'unrecognized'", func() {
+			unrecognizedPersistedDiff := types.PersistedDiff{
+				RawDiff:   fakeStorageDiff,
+				ID:        rand.Int63(),
+				Status:    storage.Unrecognized,
+				EthNodeID: db.NodeID,
+			}
+			insertTestDiff(unrecognizedPersistedDiff, db)
+
+			diffs, err := repo.GetUnrecognizedDiffs(0, 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(ConsistOf(unrecognizedPersistedDiff))
+		})
+
+		It("does not send diffs that are marked as 'new'", func() {
+			newPersistedDiff := types.PersistedDiff{
+				RawDiff:   fakeStorageDiff,
+				ID:        rand.Int63(),
+				Status:    storage.New,
+				EthNodeID: db.NodeID,
+			}
+			insertTestDiff(newPersistedDiff, db)
+
+			diffs, err := repo.GetUnrecognizedDiffs(0, 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(BeEmpty())
+		})
+
+		It("only sends diffs with a greater ID", func() {
+			unrecognizedPersistedDiff := types.PersistedDiff{
+				RawDiff:   fakeStorageDiff,
+				ID:        rand.Int63(),
+				Status:    storage.Unrecognized,
+				EthNodeID: db.NodeID,
+			}
+			insertTestDiff(unrecognizedPersistedDiff, db)
+
+			diffs, err := repo.GetUnrecognizedDiffs(int(unrecognizedPersistedDiff.ID), 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(BeEmpty())
+		})
+	})
+
 	Describe("Changing the diff status", func() {
 		var fakePersistedDiff types.PersistedDiff
 		BeforeEach(func() {
@@ -407,6 +454,72 @@ var _ = Describe("Storage diffs repository", func() {
 			Expect(diffErr).To(MatchError(sql.ErrNoRows))
 		})
 	})
+
+	Describe("GetStorageValues", func() {
+		var (
+			key      common.Hash
+			otherKey common.Hash
+		)
+
+		BeforeEach(func() {
+			key = test_data.FakeHash()
+			otherKey = test_data.FakeHash()
+			fakeStorageDiff.BlockHeight = 100
+		})
+
+		createDiff := func(storageKey common.Hash, blockHeight int, value common.Hash) int64 {
+			diff := fakeStorageDiff
+			diff.StorageKey = storageKey
+			diff.BlockHeight = blockHeight
+			diff.BlockHash = test_data.FakeHash()
+			diff.StorageValue = value
+			id, createErr := repo.CreateStorageDiff(diff)
+			Expect(createErr).NotTo(HaveOccurred())
+			return id
+		}
+
+		It("returns the latest value of each key at or before the block, in the order of the keys", func() {
+			createDiff(key, 98, common.HexToHash("0x1"))
+			createDiff(key, 100, common.HexToHash("0x2"))
+			createDiff(key, 101, common.HexToHash("0x3"))
+			createDiff(otherKey, 99, common.HexToHash("0x4"))
+
+			values, err := repo.GetStorageValues(fakeStorageDiff.HashedAddress, []common.Hash{otherKey, key}, 100)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]common.Hash{common.HexToHash("0x4"), common.HexToHash("0x2")}))
+		})
+
+		It("ignores noncanonical diffs", func() {
+			createDiff(key, 99, common.HexToHash("0x1"))
+			noncanonicalID := createDiff(key, 100, common.HexToHash("0x2"))
+			Expect(repo.MarkNoncanonical(noncanonicalID)).To(Succeed())
+
+			values, err := repo.GetStorageValues(fakeStorageDiff.HashedAddress, []common.Hash{key}, 100)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]common.Hash{common.HexToHash("0x1")}))
+		})
+
+		It("ignores diffs of other contracts", func() {
+			createDiff(key, 100, common.HexToHash("0x1"))
+
+			_, err := repo.GetStorageValues(test_data.FakeHash(), []common.Hash{key}, 100)
+
+			Expect(err).To(MatchError(types.ErrKeyNotFound))
+		})
+
+		It("returns an error if a key has no diff at or before the block", func() {
+			createDiff(key, 100, common.HexToHash("0x1"))
+			createDiff(otherKey, 101, common.HexToHash("0x2"))
+
+			_, err := repo.GetStorageValues(fakeStorageDiff.HashedAddress, []common.Hash{key, otherKey}, 100)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(types.ErrKeyNotFound))
+			Expect(err.Error()).To(ContainSubstring(otherKey.Hex()))
+		})
+	})
 })
 
 func insertTestDiff(persistedDiff types.PersistedDiff, db *postgres.DB) {
@@ -17,10 +17,13 @@
 package storage
 
 import (
+	"fmt"
 	"math/big"
+	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 )
 
 const (
@@ -54,3 +57,91 @@ func GetIncrementedKey(original common.Hash, incrementBy int64) common.Hash {
 	incremented := big.NewInt(0).Add(originalMappingAsInt, big.NewInt(incrementBy))
 	return common.BytesToHash(incremented.Bytes())
 }
+
+// KeyPath derives the storage key of a value nested in mappings, arrays and structs, one step at a time from the
+// slot of a contract variable. The resolved path, e.g. ilks[ETH-A].art, is recorded in the metadata's keys under
+// types.Path so transformers can tell deep positions apart without custom code.
+type KeyPath struct {
+	slot common.Hash
+	path string
+	keys map[types.Key]string
+}
+
+func NewKeyPath(variable, indexOnContract string) KeyPath {
+	return KeyPath{slot: common.HexToHash(indexOnContract), path: variable, keys: map[types.Key]string{}}
+}
+
+// Mapping steps into the entry of a mapping at key, which is hex encoded as Solidity hashes it: value types padded
+// to 32 bytes, strings and bytes unpadded. The key is recorded under keyName and shown as is in the path.
+func (keyPath KeyPath) Mapping(keyName types.Key, key string) KeyPath {
+	return keyPath.LabeledMapping(keyName, key, key)
+}
+
+// LabeledMapping is Mapping with the key shown in the path as label, e.g. ETH-A for a bytes32 ilk
+func (keyPath KeyPath) LabeledMapping(keyName types.Key, key, label string) KeyPath {
+	next := keyPath.step(fmt.Sprintf("[%s]", label))
+	next.slot = GetKeyForMapping(keyPath.slot.Hex()[2:], key)
+	next.keys[keyName] = key
+	return next
+}
+
+// DynamicArrayElement steps into the element at index of a dynamic array whose elements each take
+// slotsPerElement slots. The index is recorded under keyName.
+func (keyPath KeyPath) DynamicArrayElement(keyName types.Key, index, slotsPerElement int64) KeyPath {
+	next := keyPath.step(fmt.Sprintf("[%d]", index))
+	firstElement := crypto.Keccak256Hash(keyPath.slot.Bytes())
+	next.slot = GetIncrementedKey(firstElement, index*slotsPerElement)
+	next.keys[keyName] = strconv.FormatInt(index, 10)
+	return next
+}
+
+// StaticArrayElement steps into the element at index of a fixed-size array whose elements each take
+// slotsPerElement slots
+func (keyPath KeyPath) StaticArrayElement(index, slotsPerElement int64) KeyPath {
+	next := keyPath.step(fmt.Sprintf("[%d]", index))
+	next.slot = GetIncrementedKey(keyPath.slot, index*slotsPerElement)
+	return next
+}
+
+// Member steps into the member of a struct that starts slotOffset slots after the struct
+func (keyPath KeyPath) Member(member string, slotOffset int64) KeyPath {
+	next := keyPath.step("." + member)
+	next.slot = GetIncrementedKey(keyPath.slot, slotOffset)
+	return next
+}
+
+func (keyPath KeyPath) Key() common.Hash {
+	return keyPath.slot
+}
+
+func (keyPath KeyPath) Path() string {
+	return keyPath.path
+}
+
+// Metadata returns the storage key and metadata of a value at the path
+func (keyPath KeyPath) Metadata(name string, valueType types.ValueType) (common.Hash, types.ValueMetadata) {
+	return keyPath.slot, types.GetValueMetadata(name, keyPath.metadataKeys(), valueType)
+}
+
+// PackedMetadata returns the storage key and metadata of a packed slot at the path
+func (keyPath KeyPath) PackedMetadata(name string, packedNames map[int]string, packedTypes map[int]types.ValueType) (common.Hash, types.ValueMetadata) {
+	return keyPath.slot, types.GetValueMetadataForPackedSlot(name, keyPath.metadataKeys(), types.PackedSlot, packedNames, packedTypes)
+}
+
+func (keyPath KeyPath) metadataKeys() map[types.Key]string {
+	keys := make(map[types.Key]string, len(keyPath.keys)+1)
+	for keyName, key := range keyPath.keys {
+		keys[keyName] = key
+	}
+	keys[types.Path] = keyPath.path
+	return keys
+}
+
+// step copies the path so that paths derived from a common prefix don't share keys
+func (keyPath KeyPath) step(segment string) KeyPath {
+	keys := make(map[types.Key]string, len(keyPath.keys)+1)
+	for keyName, key := range keyPath.keys {
+		keys[keyName] = key
+	}
+	return KeyPath{slot: keyPath.slot, path: keyPath.path + segment, keys: keys}
+}
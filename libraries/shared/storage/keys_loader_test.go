@@ -18,7 +18,9 @@ package storage_test
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -88,4 +90,92 @@ var _ = Describe("Storage keys loader utils", func() {
 			Expect(storageKey).To(Equal(expectedStorageKey))
 		})
 	})
+
+	Describe("KeyPath", func() {
+		// ex. solidity:
+		//    	struct Ilk {
+		//        uint256 Art;
+		//        uint256 rate;
+		//    	}
+		//    	mapping (bytes32 => Ilk) public ilks;
+		//    	mapping (address => mapping (address => uint)) public can;
+		//    	uint256[] public list;
+		//    	mapping (address => uint256[]) public deposits;
+		ilk := "4554482d41000000000000000000000000000000000000000000000000000000"
+		owner := common.HexToAddress("0x1234")
+		spender := common.HexToAddress("0x5678")
+
+		It("derives the key of a struct member in a mapping", func() {
+			path := storage.NewKeyPath("ilks", storage.IndexTwo).LabeledMapping("ilk", ilk, "ETH-A").Member("rate", 1)
+
+			key, metadata := path.Metadata("rate", types.Uint256)
+
+			Expect(key).To(Equal(storage.GetIncrementedKey(storage.GetKeyForMapping(storage.IndexTwo, ilk), 1)))
+			Expect(metadata).To(Equal(types.ValueMetadata{
+				Name: "rate",
+				Keys: map[types.Key]string{"ilk": ilk, types.Path: "ilks[ETH-A].rate"},
+				Type: types.Uint256,
+			}))
+		})
+
+		It("derives the key of a nested mapping", func() {
+			path := storage.NewKeyPath("can", storage.IndexThree).
+				Mapping("owner", owner.Hash().Hex()).
+				Mapping("spender", spender.Hash().Hex())
+
+			Expect(path.Key()).To(Equal(storage.GetKeyForNestedMapping(storage.IndexThree, owner.Hash().Hex(), spender.Hash().Hex())))
+			Expect(path.Path()).To(Equal("can[" + owner.Hash().Hex() + "][" + spender.Hash().Hex() + "]"))
+		})
+
+		It("derives the key of a dynamic array element", func() {
+			path := storage.NewKeyPath("list", storage.IndexFour).DynamicArrayElement("index", 3, 1)
+
+			key, metadata := path.Metadata("list", types.Uint256)
+
+			firstElement := crypto.Keccak256Hash(common.HexToHash(storage.IndexFour).Bytes())
+			Expect(key).To(Equal(storage.GetIncrementedKey(firstElement, 3)))
+			Expect(metadata.Keys).To(Equal(map[types.Key]string{"index": "3", types.Path: "list[3]"}))
+		})
+
+		It("derives the key of an array in a mapping", func() {
+			path := storage.NewKeyPath("deposits", storage.IndexFive).
+				Mapping("owner", owner.Hash().Hex()).
+				DynamicArrayElement("index", 2, 2)
+
+			arraySlot := storage.GetKeyForMapping(storage.IndexFive, owner.Hash().Hex())
+			firstElement := crypto.Keccak256Hash(arraySlot.Bytes())
+			Expect(path.Key()).To(Equal(storage.GetIncrementedKey(firstElement, 4)))
+			Expect(path.Path()).To(Equal("deposits[" + owner.Hash().Hex() + "][2]"))
+		})
+
+		It("derives the key of a static array element", func() {
+			path := storage.NewKeyPath("pair", storage.IndexEight).StaticArrayElement(1, 1)
+
+			Expect(path.Key()).To(Equal(common.HexToHash(storage.IndexNine)))
+			Expect(path.Path()).To(Equal("pair[1]"))
+		})
+
+		It("doesn't share keys between paths derived from a common prefix", func() {
+			ilks := storage.NewKeyPath("ilks", storage.IndexTwo)
+			ethA := ilks.LabeledMapping("ilk", ilk, "ETH-A")
+			batA := ilks.LabeledMapping("ilk", "4241542d41", "BAT-A")
+
+			_, ethMetadata := ethA.Member("Art", 0).Metadata("Art", types.Uint256)
+			_, batMetadata := batA.Member("Art", 0).Metadata("Art", types.Uint256)
+
+			Expect(ethMetadata.Keys).To(Equal(map[types.Key]string{"ilk": ilk, types.Path: "ilks[ETH-A].Art"}))
+			Expect(batMetadata.Keys).To(Equal(map[types.Key]string{"ilk": "4241542d41", types.Path: "ilks[BAT-A].Art"}))
+		})
+
+		It("derives the metadata of a packed slot", func() {
+			path := storage.NewKeyPath("ilks", storage.IndexTwo).LabeledMapping("ilk", ilk, "ETH-A").Member("flags", 2)
+
+			key, metadata := path.PackedMetadata("flags", map[int]string{0: "live", 1: "frozen"}, map[int]types.ValueType{0: types.Bool, 1: types.Bool})
+
+			Expect(key).To(Equal(storage.GetIncrementedKey(storage.GetKeyForMapping(storage.IndexTwo, ilk), 2)))
+			Expect(metadata.Type).To(Equal(types.PackedSlot))
+			Expect(metadata.PackedNames).To(Equal(map[int]string{0: "live", 1: "frozen"}))
+			Expect(metadata.Keys[types.Path]).To(Equal("ilks[ETH-A].flags"))
+		})
+	})
 })
@@ -0,0 +1,644 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+)
+
+const (
+	inplaceEncoding      = "inplace"
+	mappingEncoding      = "mapping"
+	dynamicArrayEncoding = "dynamic_array"
+	bytesEncoding        = "bytes"
+
+	// static arrays longer than this are not expanded into every element's key; use a KeyTemplate for them instead
+	MaxExpandedArrayLength = 1024
+)
+
+var ErrMissingStorageLayout = errors.New("no storage layout found: compile with solc's storageLayout output selected")
+
+// Layout is the storage layout solc emits for a contract when storageLayout is in the output selection
+type Layout struct {
+	Storage []LayoutVariable      `json:"storage"`
+	Types   map[string]LayoutType `json:"types"`
+}
+
+// LayoutVariable is a state variable or struct member at a slot, and a byte offset within that slot
+type LayoutVariable struct {
+	Label  string `json:"label"`
+	Offset int    `json:"offset"`
+	Slot   string `json:"slot"`
+	Type   string `json:"type"`
+}
+
+type LayoutType struct {
+	Encoding      string           `json:"encoding"`
+	Label         string           `json:"label"`
+	NumberOfBytes string           `json:"numberOfBytes"`
+	Key           string           `json:"key,omitempty"`
+	Value         string           `json:"value,omitempty"`
+	Base          string           `json:"base,omitempty"`
+	Members       []LayoutVariable `json:"members,omitempty"`
+}
+
+// ParseLayout reads a storage layout, either on its own or as the storageLayout field of a contract's artifact
+func ParseLayout(raw []byte) (Layout, error) {
+	var artifact struct {
+		StorageLayout *Layout `json:"storageLayout"`
+		Layout
+	}
+	if err := json.Unmarshal(raw, &artifact); err != nil {
+		return Layout{}, fmt.Errorf("error parsing storage layout: %w", err)
+	}
+	layout := artifact.Layout
+	if artifact.StorageLayout != nil {
+		layout = *artifact.StorageLayout
+	}
+	if layout.Storage == nil {
+		return Layout{}, ErrMissingStorageLayout
+	}
+	return layout, nil
+}
+
+func LoadLayout(path string) (Layout, error) {
+	raw, readErr := ioutil.ReadFile(path)
+	if readErr != nil {
+		return Layout{}, fmt.Errorf("error reading storage layout: %w", readErr)
+	}
+	return ParseLayout(raw)
+}
+
+// StaticMappings returns the metadata for every storage key whose position doesn't depend on a mapping key or array
+// index: value types, members of structs, elements of static arrays, lengths of dynamic arrays and strings or bytes.
+// Variables sharing a slot are returned as a packed slot, named after their comma separated paths.
+func (layout Layout) StaticMappings() (map[common.Hash]types.ValueMetadata, error) {
+	var items []slotItem
+	for _, variable := range layout.Storage {
+		slot, slotErr := parseSlot(variable.Slot)
+		if slotErr != nil {
+			return nil, fmt.Errorf("error reading slot of %s: %w", variable.Label, slotErr)
+		}
+		expandErr := layout.expand(variable.Type, slot, variable.Offset, variable.Label, nil, &items, nil)
+		if expandErr != nil {
+			return nil, expandErr
+		}
+	}
+	return groupBySlot(items)
+}
+
+// KeyTemplates returns a template for each mapping and dynamic array in the layout, by path
+func (layout Layout) KeyTemplates() (map[string]KeyTemplate, error) {
+	templates := make(map[string]KeyTemplate)
+	for _, variable := range layout.Storage {
+		slot, slotErr := parseSlot(variable.Slot)
+		if slotErr != nil {
+			return nil, fmt.Errorf("error reading slot of %s: %w", variable.Label, slotErr)
+		}
+		var items []slotItem
+		expandErr := layout.expand(variable.Type, slot, variable.Offset, variable.Label, nil, &items, templates)
+		if expandErr != nil {
+			return nil, expandErr
+		}
+	}
+	return templates, nil
+}
+
+// KeyTemplate derives the storage keys of the entries of a mapping or dynamic array
+type KeyTemplate struct {
+	Path   string
+	Slot   common.Hash
+	TypeID string
+	layout Layout
+}
+
+// Metadata returns the metadata of the storage keys holding the entry at the given mapping keys or array indexes,
+// one for each level of nesting. Mapping keys are given as hex for addresses and bytesN, decimal or hex for
+// integers, "true" or "false" for booleans, and as text for strings. They are recorded in Keys as key0, key1...
+// along with the resolved path of each value under types.Path.
+func (template KeyTemplate) Metadata(keys ...string) (map[common.Hash]types.ValueMetadata, error) {
+	keyPath := KeyPath{slot: template.Slot, path: template.Path, keys: map[types.Key]string{}}
+	typeID := template.TypeID
+	var items []slotItem
+	for depth, key := range keys {
+		if len(items) > 0 {
+			return nil, fmt.Errorf("%s holds values, can't apply key %q", keyPath.Path(), key)
+		}
+		layoutType, typeErr := template.layout.getType(typeID)
+		if typeErr != nil {
+			return nil, typeErr
+		}
+		keyName := types.Key("key" + strconv.Itoa(depth))
+		switch layoutType.Encoding {
+		case mappingEncoding:
+			keyType, keyTypeErr := template.layout.getType(layoutType.Key)
+			if keyTypeErr != nil {
+				return nil, keyTypeErr
+			}
+			encodedKey, encodeErr := encodeMappingKey(keyType, key)
+			if encodeErr != nil {
+				return nil, fmt.Errorf("invalid key %q for %s: %w", key, keyPath.Path(), encodeErr)
+			}
+			keyPath = keyPath.LabeledMapping(keyName, hexutil.Encode(encodedKey), key)
+			keyPath.keys[keyName] = key
+			typeID = layoutType.Value
+		case dynamicArrayEncoding:
+			index, indexErr := strconv.ParseInt(key, 10, 64)
+			if indexErr != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q for %s", key, keyPath.Path())
+			}
+			perSlot, perSlotErr := template.layout.elementsPerSlot(layoutType.Base)
+			if perSlotErr != nil {
+				return nil, perSlotErr
+			}
+			if perSlot > 1 {
+				// elements sharing the slot are returned together, named by their index
+				dataSlot := crypto.Keccak256Hash(keyPath.Key().Bytes()).Big()
+				packedErr := template.layout.expandPackedElements(layoutType.Base, dataSlot, big.NewInt(index), perSlot, template.Path, nil, &items)
+				if packedErr != nil {
+					return nil, packedErr
+				}
+				keyPath = keyPath.step("")
+				keyPath.keys[keyName] = key
+				continue
+			}
+			size, sizeErr := template.layout.typeSize(layoutType.Base)
+			if sizeErr != nil {
+				return nil, sizeErr
+			}
+			keyPath = keyPath.DynamicArrayElement(keyName, index, int64((size+slotSize-1)/slotSize))
+			typeID = layoutType.Base
+		default:
+			return nil, fmt.Errorf("%s is not a mapping or dynamic array, can't apply key %q", keyPath.Path(), key)
+		}
+	}
+
+	if len(items) == 0 {
+		expandErr := template.layout.expand(typeID, keyPath.Key().Big(), 0, template.Path, nil, &items, nil)
+		if expandErr != nil {
+			return nil, expandErr
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s needs more than %d keys to reach a value", template.Path, len(keys))
+	}
+	for i := range items {
+		resolvedPath := keyPath.Path() + strings.TrimPrefix(items[i].path, template.Path)
+		items[i].keys = keyPath.metadataKeys()
+		items[i].keys[types.Path] = resolvedPath
+	}
+	return groupBySlot(items)
+}
+
+// MetadataForEncodedKeys is Metadata for mapping keys given as the words they are hashed as, e.g. from a preimage.
+// String and bytes keys can't be recovered from their hash, so mappings keyed by them aren't supported.
+func (template KeyTemplate) MetadataForEncodedKeys(keys ...common.Hash) (map[common.Hash]types.ValueMetadata, error) {
+	decodedKeys := make([]string, len(keys))
+	typeID := template.TypeID
+	for depth, key := range keys {
+		layoutType, typeErr := template.layout.getType(typeID)
+		if typeErr != nil {
+			return nil, typeErr
+		}
+		if layoutType.Encoding != mappingEncoding {
+			return nil, fmt.Errorf("%s is not a mapping %d levels deep", template.Path, depth+1)
+		}
+		keyType, keyTypeErr := template.layout.getType(layoutType.Key)
+		if keyTypeErr != nil {
+			return nil, keyTypeErr
+		}
+		decodedKey, decodeErr := decodeMappingKey(keyType, key)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("invalid key %s for %s: %w", key.Hex(), template.Path, decodeErr)
+		}
+		decodedKeys[depth] = decodedKey
+		typeID = layoutType.Value
+	}
+	return template.Metadata(decodedKeys...)
+}
+
+// MappingDepth returns how many mappings are nested in the template's variable, or 0 for a dynamic array
+func (template KeyTemplate) MappingDepth() int {
+	depth := 0
+	typeID := template.TypeID
+	for {
+		layoutType, typeErr := template.layout.getType(typeID)
+		if typeErr != nil || layoutType.Encoding != mappingEncoding {
+			return depth
+		}
+		depth++
+		typeID = layoutType.Value
+	}
+}
+
+// MappingPositions returns the slots and depths of the layout's mappings, for recording the preimages of their entries
+func (layout Layout) MappingPositions() ([]MappingPosition, error) {
+	templates, templatesErr := layout.KeyTemplates()
+	if templatesErr != nil {
+		return nil, templatesErr
+	}
+	var positions []MappingPosition
+	for _, template := range templates {
+		depth := template.MappingDepth()
+		if depth > 0 {
+			positions = append(positions, MappingPosition{Slot: template.Slot, Depth: depth})
+		}
+	}
+	return positions, nil
+}
+
+// slotItem is a value at a byte offset within a slot, where offset 0 is the slot's lowest-order byte
+type slotItem struct {
+	slot      *big.Int
+	offset    int
+	path      string
+	valueType types.ValueType
+	keys      map[types.Key]string
+}
+
+// expand appends the values stored in place for the type at slot and offset to items. When templates is not nil,
+// the mappings and dynamic arrays reached are added to it.
+func (layout Layout) expand(typeID string, slot *big.Int, offset int, path string, keys map[types.Key]string, items *[]slotItem, templates map[string]KeyTemplate) error {
+	layoutType, typeErr := layout.getType(typeID)
+	if typeErr != nil {
+		return typeErr
+	}
+	switch layoutType.Encoding {
+	case mappingEncoding:
+		if templates != nil {
+			templates[path] = KeyTemplate{Path: path, Slot: common.BigToHash(slot), TypeID: typeID, layout: layout}
+		}
+		return nil
+	case dynamicArrayEncoding:
+		if templates != nil {
+			templates[path] = KeyTemplate{Path: path, Slot: common.BigToHash(slot), TypeID: typeID, layout: layout}
+		}
+		*items = append(*items, slotItem{slot: slot, path: path + ".length", valueType: types.Uint256, keys: keys})
+		return nil
+	case bytesEncoding:
+		valueType := types.Bytes
+		if layoutType.Label == "string" {
+			valueType = types.String
+		}
+		*items = append(*items, slotItem{slot: slot, path: path, valueType: valueType, keys: keys})
+		return nil
+	case inplaceEncoding:
+		if layoutType.Members != nil {
+			return layout.expandStruct(layoutType, slot, path, keys, items, templates)
+		}
+		if layoutType.Base != "" {
+			return layout.expandStaticArray(typeID, layoutType, slot, path, keys, items, templates)
+		}
+		valueType, valueTypeErr := valueTypeFromLabel(layoutType.Label)
+		if valueTypeErr != nil {
+			return fmt.Errorf("can't decode %s: %w", path, valueTypeErr)
+		}
+		*items = append(*items, slotItem{slot: slot, offset: offset, path: path, valueType: valueType, keys: keys})
+		return nil
+	default:
+		return fmt.Errorf("unsupported encoding %q of %s", layoutType.Encoding, path)
+	}
+}
+
+func (layout Layout) expandStruct(layoutType LayoutType, slot *big.Int, path string, keys map[types.Key]string, items *[]slotItem, templates map[string]KeyTemplate) error {
+	for _, member := range layoutType.Members {
+		memberSlot, slotErr := parseSlot(member.Slot)
+		if slotErr != nil {
+			return fmt.Errorf("error reading slot of %s.%s: %w", path, member.Label, slotErr)
+		}
+		memberSlot.Add(memberSlot, slot)
+		expandErr := layout.expand(member.Type, memberSlot, member.Offset, path+"."+member.Label, keys, items, templates)
+		if expandErr != nil {
+			return expandErr
+		}
+	}
+	return nil
+}
+
+func (layout Layout) expandStaticArray(typeID string, layoutType LayoutType, slot *big.Int, path string, keys map[types.Key]string, items *[]slotItem, templates map[string]KeyTemplate) error {
+	length, lengthErr := staticArrayLength(typeID, layoutType)
+	if lengthErr != nil {
+		return fmt.Errorf("can't read length of %s: %w", path, lengthErr)
+	}
+	if length > MaxExpandedArrayLength {
+		return nil
+	}
+	for i := 0; i < length; i++ {
+		elementSlot, offset, elementErr := layout.elementPosition(layoutType.Base, slot, big.NewInt(int64(i)))
+		if elementErr != nil {
+			return elementErr
+		}
+		elementPath := fmt.Sprintf("%s[%d]", path, i)
+		expandErr := layout.expand(layoutType.Base, elementSlot, offset, elementPath, keys, items, templates)
+		if expandErr != nil {
+			return expandErr
+		}
+	}
+	return nil
+}
+
+// expandPackedElements appends the elements of an array that share a slot with the element at index
+func (layout Layout) expandPackedElements(baseTypeID string, firstSlot, index *big.Int, perSlot int, path string, keys map[types.Key]string, items *[]slotItem) error {
+	firstIndex := new(big.Int).Sub(index, new(big.Int).Mod(index, big.NewInt(int64(perSlot))))
+	for i := 0; i < perSlot; i++ {
+		elementIndex := new(big.Int).Add(firstIndex, big.NewInt(int64(i)))
+		elementSlot, offset, elementErr := layout.elementPosition(baseTypeID, firstSlot, elementIndex)
+		if elementErr != nil {
+			return elementErr
+		}
+		elementPath := fmt.Sprintf("%s[%s]", path, elementIndex.String())
+		expandErr := layout.expand(baseTypeID, elementSlot, offset, elementPath, keys, items, nil)
+		if expandErr != nil {
+			return expandErr
+		}
+	}
+	return nil
+}
+
+// elementsPerSlot returns how many elements of an array share a slot: elements of 16 bytes or less are packed,
+// larger elements start a new slot each
+func (layout Layout) elementsPerSlot(baseTypeID string) (int, error) {
+	size, sizeErr := layout.typeSize(baseTypeID)
+	if sizeErr != nil {
+		return 0, sizeErr
+	}
+	if size <= slotSize/2 {
+		return slotSize / size, nil
+	}
+	return 1, nil
+}
+
+func (layout Layout) typeSize(typeID string) (int, error) {
+	layoutType, typeErr := layout.getType(typeID)
+	if typeErr != nil {
+		return 0, typeErr
+	}
+	size, sizeErr := strconv.Atoi(layoutType.NumberOfBytes)
+	if sizeErr != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size %q of %s", layoutType.NumberOfBytes, layoutType.Label)
+	}
+	return size, nil
+}
+
+// elementPosition returns the slot and offset of an array element
+func (layout Layout) elementPosition(baseTypeID string, firstSlot, index *big.Int) (*big.Int, int, error) {
+	size, sizeErr := layout.typeSize(baseTypeID)
+	if sizeErr != nil {
+		return nil, 0, sizeErr
+	}
+	perSlot, perSlotErr := layout.elementsPerSlot(baseTypeID)
+	if perSlotErr != nil {
+		return nil, 0, perSlotErr
+	}
+	if perSlot > 1 {
+		slotIndex, position := new(big.Int).DivMod(index, big.NewInt(int64(perSlot)), new(big.Int))
+		return slotIndex.Add(slotIndex, firstSlot), int(position.Int64()) * size, nil
+	}
+	slotsPerElement := big.NewInt(int64((size + slotSize - 1) / slotSize))
+	elementSlot := new(big.Int).Mul(index, slotsPerElement)
+	return elementSlot.Add(elementSlot, firstSlot), 0, nil
+}
+
+func staticArrayLength(typeID string, layoutType LayoutType) (int, error) {
+	label := layoutType.Label
+	open := strings.LastIndex(label, "[")
+	if open < 0 || !strings.HasSuffix(label, "]") {
+		return 0, fmt.Errorf("unexpected array type %s", typeID)
+	}
+	return strconv.Atoi(label[open+1 : len(label)-1])
+}
+
+func (layout Layout) getType(typeID string) (LayoutType, error) {
+	layoutType, ok := layout.Types[typeID]
+	if !ok {
+		return LayoutType{}, fmt.Errorf("storage layout is missing type %s", typeID)
+	}
+	return layoutType, nil
+}
+
+// valueTypeFromLabel maps the label of a Solidity value type to the type it is decoded as
+func valueTypeFromLabel(label string) (types.ValueType, error) {
+	switch {
+	case label == "bool":
+		return types.Bool, nil
+	case label == "address", label == "address payable", strings.HasPrefix(label, "contract "):
+		return types.Address, nil
+	case strings.HasPrefix(label, "enum "):
+		return types.Enum, nil
+	case strings.HasPrefix(label, "uint"):
+		bits, bitsErr := typeWidth(label, "uint")
+		if bitsErr != nil {
+			return 0, bitsErr
+		}
+		return types.Uint(bits)
+	case strings.HasPrefix(label, "int"):
+		bits, bitsErr := typeWidth(label, "int")
+		if bitsErr != nil {
+			return 0, bitsErr
+		}
+		return types.Int(bits)
+	case strings.HasPrefix(label, "bytes"):
+		size, sizeErr := strconv.Atoi(strings.TrimPrefix(label, "bytes"))
+		if sizeErr != nil {
+			return 0, fmt.Errorf("unsupported type %s", label)
+		}
+		return types.FixedBytes(size)
+	default:
+		return 0, fmt.Errorf("unsupported type %s", label)
+	}
+}
+
+func typeWidth(label, prefix string) (int, error) {
+	width := strings.TrimPrefix(label, prefix)
+	if width == "" {
+		return 256, nil
+	}
+	bits, err := strconv.Atoi(width)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported type %s", label)
+	}
+	return bits, nil
+}
+
+// encodeMappingKey encodes a key the way Solidity does before hashing it with the mapping's slot: value types are
+// padded to 32 bytes, strings and bytes are used as they are
+func encodeMappingKey(keyType LayoutType, key string) ([]byte, error) {
+	if keyType.Encoding == bytesEncoding {
+		if keyType.Label == "string" {
+			return []byte(key), nil
+		}
+		return hexutil.Decode(key)
+	}
+	valueType, valueTypeErr := valueTypeFromLabel(keyType.Label)
+	if valueTypeErr != nil {
+		return nil, valueTypeErr
+	}
+	switch {
+	case valueType == types.Address:
+		if !common.IsHexAddress(key) {
+			return nil, fmt.Errorf("invalid address")
+		}
+		return common.HexToAddress(key).Hash().Bytes(), nil
+	case valueType == types.Bool:
+		switch key {
+		case "true", "1":
+			return common.BigToHash(big.NewInt(1)).Bytes(), nil
+		case "false", "0":
+			return common.Hash{}.Bytes(), nil
+		}
+		return nil, fmt.Errorf("invalid bool")
+	case valueType.IsFixedBytes():
+		size, _ := valueType.Size()
+		decoded, decodeErr := hexutil.Decode(key)
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		if len(decoded) > size {
+			return nil, fmt.Errorf("longer than %d bytes", size)
+		}
+		return common.RightPadBytes(decoded, slotSize), nil
+	default:
+		n, ok := new(big.Int).SetString(key, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer")
+		}
+		if n.Sign() < 0 {
+			n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if n.Sign() < 0 || n.BitLen() > 256 {
+			return nil, fmt.Errorf("integer out of range")
+		}
+		return common.BigToHash(n).Bytes(), nil
+	}
+}
+
+// decodeMappingKey reverses encodeMappingKey for keys of value types
+func decodeMappingKey(keyType LayoutType, key common.Hash) (string, error) {
+	if keyType.Encoding == bytesEncoding {
+		return "", fmt.Errorf("%s keys are hashed unpadded", keyType.Label)
+	}
+	valueType, valueTypeErr := valueTypeFromLabel(keyType.Label)
+	if valueTypeErr != nil {
+		return "", valueTypeErr
+	}
+	switch {
+	case valueType == types.Address:
+		if new(big.Int).Rsh(key.Big(), 160).Sign() != 0 {
+			return "", fmt.Errorf("invalid address")
+		}
+		return common.BytesToAddress(key.Bytes()).Hex(), nil
+	case valueType == types.Bool:
+		switch key {
+		case common.Hash{}:
+			return "false", nil
+		case common.BigToHash(big.NewInt(1)):
+			return "true", nil
+		}
+		return "", fmt.Errorf("invalid bool")
+	case valueType.IsFixedBytes():
+		size, _ := valueType.Size()
+		return hexutil.Encode(key.Bytes()[:size]), nil
+	case valueType.IsSignedInteger():
+		n := key.Big()
+		if n.Bit(255) == 1 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return n.String(), nil
+	default:
+		return key.Big().String(), nil
+	}
+}
+
+func parseSlot(slot string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(slot, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid slot %q", slot)
+	}
+	return n, nil
+}
+
+// groupBySlot builds the metadata for each slot, packing the items that share one in order of their offsets
+func groupBySlot(items []slotItem) (map[common.Hash]types.ValueMetadata, error) {
+	itemsBySlot := make(map[common.Hash][]slotItem)
+	for _, item := range items {
+		key := common.BigToHash(item.slot)
+		itemsBySlot[key] = append(itemsBySlot[key], item)
+	}
+	mappings := make(map[common.Hash]types.ValueMetadata, len(itemsBySlot))
+	for key, slotItems := range itemsBySlot {
+		if len(slotItems) == 1 && slotItems[0].offset == 0 {
+			item := slotItems[0]
+			mappings[key] = types.GetValueMetadata(item.path, item.keys, item.valueType)
+			continue
+		}
+		metadata, packErr := packSlot(slotItems)
+		if packErr != nil {
+			return nil, fmt.Errorf("error packing slot %s: %w", key.Hex(), packErr)
+		}
+		mappings[key] = metadata
+	}
+	return mappings, nil
+}
+
+func packSlot(items []slotItem) (types.ValueMetadata, error) {
+	sort.Slice(items, func(i, j int) bool { return items[i].offset < items[j].offset })
+	names := make(map[int]string, len(items))
+	packedTypes := make(map[int]types.ValueType, len(items))
+	paths := make([]string, len(items))
+	nextOffset := 0
+	for position, item := range items {
+		size, sizeErr := item.valueType.Size()
+		if sizeErr != nil {
+			return types.ValueMetadata{}, fmt.Errorf("%s can't be packed: %w", item.path, sizeErr)
+		}
+		if item.offset != nextOffset {
+			return types.ValueMetadata{}, fmt.Errorf("%s is at offset %d, expected %d", item.path, item.offset, nextOffset)
+		}
+		nextOffset += size
+		names[position] = item.path
+		packedTypes[position] = item.valueType
+		paths[position] = item.path
+	}
+	if nextOffset > slotSize {
+		return types.ValueMetadata{}, fmt.Errorf("items take %d bytes", nextOffset)
+	}
+	keys := items[0].keys
+	if _, ok := keys[types.Path]; ok {
+		resolvedPaths := make([]string, len(items))
+		for position, item := range items {
+			resolvedPaths[position] = item.keys[types.Path]
+		}
+		keys = make(map[types.Key]string, len(items[0].keys))
+		for name, value := range items[0].keys {
+			keys[name] = value
+		}
+		keys[types.Path] = strings.Join(resolvedPaths, ",")
+	}
+	return types.GetValueMetadataForPackedSlot(strings.Join(paths, ","), keys, types.PackedSlot, names, packedTypes), nil
+}
@@ -0,0 +1,354 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// storage layout emitted by solc for:
+//
+//	contract Example {
+//		struct Ilk { uint256 Art; uint256 rate; }
+//		uint256 total;
+//		address owner;
+//		bool paused;
+//		uint48 era;
+//		mapping(bytes32 => Ilk) ilks;
+//		mapping(address => mapping(address => uint256)) can;
+//		uint256[] list;
+//		string name;
+//		Ilk config;
+//		uint128[3] pair;
+//		uint8[] small;
+//	}
+const exampleLayout = `{
+	"storageLayout": {
+		"storage": [
+			{"astId": 7, "contract": "Example.sol:Example", "label": "total", "offset": 0, "slot": "0", "type": "t_uint256"},
+			{"astId": 9, "contract": "Example.sol:Example", "label": "owner", "offset": 0, "slot": "1", "type": "t_address"},
+			{"astId": 11, "contract": "Example.sol:Example", "label": "paused", "offset": 20, "slot": "1", "type": "t_bool"},
+			{"astId": 13, "contract": "Example.sol:Example", "label": "era", "offset": 21, "slot": "1", "type": "t_uint48"},
+			{"astId": 17, "contract": "Example.sol:Example", "label": "ilks", "offset": 0, "slot": "2", "type": "t_mapping(t_bytes32,t_struct(Ilk)6_storage)"},
+			{"astId": 23, "contract": "Example.sol:Example", "label": "can", "offset": 0, "slot": "3", "type": "t_mapping(t_address,t_mapping(t_address,t_uint256))"},
+			{"astId": 26, "contract": "Example.sol:Example", "label": "list", "offset": 0, "slot": "4", "type": "t_array(t_uint256)dyn_storage"},
+			{"astId": 28, "contract": "Example.sol:Example", "label": "name", "offset": 0, "slot": "5", "type": "t_string_storage"},
+			{"astId": 30, "contract": "Example.sol:Example", "label": "config", "offset": 0, "slot": "6", "type": "t_struct(Ilk)6_storage"},
+			{"astId": 34, "contract": "Example.sol:Example", "label": "pair", "offset": 0, "slot": "8", "type": "t_array(t_uint128)3_storage"},
+			{"astId": 37, "contract": "Example.sol:Example", "label": "small", "offset": 0, "slot": "10", "type": "t_array(t_uint8)dyn_storage"}
+		],
+		"types": {
+			"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+			"t_array(t_uint128)3_storage": {"base": "t_uint128", "encoding": "inplace", "label": "uint128[3]", "numberOfBytes": "64"},
+			"t_array(t_uint256)dyn_storage": {"base": "t_uint256", "encoding": "dynamic_array", "label": "uint256[]", "numberOfBytes": "32"},
+			"t_array(t_uint8)dyn_storage": {"base": "t_uint8", "encoding": "dynamic_array", "label": "uint8[]", "numberOfBytes": "32"},
+			"t_bool": {"encoding": "inplace", "label": "bool", "numberOfBytes": "1"},
+			"t_bytes32": {"encoding": "inplace", "label": "bytes32", "numberOfBytes": "32"},
+			"t_mapping(t_address,t_mapping(t_address,t_uint256))": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => mapping(address => uint256))", "numberOfBytes": "32", "value": "t_mapping(t_address,t_uint256)"},
+			"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+			"t_mapping(t_bytes32,t_struct(Ilk)6_storage)": {"encoding": "mapping", "key": "t_bytes32", "label": "mapping(bytes32 => struct Example.Ilk)", "numberOfBytes": "32", "value": "t_struct(Ilk)6_storage"},
+			"t_string_storage": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+			"t_struct(Ilk)6_storage": {
+				"encoding": "inplace",
+				"label": "struct Example.Ilk",
+				"members": [
+					{"astId": 3, "contract": "Example.sol:Example", "label": "Art", "offset": 0, "slot": "0", "type": "t_uint256"},
+					{"astId": 5, "contract": "Example.sol:Example", "label": "rate", "offset": 0, "slot": "1", "type": "t_uint256"}
+				],
+				"numberOfBytes": "64"
+			},
+			"t_uint128": {"encoding": "inplace", "label": "uint128", "numberOfBytes": "16"},
+			"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"},
+			"t_uint48": {"encoding": "inplace", "label": "uint48", "numberOfBytes": "6"},
+			"t_uint8": {"encoding": "inplace", "label": "uint8", "numberOfBytes": "1"}
+		}
+	}
+}`
+
+var _ = Describe("Storage layout", func() {
+	var layout storage.Layout
+
+	BeforeEach(func() {
+		var parseErr error
+		layout, parseErr = storage.ParseLayout([]byte(exampleLayout))
+		Expect(parseErr).NotTo(HaveOccurred())
+	})
+
+	Describe("ParseLayout", func() {
+		It("parses a bare storage layout", func() {
+			bareLayout, err := storage.ParseLayout([]byte(`{"storage": [], "types": null}`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bareLayout.Storage).To(BeEmpty())
+		})
+
+		It("returns error if there is no storage layout", func() {
+			_, err := storage.ParseLayout([]byte(`{"abi": []}`))
+
+			Expect(err).To(MatchError(storage.ErrMissingStorageLayout))
+		})
+	})
+
+	Describe("StaticMappings", func() {
+		var mappings map[common.Hash]types.ValueMetadata
+
+		BeforeEach(func() {
+			var err error
+			mappings, err = layout.StaticMappings()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns metadata for variables in their own slot", func() {
+			Expect(mappings[common.HexToHash(storage.IndexZero)]).To(Equal(types.ValueMetadata{Name: "total", Type: types.Uint256}))
+			Expect(mappings[common.HexToHash(storage.IndexFive)]).To(Equal(types.ValueMetadata{Name: "name", Type: types.String}))
+		})
+
+		It("returns packed slot metadata in order of offset", func() {
+			Expect(mappings[common.HexToHash(storage.IndexOne)]).To(Equal(types.ValueMetadata{
+				Name:        "owner,paused,era",
+				Type:        types.PackedSlot,
+				PackedNames: map[int]string{0: "owner", 1: "paused", 2: "era"},
+				PackedTypes: map[int]types.ValueType{0: types.Address, 1: types.Bool, 2: types.Uint48},
+			}))
+		})
+
+		It("returns metadata that decodes the packed slot", func() {
+			owner := "1234567890abcdef1234567890abcdef12345678"
+			packedValue := common.HexToHash("0000000000" + "00000000002a" + "01" + owner)
+			diff := types.PersistedDiff{RawDiff: types.RawDiff{StorageValue: packedValue}}
+
+			decoded, err := storage.Decode(diff, mappings[common.HexToHash(storage.IndexOne)])
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decoded).To(Equal(map[int]string{0: common.HexToAddress(owner).Hex(), 1: "true", 2: "42"}))
+		})
+
+		It("returns metadata for struct members and static array elements", func() {
+			Expect(mappings[common.HexToHash(storage.IndexSix)].Name).To(Equal("config.Art"))
+			Expect(mappings[common.HexToHash(storage.IndexSeven)].Name).To(Equal("config.rate"))
+			Expect(mappings[common.HexToHash(storage.IndexEight)]).To(Equal(types.ValueMetadata{
+				Name:        "pair[0],pair[1]",
+				Type:        types.PackedSlot,
+				PackedNames: map[int]string{0: "pair[0]", 1: "pair[1]"},
+				PackedTypes: map[int]types.ValueType{0: types.Uint128, 1: types.Uint128},
+			}))
+			Expect(mappings[common.HexToHash(storage.IndexNine)]).To(Equal(types.ValueMetadata{Name: "pair[2]", Type: types.Uint128}))
+		})
+
+		It("returns metadata for the lengths of dynamic arrays", func() {
+			Expect(mappings[common.HexToHash(storage.IndexFour)]).To(Equal(types.ValueMetadata{Name: "list.length", Type: types.Uint256}))
+			Expect(mappings[common.HexToHash(storage.IndexTen)].Name).To(Equal("small.length"))
+		})
+
+		It("does not return metadata for mappings", func() {
+			Expect(len(mappings)).To(Equal(9))
+			Expect(mappings).NotTo(HaveKey(common.HexToHash(storage.IndexTwo)))
+			Expect(mappings).NotTo(HaveKey(common.HexToHash(storage.IndexThree)))
+		})
+
+		It("returns error if a type is missing from the layout", func() {
+			delete(layout.Types, "t_uint48")
+
+			_, err := layout.StaticMappings()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("missing type t_uint48"))
+		})
+	})
+
+	Describe("KeyTemplates", func() {
+		var templates map[string]storage.KeyTemplate
+
+		BeforeEach(func() {
+			var err error
+			templates, err = layout.KeyTemplates()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns a template for each mapping and dynamic array", func() {
+			Expect(templates).To(HaveLen(4))
+			Expect(templates).To(HaveKey("ilks"))
+			Expect(templates).To(HaveKey("can"))
+			Expect(templates).To(HaveKey("list"))
+			Expect(templates).To(HaveKey("small"))
+		})
+
+		It("derives the keys of a struct in a mapping", func() {
+			ilk := "0x4554482d41"
+
+			mappings, err := templates["ilks"].Metadata(ilk)
+
+			Expect(err).NotTo(HaveOccurred())
+			paddedIlk := common.RightPadBytes(common.FromHex(ilk), 32)
+			artKey := storage.GetKeyForMapping(storage.IndexTwo, common.Bytes2Hex(paddedIlk))
+			rateKey := storage.GetIncrementedKey(artKey, 1)
+			artKeys := map[types.Key]string{"key0": ilk, types.Path: "ilks[0x4554482d41].Art"}
+			rateKeys := map[types.Key]string{"key0": ilk, types.Path: "ilks[0x4554482d41].rate"}
+			Expect(mappings).To(Equal(map[common.Hash]types.ValueMetadata{
+				artKey:  {Name: "ilks.Art", Keys: artKeys, Type: types.Uint256},
+				rateKey: {Name: "ilks.rate", Keys: rateKeys, Type: types.Uint256},
+			}))
+		})
+
+		It("derives the keys of nested mappings", func() {
+			owner := common.HexToAddress("0x1234")
+			spender := common.HexToAddress("0x5678")
+
+			mappings, err := templates["can"].Metadata(owner.Hex(), spender.Hex())
+
+			Expect(err).NotTo(HaveOccurred())
+			key := storage.GetKeyForNestedMapping(storage.IndexThree, owner.Hash().Hex()[2:], spender.Hash().Hex()[2:])
+			keys := map[types.Key]string{
+				"key0":     owner.Hex(),
+				"key1":     spender.Hex(),
+				types.Path: "can[" + owner.Hex() + "][" + spender.Hex() + "]",
+			}
+			Expect(mappings).To(Equal(map[common.Hash]types.ValueMetadata{
+				key: {Name: "can", Keys: keys, Type: types.Uint256},
+			}))
+		})
+
+		It("derives the keys of dynamic array elements", func() {
+			mappings, err := templates["list"].Metadata("2")
+
+			Expect(err).NotTo(HaveOccurred())
+			key := storage.GetIncrementedKey(crypto.Keccak256Hash(common.HexToHash(storage.IndexFour).Bytes()), 2)
+			Expect(mappings).To(Equal(map[common.Hash]types.ValueMetadata{
+				key: {Name: "list", Keys: map[types.Key]string{"key0": "2", types.Path: "list[2]"}, Type: types.Uint256},
+			}))
+		})
+
+		It("returns the packed elements sharing a slot with a dynamic array element", func() {
+			mappings, err := templates["small"].Metadata("33")
+
+			Expect(err).NotTo(HaveOccurred())
+			key := storage.GetIncrementedKey(crypto.Keccak256Hash(common.HexToHash(storage.IndexTen).Bytes()), 1)
+			Expect(mappings).To(HaveKey(key))
+			metadata := mappings[key]
+			Expect(metadata.Type).To(Equal(types.PackedSlot))
+			Expect(metadata.PackedNames).To(HaveLen(32))
+			Expect(metadata.PackedNames[0]).To(Equal("small[32]"))
+			Expect(metadata.PackedNames[31]).To(Equal("small[63]"))
+			Expect(metadata.Keys["key0"]).To(Equal("33"))
+			Expect(metadata.Keys[types.Path]).To(HavePrefix("small[32],small[33],"))
+		})
+
+		It("returns error if there are too few keys to reach a value", func() {
+			_, err := templates["can"].Metadata(common.HexToAddress("0x1234").Hex())
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("needs more than 1 keys"))
+		})
+
+		It("returns error if there are too many keys", func() {
+			_, err := templates["list"].Metadata("1", "2")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns error if a key doesn't match the mapping's key type", func() {
+			_, err := templates["can"].Metadata("not an address", common.HexToAddress("0x1234").Hex())
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid key"))
+		})
+
+		It("encodes negative integer keys as two's complement", func() {
+			layout.Types["t_int256"] = storage.LayoutType{Encoding: "inplace", Label: "int256", NumberOfBytes: "32"}
+			layout.Types["t_mapping(t_int256,t_uint256)"] = storage.LayoutType{
+				Encoding:      "mapping",
+				Key:           "t_int256",
+				Label:         "mapping(int256 => uint256)",
+				NumberOfBytes: "32",
+				Value:         "t_uint256",
+			}
+			layout.Storage = []storage.LayoutVariable{{Label: "balances", Slot: "0", Type: "t_mapping(t_int256,t_uint256)"}}
+			intTemplates, templatesErr := layout.KeyTemplates()
+			Expect(templatesErr).NotTo(HaveOccurred())
+
+			mappings, err := intTemplates["balances"].Metadata("-1")
+
+			Expect(err).NotTo(HaveOccurred())
+			minusOne := "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+			Expect(mappings).To(HaveKey(storage.GetKeyForMapping(storage.IndexZero, minusOne)))
+		})
+
+		It("derives the keys of mapping entries from encoded keys", func() {
+			ilk := common.RightPadBytes([]byte("ETH-A"), 32)
+
+			mappings, err := templates["ilks"].MetadataForEncodedKeys(common.BytesToHash(ilk))
+
+			Expect(err).NotTo(HaveOccurred())
+			artKey := storage.GetKeyForMapping(storage.IndexTwo, common.Bytes2Hex(ilk))
+			Expect(mappings).To(HaveKey(artKey))
+			Expect(mappings[artKey].Keys).To(Equal(map[types.Key]string{
+				"key0":     hexutil.Encode(ilk),
+				types.Path: "ilks[" + hexutil.Encode(ilk) + "].Art",
+			}))
+		})
+
+		It("decodes encoded keys to the same keys as Metadata is given", func() {
+			owner := common.HexToAddress("0x1234")
+			spender := common.HexToAddress("0x5678")
+
+			fromEncoded, encodedErr := templates["can"].MetadataForEncodedKeys(owner.Hash(), spender.Hash())
+			fromText, textErr := templates["can"].Metadata(owner.Hex(), spender.Hex())
+
+			Expect(encodedErr).NotTo(HaveOccurred())
+			Expect(textErr).NotTo(HaveOccurred())
+			Expect(fromEncoded).To(Equal(fromText))
+		})
+
+		It("returns error if an encoded key doesn't fit the mapping's key type", func() {
+			_, err := templates["can"].MetadataForEncodedKeys(crypto.Keccak256Hash([]byte("not an address")), common.Hash{})
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid key"))
+		})
+
+		It("returns error if there are more encoded keys than nested mappings", func() {
+			_, err := templates["list"].MetadataForEncodedKeys(common.Hash{})
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not a mapping"))
+		})
+
+		It("returns how many mappings are nested in a template's variable", func() {
+			Expect(templates["ilks"].MappingDepth()).To(Equal(1))
+			Expect(templates["can"].MappingDepth()).To(Equal(2))
+			Expect(templates["list"].MappingDepth()).To(Equal(0))
+		})
+	})
+
+	Describe("MappingPositions", func() {
+		It("returns the slot and depth of each mapping", func() {
+			positions, err := layout.MappingPositions()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(positions).To(ConsistOf(
+				storage.MappingPosition{Slot: common.HexToHash(storage.IndexTwo), Depth: 1},
+				storage.MappingPosition{Slot: common.HexToHash(storage.IndexThree), Depth: 2},
+			))
+		})
+	})
+})
@@ -0,0 +1,408 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AddressPreimageSource     = "address"
+	EventLogPreimageSource    = "event_log"
+	TransactionPreimageSource = "transaction"
+)
+
+var (
+	// PreimageRowsLimit is how many rows of a source are scanned for keys at a time
+	PreimageRowsLimit = 500
+	// MaxNestedKeyCandidates is how many words of a log or transaction are tried as the inner keys of nested mappings
+	MaxNestedKeyCandidates = 8
+)
+
+// MappingPosition is the slot of a mapping whose entries' preimages are recorded, and how many mappings deep it goes
+// (e.g. 2 for mapping(address => mapping(address => uint)))
+type MappingPosition struct {
+	Slot  common.Hash
+	Depth int
+}
+
+// PreimageRecorder records the preimages of a contract's mapping entries for the words observed in its event logs, the
+// input data of transactions sent to it and the known addresses, so that storage diffs for those entries can be
+// recognized without loading their keys. Known addresses are those of the watched contracts and any added explicitly.
+type PreimageRecorder interface {
+	AddPositions(address common.Address, positions ...MappingPosition)
+	AddKnownAddresses(addresses ...common.Address)
+	RecordPreimages() (int64, error)
+}
+
+// observedKeys are the words of one row of a source that may be mapping keys
+type observedKeys struct {
+	sourceID int64
+	keys     []common.Hash
+}
+
+// preimageSource reads the keys observed in a contract's rows of a table with IDs greater than the cursor
+type preimageSource struct {
+	name string
+	read func(address common.Address, cursor int64) ([]observedKeys, error)
+	// persisted sources resume from their stored cursor after a restart
+	persisted bool
+}
+
+// sourceCursor is how far a contract's rows of a source were scanned. Serial IDs are taken before their rows commit,
+// so a row can turn up behind the last one scanned while the transaction inserting it is still open. Rows scanned in
+// one pass are only settled once every transaction in progress at its end has finished, and are scanned again from
+// the settled ID until then.
+type sourceCursor struct {
+	settled int64
+	scanned int64
+}
+
+type preimageRecorder struct {
+	db             *postgres.DB
+	repository     PreimageRepository
+	sources        []preimageSource
+	positions      map[common.Address]map[common.Hash]int
+	knownAddresses map[common.Address]bool
+	cursors        map[common.Address]map[string]*sourceCursor
+	// horizon is the first transaction ID not yet assigned at the end of the last pass
+	horizon int64
+}
+
+func NewPreimageRecorder(db *postgres.DB) PreimageRecorder {
+	recorder := &preimageRecorder{
+		db:             db,
+		repository:     NewPreimageRepository(db),
+		positions:      make(map[common.Address]map[common.Hash]int),
+		knownAddresses: make(map[common.Address]bool),
+		cursors:        make(map[common.Address]map[string]*sourceCursor),
+	}
+	recorder.sources = []preimageSource{
+		{name: EventLogPreimageSource, read: recorder.readEventLogKeys, persisted: true},
+		{name: TransactionPreimageSource, read: recorder.readTransactionKeys, persisted: true},
+		// known addresses are few and may change between runs, so their rows are scanned from the start on each run
+		{name: AddressPreimageSource, read: recorder.readAddressKeys},
+	}
+	return recorder
+}
+
+// AddPositions adds mappings of the contract whose preimages are recorded, as deep as the deepest position given for
+// their slot
+func (recorder *preimageRecorder) AddPositions(address common.Address, positions ...MappingPosition) {
+	recorder.AddKnownAddresses(address)
+	if recorder.positions[address] == nil {
+		recorder.positions[address] = make(map[common.Hash]int)
+	}
+	for _, position := range positions {
+		if position.Depth > recorder.positions[address][position.Slot] {
+			recorder.positions[address][position.Slot] = position.Depth
+			// the contract's rows are rescanned from where the least recorded position stops
+			delete(recorder.cursors, address)
+		}
+	}
+}
+
+// AddKnownAddresses adds addresses whose preimages are recorded for the mappings of every watched contract once they
+// are in public.addresses
+func (recorder *preimageRecorder) AddKnownAddresses(addresses ...common.Address) {
+	for _, address := range addresses {
+		if !recorder.knownAddresses[address] {
+			recorder.knownAddresses[address] = true
+			// every contract's rows of known addresses are scanned again for the new one
+			for _, cursors := range recorder.cursors {
+				cursors[AddressPreimageSource] = &sourceCursor{}
+			}
+		}
+	}
+}
+
+// RecordPreimages scans the rows added to each source since the last call, returning how many new preimages were recorded
+func (recorder *preimageRecorder) RecordPreimages() (int64, error) {
+	oldestRunning, _, snapshotErr := recorder.transactionSnapshot()
+	if snapshotErr != nil {
+		return 0, snapshotErr
+	}
+	// the rows scanned by the last pass are settled if the transactions that could still insert rows behind them
+	// have finished; if this pass fails, the next one can't tell which rows that were
+	settled := recorder.horizon > 0 && oldestRunning >= recorder.horizon
+	recorder.horizon = 0
+	var recorded int64
+	for address := range recorder.positions {
+		created, recordErr := recorder.recordContractPreimages(address, settled)
+		recorded += created
+		if recordErr != nil {
+			return recorded, fmt.Errorf("error recording preimages for %s: %w", address.Hex(), recordErr)
+		}
+	}
+	_, horizon, snapshotErr := recorder.transactionSnapshot()
+	if snapshotErr != nil {
+		return recorded, snapshotErr
+	}
+	recorder.horizon = horizon
+	if recorded > 0 {
+		logrus.Infof("recorded %d new storage preimages", recorded)
+	}
+	return recorded, nil
+}
+
+func (recorder *preimageRecorder) recordContractPreimages(address common.Address, settled bool) (int64, error) {
+	positions := recorder.contractPositions(address)
+	if len(positions) == 0 {
+		return 0, nil
+	}
+	if recorder.cursors[address] == nil {
+		cursorsErr := recorder.loadCursors(address, positions)
+		if cursorsErr != nil {
+			return 0, fmt.Errorf("error loading preimage cursors: %w", cursorsErr)
+		}
+	}
+	var recorded int64
+	for _, source := range recorder.sources {
+		cursor := recorder.cursors[address][source.name]
+		lastID := cursor.settled
+		for {
+			observations, readErr := source.read(address, lastID)
+			if readErr != nil {
+				return recorded, fmt.Errorf("error reading keys from %s rows: %w", source.name, readErr)
+			}
+			if len(observations) == 0 {
+				break
+			}
+			created, createErr := recorder.repository.CreatePreimages(preimages(positions, source.name, observations))
+			if createErr != nil {
+				return recorded, createErr
+			}
+			recorded += created
+			lastID = observations[len(observations)-1].sourceID
+			if len(observations) < PreimageRowsLimit {
+				break
+			}
+		}
+		if settled && cursor.scanned > cursor.settled {
+			cursor.settled = cursor.scanned
+			if source.persisted {
+				cursorErr := recorder.repository.UpdateCursor(address, source.name, positions, cursor.settled)
+				if cursorErr != nil {
+					return recorded, cursorErr
+				}
+			}
+		}
+		cursor.scanned = lastID
+		if cursor.scanned < cursor.settled {
+			// rows scanned before were deleted, e.g. logs of a reorged block
+			cursor.scanned = cursor.settled
+		}
+	}
+	return recorded, nil
+}
+
+// transactionSnapshot returns the ID of the oldest transaction still in progress and the first ID not yet assigned
+func (recorder *preimageRecorder) transactionSnapshot() (int64, int64, error) {
+	var snapshot struct {
+		Xmin int64
+		Xmax int64
+	}
+	err := recorder.db.Get(&snapshot, `SELECT txid_snapshot_xmin(txids) AS xmin, txid_snapshot_xmax(txids) AS xmax
+		FROM txid_current_snapshot() AS txids`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error getting transaction snapshot: %w", err)
+	}
+	return snapshot.Xmin, snapshot.Xmax, nil
+}
+
+func (recorder *preimageRecorder) contractPositions(address common.Address) []MappingPosition {
+	var positions []MappingPosition
+	for slot, depth := range recorder.positions[address] {
+		positions = append(positions, MappingPosition{Slot: slot, Depth: depth})
+	}
+	return positions
+}
+
+func (recorder *preimageRecorder) loadCursors(address common.Address, positions []MappingPosition) error {
+	cursors := make(map[string]*sourceCursor)
+	for _, source := range recorder.sources {
+		cursors[source.name] = &sourceCursor{}
+		if !source.persisted {
+			continue
+		}
+		cursor, cursorErr := recorder.repository.GetCursor(address, source.name, positions)
+		if cursorErr != nil {
+			return cursorErr
+		}
+		cursors[source.name] = &sourceCursor{settled: cursor, scanned: cursor}
+	}
+	recorder.cursors[address] = cursors
+	return nil
+}
+
+func preimages(positions []MappingPosition, source string, observations []observedKeys) []types.Preimage {
+	var preimages []types.Preimage
+	seen := make(map[common.Hash]bool)
+	for _, observation := range observations {
+		for _, position := range positions {
+			for _, preimage := range MappingPreimages(observation.keys, position, source, observation.sourceID) {
+				if !seen[preimage.Hash] {
+					seen[preimage.Hash] = true
+					preimages = append(preimages, preimage)
+				}
+			}
+		}
+	}
+	return preimages
+}
+
+func (recorder *preimageRecorder) readEventLogKeys(address common.Address, cursor int64) ([]observedKeys, error) {
+	rows, queryErr := recorder.db.Query(`SELECT event_logs.id, event_logs.topics, event_logs.data FROM public.event_logs
+		JOIN public.addresses ON event_logs.address = addresses.id
+		WHERE addresses.address = $1 AND event_logs.id > $2
+		ORDER BY event_logs.id ASC LIMIT $3`, address.Hex(), cursor, PreimageRowsLimit)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+	var observations []observedKeys
+	for rows.Next() {
+		var (
+			id     int64
+			topics pq.ByteaArray
+			data   []byte
+		)
+		if scanErr := rows.Scan(&id, &topics, &data); scanErr != nil {
+			return nil, scanErr
+		}
+		observations = append(observations, observedKeys{sourceID: id, keys: LogKeys(topics, data)})
+	}
+	return observations, rows.Err()
+}
+
+func (recorder *preimageRecorder) readTransactionKeys(address common.Address, cursor int64) ([]observedKeys, error) {
+	// recipients are stored as returned by the node, which may not be checksummed
+	rows, queryErr := recorder.db.Query(`SELECT id, input_data FROM public.transactions
+		WHERE LOWER(tx_to) = $1 AND id > $2 ORDER BY id ASC LIMIT $3`,
+		strings.ToLower(address.Hex()), cursor, PreimageRowsLimit)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+	var observations []observedKeys
+	for rows.Next() {
+		var (
+			id    int64
+			input []byte
+		)
+		if scanErr := rows.Scan(&id, &input); scanErr != nil {
+			return nil, scanErr
+		}
+		observations = append(observations, observedKeys{sourceID: id, keys: InputKeys(input)})
+	}
+	return observations, rows.Err()
+}
+
+func (recorder *preimageRecorder) readAddressKeys(_ common.Address, cursor int64) ([]observedKeys, error) {
+	var knownAddresses []string
+	for address := range recorder.knownAddresses {
+		knownAddresses = append(knownAddresses, address.Hex())
+	}
+	rows, queryErr := recorder.db.Query(`SELECT id, address FROM public.addresses
+		WHERE address = ANY($1) AND id > $2 ORDER BY id ASC LIMIT $3`, pq.Array(knownAddresses), cursor, PreimageRowsLimit)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+	var observations []observedKeys
+	for rows.Next() {
+		var (
+			id      int64
+			address string
+		)
+		if scanErr := rows.Scan(&id, &address); scanErr != nil {
+			return nil, scanErr
+		}
+		observations = append(observations, observedKeys{sourceID: id, keys: []common.Hash{common.HexToAddress(address).Hash()}})
+	}
+	return observations, rows.Err()
+}
+
+// LogKeys returns the words of a log that may be mapping keys: its indexed arguments and the words of its data
+func LogKeys(topics [][]byte, data []byte) []common.Hash {
+	var words [][]byte
+	if len(topics) > 1 {
+		// the first topic is the event's signature
+		words = append(words, topics[1:]...)
+	}
+	return uniqueKeys(append(words, splitWords(data)...))
+}
+
+// InputKeys returns the words of a transaction's input data that may be mapping keys: the arguments after the
+// function selector
+func InputKeys(input []byte) []common.Hash {
+	if len(input) < 4 {
+		return nil
+	}
+	return uniqueKeys(splitWords(input[4:]))
+}
+
+// MappingPreimages returns the preimages of the entries at keys in the mapping at the position. The inner keys of
+// nested mappings are taken from the first MaxNestedKeyCandidates keys, e.g. owner and spender of Approval(owner,
+// spender, value).
+func MappingPreimages(keys []common.Hash, position MappingPosition, source string, sourceID int64) []types.Preimage {
+	nestedKeys := keys
+	if len(nestedKeys) > MaxNestedKeyCandidates {
+		nestedKeys = nestedKeys[:MaxNestedKeyCandidates]
+	}
+	var preimages []types.Preimage
+	var nest func(slot common.Hash, level int, levelKeys []common.Hash)
+	nest = func(slot common.Hash, level int, levelKeys []common.Hash) {
+		for _, key := range levelKeys {
+			preimage := types.NewPreimage(key, slot, source, sourceID)
+			preimages = append(preimages, preimage)
+			if level+1 < position.Depth {
+				nest(preimage.Hash, level+1, nestedKeys)
+			}
+		}
+	}
+	nest(position.Slot, 0, keys)
+	return preimages
+}
+
+func splitWords(data []byte) [][]byte {
+	var words [][]byte
+	for start := 0; start+slotSize <= len(data); start += slotSize {
+		words = append(words, data[start:start+slotSize])
+	}
+	return words
+}
+
+func uniqueKeys(words [][]byte) []common.Hash {
+	var keys []common.Hash
+	seen := make(map[common.Hash]bool)
+	for _, word := range words {
+		key := common.BytesToHash(word)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
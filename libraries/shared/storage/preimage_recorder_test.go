@@ -0,0 +1,325 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage_test
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	storageTypes "github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preimage recorder", func() {
+	var (
+		owner   = common.HexToAddress("0x1234").Hash()
+		spender = common.HexToAddress("0x5678").Hash()
+		amount  = common.BigToHash(big.NewInt(100))
+		slot    = common.HexToHash(storage.IndexTwo)
+	)
+
+	Describe("LogKeys", func() {
+		It("returns the indexed arguments and data words of a log", func() {
+			topics := [][]byte{test_data.FakeHash().Bytes(), owner.Bytes(), spender.Bytes()}
+
+			keys := storage.LogKeys(topics, amount.Bytes())
+
+			Expect(keys).To(Equal([]common.Hash{owner, spender, amount}))
+		})
+
+		It("doesn't repeat keys", func() {
+			topics := [][]byte{test_data.FakeHash().Bytes(), owner.Bytes()}
+
+			keys := storage.LogKeys(topics, owner.Bytes())
+
+			Expect(keys).To(Equal([]common.Hash{owner}))
+		})
+	})
+
+	Describe("InputKeys", func() {
+		It("returns the words of a transaction's arguments", func() {
+			input := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, append(spender.Bytes(), amount.Bytes()...)...)
+
+			keys := storage.InputKeys(input)
+
+			Expect(keys).To(Equal([]common.Hash{spender, amount}))
+		})
+
+		It("returns no keys for input without arguments", func() {
+			Expect(storage.InputKeys([]byte{0xa9, 0x05})).To(BeEmpty())
+		})
+	})
+
+	Describe("MappingPreimages", func() {
+		It("returns the preimage of each key's entry in the mapping", func() {
+			position := storage.MappingPosition{Slot: slot, Depth: 1}
+
+			preimages := storage.MappingPreimages([]common.Hash{owner, spender}, position, storage.EventLogPreimageSource, 1)
+
+			Expect(preimages).To(ConsistOf(
+				storageTypes.NewPreimage(owner, slot, storage.EventLogPreimageSource, 1),
+				storageTypes.NewPreimage(spender, slot, storage.EventLogPreimageSource, 1),
+			))
+			Expect(preimages[0].Hash).To(Equal(storage.GetKeyForMapping(storage.IndexTwo, owner.Hex())))
+		})
+
+		It("returns the preimages of entries in nested mappings", func() {
+			position := storage.MappingPosition{Slot: slot, Depth: 2}
+
+			preimages := storage.MappingPreimages([]common.Hash{owner, spender}, position, storage.EventLogPreimageSource, 1)
+
+			Expect(preimages).To(HaveLen(6))
+			var hashes []common.Hash
+			for _, preimage := range preimages {
+				hashes = append(hashes, preimage.Hash)
+			}
+			Expect(hashes).To(ContainElement(storage.GetKeyForNestedMapping(storage.IndexTwo, owner.Hex(), spender.Hex())))
+			Expect(hashes).To(ContainElement(storage.GetKeyForNestedMapping(storage.IndexTwo, spender.Hex(), owner.Hex())))
+		})
+
+		It("only tries the first keys as inner keys of nested mappings", func() {
+			var keys []common.Hash
+			for i := 0; i <= storage.MaxNestedKeyCandidates; i++ {
+				keys = append(keys, common.BigToHash(big.NewInt(int64(i))))
+			}
+			position := storage.MappingPosition{Slot: slot, Depth: 2}
+
+			preimages := storage.MappingPreimages(keys, position, storage.EventLogPreimageSource, 1)
+
+			Expect(preimages).To(HaveLen(len(keys) * (1 + storage.MaxNestedKeyCandidates)))
+		})
+	})
+
+	Describe("RecordPreimages", func() {
+		var (
+			db               = test_config.NewTestDB(test_config.NewTestNode())
+			contract         = common.HexToAddress("0xAbCd000000000000000000000000000000001234")
+			otherContract    = common.HexToAddress("0x9876")
+			headerID         int64
+			headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+			recorder         storage.PreimageRecorder
+			repository       storage.PreimageRepository
+		)
+
+		createLog := func(address common.Address, key common.Hash) {
+			log := types.Log{
+				Address: address,
+				Topics:  []common.Hash{test_data.FakeHash(), key},
+				Data:    amount.Bytes(),
+				TxHash:  test_data.FakeHash(),
+			}
+			test_data.CreateMatchingTx(log, headerID, headerRepository)
+			createErr := repositories.NewEventLogRepository(db).CreateEventLogs(headerID, []types.Log{log})
+			Expect(createErr).NotTo(HaveOccurred())
+		}
+
+		createTransaction := func(recipient common.Address, key common.Hash) {
+			transaction := core.TransactionModel{
+				Data:     append([]byte{0xa9, 0x05, 0x9c, 0xbb}, key.Bytes()...),
+				GasPrice: "0",
+				Hash:     test_data.FakeHash().Hex(),
+				// recipients are stored as returned by the node
+				To:    strings.ToLower(recipient.Hex()),
+				Value: "0",
+			}
+			createErr := headerRepository.CreateTransactions(headerID, []core.TransactionModel{transaction})
+			Expect(createErr).NotTo(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			test_config.CleanTestDB(db)
+			var insertHeaderErr error
+			headerID, insertHeaderErr = headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
+			Expect(insertHeaderErr).NotTo(HaveOccurred())
+			recorder = storage.NewPreimageRecorder(db)
+			recorder.AddPositions(contract, storage.MappingPosition{Slot: slot, Depth: 1})
+			repository = storage.NewPreimageRepository(db)
+		})
+
+		It("records nothing if there are no mapping positions", func() {
+			createLog(contract, owner)
+
+			recorded, err := storage.NewPreimageRecorder(db).RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(BeZero())
+		})
+
+		It("records the preimages of keys in the contract's event logs", func() {
+			createLog(contract, owner)
+
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).NotTo(BeZero())
+			for _, key := range []common.Hash{owner, amount} {
+				preimage, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, key.Hex()), 0)
+				Expect(preimageErr).NotTo(HaveOccurred())
+				Expect(preimage.Key).To(Equal(key))
+				Expect(preimage.Slot).To(Equal(slot))
+				Expect(preimage.Source).To(Equal(storage.EventLogPreimageSource))
+			}
+		})
+
+		It("records the preimages of keys in the input data of transactions sent to the contract", func() {
+			createTransaction(contract, spender)
+
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(Equal(int64(1)))
+			preimage, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, spender.Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+			Expect(preimage.Source).To(Equal(storage.TransactionPreimageSource))
+		})
+
+		It("records the preimages of the watched contracts' addresses", func() {
+			_, addressErr := db.Exec(`INSERT INTO public.addresses (address) VALUES ($1)`, contract.Hex())
+			Expect(addressErr).NotTo(HaveOccurred())
+
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(Equal(int64(1)))
+			preimage, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, contract.Hash().Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+			Expect(preimage.Source).To(Equal(storage.AddressPreimageSource))
+		})
+
+		It("records the preimages of addresses added as known", func() {
+			known := common.BytesToAddress(owner.Bytes())
+			_, addressErr := db.Exec(`INSERT INTO public.addresses (address) VALUES ($1), ($2)`, known.Hex(), otherContract.Hex())
+			Expect(addressErr).NotTo(HaveOccurred())
+			_, firstErr := recorder.RecordPreimages()
+			Expect(firstErr).NotTo(HaveOccurred())
+			_, unknownErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, owner.Hex()), 0)
+			Expect(unknownErr).To(HaveOccurred())
+
+			recorder.AddKnownAddresses(known)
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(Equal(int64(1)))
+			preimage, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, owner.Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+			Expect(preimage.Source).To(Equal(storage.AddressPreimageSource))
+		})
+
+		It("ignores the logs and transactions of other contracts", func() {
+			createLog(otherContract, owner)
+			createTransaction(otherContract, spender)
+
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(BeZero())
+		})
+
+		It("doesn't scan rows again", func() {
+			createLog(contract, owner)
+			_, firstErr := recorder.RecordPreimages()
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			recorded, err := recorder.RecordPreimages()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(BeZero())
+		})
+
+		It("doesn't scan settled rows again after a restart, even if they recorded no new preimages", func() {
+			createLog(contract, owner)
+			_, firstErr := recorder.RecordPreimages()
+			Expect(firstErr).NotTo(HaveOccurred())
+			// rows are settled by the pass after the one that scanned them
+			_, secondErr := recorder.RecordPreimages()
+			Expect(secondErr).NotTo(HaveOccurred())
+			// a rescan would record these again; known addresses are scanned again on each run
+			db.MustExec(`DELETE FROM public.storage_preimage WHERE source != $1`, storage.AddressPreimageSource)
+
+			restarted := storage.NewPreimageRecorder(db)
+			restarted.AddPositions(contract, storage.MappingPosition{Slot: slot, Depth: 1})
+			recorded, err := restarted.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(BeZero())
+		})
+
+		It("records the keys of rows committed behind those already scanned", func() {
+			tx, beginErr := db.Beginx()
+			Expect(beginErr).NotTo(HaveOccurred())
+			input := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, owner.Bytes()...)
+			_, insertErr := tx.Exec(`INSERT INTO public.transactions (header_id, hash, input_data, tx_to) VALUES ($1, $2, $3, $4)`,
+				headerID, test_data.FakeHash().Hex(), input, strings.ToLower(contract.Hex()))
+			Expect(insertErr).NotTo(HaveOccurred())
+			createTransaction(contract, spender)
+			for i := 0; i < 2; i++ {
+				_, recordErr := recorder.RecordPreimages()
+				Expect(recordErr).NotTo(HaveOccurred())
+			}
+			_, uncommittedErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, owner.Hex()), 0)
+			Expect(uncommittedErr).To(HaveOccurred())
+
+			Expect(tx.Commit()).To(Succeed())
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).To(Equal(int64(1)))
+			preimage, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexTwo, owner.Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+			Expect(preimage.Source).To(Equal(storage.TransactionPreimageSource))
+		})
+
+		It("scans rows again for mappings added later", func() {
+			createLog(contract, owner)
+			_, firstErr := recorder.RecordPreimages()
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			recorder.AddPositions(contract, storage.MappingPosition{Slot: common.HexToHash(storage.IndexThree), Depth: 1})
+			recorded, err := recorder.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).NotTo(BeZero())
+			_, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexThree, owner.Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+		})
+
+		It("scans rows again after a restart with mappings added", func() {
+			createLog(contract, owner)
+			_, firstErr := recorder.RecordPreimages()
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			restarted := storage.NewPreimageRecorder(db)
+			restarted.AddPositions(contract,
+				storage.MappingPosition{Slot: slot, Depth: 1},
+				storage.MappingPosition{Slot: common.HexToHash(storage.IndexThree), Depth: 1})
+			recorded, err := restarted.RecordPreimages()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorded).NotTo(BeZero())
+			_, preimageErr := repository.GetPreimage(storage.GetKeyForMapping(storage.IndexThree, owner.Hex()), 0)
+			Expect(preimageErr).NotTo(HaveOccurred())
+		})
+	})
+})
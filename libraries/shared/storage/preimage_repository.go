@@ -0,0 +1,122 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+type PreimageRepository interface {
+	CreatePreimages(preimages []types.Preimage) (int64, error)
+	GetPreimage(key common.Hash, maxOffset int64) (types.Preimage, error)
+	GetCursor(address common.Address, source string, positions []MappingPosition) (int64, error)
+	UpdateCursor(address common.Address, source string, positions []MappingPosition, sourceID int64) error
+}
+
+type preimageRepository struct {
+	db *postgres.DB
+}
+
+func NewPreimageRepository(db *postgres.DB) PreimageRepository {
+	return preimageRepository{db: db}
+}
+
+// CreatePreimages writes the preimages that aren't already known, returning how many were new
+func (repository preimageRepository) CreatePreimages(preimages []types.Preimage) (int64, error) {
+	if len(preimages) == 0 {
+		return 0, nil
+	}
+	var hashes, keys, slots pq.ByteaArray
+	sources := make([]string, len(preimages))
+	sourceIDs := make([]int64, len(preimages))
+	for i, preimage := range preimages {
+		hashes = append(hashes, preimage.Hash.Bytes())
+		keys = append(keys, preimage.Key.Bytes())
+		slots = append(slots, preimage.Slot.Bytes())
+		sources[i] = preimage.Source
+		sourceIDs[i] = preimage.SourceID
+	}
+	result, err := repository.db.Exec(`INSERT INTO public.storage_preimage (hash, key, slot, source, source_id)
+		SELECT * FROM UNNEST($1::BYTEA[], $2::BYTEA[], $3::BYTEA[], $4::TEXT[], $5::BIGINT[])
+		ON CONFLICT (hash) DO NOTHING`, hashes, keys, slots, pq.Array(sources), pq.Array(sourceIDs))
+	if err != nil {
+		return 0, fmt.Errorf("error creating storage preimages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetPreimage returns the preimage of the storage key, or of the closest key at most maxOffset slots before it, since
+// members of a struct in a mapping follow the entry's hash. Returns sql.ErrNoRows if there is none.
+func (repository preimageRepository) GetPreimage(key common.Hash, maxOffset int64) (types.Preimage, error) {
+	lowerBound := new(big.Int).Sub(key.Big(), big.NewInt(maxOffset))
+	if lowerBound.Sign() < 0 {
+		lowerBound.SetInt64(0)
+	}
+	var preimage types.Preimage
+	err := repository.db.Get(&preimage, `SELECT id, hash, key, slot, source, source_id FROM public.storage_preimage
+		WHERE hash <= $1 AND hash >= $2 ORDER BY hash DESC LIMIT 1`, key.Bytes(), common.BigToHash(lowerBound).Bytes())
+	if err != nil {
+		return preimage, fmt.Errorf("error getting preimage of storage key %s: %w", key.Hex(), err)
+	}
+	return preimage, nil
+}
+
+// GetCursor returns the ID of the last row of the source scanned for the contract's positions, or 0 if any of them
+// hasn't been scanned as deep as it goes
+func (repository preimageRepository) GetCursor(address common.Address, source string, positions []MappingPosition) (int64, error) {
+	slots, depths := positionColumns(positions)
+	var sourceID int64
+	err := repository.db.Get(&sourceID, `SELECT COALESCE(MIN(COALESCE(scanned.source_id, 0)), 0)
+		FROM UNNEST($3::BYTEA[], $4::INTEGER[]) AS mapping_position (slot, depth)
+		LEFT JOIN public.storage_preimage_cursor AS scanned ON scanned.address = $1 AND scanned.source = $2
+			AND scanned.slot = mapping_position.slot AND scanned.depth >= mapping_position.depth`,
+		address.Hex(), source, slots, pq.Array(depths))
+	if err != nil {
+		return 0, fmt.Errorf("error getting %s cursor for %s: %w", source, address.Hex(), err)
+	}
+	return sourceID, nil
+}
+
+// UpdateCursor records that the rows of the source up to sourceID were scanned for the contract's positions
+func (repository preimageRepository) UpdateCursor(address common.Address, source string, positions []MappingPosition, sourceID int64) error {
+	slots, depths := positionColumns(positions)
+	_, err := repository.db.Exec(`INSERT INTO public.storage_preimage_cursor (address, source, slot, depth, source_id)
+		SELECT $1, $2, mapping_position.slot, mapping_position.depth, $5
+		FROM UNNEST($3::BYTEA[], $4::INTEGER[]) AS mapping_position (slot, depth)
+		ON CONFLICT (address, source, slot) DO UPDATE SET depth = excluded.depth, source_id = excluded.source_id`,
+		address.Hex(), source, slots, pq.Array(depths), sourceID)
+	if err != nil {
+		return fmt.Errorf("error updating %s cursor for %s: %w", source, address.Hex(), err)
+	}
+	return nil
+}
+
+func positionColumns(positions []MappingPosition) (pq.ByteaArray, []int64) {
+	var slots pq.ByteaArray
+	depths := make([]int64, len(positions))
+	for i, position := range positions {
+		slots = append(slots, position.Slot.Bytes())
+		depths[i] = int64(position.Depth)
+	}
+	return slots, depths
+}
@@ -0,0 +1,160 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package storage_test
+
+import (
+	"database/sql"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preimage repository", func() {
+	var (
+		db         = test_config.NewTestDB(test_config.NewTestNode())
+		repository storage.PreimageRepository
+		preimage   types.Preimage
+	)
+
+	BeforeEach(func() {
+		test_config.CleanTestDB(db)
+		repository = storage.NewPreimageRepository(db)
+		preimage = types.NewPreimage(test_data.FakeHash(), common.HexToHash(storage.IndexTwo), storage.EventLogPreimageSource, 3)
+	})
+
+	Describe("CreatePreimages", func() {
+		It("persists preimages", func() {
+			created, err := repository.CreatePreimages([]types.Preimage{preimage})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(Equal(int64(1)))
+			var persisted types.Preimage
+			getErr := db.Get(&persisted, `SELECT id, hash, key, slot, source, source_id FROM public.storage_preimage`)
+			Expect(getErr).NotTo(HaveOccurred())
+			preimage.ID = persisted.ID
+			Expect(persisted).To(Equal(preimage))
+		})
+
+		It("doesn't count preimages that are already known", func() {
+			_, firstErr := repository.CreatePreimages([]types.Preimage{preimage})
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			created, err := repository.CreatePreimages([]types.Preimage{preimage})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(BeZero())
+		})
+	})
+
+	Describe("GetPreimage", func() {
+		BeforeEach(func() {
+			_, createErr := repository.CreatePreimages([]types.Preimage{preimage})
+			Expect(createErr).NotTo(HaveOccurred())
+		})
+
+		It("returns the preimage of a storage key", func() {
+			result, err := repository.GetPreimage(preimage.Hash, 0)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Key).To(Equal(preimage.Key))
+			Expect(result.Slot).To(Equal(preimage.Slot))
+		})
+
+		It("returns the preimage of a key up to max offset slots before the storage key", func() {
+			result, err := repository.GetPreimage(storage.GetIncrementedKey(preimage.Hash, 2), 2)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Hash).To(Equal(preimage.Hash))
+		})
+
+		It("returns sql.ErrNoRows if there is no preimage within max offset slots", func() {
+			_, err := repository.GetPreimage(storage.GetIncrementedKey(preimage.Hash, 2), 1)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+	})
+
+	Describe("cursors", func() {
+		var (
+			address   = common.HexToAddress("0x1234")
+			position  = storage.MappingPosition{Slot: common.HexToHash(storage.IndexTwo), Depth: 1}
+			positions = []storage.MappingPosition{position}
+		)
+
+		It("returns the ID of the last row scanned for the positions", func() {
+			updateErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 5)
+			Expect(updateErr).NotTo(HaveOccurred())
+
+			sourceID, err := repository.GetCursor(address, storage.EventLogPreimageSource, positions)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sourceID).To(Equal(int64(5)))
+		})
+
+		It("keeps a cursor per contract and source", func() {
+			updateErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 5)
+			Expect(updateErr).NotTo(HaveOccurred())
+
+			otherSourceID, otherSourceErr := repository.GetCursor(address, storage.TransactionPreimageSource, positions)
+			Expect(otherSourceErr).NotTo(HaveOccurred())
+			Expect(otherSourceID).To(BeZero())
+			otherContractID, otherContractErr := repository.GetCursor(common.HexToAddress("0x5678"), storage.EventLogPreimageSource, positions)
+			Expect(otherContractErr).NotTo(HaveOccurred())
+			Expect(otherContractID).To(BeZero())
+		})
+
+		It("moves the cursor forward", func() {
+			firstErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 5)
+			Expect(firstErr).NotTo(HaveOccurred())
+			secondErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 9)
+			Expect(secondErr).NotTo(HaveOccurred())
+
+			sourceID, err := repository.GetCursor(address, storage.EventLogPreimageSource, positions)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sourceID).To(Equal(int64(9)))
+		})
+
+		It("returns 0 if a position wasn't scanned", func() {
+			updateErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 5)
+			Expect(updateErr).NotTo(HaveOccurred())
+			added := storage.MappingPosition{Slot: common.HexToHash(storage.IndexThree), Depth: 1}
+
+			sourceID, err := repository.GetCursor(address, storage.EventLogPreimageSource, append(positions, added))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sourceID).To(BeZero())
+		})
+
+		It("returns 0 if a position wasn't scanned as deep as it goes", func() {
+			updateErr := repository.UpdateCursor(address, storage.EventLogPreimageSource, positions, 5)
+			Expect(updateErr).NotTo(HaveOccurred())
+			deeper := storage.MappingPosition{Slot: position.Slot, Depth: 2}
+
+			sourceID, err := repository.GetCursor(address, storage.EventLogPreimageSource, []storage.MappingPosition{deeper})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sourceID).To(BeZero())
+		})
+	})
+})
@@ -45,4 +45,14 @@ func (e ErrRowMalformed) Error() string {
 	return fmt.Sprintf("storage row malformed: length %d, expected %d", e.Length, ExpectedRowLength)
 }
 
+// ErrValueSpansSlots is returned when decoding a string or bytes value that is too long to be stored in its own
+// slot; its data is held in the slots returned by storage.DynamicValueDataSlots.
+type ErrValueSpansSlots struct {
+	Length int
+}
+
+func (e ErrValueSpansSlots) Error() string {
+	return fmt.Sprintf("value of length %d spans multiple storage slots", e.Length)
+}
+
 var ErrKeyNotFound = errors.New("unknown storage key")
@@ -0,0 +1,44 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Preimage is the key and slot whose keccak256 hash is the storage key of a mapping entry
+type Preimage struct {
+	ID       int64
+	Hash     common.Hash
+	Key      common.Hash
+	Slot     common.Hash
+	Source   string
+	SourceID int64 `db:"source_id"`
+}
+
+// NewPreimage records that the entry at key in the mapping at slot lives at keccak256(key . slot). Source and sourceID
+// identify where the key was observed.
+func NewPreimage(key, slot common.Hash, source string, sourceID int64) Preimage {
+	return Preimage{
+		Hash:     crypto.Keccak256Hash(key.Bytes(), slot.Bytes()),
+		Key:      key,
+		Slot:     slot,
+		Source:   source,
+		SourceID: sourceID,
+	}
+}
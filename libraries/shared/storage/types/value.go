@@ -29,10 +29,121 @@ const (
 	Bytes32
 	Address
 	PackedSlot
+	Bool
+	Enum
+	String
+	Bytes
 )
 
+// Integers and fixed-size byte arrays of other widths carry their kind in the high bits and their width in bytes
+// in the low bits; Uint, Int and FixedBytes build them for any width Solidity supports.
+const (
+	widthMask      ValueType = 0xff
+	uintKind       ValueType = 1 << 8
+	intKind        ValueType = 2 << 8
+	fixedBytesKind ValueType = 3 << 8
+)
+
+const (
+	Uint16 = uintKind | 2
+	Uint64 = uintKind | 8
+	Int8   = intKind | 1
+	Int16  = intKind | 2
+	Int32  = intKind | 4
+	Int64  = intKind | 8
+	Int128 = intKind | 16
+	Int256 = intKind | 32
+)
+
+// Uint returns the type of a uint<bits> value
+func Uint(bits int) (ValueType, error) {
+	switch bits {
+	case 8:
+		return Uint8, nil
+	case 32:
+		return Uint32, nil
+	case 48:
+		return Uint48, nil
+	case 128:
+		return Uint128, nil
+	case 256:
+		return Uint256, nil
+	}
+	if !isValidIntegerWidth(bits) {
+		return 0, fmt.Errorf("invalid uint width: %d", bits)
+	}
+	return uintKind | ValueType(bits/bitsPerByte), nil
+}
+
+// Int returns the type of an int<bits> value
+func Int(bits int) (ValueType, error) {
+	if !isValidIntegerWidth(bits) {
+		return 0, fmt.Errorf("invalid int width: %d", bits)
+	}
+	return intKind | ValueType(bits/bitsPerByte), nil
+}
+
+// FixedBytes returns the type of a bytes<size> value
+func FixedBytes(size int) (ValueType, error) {
+	if size == 32 {
+		return Bytes32, nil
+	}
+	if size < 1 || size > 32 {
+		return 0, fmt.Errorf("invalid bytes size: %d", size)
+	}
+	return fixedBytesKind | ValueType(size), nil
+}
+
+const bitsPerByte = 8
+
+func isValidIntegerWidth(bits int) bool {
+	return bits >= 8 && bits <= 256 && bits%bitsPerByte == 0
+}
+
+func (valueType ValueType) IsUnsignedInteger() bool {
+	switch valueType {
+	case Uint8, Uint32, Uint48, Uint128, Uint256:
+		return true
+	}
+	return valueType&^widthMask == uintKind
+}
+
+func (valueType ValueType) IsSignedInteger() bool {
+	return valueType&^widthMask == intKind
+}
+
+func (valueType ValueType) IsFixedBytes() bool {
+	return valueType == Bytes32 || valueType&^widthMask == fixedBytesKind
+}
+
+// Size returns the number of bytes a value of this type occupies in a storage slot.
+// Strings, bytes and packed slots have no fixed size and return an error.
+func (valueType ValueType) Size() (int, error) {
+	switch valueType {
+	case Uint8, Bool, Enum:
+		return 1, nil
+	case Uint32:
+		return 4, nil
+	case Uint48:
+		return 6, nil
+	case Uint128:
+		return 16, nil
+	case Uint256, Bytes32:
+		return 32, nil
+	case Address:
+		return 20, nil
+	}
+	if valueType.IsUnsignedInteger() || valueType.IsSignedInteger() || valueType.IsFixedBytes() {
+		return int(valueType & widthMask), nil
+	}
+	return 0, fmt.Errorf("value type %d has no fixed size", valueType)
+}
+
 type Key string
 
+// Path is the key under which metadata records the resolved position of a nested value, e.g. ilks[ETH-A].art
+const Path Key = "path"
+
 type ValueMetadata struct {
 	Name        string
 	Keys        map[Key]string
@@ -75,4 +75,49 @@ var _ = Describe("Storage value metadata getter", func() {
 			Expect(getMetadata).To(Panic())
 		})
 	})
+
+	Describe("sized types", func() {
+		It("returns the existing types for widths that have them", func() {
+			uint48, uintErr := types.Uint(48)
+			bytes32, bytesErr := types.FixedBytes(32)
+
+			Expect(uintErr).NotTo(HaveOccurred())
+			Expect(uint48).To(Equal(types.Uint48))
+			Expect(bytesErr).NotTo(HaveOccurred())
+			Expect(bytes32).To(Equal(types.Bytes32))
+		})
+
+		It("returns types of any width Solidity supports", func() {
+			int24, intErr := types.Int(24)
+			Expect(intErr).NotTo(HaveOccurred())
+			Expect(int24.IsSignedInteger()).To(BeTrue())
+			Expect(int24.Size()).To(Equal(3))
+
+			uint64Type, uintErr := types.Uint(64)
+			Expect(uintErr).NotTo(HaveOccurred())
+			Expect(uint64Type).To(Equal(types.Uint64))
+			Expect(uint64Type.IsUnsignedInteger()).To(BeTrue())
+
+			bytes4, bytesErr := types.FixedBytes(4)
+			Expect(bytesErr).NotTo(HaveOccurred())
+			Expect(bytes4.IsFixedBytes()).To(BeTrue())
+			Expect(bytes4.Size()).To(Equal(4))
+		})
+
+		It("returns error for widths Solidity doesn't support", func() {
+			_, uintErr := types.Uint(12)
+			_, intErr := types.Int(264)
+			_, bytesErr := types.FixedBytes(33)
+
+			Expect(uintErr).To(HaveOccurred())
+			Expect(intErr).To(HaveOccurred())
+			Expect(bytesErr).To(HaveOccurred())
+		})
+
+		It("returns error for the size of types stored across slots", func() {
+			_, err := types.String.Size()
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -27,7 +27,7 @@ func CreateTestLog(headerID int64, db *postgres.DB) core.EventLog {
 	}
 
 	tx := getFakeTransactionFromHash(txHash)
-	headerRepository := repositories.NewHeaderRepository(db)
+	headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 	txErr := headerRepository.CreateTransactions(headerID, []core.TransactionModel{tx})
 	Expect(txErr).NotTo(HaveOccurred())
 
@@ -57,7 +57,7 @@ func getFakeTransactionFromHash(txHash common.Hash) core.TransactionModel {
 		Data:     nil,
 		From:     getRandomAddress(),
 		GasLimit: 0,
-		GasPrice: 0,
+		GasPrice: "0",
 		Hash:     hashToPrefixedString(txHash),
 		Nonce:    0,
 		Raw:      nil,
@@ -17,6 +17,8 @@
 package transactions
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/makerdao/vulcanizedb/pkg/core"
@@ -32,10 +34,12 @@ type ITransactionsSyncer interface {
 type TransactionsSyncer struct {
 	BlockChain core.BlockChain
 	Repository datastore.HeaderRepository
+	// SyncReceipts fetches and persists the receipts of the transactions along with them
+	SyncReceipts bool
 }
 
 func NewTransactionsSyncer(db *postgres.DB, blockChain core.BlockChain) TransactionsSyncer {
-	repository := repositories.NewHeaderRepository(db)
+	repository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 	return TransactionsSyncer{
 		BlockChain: blockChain,
 		Repository: repository,
@@ -51,6 +55,9 @@ func (syncer TransactionsSyncer) SyncTransactions(headerID int64, logs []types.L
 	if transactionErr != nil {
 		return transactionErr
 	}
+	if syncer.SyncReceipts {
+		return syncer.syncReceipts(headerID, transactionHashes, transactions)
+	}
 	writeErr := syncer.Repository.CreateTransactions(headerID, transactions)
 	if writeErr != nil {
 		return writeErr
@@ -58,6 +65,25 @@ func (syncer TransactionsSyncer) SyncTransactions(headerID int64, logs []types.L
 	return nil
 }
 
+func (syncer TransactionsSyncer) syncReceipts(headerID int64, transactionHashes []common.Hash, transactions []core.TransactionModel) error {
+	receipts, receiptErr := syncer.BlockChain.GetTransactionReceipts(transactionHashes)
+	if receiptErr != nil {
+		return receiptErr
+	}
+	receiptsByHash := make(map[common.Hash]core.Receipt, len(receipts))
+	for _, receipt := range receipts {
+		receiptsByHash[common.HexToHash(receipt.TxHash)] = receipt
+	}
+	for i := range transactions {
+		receipt, found := receiptsByHash[common.HexToHash(transactions[i].Hash)]
+		if !found {
+			return fmt.Errorf("no receipt fetched for transaction %s", transactions[i].Hash)
+		}
+		transactions[i].Receipt = receipt
+	}
+	return syncer.Repository.CreateTransactionsWithReceipts(headerID, transactions)
+}
+
 func getUniqueTransactionHashes(logs []types.Log) []common.Hash {
 	seen := make(map[common.Hash]struct{}, len(logs))
 	var result []common.Hash
@@ -17,6 +17,7 @@
 package transactions_test
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/makerdao/vulcanizedb/libraries/shared/transactions"
 	"github.com/makerdao/vulcanizedb/pkg/core"
@@ -95,4 +96,60 @@ var _ = Describe("Transaction syncer", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError(fakes.FakeError))
 	})
+
+	Describe("when syncing receipts", func() {
+		var mockHeaderRepository *fakes.MockHeaderRepository
+
+		BeforeEach(func() {
+			syncer.SyncReceipts = true
+			mockHeaderRepository = fakes.NewMockHeaderRepository()
+			syncer.Repository = mockHeaderRepository
+			blockChain.Transactions = []core.TransactionModel{{Hash: fakes.FakeHash.Hex()}}
+			blockChain.Receipts = []core.Receipt{{TxHash: fakes.FakeHash.Hex(), GasUsed: 21000, Status: 1}}
+		})
+
+		It("fetches the receipts of the transactions", func() {
+			err := syncer.SyncTransactions(0, []types.Log{{TxHash: fakes.FakeHash}, {TxHash: fakes.FakeHash}})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blockChain.GetTransactionReceiptsPassedHashes).To(Equal([]common.Hash{fakes.FakeHash}))
+		})
+
+		It("persists the transactions with their receipts", func() {
+			err := syncer.SyncTransactions(0, []types.Log{{TxHash: fakes.FakeHash}})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockHeaderRepository.CreateTransactionsCalled).To(BeFalse())
+			Expect(mockHeaderRepository.CreateTransactionsWithReceiptsPassed).To(Equal([]core.TransactionModel{{
+				Hash:    fakes.FakeHash.Hex(),
+				Receipt: blockChain.Receipts[0],
+			}}))
+		})
+
+		It("returns error if fetching receipts fails", func() {
+			blockChain.GetTransactionReceiptsError = fakes.FakeError
+
+			err := syncer.SyncTransactions(0, []types.Log{{TxHash: fakes.FakeHash}})
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(mockHeaderRepository.CreateTransactionsWithReceiptsPassed).To(BeNil())
+		})
+
+		It("returns error if a transaction's receipt is missing", func() {
+			blockChain.Receipts = nil
+
+			err := syncer.SyncTransactions(0, []types.Log{{TxHash: fakes.FakeHash}})
+
+			Expect(err).To(HaveOccurred())
+			Expect(mockHeaderRepository.CreateTransactionsWithReceiptsPassed).To(BeNil())
+		})
+
+		It("returns error if persisting transactions with receipts fails", func() {
+			mockHeaderRepository.CreateTransactionsWithReceiptsError = fakes.FakeError
+
+			err := syncer.SyncTransactions(0, []types.Log{{TxHash: fakes.FakeHash}})
+
+			Expect(err).To(MatchError(fakes.FakeError))
+		})
+	})
 })
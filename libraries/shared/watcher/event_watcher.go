@@ -100,7 +100,15 @@ func (watcher *EventWatcher) Execute(recheckHeaders constants.TransformerExecuti
 }
 
 func (watcher *EventWatcher) extractLogs(recheckHeaders constants.TransformerExecution, errs chan error, quitChan chan bool) {
-	call := func() error { return watcher.LogExtractor.ExtractLogs(recheckHeaders) }
+	// orphaned headers are cleaned up before extraction so that transformers never remove data derived from the
+	// logs re-extracted for a replacement header
+	call := func() error {
+		cleanUpErr := watcher.LogDelegator.CleanUpOrphanedHeaders()
+		if cleanUpErr != nil {
+			return cleanUpErr
+		}
+		return watcher.LogExtractor.ExtractLogs(recheckHeaders)
+	}
 	// io.ErrUnexpectedEOF errors are sometimes returned from fetching logs at the head of the chain when fetching from an uncle or fork block
 	expectedErrors := []error{watcher.ExpectedExtractorError, io.ErrUnexpectedEOF}
 	watcher.withRetry(call, expectedErrors, "extracting", errs, quitChan)
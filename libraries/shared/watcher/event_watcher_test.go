@@ -104,6 +104,24 @@ var _ = Describe("Event Watcher", func() {
 			Expect(extractor.ExtractLogsCount > 0).To(BeTrue())
 		})
 
+		It("cleans up orphaned headers before extracting logs", func() {
+			extractor.ExtractLogsErrors = []error{nil, errExecuteClosed}
+
+			err := eventWatcher.Execute(constants.HeaderUnchecked)
+
+			Expect(err).To(MatchError(errExecuteClosed))
+			Expect(delegator.CleanUpCallCount > 0).To(BeTrue())
+		})
+
+		It("returns error without extracting logs if cleaning up orphaned headers fails", func() {
+			delegator.CleanUpError = fakes.FakeError
+
+			err := eventWatcher.Execute(constants.HeaderUnchecked)
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(extractor.ExtractLogsCount).To(BeZero())
+		})
+
 		It("returns error if extracting logs fails", func() {
 			extractor.ExtractLogsErrors = []error{fakes.FakeError}
 
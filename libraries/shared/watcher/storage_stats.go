@@ -0,0 +1,110 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package watcher
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage"
+	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
+)
+
+// ContractStats describes the storage diffs a watcher has handled for one contract since it started
+type ContractStats struct {
+	Address         common.Address
+	HashedAddress   common.Hash
+	Transformed     int64
+	Unrecognized    int64         // diffs marked unrecognized, not counting retries of diffs already marked
+	Failed          int64         // failed attempts, not counting diffs waiting for their header
+	BusyTime        time.Duration // time spent transforming the contract's diffs
+	LastBlockHeight int           // greatest block height of a diff handled
+	Lag             int64         // blocks between the head of the chain and LastBlockHeight
+}
+
+// Throughput returns how many diffs were transformed per second spent transforming the contract's diffs
+func (stats ContractStats) Throughput() float64 {
+	if stats.BusyTime <= 0 {
+		return 0
+	}
+	return float64(stats.Transformed) / stats.BusyTime.Seconds()
+}
+
+// storageStats collects ContractStats from the goroutines transforming each contract's diffs
+type storageStats struct {
+	mutex     sync.Mutex
+	contracts map[common.Hash]*ContractStats
+}
+
+func newStorageStats() *storageStats {
+	return &storageStats{contracts: make(map[common.Hash]*ContractStats)}
+}
+
+func (stats *storageStats) record(address common.Address, diff types.PersistedDiff, transformErr error, duration time.Duration) {
+	if stats == nil || isAwaitingHeader(transformErr) {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	contract, ok := stats.contracts[diff.HashedAddress]
+	if !ok {
+		contract = &ContractStats{Address: address, HashedAddress: diff.HashedAddress}
+		stats.contracts[diff.HashedAddress] = contract
+	}
+	switch {
+	case transformErr == nil:
+		contract.Transformed++
+	case errors.Is(transformErr, types.ErrKeyNotFound):
+		if diff.Status != storage.Unrecognized {
+			contract.Unrecognized++
+		}
+	default:
+		contract.Failed++
+	}
+	contract.BusyTime += duration
+	if diff.BlockHeight > contract.LastBlockHeight {
+		contract.LastBlockHeight = diff.BlockHeight
+	}
+}
+
+func isAwaitingHeader(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrHeaderMismatch)
+}
+
+// snapshot returns a copy of each contract's stats, ordered by hashed address, with their lag behind headBlockNumber
+func (stats *storageStats) snapshot(headBlockNumber int64) []ContractStats {
+	if stats == nil {
+		return nil
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	result := make([]ContractStats, 0, len(stats.contracts))
+	for _, contract := range stats.contracts {
+		snapshot := *contract
+		snapshot.Lag = headBlockNumber - int64(snapshot.LastBlockHeight)
+		result = append(result, snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].HashedAddress.Bytes(), result[j].HashedAddress.Bytes()) < 0
+	})
+	return result
+}
@@ -20,6 +20,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	storage2 "github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
@@ -36,6 +38,14 @@ var (
 	ErrHeaderMismatch = errors.New("header hash doesn't match between db and diff")
 	ReorgWindow       = 250
 	ResultsLimit      = 500
+	// DefaultStorageWorkers is how many contracts' diffs are transformed at once
+	DefaultStorageWorkers = 8
+	// MinIdleBackoff is how long the watcher waits after a pass with no diffs to transform; it doubles per idle pass
+	MinIdleBackoff = 100 * time.Millisecond
+	// MaxIdleBackoff is the longest the watcher waits between passes with no diffs to transform
+	MaxIdleBackoff = 7 * time.Second
+	// StatsInterval is how often the throughput and lag of each contract are logged
+	StatsInterval = time.Minute
 )
 
 type IStorageWatcher interface {
@@ -50,10 +60,13 @@ type StorageWatcher struct {
 	StorageDiffRepository     storage.DiffRepository
 	DiffBlocksFromHeadOfChain int64 // the number of blocks from the head of the chain where diffs should be processed
 	StatusWriter              fs.StatusWriter
+	PreimageRecorder          storage.PreimageRecorder // optional; records preimages of mapping entries and retries unrecognized diffs as they arrive
+	Workers                   int                      // how many contracts' diffs are transformed concurrently; diffs of one contract are transformed in order
+	stats                     *storageStats
 }
 
 func NewStorageWatcher(db *postgres.DB, backFromHeadOfChain int64, statusWriter fs.StatusWriter) StorageWatcher {
-	headerRepository := repositories.NewHeaderRepository(db)
+	headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 	storageDiffRepository := storage.NewDiffRepository(db)
 	transformers := make(map[common.Hash]storage2.ITransformer)
 	return StorageWatcher{
@@ -63,6 +76,8 @@ func NewStorageWatcher(db *postgres.DB, backFromHeadOfChain int64, statusWriter
 		StorageDiffRepository:     storageDiffRepository,
 		DiffBlocksFromHeadOfChain: backFromHeadOfChain,
 		StatusWriter:              statusWriter,
+		Workers:                   DefaultStorageWorkers,
+		stats:                     newStorageStats(),
 	}
 }
 
@@ -70,21 +85,93 @@ func (watcher StorageWatcher) AddTransformers(initializers []storage2.Transforme
 	for _, initializer := range initializers {
 		storageTransformer := initializer(watcher.db)
 		watcher.KeccakAddressTransformers[storageTransformer.KeccakContractAddress()] = storageTransformer
+		watcher.addPreimagePositions(storageTransformer)
 	}
 }
 
+// addPreimagePositions records preimages for the mappings of transformers that can recognize keys from them
+func (watcher StorageWatcher) addPreimagePositions(storageTransformer storage2.ITransformer) {
+	if watcher.PreimageRecorder == nil {
+		return
+	}
+	lookup, ok := storageTransformer.GetStorageKeysLookup().(storage2.PreimageKeysLookup)
+	if !ok {
+		return
+	}
+	positions, positionsErr := lookup.MappingPositions()
+	if positionsErr != nil {
+		logrus.Errorf("error getting mapping positions of %s, not recording their preimages: %s",
+			storageTransformer.GetContractAddress().Hex(), positionsErr.Error())
+		return
+	}
+	watcher.PreimageRecorder.AddPositions(storageTransformer.GetContractAddress(), positions...)
+}
+
 func (watcher StorageWatcher) Execute() error {
 	writeErr := watcher.StatusWriter.Write()
 	if writeErr != nil {
 		return fmt.Errorf("error confirming health check: %w", writeErr)
 	}
 
+	backoff := MinIdleBackoff
+	lastStatsLog := time.Now()
 	for {
-		err := watcher.transformDiffs()
+		preimagesErr := watcher.recordPreimages()
+		if preimagesErr != nil {
+			logrus.Errorf("error recording preimages: %s", preimagesErr.Error())
+			return preimagesErr
+		}
+		transformed, err := watcher.transformDiffs()
 		if err != nil {
 			logrus.Errorf("error transforming diffs: %s", err.Error())
 			return err
 		}
+		if time.Since(lastStatsLog) >= StatsInterval {
+			watcher.logStats()
+			lastStatsLog = time.Now()
+		}
+		if transformed > 0 {
+			backoff = MinIdleBackoff
+			continue
+		}
+		time.Sleep(backoff)
+		backoff = nextIdleBackoff(backoff)
+	}
+}
+
+func nextIdleBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > MaxIdleBackoff {
+		return MaxIdleBackoff
+	}
+	return backoff
+}
+
+// Stats returns the diffs handled for each contract since the watcher started, and how far each lags behind the
+// most recent header
+func (watcher StorageWatcher) Stats() ([]ContractStats, error) {
+	headBlockNumber, headErr := watcher.HeaderRepository.GetMostRecentHeaderBlockNumber()
+	if headErr != nil {
+		return nil, fmt.Errorf("error getting most recent header block number: %w", headErr)
+	}
+	return watcher.stats.snapshot(headBlockNumber), nil
+}
+
+func (watcher StorageWatcher) logStats() {
+	stats, statsErr := watcher.Stats()
+	if statsErr != nil {
+		logrus.Warnf("error getting storage watcher stats: %s", statsErr.Error())
+		return
+	}
+	for _, contract := range stats {
+		logrus.WithFields(logrus.Fields{
+			"contract":       contract.Address.Hex(),
+			"transformed":    contract.Transformed,
+			"unrecognized":   contract.Unrecognized,
+			"failed":         contract.Failed,
+			"diffsPerSecond": fmt.Sprintf("%.2f", contract.Throughput()),
+			"lag":            contract.Lag,
+		}).Info("storage diffs handled")
 	}
 }
 
@@ -110,31 +197,148 @@ func (watcher StorageWatcher) getMinDiffID() (int, error) {
 	return minID, nil
 }
 
-func (watcher StorageWatcher) transformDiffs() error {
+// transformDiffs transforms new diffs a page at a time, and returns how many changed status
+func (watcher StorageWatcher) transformDiffs() (int, error) {
 	minID, minIDErr := watcher.getMinDiffID()
 	if minIDErr != nil && !errors.Is(minIDErr, sql.ErrNoRows) {
-		return fmt.Errorf("error getting min diff ID: %w", minIDErr)
+		return 0, fmt.Errorf("error getting min diff ID: %w", minIDErr)
 	}
 
+	total := 0
 	for {
 		diffs, extractErr := watcher.StorageDiffRepository.GetNewDiffs(minID, ResultsLimit)
 		if extractErr != nil {
-			return fmt.Errorf("error getting new diffs: %w", extractErr)
+			return total, fmt.Errorf("error getting new diffs: %w", extractErr)
 		}
-		for _, diff := range diffs {
-			transformErr := watcher.transformDiff(diff)
-			if handleErr := watcher.handleTransformError(transformErr, diff); handleErr != nil {
-				return fmt.Errorf("error transforming diff: %w", handleErr)
-			}
+		transformed, transformErr := watcher.transformDiffsByContract(diffs)
+		total += transformed
+		if transformErr != nil {
+			return total, fmt.Errorf("error transforming diff: %w", transformErr)
 		}
 		lenDiffs := len(diffs)
 		if lenDiffs > 0 {
 			minID = int(diffs[lenDiffs-1].ID)
 		}
 		if lenDiffs < ResultsLimit {
+			return total, nil
+		}
+	}
+}
+
+// transformDiffsByContract transforms the diffs of up to Workers contracts concurrently, in order within each
+// contract, and returns how many diffs changed status. Diffs of contracts without a transformer are marked unwatched.
+func (watcher StorageWatcher) transformDiffsByContract(diffs []types.PersistedDiff) (int, error) {
+	var (
+		contracts []common.Hash
+		partition = make(map[common.Hash][]types.PersistedDiff)
+		total     = 0
+	)
+	for _, diff := range diffs {
+		if _, watching := watcher.getTransformer(diff); !watching {
+			markUnwatchedErr := watcher.StorageDiffRepository.MarkUnwatched(diff.ID)
+			if markUnwatchedErr != nil {
+				return total, fmt.Errorf("error marking diff %s: %w", storage.Unwatched, markUnwatchedErr)
+			}
+			total++
+			continue
+		}
+		if _, ok := partition[diff.HashedAddress]; !ok {
+			contracts = append(contracts, diff.HashedAddress)
+		}
+		partition[diff.HashedAddress] = append(partition[diff.HashedAddress], diff)
+	}
+
+	workers := watcher.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		firstErr  error
+		mutex     sync.Mutex
+		semaphore = make(chan struct{}, workers)
+		wg        sync.WaitGroup
+	)
+	for _, hashedAddress := range contracts {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(contractDiffs []types.PersistedDiff) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			transformed, err := watcher.transformContractDiffs(contractDiffs)
+			mutex.Lock()
+			defer mutex.Unlock()
+			total += transformed
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(partition[hashedAddress])
+	}
+	wg.Wait()
+	return total, firstErr
+}
+
+// transformContractDiffs transforms one contract's diffs in order, returning how many changed status
+func (watcher StorageWatcher) transformContractDiffs(diffs []types.PersistedDiff) (int, error) {
+	transformed := 0
+	for _, diff := range diffs {
+		start := time.Now()
+		transformErr := watcher.transformDiff(diff)
+		newlyUnrecognized := errors.Is(transformErr, types.ErrKeyNotFound) && diff.Status != storage.Unrecognized
+		if t, watching := watcher.getTransformer(diff); watching {
+			watcher.stats.record(t.GetContractAddress(), diff, transformErr, time.Since(start))
+		}
+		if handleErr := watcher.handleTransformError(transformErr, diff); handleErr != nil {
+			return transformed, handleErr
+		}
+		if transformErr == nil || newlyUnrecognized {
+			transformed++
+		}
+	}
+	return transformed, nil
+}
+
+// recordPreimages records the preimages of keys observed since the last pass. When there are new ones, unrecognized
+// diffs older than those revisited by transformDiffs are retried, since they may now be recognized.
+func (watcher StorageWatcher) recordPreimages() error {
+	if watcher.PreimageRecorder == nil {
+		return nil
+	}
+	recorded, recordErr := watcher.PreimageRecorder.RecordPreimages()
+	if recordErr != nil {
+		return fmt.Errorf("error recording preimages: %w", recordErr)
+	}
+	if recorded == 0 {
+		return nil
+	}
+	maxID, maxIDErr := watcher.getMinDiffID()
+	if maxIDErr != nil && !errors.Is(maxIDErr, sql.ErrNoRows) {
+		return fmt.Errorf("error getting min diff ID: %w", maxIDErr)
+	}
+	return watcher.retryUnrecognizedDiffs(maxID)
+}
+
+func (watcher StorageWatcher) retryUnrecognizedDiffs(maxID int) error {
+	minID := 0
+	for minID < maxID {
+		diffs, getDiffsErr := watcher.StorageDiffRepository.GetUnrecognizedDiffs(minID, ResultsLimit)
+		if getDiffsErr != nil {
+			return fmt.Errorf("error getting unrecognized diffs: %w", getDiffsErr)
+		}
+		for _, diff := range diffs {
+			if int(diff.ID) > maxID {
+				return nil
+			}
+			transformErr := watcher.transformDiff(diff)
+			if handleErr := watcher.handleTransformError(transformErr, diff); handleErr != nil {
+				return fmt.Errorf("error retrying diff: %w", handleErr)
+			}
+		}
+		if len(diffs) < ResultsLimit {
 			return nil
 		}
+		minID = int(diffs[len(diffs)-1].ID)
 	}
+	return nil
 }
 
 func (watcher StorageWatcher) transformDiff(diff types.PersistedDiff) error {
@@ -150,7 +354,7 @@ func (watcher StorageWatcher) transformDiff(diff types.PersistedDiff) error {
 	headerID, headerErr := watcher.getHeaderID(diff)
 	if headerErr != nil {
 		if errors.Is(headerErr, ErrHeaderMismatch) {
-			return watcher.handleDiffWithInvalidHeaderHash(diff)
+			return watcher.handleDiffWithInvalidHeaderHash(diff, headerErr)
 		}
 		return fmt.Errorf("error getting header for diff: %w", headerErr)
 	}
@@ -187,7 +391,9 @@ func (watcher StorageWatcher) getHeaderID(diff types.PersistedDiff) (int64, erro
 	return header.Id, nil
 }
 
-func (watcher StorageWatcher) handleDiffWithInvalidHeaderHash(diff types.PersistedDiff) error {
+// handleDiffWithInvalidHeaderHash marks a diff noncanonical once it is outside the reorg window; until then the header
+// mismatch is returned so that the diff is retried
+func (watcher StorageWatcher) handleDiffWithInvalidHeaderHash(diff types.PersistedDiff, mismatchErr error) error {
 	maxBlock, maxBlockErr := watcher.HeaderRepository.GetMostRecentHeaderBlockNumber()
 	if maxBlockErr != nil {
 		msg := "error getting max block while handling diff %d with invalid header hash: %w"
@@ -196,7 +402,7 @@ func (watcher StorageWatcher) handleDiffWithInvalidHeaderHash(diff types.Persist
 	if diff.BlockHeight < int(maxBlock)-ReorgWindow {
 		return watcher.StorageDiffRepository.MarkNoncanonical(diff.ID)
 	}
-	return nil
+	return mismatchErr
 }
 
 func (watcher StorageWatcher) handleTransformError(transformErr error, diff types.PersistedDiff) error {
@@ -217,5 +423,5 @@ func (watcher StorageWatcher) handleTransformError(transformErr error, diff type
 }
 
 func isCommonTransformError(err error) bool {
-	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, types.ErrKeyNotFound)
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, types.ErrKeyNotFound) || errors.Is(err, ErrHeaderMismatch)
 }
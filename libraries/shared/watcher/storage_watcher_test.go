@@ -20,10 +20,12 @@ import (
 	"database/sql"
 	"errors"
 	"math/rand"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/makerdao/vulcanizedb/libraries/shared/factories/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/mocks"
+	sharedStorage "github.com/makerdao/vulcanizedb/libraries/shared/storage"
 	"github.com/makerdao/vulcanizedb/libraries/shared/storage/types"
 	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
 	"github.com/makerdao/vulcanizedb/libraries/shared/watcher"
@@ -45,6 +47,24 @@ var _ = Describe("Storage Watcher", func() {
 
 			Expect(w.KeccakAddressTransformers[fakeHashedAddress]).To(Equal(fakeTransformer))
 		})
+
+		It("records preimages for the mappings of transformers that recognize keys from them", func() {
+			positions := []sharedStorage.MappingPosition{{Slot: common.HexToHash(sharedStorage.IndexTwo), Depth: 2}}
+			lookup := &mocks.MockPreimageKeysLookup{Positions: positions}
+			fakeTransformer := &mocks.MockStorageTransformer{
+				Address:           fakes.FakeAddress,
+				KeccakOfAddress:   test_data.FakeHash(),
+				StorageKeysLookup: lookup,
+			}
+			recorder := &mocks.MockPreimageRecorder{}
+			w := watcher.NewStorageWatcher(test_config.NewTestDB(test_config.NewTestNode()), -1, &statusWriter)
+			w.PreimageRecorder = recorder
+
+			w.AddTransformers([]storage.TransformerInitializer{fakeTransformer.FakeTransformerInitializer})
+
+			Expect(recorder.AddPositionsPassedAddresses).To(Equal([]common.Address{fakes.FakeAddress}))
+			Expect(recorder.AddPositionsPassedPositions).To(Equal(positions))
+		})
 	})
 
 	Describe("Execute", func() {
@@ -141,6 +161,82 @@ var _ = Describe("Storage Watcher", func() {
 			Expect(mockDiffsRepository.MarkUnwatchedPassedID).To(Equal(unwatchedDiff.ID))
 		})
 
+		It("transforms the diffs of each contract in order", func() {
+			blockHash := test_data.FakeHash()
+			mockHeaderRepository.GetHeaderByBlockNumberReturnHash = blockHash.Hex()
+			transformerOne := &mocks.MockStorageTransformer{KeccakOfAddress: test_data.FakeHash()}
+			transformerTwo := &mocks.MockStorageTransformer{KeccakOfAddress: test_data.FakeHash()}
+			storageWatcher.AddTransformers([]storage.TransformerInitializer{
+				transformerOne.FakeTransformerInitializer,
+				transformerTwo.FakeTransformerInitializer,
+			})
+			var diffs []types.PersistedDiff
+			for i := 1; i <= 6; i++ {
+				hashedAddress := transformerOne.KeccakOfAddress
+				if i%2 == 0 {
+					hashedAddress = transformerTwo.KeccakOfAddress
+				}
+				diffs = append(diffs, types.PersistedDiff{
+					RawDiff: types.RawDiff{HashedAddress: hashedAddress, BlockHash: blockHash},
+					ID:      int64(i),
+				})
+			}
+			mockDiffsRepository.GetNewDiffsDiffs = diffs
+			mockDiffsRepository.GetNewDiffsErrors = []error{nil, fakes.FakeError}
+
+			err := storageWatcher.Execute()
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(transformerOne.PassedDiffs).To(Equal([]types.PersistedDiff{diffs[0], diffs[2], diffs[4]}))
+			Expect(transformerTwo.PassedDiffs).To(Equal([]types.PersistedDiff{diffs[1], diffs[3], diffs[5]}))
+		})
+
+		It("transforms the diffs of each contract in order with a single worker", func() {
+			storageWatcher.Workers = 0
+			blockHash := test_data.FakeHash()
+			mockHeaderRepository.GetHeaderByBlockNumberReturnHash = blockHash.Hex()
+			mockTransformer := &mocks.MockStorageTransformer{KeccakOfAddress: test_data.FakeHash()}
+			storageWatcher.AddTransformers([]storage.TransformerInitializer{mockTransformer.FakeTransformerInitializer})
+			diffs := []types.PersistedDiff{
+				{RawDiff: types.RawDiff{HashedAddress: mockTransformer.KeccakOfAddress, BlockHash: blockHash}, ID: 1},
+				{RawDiff: types.RawDiff{HashedAddress: mockTransformer.KeccakOfAddress, BlockHash: blockHash}, ID: 2},
+			}
+			mockDiffsRepository.GetNewDiffsDiffs = diffs
+			mockDiffsRepository.GetNewDiffsErrors = []error{nil, fakes.FakeError}
+
+			err := storageWatcher.Execute()
+
+			Expect(err).To(MatchError(fakes.FakeError))
+			Expect(mockTransformer.PassedDiffs).To(Equal(diffs))
+		})
+
+		Describe("when there are no diffs to transform", func() {
+			var originalMinIdleBackoff, originalMaxIdleBackoff time.Duration
+
+			BeforeEach(func() {
+				originalMinIdleBackoff = watcher.MinIdleBackoff
+				originalMaxIdleBackoff = watcher.MaxIdleBackoff
+				watcher.MinIdleBackoff = 20 * time.Millisecond
+				watcher.MaxIdleBackoff = 30 * time.Millisecond
+			})
+
+			AfterEach(func() {
+				watcher.MinIdleBackoff = originalMinIdleBackoff
+				watcher.MaxIdleBackoff = originalMaxIdleBackoff
+			})
+
+			It("waits longer after each pass, up to the max idle backoff", func() {
+				mockDiffsRepository.GetNewDiffsErrors = []error{nil, nil, nil, fakes.FakeError}
+				start := time.Now()
+
+				err := storageWatcher.Execute()
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(len(mockDiffsRepository.GetNewDiffsPassedMinIDs)).To(Equal(4))
+				Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+			})
+		})
+
 		Describe("When the watcher is configured to skip old diffs", func() {
 			var diffs []types.PersistedDiff
 			var numberOfBlocksFromHeadOfChain = int64(500)
@@ -245,6 +341,76 @@ var _ = Describe("Storage Watcher", func() {
 			})
 		})
 
+		Describe("when recording preimages", func() {
+			var (
+				recorder         *mocks.MockPreimageRecorder
+				mockTransformer  *mocks.MockStorageTransformer
+				unrecognizedDiff types.PersistedDiff
+			)
+
+			BeforeEach(func() {
+				recorder = &mocks.MockPreimageRecorder{}
+				storageWatcher.PreimageRecorder = recorder
+				storageWatcher.DiffBlocksFromHeadOfChain = 500
+				hashedAddress := test_data.FakeHash()
+				mockTransformer = &mocks.MockStorageTransformer{KeccakOfAddress: hashedAddress}
+				storageWatcher.AddTransformers([]storage.TransformerInitializer{mockTransformer.FakeTransformerInitializer})
+
+				fakeBlockHash := test_data.FakeHash()
+				mockHeaderRepository.GetHeaderByBlockNumberReturnID = rand.Int63()
+				mockHeaderRepository.GetHeaderByBlockNumberReturnHash = fakeBlockHash.Hex()
+				unrecognizedDiff = types.PersistedDiff{
+					RawDiff: types.RawDiff{HashedAddress: hashedAddress, BlockHash: fakeBlockHash},
+					ID:      5,
+				}
+				mockDiffsRepository.GetUnrecognizedDiffsDiffs = []types.PersistedDiff{unrecognizedDiff}
+				// diffs after ID 10 are revisited by the regular pass
+				mockDiffsRepository.GetFirstDiffIDToReturn = 11
+				mockDiffsRepository.GetNewDiffsErrors = []error{fakes.FakeError}
+			})
+
+			It("returns error if recording preimages fails", func() {
+				recorder.RecordPreimagesErr = fakes.FakeError
+
+				err := storageWatcher.Execute()
+
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(mockDiffsRepository.GetNewDiffsPassedMinIDs).To(BeEmpty())
+			})
+
+			It("retries older unrecognized diffs when new preimages are recorded", func() {
+				recorder.RecordPreimagesReturn = 1
+
+				err := storageWatcher.Execute()
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(recorder.RecordPreimagesCalled).To(BeTrue())
+				Expect(mockDiffsRepository.GetUnrecognizedDiffsPassedMinIDs).To(ConsistOf(0))
+				Expect(mockTransformer.PassedDiff.ID).To(Equal(unrecognizedDiff.ID))
+				Expect(mockDiffsRepository.MarkCheckedPassedID).To(Equal(unrecognizedDiff.ID))
+			})
+
+			It("doesn't retry unrecognized diffs that the regular pass revisits", func() {
+				recorder.RecordPreimagesReturn = 1
+				unrecognizedDiff.ID = 11
+				mockDiffsRepository.GetUnrecognizedDiffsDiffs = []types.PersistedDiff{unrecognizedDiff}
+
+				err := storageWatcher.Execute()
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(mockDiffsRepository.MarkCheckedPassedID).To(BeZero())
+			})
+
+			It("doesn't retry unrecognized diffs if no new preimages were recorded", func() {
+				err := storageWatcher.Execute()
+
+				Expect(err).To(MatchError(fakes.FakeError))
+				Expect(recorder.RecordPreimagesCalled).To(BeTrue())
+				Expect(mockDiffsRepository.GetUnrecognizedDiffsPassedMinIDs).To(BeEmpty())
+			})
+		})
+
 		Describe("when diff's address is watched", func() {
 			var (
 				hashedAddress   common.Hash
@@ -388,6 +554,41 @@ var _ = Describe("Storage Watcher", func() {
 					Expect(err).To(MatchError(fakes.FakeError))
 					Expect(mockDiffsRepository.MarkCheckedPassedID).To(Equal(fakePersistedDiff.ID))
 				})
+
+				It("records the diffs transformed for the contract", func() {
+					mockTransformer.Address = test_data.FakeAddress()
+					fakePersistedDiff.BlockHeight = 10
+					mockDiffsRepository.GetNewDiffsDiffs = []types.PersistedDiff{fakePersistedDiff}
+					mockDiffsRepository.GetNewDiffsErrors = []error{nil, fakes.FakeError}
+					mockHeaderRepository.MostRecentHeaderBlockNumber = 15
+
+					err := storageWatcher.Execute()
+
+					Expect(err).To(MatchError(fakes.FakeError))
+					stats, statsErr := storageWatcher.Stats()
+					Expect(statsErr).NotTo(HaveOccurred())
+					Expect(len(stats)).To(Equal(1))
+					Expect(stats[0].Address).To(Equal(mockTransformer.Address))
+					Expect(stats[0].HashedAddress).To(Equal(hashedAddress))
+					Expect(stats[0].Transformed).To(Equal(int64(1)))
+					Expect(stats[0].LastBlockHeight).To(Equal(10))
+					Expect(stats[0].Lag).To(Equal(int64(5)))
+				})
+
+				It("doesn't count retries of unrecognized diffs as unrecognized again", func() {
+					mockTransformer.ExecuteErr = types.ErrKeyNotFound
+					fakePersistedDiff.Status = sharedStorage.Unrecognized
+					mockDiffsRepository.GetNewDiffsDiffs = []types.PersistedDiff{fakePersistedDiff}
+					mockDiffsRepository.GetNewDiffsErrors = []error{nil, fakes.FakeError}
+
+					err := storageWatcher.Execute()
+
+					Expect(err).To(MatchError(fakes.FakeError))
+					stats, statsErr := storageWatcher.Stats()
+					Expect(statsErr).NotTo(HaveOccurred())
+					Expect(len(stats)).To(Equal(1))
+					Expect(stats[0].Unrecognized).To(BeZero())
+				})
 			})
 		})
 	})
@@ -16,6 +16,177 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	IPCTransport  = "ipc"
+	HTTPTransport = "http"
+	WSTransport   = "ws"
+)
+
+var (
+	ErrMissingClientEndpoint = errors.New("client config requires a url or ipcPath")
+	ErrHeadersOverWebsocket  = errors.New("custom headers and bearer tokens are not supported over websocket; use basic auth or an http endpoint")
+	ErrHeadersOverIPC        = errors.New("auth headers are not supported over ipc")
+)
+
+// Client describes how to reach an Ethereum node's JSON-RPC endpoint.
+// IPCPath is kept for backwards compatibility; URL takes precedence when both are set.
+// When Endpoints is set, each entry is dialed and requests fail over between them.
 type Client struct {
-	IPCPath string
+	IPCPath           string
+	URL               string
+	Transport         string
+	Headers           map[string]string
+	BearerToken       string
+	BasicAuthUser     string
+	BasicAuthPassword string
+	Timeout           time.Duration
+	TLS               ClientTLS
+	NodeID            string
+	RequestsPerSecond float64
+	MaxBatchSize      int
+	Endpoints         []Client
+}
+
+type ClientTLS struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+func (c Client) Endpoint() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return c.IPCPath
+}
+
+// TransportType returns the configured transport, inferring it from the endpoint's scheme when unset
+func (c Client) TransportType() (string, error) {
+	transport := strings.ToLower(c.Transport)
+	switch transport {
+	case IPCTransport, HTTPTransport, WSTransport:
+		return transport, nil
+	case "":
+		return inferTransport(c.Endpoint()), nil
+	default:
+		return "", fmt.Errorf("unsupported client transport %q: expected ipc, http or ws", c.Transport)
+	}
+}
+
+func inferTransport(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return IPCTransport
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return HTTPTransport
+	case "ws", "wss":
+		return WSTransport
+	default:
+		return IPCTransport
+	}
+}
+
+// Host returns the endpoint's host and port, without credentials, path or query.
+// It is empty for IPC endpoints.
+func (c Client) Host() string {
+	transport, err := c.TransportType()
+	if err != nil || transport == IPCTransport {
+		return ""
+	}
+	u, err := url.Parse(c.Endpoint())
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// StableNodeID identifies the node without depending on the raw endpoint string, since hosted providers
+// commonly embed API keys in the URL path. An explicitly configured NodeID always wins.
+func (c Client) StableNodeID() string {
+	if c.NodeID != "" {
+		return c.NodeID
+	}
+	if host := c.Host(); host != "" {
+		return host
+	}
+	return IPCTransport
+}
+
+// LegacyNodeID is the ID releases before StableNodeID recorded for the node: the configured ipcPath verbatim. It is
+// empty when there was none or it matches StableNodeID.
+func (c Client) LegacyNodeID() string {
+	if c.IPCPath == c.StableNodeID() {
+		return ""
+	}
+	return c.IPCPath
+}
+
+// AuthHeaders returns the headers to attach to every HTTP request
+func (c Client) AuthHeaders() http.Header {
+	headers := http.Header{}
+	for key, value := range c.Headers {
+		headers.Set(key, value)
+	}
+	if c.BearerToken != "" {
+		headers.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	return headers
+}
+
+func (c Client) hasBasicAuth() bool {
+	return c.BasicAuthUser != "" || c.BasicAuthPassword != ""
+}
+
+func (c Client) Validate() error {
+	if len(c.Endpoints) > 0 {
+		for i, endpoint := range c.Endpoints {
+			if len(endpoint.Endpoints) > 0 {
+				return fmt.Errorf("client endpoint %d: nested endpoints are not supported", i)
+			}
+			if err := endpoint.Validate(); err != nil {
+				return fmt.Errorf("client endpoint %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	if c.Endpoint() == "" {
+		return ErrMissingClientEndpoint
+	}
+	transport, err := c.TransportType()
+	if err != nil {
+		return err
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("client timeout must not be negative, got %s", c.Timeout)
+	}
+	if c.RequestsPerSecond < 0 || c.MaxBatchSize < 0 {
+		return errors.New("client requestsPerSecond and maxBatchSize must not be negative")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("client TLS certFile and keyFile must be set together")
+	}
+	hasHeaders := len(c.Headers) > 0 || c.BearerToken != ""
+	switch transport {
+	case WSTransport:
+		if hasHeaders {
+			return ErrHeadersOverWebsocket
+		}
+	case IPCTransport:
+		if hasHeaders || c.hasBasicAuth() {
+			return ErrHeadersOverIPC
+		}
+	}
+	return nil
 }
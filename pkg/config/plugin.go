@@ -39,6 +39,24 @@ type Transformer struct {
 	MigrationPath  string
 	MigrationRank  uint64
 	RepositoryPath string
+	ABIEvent       ABIEvent // Optional; set for eth_event transformers built from a contract ABI instead of Path
+}
+
+// ABIEvent configures an eth_event transformer that stores an event of a contract ABI with event.NewABITransformer,
+// so that it needs no transformer code of its own
+type ABIEvent struct {
+	AbiPath           string
+	EventName         string
+	SchemaName        string
+	TableName         string
+	ContractAddresses []string
+	StartingBlock     int64
+	EndingBlock       int64
+}
+
+// IsABIEvent reports whether the transformer is built from an event of a contract ABI rather than imported from Path
+func (transformer Transformer) IsABIEvent() bool {
+	return transformer.ABIEvent.AbiPath != ""
 }
 
 func (pluginConfig *Plugin) GetPluginPaths() (string, string, error) {
@@ -239,3 +239,18 @@ var _ = Describe("GetMigrationsPaths", func() {
 		Expect(err.Error()).To(ContainSubstring("duplicate paths with different ranks present"))
 	})
 })
+
+var _ = Describe("IsABIEvent", func() {
+	It("is true for transformers configured with an abi path", func() {
+		transformer := config.Transformer{
+			Type:     config.EthEvent,
+			ABIEvent: config.ABIEvent{AbiPath: "test/abi/path.json", EventName: "Transfer"},
+		}
+
+		Expect(transformer.IsABIEvent()).To(BeTrue())
+	})
+
+	It("is false for transformers imported from a path", func() {
+		Expect(allDifferentPathsConfig.Transformers["transformer1"].IsABIEvent()).To(BeFalse())
+	})
+})
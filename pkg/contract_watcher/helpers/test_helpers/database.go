@@ -18,7 +18,6 @@ package test_helpers
 
 import (
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/makerdao/vulcanizedb/pkg/config"
 	"github.com/makerdao/vulcanizedb/pkg/contract_watcher/constants"
 	"github.com/makerdao/vulcanizedb/pkg/contract_watcher/contract"
@@ -57,10 +56,9 @@ type NewOwnerLog struct {
 
 func SetupDBandBC() (*postgres.DB, core.BlockChain) {
 	con := test_config.TestClient
-	testIPC := con.IPCPath
-	rawRPCClient, err := rpc.Dial(testIPC)
+	rawRPCClient, err := client.Dial(con)
 	Expect(err).NotTo(HaveOccurred())
-	rpcClient := client.NewRpcClient(rawRPCClient, testIPC)
+	rpcClient := client.NewRpcClient(rawRPCClient, con)
 	ethClient := ethclient.NewClient(rawRPCClient)
 	blockChainClient := client.NewEthClient(ethClient)
 	madeNode := node.MakeNode(rpcClient)
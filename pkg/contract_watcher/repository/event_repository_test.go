@@ -122,7 +122,7 @@ var _ = Describe("Repository", func() {
 		var err error
 
 		BeforeEach(func() {
-			headerRepository := repositories.NewHeaderRepository(db)
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 			headerID, err = headerRepository.CreateOrUpdateHeader(mocks.MockHeader1)
 			Expect(err).ToNot(HaveOccurred())
 			c := converter.NewConverter()
@@ -45,7 +45,7 @@ var _ = Describe("Repository", func() {
 	BeforeEach(func() {
 		db, _ = test_helpers.SetupDBandBC()
 		contractHeaderRepo = repository.NewHeaderRepository(db)
-		coreHeaderRepo = repositories.NewHeaderRepository(db)
+		coreHeaderRepo = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 	})
 
 	AfterEach(func() {
@@ -36,7 +36,7 @@ var _ = Describe("Block Retriever", func() {
 
 	BeforeEach(func() {
 		test_config.CleanTestDB(db)
-		headerRepository = repositories.NewHeaderRepository(db)
+		headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 		r = retriever.NewBlockRetriever(db)
 	})
 
@@ -1,8 +1,37 @@
 package core
 
+import (
+	"fmt"
+)
+
 type BatchElem struct {
 	Method string
 	Args   []interface{}
 	Result interface{}
 	Error  error
 }
+
+// FailedBatchElem identifies a batch element the node answered with an error
+type FailedBatchElem struct {
+	Index  int
+	Method string
+	Args   []interface{}
+	Err    error
+}
+
+// PartialBatchError is returned when some elements of a batch still failed after being retried.
+// Results for the failed elements must not be used.
+type PartialBatchError struct {
+	Failed []FailedBatchElem
+	Total  int
+}
+
+func (e *PartialBatchError) Error() string {
+	first := e.Failed[0]
+	return fmt.Sprintf("%d of %d batch requests failed, first failure: %s %v: %s",
+		len(e.Failed), e.Total, first.Method, first.Args, first.Err.Error())
+}
+
+func (e *PartialBatchError) Unwrap() error {
+	return e.Failed[0].Err
+}
@@ -30,9 +30,11 @@ type BlockChain interface {
 	GetHeaderByNumber(blockNumber int64) (Header, error)
 	GetHeadersByNumbers(blockNumbers []int64) ([]Header, error)
 	GetTransactions(transactionHashes []common.Hash) ([]TransactionModel, error)
+	GetTransactionReceipts(transactionHashes []common.Hash) ([]Receipt, error)
 	LastBlock() (*big.Int, error)
 	BatchGetStorageAt(account common.Address, keys []common.Hash, blockNumber *big.Int) (map[common.Hash][]byte, error)
 	Node() Node
+	SubscribeNewHeads(heads chan NewHead) (Subscription, error)
 }
 
 type ContractDataFetcher interface {
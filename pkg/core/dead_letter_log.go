@@ -0,0 +1,35 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import "time"
+
+// DeadLetterLog records an event log that a transformer failed to transform, along with the most recent error
+type DeadLetterLog struct {
+	ID              int64
+	EventLogID      int64  `db:"event_log_id"`
+	TransformerName string `db:"transformer_name"`
+	Error           string
+	Attempts        int
+	Discarded       bool
+	BlockNumber     int64  `db:"block_number"`
+	TxHash          string `db:"tx_hash"`
+	LogIndex        uint   `db:"log_index"`
+	Created         time.Time
+	Updated         time.Time
+	NextAttemptAt   time.Time `db:"next_attempt_at"`
+}
@@ -26,10 +26,17 @@ type Header struct {
 	Id          int64
 	BlockNumber int64 `db:"block_number"`
 	Hash        string
+	ParentHash  string `db:"parent_hash"`
 	Raw         []byte
 	Timestamp   string `db:"block_timestamp"`
 }
 
+// NewHead is the part of an eth_subscribe("newHeads") notification needed to fetch the announced header
+type NewHead struct {
+	Number *hexutil.Big `json:"number"`
+	Hash   common.Hash  `json:"hash"`
+}
+
 type POAHeader struct {
 	ParentHash  common.Hash    `json:"parentHash"       gencodec:"required"`
 	UncleHash   common.Hash    `json:"sha3Uncles"       gencodec:"required"`
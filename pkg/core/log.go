@@ -31,5 +31,5 @@ type EventLog struct {
 	ID          int64
 	HeaderID    int64 `db:"header_id"`
 	Log         types.Log
-	Transformed bool
+	Transformed bool // Only set for logs transformed before transformations were recorded per transformer
 }
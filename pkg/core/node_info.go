@@ -33,11 +33,14 @@ const (
 	KOVAN_NETWORK_ID = 42
 )
 
+// Node identifies the node data is synced from. LegacyID is the ID an earlier release recorded for the same node, if it
+// differs from ID; the node's existing eth_nodes row is then renamed to ID rather than a new one being created.
 type Node struct {
 	GenesisBlock string
 	NetworkID    float64
 	ID           string
 	ClientName   string
+	LegacyID     string
 }
 
 type ParityNodeInfo struct {
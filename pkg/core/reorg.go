@@ -0,0 +1,55 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+// ReorgEvent describes a branch of stored headers that was found to be orphaned and replaced with the canonical chain
+type ReorgEvent struct {
+	CommonAncestorBlockNumber int64
+	Depth                     int
+	ReplacedHeaders           []ReplacedHeader
+}
+
+type ReplacedHeader struct {
+	BlockNumber int64
+	OldHeaderID int64
+	OldHash     string
+	NewHash     string
+}
+
+// OrphanedHeader records a header that was deleted when a reorg replaced it
+type OrphanedHeader struct {
+	ID          int64
+	HeaderID    int64 `db:"header_id"`
+	BlockNumber int64 `db:"block_number"`
+	Hash        string
+}
+
+func (event ReorgEvent) OldHashes() []string {
+	hashes := make([]string, len(event.ReplacedHeaders))
+	for i, replaced := range event.ReplacedHeaders {
+		hashes[i] = replaced.OldHash
+	}
+	return hashes
+}
+
+func (event ReorgEvent) NewHashes() []string {
+	hashes := make([]string, len(event.ReplacedHeaders))
+	for i, replaced := range event.ReplacedHeaders {
+		hashes[i] = replaced.NewHash
+	}
+	return hashes
+}
@@ -23,6 +23,8 @@ import (
 type RpcClient interface {
 	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
 	BatchCall(batch []BatchElem) error
-	IpcPath() string
+	BatchCallContext(ctx context.Context, batch []BatchElem) error
+	NodeID() string
+	Host() string
 	Subscribe(namespace string, payloadChan interface{}, args ...interface{}) (Subscription, error)
 }
@@ -16,31 +16,86 @@
 
 package core
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	LegacyTxType     = 0
+	AccessListTxType = 1
+	DynamicFeeTxType = 2
+)
+
+// TransactionModel holds big values as decimal strings; fields that don't apply to a transaction's type are empty.
+// EffectiveGasPrice is the price per gas actually paid, as reported by the node for mined transactions.
 type TransactionModel struct {
-	Data     []byte `db:"input_data"`
-	From     string `db:"tx_from"`
-	GasLimit uint64 `db:"gas_limit"`
-	GasPrice int64  `db:"gas_price"`
-	Hash     string
-	Nonce    uint64
-	Raw      []byte `db:"raw"`
+	AccessList           AccessList `db:"access_list"`
+	ChainID              string     `db:"chain_id"`
+	Data                 []byte     `db:"input_data"`
+	EffectiveGasPrice    string     `db:"effective_gas_price"`
+	From                 string     `db:"tx_from"`
+	GasLimit             uint64     `db:"gas_limit"`
+	GasPrice             string     `db:"gas_price"`
+	Hash                 string
+	MaxFeePerGas         string `db:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string `db:"max_priority_fee_per_gas"`
+	Nonce                uint64
+	Raw                  []byte `db:"raw"`
 	Receipt
 	To      string `db:"tx_to"`
 	TxIndex int64  `db:"tx_index"`
+	Type    uint8  `db:"tx_type"`
 	Value   string
 }
 
+// AccessList is the EIP-2930 list of addresses and storage keys a transaction declares it will access
+type AccessList []AccessTuple
+
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// Value stores the access list as JSON, or NULL when the transaction has none
+func (accessList AccessList) Value() (driver.Value, error) {
+	if accessList == nil {
+		return nil, nil
+	}
+	return json.Marshal(accessList)
+}
+
+func (accessList *AccessList) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*accessList = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, accessList)
+	case string:
+		return json.Unmarshal([]byte(value), accessList)
+	default:
+		return fmt.Errorf("cannot scan %T into access list", src)
+	}
+}
+
 type RpcTransaction struct {
-	Nonce            string `json:"nonce"`
-	GasPrice         string `json:"gasPrice"`
-	GasLimit         string `json:"gas"`
-	Recipient        string `json:"to"`
-	Amount           string `json:"value"`
-	Payload          string `json:"input"`
-	V                string `json:"v"`
-	R                string `json:"r"`
-	S                string `json:"s"`
-	Hash             string
-	From             string
-	TransactionIndex string `json:"transactionIndex"`
+	Type                 string     `json:"type"`
+	ChainID              string     `json:"chainId"`
+	Nonce                string     `json:"nonce"`
+	GasPrice             string     `json:"gasPrice"`
+	MaxFeePerGas         string     `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string     `json:"maxPriorityFeePerGas"`
+	GasLimit             string     `json:"gas"`
+	Recipient            string     `json:"to"`
+	Amount               string     `json:"value"`
+	Payload              string     `json:"input"`
+	AccessList           AccessList `json:"accessList"`
+	V                    string     `json:"v"`
+	R                    string     `json:"r"`
+	S                    string     `json:"s"`
+	Hash                 string
+	From                 string
+	TransactionIndex     string `json:"transactionIndex"`
 }
@@ -0,0 +1,35 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+// WatchedLog is a contract address and topics whose logs are extracted.
+// Headers from NextBackFillBlock through BackFillEndingBlock were checked before the log was watched, and still need
+// to be checked for it.
+type WatchedLog struct {
+	ID                  int64
+	ContractAddress     string `db:"contract_address"`
+	TopicZero           string `db:"topic_zero"`
+	TopicOne            string `db:"topic_one"`
+	TopicTwo            string `db:"topic_two"`
+	TopicThree          string `db:"topic_three"`
+	NextBackFillBlock   int64  `db:"next_backfill_block"`
+	BackFillEndingBlock int64  `db:"backfill_ending_block"`
+}
+
+func (log WatchedLog) Topics() Topics {
+	return Topics{log.TopicZero, log.TopicOne, log.TopicTwo, log.TopicThree}
+}
@@ -44,6 +44,12 @@ func NewDB(databaseConfig config.Database, node core.Node) (*DB, error) {
 }
 
 func (db *DB) CreateNode(node *core.Node) error {
+	if node.LegacyID != "" {
+		adoptErr := db.adoptLegacyNode(node)
+		if adoptErr != nil {
+			return ErrUnableToSetNode(adoptErr)
+		}
+	}
 	var nodeID int64
 	err := db.QueryRow(
 		`WITH nodeID AS (
@@ -62,3 +68,14 @@ func (db *DB) CreateNode(node *core.Node) error {
 	db.NodeID = nodeID
 	return nil
 }
+
+// adoptLegacyNode renames the eth_nodes row recorded under the node's legacy ID to its current ID, so that data synced
+// before the ID changed stays attached to the node. A row already recorded under the current ID is left alone.
+func (db *DB) adoptLegacyNode(node *core.Node) error {
+	_, err := db.Exec(`UPDATE eth_nodes SET eth_node_id = $3
+		WHERE genesis_block = $1 AND network_id = $2 AND eth_node_id = $4 AND client_name = $5
+		  AND NOT EXISTS(SELECT 1 FROM eth_nodes
+		                 WHERE genesis_block = $1 AND network_id = $2 AND eth_node_id = $3 AND client_name = $5)`,
+		node.GenesisBlock, node.NetworkID, node.ID, node.LegacyID, node.ClientName)
+	return err
+}
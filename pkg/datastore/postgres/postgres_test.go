@@ -89,6 +89,40 @@ var _ = Describe("Postgres DB", func() {
 		Expect(err.Error()).To(ContainSubstring(postgres.DbConnectionFailedMsg))
 	})
 
+	Describe("when the node was recorded under a legacy ID", func() {
+		var (
+			legacyNode core.Node
+			legacyDB   *postgres.DB
+		)
+
+		BeforeEach(func() {
+			legacyNode = test_config.NewTestNode()
+			var err error
+			legacyDB, err = postgres.NewDB(test_config.DBConfig, legacyNode)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			legacyDB.MustExec(`DELETE FROM eth_nodes WHERE id = $1`, legacyDB.NodeID)
+			Expect(legacyDB.Close()).To(Succeed())
+		})
+
+		It("renames the existing node to the current ID", func() {
+			node := legacyNode
+			node.ID, node.LegacyID = test_config.NewTestNode().ID, legacyNode.ID
+
+			db, err := postgres.NewDB(test_config.DBConfig, node)
+
+			Expect(err).NotTo(HaveOccurred())
+			defer db.Close()
+			Expect(db.NodeID).To(Equal(legacyDB.NodeID))
+			var nodeID string
+			getErr := db.Get(&nodeID, `SELECT eth_node_id FROM eth_nodes WHERE id = $1`, db.NodeID)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(nodeID).To(Equal(node.ID))
+		})
+	})
+
 	It("throws error when can't create node", func() {
 		badHash := fmt.Sprintf("x %s", strings.Repeat("1", 100))
 		node := core.Node{GenesisBlock: badHash, NetworkID: 1, ID: "x123", ClientName: "geth"}
@@ -0,0 +1,47 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories
+
+import (
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+type BackFillProgressRepository struct {
+	db *postgres.DB
+}
+
+func NewBackFillProgressRepository(db *postgres.DB) BackFillProgressRepository {
+	return BackFillProgressRepository{db: db}
+}
+
+// GetCompletedRanges returns the block ranges already back-filled for the watched logs identified by the hash
+func (repository BackFillProgressRepository) GetCompletedRanges(watchedLogsHash string) ([]core.BlockRange, error) {
+	var ranges []core.BlockRange
+	err := repository.db.Select(&ranges,
+		`SELECT starting_block, ending_block FROM public.backfill_progress WHERE watched_logs_hash = $1 ORDER BY starting_block ASC`,
+		watchedLogsHash)
+	return ranges, err
+}
+
+// MarkRangeComplete records that logs in the block range have been back-filled for the watched logs identified by the hash
+func (repository BackFillProgressRepository) MarkRangeComplete(watchedLogsHash string, blockRange core.BlockRange) error {
+	_, err := repository.db.Exec(
+		`INSERT INTO public.backfill_progress (watched_logs_hash, starting_block, ending_block) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, watchedLogsHash, blockRange.StartingBlock, blockRange.EndingBlock)
+	return err
+}
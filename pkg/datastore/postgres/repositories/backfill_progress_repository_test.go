@@ -0,0 +1,78 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories_test
+
+import (
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Back-fill progress repository", func() {
+	var (
+		db         *postgres.DB
+		repository datastore.BackFillProgressRepository
+		hash       = fakes.FakeHash.Hex()
+		blockRange = core.BlockRange{StartingBlock: 1, EndingBlock: 1000}
+	)
+
+	BeforeEach(func() {
+		db = test_config.NewTestDB(test_config.NewTestNode())
+		test_config.CleanTestDB(db)
+		repository = repositories.NewBackFillProgressRepository(db)
+	})
+
+	AfterEach(func() {
+		closeErr := db.Close()
+		Expect(closeErr).NotTo(HaveOccurred())
+	})
+
+	It("returns ranges marked complete for the watched logs", func() {
+		err := repository.MarkRangeComplete(hash, blockRange)
+
+		Expect(err).NotTo(HaveOccurred())
+		completedRanges, getErr := repository.GetCompletedRanges(hash)
+		Expect(getErr).NotTo(HaveOccurred())
+		Expect(completedRanges).To(ConsistOf(blockRange))
+	})
+
+	It("does not return ranges completed for other watched logs", func() {
+		err := repository.MarkRangeComplete(hash, blockRange)
+
+		Expect(err).NotTo(HaveOccurred())
+		completedRanges, getErr := repository.GetCompletedRanges(fakes.AnotherFakeHash.Hex())
+		Expect(getErr).NotTo(HaveOccurred())
+		Expect(completedRanges).To(BeEmpty())
+	})
+
+	It("ignores ranges marked complete more than once", func() {
+		err := repository.MarkRangeComplete(hash, blockRange)
+		Expect(err).NotTo(HaveOccurred())
+
+		repeatErr := repository.MarkRangeComplete(hash, blockRange)
+
+		Expect(repeatErr).NotTo(HaveOccurred())
+		completedRanges, getErr := repository.GetCompletedRanges(hash)
+		Expect(getErr).NotTo(HaveOccurred())
+		Expect(completedRanges).To(HaveLen(1))
+	})
+})
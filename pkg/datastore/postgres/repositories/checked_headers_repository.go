@@ -17,6 +17,7 @@
 package repositories
 
 import (
+	"github.com/lib/pq"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 )
@@ -39,6 +40,12 @@ func (repo CheckedHeadersRepository) MarkHeaderChecked(headerID int64) error {
 	return err
 }
 
+// Record at least one check for each header, without consuming rechecks of headers already checked
+func (repo CheckedHeadersRepository) MarkHeadersChecked(headerIDs []int64) error {
+	_, err := repo.db.Exec(`UPDATE public.headers SET check_count = 1 WHERE id = ANY($1) AND check_count < 1`, pq.Array(headerIDs))
+	return err
+}
+
 // Zero out check count for header with the given block number
 func (repo CheckedHeadersRepository) MarkSingleHeaderUnchecked(blockNumber int64) error {
 	_, err := repo.db.Exec(`UPDATE public.headers SET check_count = 0 WHERE block_number = $1`, blockNumber)
@@ -41,7 +41,7 @@ var _ = Describe("Checked Headers repository", func() {
 
 	Describe("MarkHeaderChecked", func() {
 		It("marks passed header as checked on insert", func() {
-			headerRepository := repositories.NewHeaderRepository(db)
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 			headerID, headerErr := headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
 			Expect(headerErr).NotTo(HaveOccurred())
 
@@ -55,7 +55,7 @@ var _ = Describe("Checked Headers repository", func() {
 		})
 
 		It("increments check count on update", func() {
-			headerRepository := repositories.NewHeaderRepository(db)
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 			headerID, headerErr := headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
 			Expect(headerErr).NotTo(HaveOccurred())
 
@@ -72,6 +72,40 @@ var _ = Describe("Checked Headers repository", func() {
 		})
 	})
 
+	Describe("MarkHeadersChecked", func() {
+		It("records one check for unchecked headers", func() {
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+			headerID, headerErr := headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
+			Expect(headerErr).NotTo(HaveOccurred())
+
+			err := repo.MarkHeadersChecked([]int64{headerID})
+
+			Expect(err).NotTo(HaveOccurred())
+			var checkedCount int
+			fetchErr := db.Get(&checkedCount, `SELECT check_count FROM public.headers WHERE id = $1`, headerID)
+			Expect(fetchErr).NotTo(HaveOccurred())
+			Expect(checkedCount).To(Equal(1))
+		})
+
+		It("does not change the check count of headers already checked", func() {
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+			headerID, headerErr := headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
+			Expect(headerErr).NotTo(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				markErr := repo.MarkHeaderChecked(headerID)
+				Expect(markErr).NotTo(HaveOccurred())
+			}
+
+			err := repo.MarkHeadersChecked([]int64{headerID})
+
+			Expect(err).NotTo(HaveOccurred())
+			var checkedCount int
+			fetchErr := db.Get(&checkedCount, `SELECT check_count FROM public.headers WHERE id = $1`, headerID)
+			Expect(fetchErr).NotTo(HaveOccurred())
+			Expect(checkedCount).To(Equal(2))
+		})
+	})
+
 	Describe("MarkSingleHeaderUnchecked", func() {
 		It("marks headers with matching block number as unchecked", func() {
 			blockNumberOne := rand.Int63()
@@ -80,7 +114,7 @@ var _ = Describe("Checked Headers repository", func() {
 			fakeHeaderOne := fakes.GetFakeHeader(blockNumberOne)
 			fakeHeaderTwo := fakes.GetFakeHeader(blockNumberTwo)
 			fakeHeaderThree := fakes.GetFakeHeader(blockNumberThree)
-			headerRepository := repositories.NewHeaderRepository(db)
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 			// insert three headers with incrementing block number
 			headerIdOne, insertHeaderOneErr := headerRepository.CreateOrUpdateHeader(fakeHeaderOne)
 			Expect(insertHeaderOneErr).NotTo(HaveOccurred())
@@ -130,7 +164,7 @@ var _ = Describe("Checked Headers repository", func() {
 		)
 
 		BeforeEach(func() {
-			headerRepository = repositories.NewHeaderRepository(db)
+			headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 
 			lastBlock = rand.Int63()
 			thirdBlock = lastBlock - 15
@@ -225,7 +259,7 @@ var _ = Describe("Checked Headers repository", func() {
 
 			It("only returns headers associated with any node", func() {
 				dbTwo := test_config.NewTestDB(core.Node{ID: "second"})
-				headerRepositoryTwo := repositories.NewHeaderRepository(dbTwo)
+				headerRepositoryTwo := repositories.NewHeaderRepository(dbTwo, repositories.DefaultHeaderReorgWindow)
 				repoTwo := repositories.NewCheckedHeadersRepository(dbTwo)
 				for _, n := range blockNumbers {
 					_, err = headerRepositoryTwo.CreateOrUpdateHeader(fakes.GetFakeHeader(n + 10))
@@ -320,7 +354,7 @@ var _ = Describe("Checked Headers repository", func() {
 
 			It("returns headers associated with any node", func() {
 				dbTwo := test_config.NewTestDB(core.Node{ID: "second"})
-				headerRepositoryTwo := repositories.NewHeaderRepository(dbTwo)
+				headerRepositoryTwo := repositories.NewHeaderRepository(dbTwo, repositories.DefaultHeaderReorgWindow)
 				repoTwo := repositories.NewCheckedHeadersRepository(dbTwo)
 				for _, n := range blockNumbers {
 					_, err = headerRepositoryTwo.CreateOrUpdateHeader(fakes.GetFakeHeader(n + 10))
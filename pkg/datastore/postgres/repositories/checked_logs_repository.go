@@ -17,6 +17,10 @@
 package repositories
 
 import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 	"github.com/sirupsen/logrus"
 )
@@ -29,11 +33,42 @@ func NewCheckedLogsRepository(db *postgres.DB) CheckedLogsRepository {
 	return CheckedLogsRepository{db: db}
 }
 
-// Return whether a given address + topic0 has been fetched on a previous run of vDB
-func (repository CheckedLogsRepository) AlreadyWatchingLog(addresses []string, topic0 string) (bool, error) {
+// Return whether a given address + topics have been fetched on a previous run of vDB.
+// Logs without topic1-3 filters are fetched for every combination of watched address and topic0, so a row without
+// filters for each address and for the topic0 suffices. Logs with filters were only fetched by an identical filter.
+func (repository CheckedLogsRepository) AlreadyWatchingLog(addresses []string, topics core.Topics) (bool, error) {
+	watchedWithoutFilters, unfilteredErr := repository.alreadyWatchingWithoutFilters(addresses, topics[0])
+	if unfilteredErr != nil || watchedWithoutFilters {
+		return watchedWithoutFilters, unfilteredErr
+	}
+	if !hasTopicFilters(topics) {
+		return false, nil
+	}
+
+	for _, address := range addresses {
+		var filterExists bool
+		getFilterExistsErr := repository.db.Get(&filterExists, `SELECT EXISTS(SELECT 1 FROM public.watched_logs
+			WHERE contract_address = $1
+			  AND topic_zero = $2
+			  AND topic_one IS NOT DISTINCT FROM $3
+			  AND topic_two IS NOT DISTINCT FROM $4
+			  AND topic_three IS NOT DISTINCT FROM $5)`,
+			address, topics[0], nullIfEmpty(topics[1]), nullIfEmpty(topics[2]), nullIfEmpty(topics[3]))
+		if getFilterExistsErr != nil {
+			return false, getFilterExistsErr
+		}
+		if !filterExists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (repository CheckedLogsRepository) alreadyWatchingWithoutFilters(addresses []string, topic0 string) (bool, error) {
 	for _, address := range addresses {
 		var addressExists bool
-		getAddressExistsErr := repository.db.Get(&addressExists, `SELECT EXISTS(SELECT 1 FROM public.watched_logs WHERE contract_address = $1)`, address)
+		getAddressExistsErr := repository.db.Get(&addressExists, `SELECT EXISTS(SELECT 1 FROM public.watched_logs
+			WHERE contract_address = $1 AND topic_one IS NULL AND topic_two IS NULL AND topic_three IS NULL)`, address)
 		if getAddressExistsErr != nil {
 			return false, getAddressExistsErr
 		}
@@ -42,21 +77,36 @@ func (repository CheckedLogsRepository) AlreadyWatchingLog(addresses []string, t
 		}
 	}
 	var topicZeroExists bool
-	getTopicZeroExistsErr := repository.db.Get(&topicZeroExists, `SELECT EXISTS(SELECT 1 FROM public.watched_logs WHERE topic_zero = $1)`, topic0)
+	getTopicZeroExistsErr := repository.db.Get(&topicZeroExists, `SELECT EXISTS(SELECT 1 FROM public.watched_logs
+		WHERE topic_zero = $1 AND topic_one IS NULL AND topic_two IS NULL AND topic_three IS NULL)`, topic0)
 	if getTopicZeroExistsErr != nil {
 		return false, getTopicZeroExistsErr
 	}
 	return topicZeroExists, nil
 }
 
-// Persist that a given address + topic0 has is being fetched on this run of vDB
-func (repository CheckedLogsRepository) MarkLogWatched(addresses []string, topic0 string) error {
+// Persist that a given address + topics are being fetched on this run of vDB.
+// Headers from startingBlock that were already checked for other logs are recorded as needing a back-fill for these.
+func (repository CheckedLogsRepository) MarkLogWatched(addresses []string, topics core.Topics, startingBlock, endingBlock int64) error {
 	tx, txErr := repository.db.Beginx()
 	if txErr != nil {
 		return txErr
 	}
+	var backFillEndingBlock sql.NullInt64
+	getEndingBlockErr := tx.Get(&backFillEndingBlock, `SELECT MAX(block_number) FROM public.headers
+		WHERE check_count > 0 AND ($1::BIGINT = -1 OR block_number <= $1::BIGINT)`, endingBlock)
+	if getEndingBlockErr != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			logrus.Errorf("error rolling back transaction inserting checked logs: %s", rollbackErr.Error())
+		}
+		return getEndingBlockErr
+	}
 	for _, address := range addresses {
-		_, insertErr := tx.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero) VALUES ($1, $2)`, address, topic0)
+		_, insertErr := tx.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero, topic_one, topic_two, topic_three,
+			next_backfill_block, backfill_ending_block) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			address, topics[0], nullIfEmpty(topics[1]), nullIfEmpty(topics[2]), nullIfEmpty(topics[3]),
+			startingBlock, backFillEndingBlock)
 		if insertErr != nil {
 			rollbackErr := tx.Rollback()
 			if rollbackErr != nil {
@@ -67,3 +117,53 @@ func (repository CheckedLogsRepository) MarkLogWatched(addresses []string, topic
 	}
 	return tx.Commit()
 }
+
+// Return watched logs with already-checked headers that still need to be checked for them
+func (repository CheckedLogsRepository) GetLogsToBackFill() ([]core.WatchedLog, error) {
+	var watchedLogs []core.WatchedLog
+	err := repository.db.Select(&watchedLogs, `SELECT id, contract_address, topic_zero, COALESCE(topic_one, '') AS topic_one,
+			COALESCE(topic_two, '') AS topic_two, COALESCE(topic_three, '') AS topic_three,
+			next_backfill_block, backfill_ending_block
+		FROM public.watched_logs
+		WHERE next_backfill_block <= backfill_ending_block
+		ORDER BY id ASC`)
+	return watchedLogs, err
+}
+
+// Return every watched address + topics, without their back-fill progress
+func (repository CheckedLogsRepository) GetWatchedLogs() ([]core.WatchedLog, error) {
+	var watchedLogs []core.WatchedLog
+	err := repository.db.Select(&watchedLogs, `SELECT id, contract_address, topic_zero, COALESCE(topic_one, '') AS topic_one,
+			COALESCE(topic_two, '') AS topic_two, COALESCE(topic_three, '') AS topic_three
+		FROM public.watched_logs
+		ORDER BY id ASC`)
+	return watchedLogs, err
+}
+
+// Record that headers from fromBlock through throughBlock have been checked for the given watched logs. Logs whose
+// back-fill was moved to before fromBlock in the meantime are left to be back-filled from there.
+func (repository CheckedLogsRepository) MarkLogsBackFilledThrough(watchedLogIDs []int64, fromBlock, throughBlock int64) error {
+	_, err := repository.db.Exec(`UPDATE public.watched_logs SET next_backfill_block = $3
+		WHERE id = ANY($1) AND next_backfill_block = $2`, pq.Array(watchedLogIDs), fromBlock, throughBlock+1)
+	return err
+}
+
+// Record that headers in the range were marked checked without being checked for the given watched logs, so they
+// still need a back-fill for them. The range is merged into any back-fill the logs already need.
+func (repository CheckedLogsRepository) MarkLogsForBackFill(watchedLogIDs []int64, blockRange core.BlockRange) error {
+	_, err := repository.db.Exec(`UPDATE public.watched_logs
+		SET next_backfill_block   = CASE WHEN next_backfill_block <= backfill_ending_block
+		                                 THEN LEAST(next_backfill_block, $2) ELSE $2 END,
+		    backfill_ending_block = CASE WHEN next_backfill_block <= backfill_ending_block
+		                                 THEN GREATEST(backfill_ending_block, $3) ELSE $3 END
+		WHERE id = ANY($1)`, pq.Array(watchedLogIDs), blockRange.StartingBlock, blockRange.EndingBlock)
+	return err
+}
+
+func hasTopicFilters(topics core.Topics) bool {
+	return topics[1] != "" || topics[2] != "" || topics[3] != ""
+}
+
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
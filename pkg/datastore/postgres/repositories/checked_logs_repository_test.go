@@ -17,7 +17,10 @@
 package repositories_test
 
 import (
+	"database/sql"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
@@ -33,6 +36,7 @@ var _ = Describe("Checked logs repository", func() {
 		fakeAddress   = fakes.FakeAddress.Hex()
 		fakeAddresses = []string{fakeAddress}
 		fakeTopicZero = fakes.FakeHash.Hex()
+		fakeTopics    = core.Topics{fakeTopicZero}
 		repository    datastore.CheckedLogsRepository
 	)
 
@@ -52,7 +56,7 @@ var _ = Describe("Checked logs repository", func() {
 			_, insertErr := db.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero) VALUES ($1, $2)`, fakeAddress, fakeTopicZero)
 			Expect(insertErr).NotTo(HaveOccurred())
 
-			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopicZero)
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopics)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(hasBeenChecked).To(BeTrue())
@@ -68,7 +72,7 @@ var _ = Describe("Checked logs repository", func() {
 			_, insertTwoErr := db.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero) VALUES ($1, $2)`, anotherFakeAddress, fakeTopicZero)
 			Expect(insertTwoErr).NotTo(HaveOccurred())
 
-			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopicZero)
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopics)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(hasBeenChecked).To(BeTrue())
@@ -79,7 +83,7 @@ var _ = Describe("Checked logs repository", func() {
 			_, insertErr := db.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero) VALUES ($1, $2)`, fakeAddress, fakeTopicZero)
 			Expect(insertErr).NotTo(HaveOccurred())
 
-			hasBeenChecked, err := repository.AlreadyWatchingLog(append(fakeAddresses, anotherFakeAddress), fakeTopicZero)
+			hasBeenChecked, err := repository.AlreadyWatchingLog(append(fakeAddresses, anotherFakeAddress), fakeTopics)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(hasBeenChecked).To(BeFalse())
@@ -90,7 +94,55 @@ var _ = Describe("Checked logs repository", func() {
 			_, insertErr := db.Exec(`INSERT INTO public.watched_logs (contract_address, topic_zero) VALUES ($1, $2)`, fakeAddress, anotherFakeTopicZero)
 			Expect(insertErr).NotTo(HaveOccurred())
 
-			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopicZero)
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopics)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hasBeenChecked).To(BeFalse())
+		})
+	})
+
+	Describe("AlreadyWatchingLog with topic filters", func() {
+		var (
+			fakeTopicTwo   = fakes.AnotherFakeHash.Hex()
+			filteredTopics = core.Topics{fakeTopicZero, "", fakeTopicTwo}
+		)
+
+		It("returns true if the address, topic0 and topic filters are already present in the db", func() {
+			markErr := repository.MarkLogWatched(fakeAddresses, filteredTopics, 0, -1)
+			Expect(markErr).NotTo(HaveOccurred())
+
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, filteredTopics)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hasBeenChecked).To(BeTrue())
+		})
+
+		It("returns true if the address and topic0 are already watched without topic filters", func() {
+			markErr := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, -1)
+			Expect(markErr).NotTo(HaveOccurred())
+
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, filteredTopics)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hasBeenChecked).To(BeTrue())
+		})
+
+		It("returns false if the log was only watched with different topic filters", func() {
+			otherFilteredTopics := core.Topics{fakeTopicZero, fakeTopicTwo}
+			markErr := repository.MarkLogWatched(fakeAddresses, otherFilteredTopics, 0, -1)
+			Expect(markErr).NotTo(HaveOccurred())
+
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, filteredTopics)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hasBeenChecked).To(BeFalse())
+		})
+
+		It("returns false for a log without filters if only filtered logs were watched", func() {
+			markErr := repository.MarkLogWatched(fakeAddresses, filteredTopics, 0, -1)
+			Expect(markErr).NotTo(HaveOccurred())
+
+			hasBeenChecked, err := repository.AlreadyWatchingLog(fakeAddresses, fakeTopics)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(hasBeenChecked).To(BeFalse())
@@ -100,7 +152,7 @@ var _ = Describe("Checked logs repository", func() {
 	Describe("MarkLogWatched", func() {
 		It("adds a row for all of transformer's addresses + topic0", func() {
 			anotherFakeAddress := common.HexToAddress("0x" + fakes.RandomString(40)).Hex()
-			err := repository.MarkLogWatched(append(fakeAddresses, anotherFakeAddress), fakeTopicZero)
+			err := repository.MarkLogWatched(append(fakeAddresses, anotherFakeAddress), fakeTopics, 0, -1)
 
 			Expect(err).NotTo(HaveOccurred())
 			var comboOneExists, comboTwoExists bool
@@ -111,5 +163,155 @@ var _ = Describe("Checked logs repository", func() {
 			Expect(getComboTwoErr).NotTo(HaveOccurred())
 			Expect(comboTwoExists).To(BeTrue())
 		})
+
+		It("records the transformer's topic filters", func() {
+			topicTwo := fakes.AnotherFakeHash.Hex()
+			err := repository.MarkLogWatched(fakeAddresses, core.Topics{fakeTopicZero, "", topicTwo}, 0, -1)
+
+			Expect(err).NotTo(HaveOccurred())
+			var watchedLog struct {
+				TopicOne   sql.NullString `db:"topic_one"`
+				TopicTwo   sql.NullString `db:"topic_two"`
+				TopicThree sql.NullString `db:"topic_three"`
+			}
+			getErr := db.Get(&watchedLog, `SELECT topic_one, topic_two, topic_three FROM public.watched_logs WHERE contract_address = $1`, fakeAddress)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(watchedLog.TopicOne.Valid).To(BeFalse())
+			Expect(watchedLog.TopicTwo.String).To(Equal(topicTwo))
+			Expect(watchedLog.TopicThree.Valid).To(BeFalse())
+		})
+	})
+
+	Describe("back-filling newly watched logs", func() {
+		BeforeEach(func() {
+			headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+			checkedHeadersRepository := repositories.NewCheckedHeadersRepository(db)
+			for _, blockNumber := range []int64{1, 2, 3} {
+				headerID, createErr := headerRepository.CreateOrUpdateHeader(fakes.GetFakeHeader(blockNumber))
+				Expect(createErr).NotTo(HaveOccurred())
+				if blockNumber < 3 {
+					markErr := checkedHeadersRepository.MarkHeaderChecked(headerID)
+					Expect(markErr).NotTo(HaveOccurred())
+				}
+			}
+		})
+
+		It("records already-checked headers from the starting block as needing a back-fill", func() {
+			err := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, -1)
+
+			Expect(err).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			Expect(watchedLogs[0].ContractAddress).To(Equal(fakeAddress))
+			Expect(watchedLogs[0].Topics()).To(Equal(fakeTopics))
+			Expect(watchedLogs[0].NextBackFillBlock).To(Equal(int64(0)))
+			Expect(watchedLogs[0].BackFillEndingBlock).To(Equal(int64(2)))
+		})
+
+		It("limits the back-fill to the transformer's ending block", func() {
+			err := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			Expect(watchedLogs[0].BackFillEndingBlock).To(Equal(int64(1)))
+		})
+
+		It("does not back-fill logs watched after their starting block was checked", func() {
+			err := repository.MarkLogWatched(fakeAddresses, fakeTopics, 3, -1)
+
+			Expect(err).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(watchedLogs).To(BeEmpty())
+		})
+
+		It("excludes logs back-filled through their ending block", func() {
+			markWatchedErr := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, -1)
+			Expect(markWatchedErr).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+
+			err := repository.MarkLogsBackFilledThrough([]int64{watchedLogs[0].ID}, 0, 2)
+
+			Expect(err).NotTo(HaveOccurred())
+			remaining, getRemainingErr := repository.GetLogsToBackFill()
+			Expect(getRemainingErr).NotTo(HaveOccurred())
+			Expect(remaining).To(BeEmpty())
+		})
+
+		It("does not record progress for logs whose back-fill was moved to an earlier block", func() {
+			markWatchedErr := repository.MarkLogWatched(fakeAddresses, fakeTopics, 1, -1)
+			Expect(markWatchedErr).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			markForBackFillErr := repository.MarkLogsForBackFill([]int64{watchedLogs[0].ID},
+				core.BlockRange{StartingBlock: 0, EndingBlock: 1})
+			Expect(markForBackFillErr).NotTo(HaveOccurred())
+
+			err := repository.MarkLogsBackFilledThrough([]int64{watchedLogs[0].ID}, 1, 2)
+
+			Expect(err).NotTo(HaveOccurred())
+			remaining, getRemainingErr := repository.GetLogsToBackFill()
+			Expect(getRemainingErr).NotTo(HaveOccurred())
+			Expect(len(remaining)).To(Equal(1))
+			Expect(remaining[0].NextBackFillBlock).To(Equal(int64(0)))
+		})
+	})
+
+	Describe("GetWatchedLogs", func() {
+		It("returns watched logs whether or not they need a back-fill", func() {
+			markWatchedErr := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, -1)
+			Expect(markWatchedErr).NotTo(HaveOccurred())
+
+			watchedLogs, err := repository.GetWatchedLogs()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			Expect(watchedLogs[0].ContractAddress).To(Equal(fakeAddress))
+			Expect(watchedLogs[0].Topics()).To(Equal(fakeTopics))
+		})
+	})
+
+	Describe("MarkLogsForBackFill", func() {
+		var watchedLogID int64
+
+		BeforeEach(func() {
+			markWatchedErr := repository.MarkLogWatched(fakeAddresses, fakeTopics, 0, -1)
+			Expect(markWatchedErr).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetWatchedLogs()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			watchedLogID = watchedLogs[0].ID
+		})
+
+		It("records the range as needing a back-fill for logs without one", func() {
+			markBackFilledErr := repository.MarkLogsBackFilledThrough([]int64{watchedLogID}, 0, 2)
+			Expect(markBackFilledErr).NotTo(HaveOccurred())
+
+			err := repository.MarkLogsForBackFill([]int64{watchedLogID}, core.BlockRange{StartingBlock: 10, EndingBlock: 19})
+
+			Expect(err).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			Expect(watchedLogs[0].NextBackFillBlock).To(Equal(int64(10)))
+			Expect(watchedLogs[0].BackFillEndingBlock).To(Equal(int64(19)))
+		})
+
+		It("merges the range into a back-fill the logs already need", func() {
+			err := repository.MarkLogsForBackFill([]int64{watchedLogID}, core.BlockRange{StartingBlock: 10, EndingBlock: 19})
+
+			Expect(err).NotTo(HaveOccurred())
+			watchedLogs, getErr := repository.GetLogsToBackFill()
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(watchedLogs)).To(Equal(1))
+			Expect(watchedLogs[0].NextBackFillBlock).To(Equal(int64(0)))
+			Expect(watchedLogs[0].BackFillEndingBlock).To(Equal(int64(19)))
+		})
 	})
 })
@@ -0,0 +1,86 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+type DeadLetterRepository struct {
+	db *postgres.DB
+}
+
+func NewDeadLetterRepository(db *postgres.DB) DeadLetterRepository {
+	return DeadLetterRepository{db: db}
+}
+
+// RecordFailure dead-letters an event log for a transformer, or bumps its attempt count if it already was,
+// and returns how many times the transformer has failed on the log. The log isn't attempted again until retryDelay
+// has passed, with the delay doubling on each further failure.
+func (repository DeadLetterRepository) RecordFailure(eventLogID int64, transformerName, errorMessage string, retryDelay time.Duration) (int, error) {
+	var attempts int
+	err := repository.db.Get(&attempts,
+		`INSERT INTO public.dead_letter_logs (event_log_id, transformer_name, error, next_attempt_at)
+		VALUES ($1, $2, $3, NOW() + $4::DOUBLE PRECISION * INTERVAL '1 second')
+		ON CONFLICT (event_log_id, transformer_name) DO UPDATE
+		SET error = excluded.error, attempts = dead_letter_logs.attempts + 1, updated = NOW(),
+			next_attempt_at = NOW() + $4::DOUBLE PRECISION * POWER(2, dead_letter_logs.attempts) * INTERVAL '1 second'
+		RETURNING attempts`, eventLogID, transformerName, errorMessage, retryDelay.Seconds())
+	return attempts, err
+}
+
+// GetSkippedLogIDs returns which of the event logs the transformer should not attempt now, because they have
+// failed at least maxAttempts times, were discarded, or are waiting for their next attempt
+func (repository DeadLetterRepository) GetSkippedLogIDs(transformerName string, eventLogIDs []int64, maxAttempts int) ([]int64, error) {
+	var skippedIDs []int64
+	err := repository.db.Select(&skippedIDs,
+		`SELECT event_log_id FROM public.dead_letter_logs
+		WHERE transformer_name = $1 AND event_log_id = ANY($2)
+			AND (discarded OR attempts >= $3 OR next_attempt_at > NOW())`,
+		transformerName, pq.Array(eventLogIDs), maxAttempts)
+	return skippedIDs, err
+}
+
+// GetDeadLetterLogs returns dead-lettered logs for the transformer, or for every transformer if the name is empty
+func (repository DeadLetterRepository) GetDeadLetterLogs(transformerName string) ([]core.DeadLetterLog, error) {
+	var deadLetterLogs []core.DeadLetterLog
+	err := repository.db.Select(&deadLetterLogs,
+		`SELECT dead_letter_logs.id, event_log_id, transformer_name, error, attempts, discarded,
+			event_logs.block_number, event_logs.tx_hash, event_logs.log_index, created, updated, next_attempt_at
+		FROM public.dead_letter_logs
+			JOIN public.event_logs ON dead_letter_logs.event_log_id = event_logs.id
+		WHERE $1::TEXT = '' OR transformer_name = $1
+		ORDER BY dead_letter_logs.id ASC`, transformerName)
+	return deadLetterLogs, err
+}
+
+// RetryDeadLetterLogs clears the failure history of the dead-lettered logs so that their transformers attempt them again
+func (repository DeadLetterRepository) RetryDeadLetterLogs(ids []int64) error {
+	_, err := repository.db.Exec(`DELETE FROM public.dead_letter_logs WHERE id = ANY($1)`, pq.Array(ids))
+	return err
+}
+
+// DiscardDeadLetterLogs stops their transformers from attempting the dead-lettered logs, regardless of attempt count
+func (repository DeadLetterRepository) DiscardDeadLetterLogs(ids []int64) error {
+	_, err := repository.db.Exec(`UPDATE public.dead_letter_logs SET discarded = true, updated = NOW() WHERE id = ANY($1)`,
+		pq.Array(ids))
+	return err
+}
@@ -0,0 +1,215 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories_test
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dead letter repository", func() {
+	var (
+		db              = test_config.NewTestDB(test_config.NewTestNode())
+		repository      datastore.DeadLetterRepository
+		eventLogID      int64
+		log             types.Log
+		transformerName = "transformer"
+		maxAttempts     = 2
+	)
+
+	BeforeEach(func() {
+		test_config.CleanTestDB(db)
+		repository = repositories.NewDeadLetterRepository(db)
+
+		headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+		headerID, headerErr := headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
+		Expect(headerErr).NotTo(HaveOccurred())
+		log = test_data.GenericTestLog()
+		test_data.CreateMatchingTx(log, headerID, headerRepository)
+		logsErr := repositories.NewEventLogRepository(db).CreateEventLogs(headerID, []types.Log{log})
+		Expect(logsErr).NotTo(HaveOccurred())
+		idErr := db.Get(&eventLogID, `SELECT id FROM public.event_logs`)
+		Expect(idErr).NotTo(HaveOccurred())
+	})
+
+	Describe("RecordFailure", func() {
+		It("dead-letters the log for the transformer", func() {
+			attempts, err := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(attempts).To(Equal(1))
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(deadLetterLogs)).To(Equal(1))
+			Expect(deadLetterLogs[0].EventLogID).To(Equal(eventLogID))
+			Expect(deadLetterLogs[0].TransformerName).To(Equal(transformerName))
+			Expect(deadLetterLogs[0].Error).To(Equal(fakes.FakeError.Error()))
+			Expect(deadLetterLogs[0].Attempts).To(Equal(1))
+			Expect(deadLetterLogs[0].Discarded).To(BeFalse())
+			Expect(deadLetterLogs[0].BlockNumber).To(Equal(int64(log.BlockNumber)))
+			Expect(deadLetterLogs[0].TxHash).To(Equal(log.TxHash.Hex()))
+			Expect(deadLetterLogs[0].LogIndex).To(Equal(log.Index))
+		})
+
+		It("increments attempts and keeps the latest error on repeated failures", func() {
+			_, firstErr := repository.RecordFailure(eventLogID, transformerName, "first error", 0)
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			attempts, err := repository.RecordFailure(eventLogID, transformerName, "second error", 0)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(attempts).To(Equal(2))
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(deadLetterLogs)).To(Equal(1))
+			Expect(deadLetterLogs[0].Error).To(Equal("second error"))
+		})
+
+		It("delays the next attempt, doubling the delay on each further failure", func() {
+			_, firstErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), time.Hour)
+			Expect(firstErr).NotTo(HaveOccurred())
+			firstDeadLetterLogs, firstGetErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(firstGetErr).NotTo(HaveOccurred())
+			Expect(firstDeadLetterLogs[0].NextAttemptAt.Sub(firstDeadLetterLogs[0].Updated)).To(Equal(time.Hour))
+
+			_, err := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), time.Hour)
+
+			Expect(err).NotTo(HaveOccurred())
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(deadLetterLogs[0].NextAttemptAt.Sub(deadLetterLogs[0].Updated)).To(Equal(2 * time.Hour))
+		})
+
+		It("tracks failures separately per transformer", func() {
+			_, firstErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			attempts, err := repository.RecordFailure(eventLogID, "other transformer", fakes.FakeError.Error(), 0)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(attempts).To(Equal(1))
+			allDeadLetterLogs, getErr := repository.GetDeadLetterLogs("")
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(allDeadLetterLogs)).To(Equal(2))
+		})
+	})
+
+	Describe("GetSkippedLogIDs", func() {
+		It("does not skip logs that have failed fewer than the max attempts", func() {
+			_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+			Expect(recordErr).NotTo(HaveOccurred())
+
+			skippedIDs, err := repository.GetSkippedLogIDs(transformerName, []int64{eventLogID}, maxAttempts)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(BeEmpty())
+		})
+
+		It("skips logs that have failed the max attempts", func() {
+			for i := 0; i < maxAttempts; i++ {
+				_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+				Expect(recordErr).NotTo(HaveOccurred())
+			}
+
+			skippedIDs, err := repository.GetSkippedLogIDs(transformerName, []int64{eventLogID}, maxAttempts)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(ConsistOf(eventLogID))
+		})
+
+		It("only skips logs for the transformer that failed on them", func() {
+			for i := 0; i < maxAttempts; i++ {
+				_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+				Expect(recordErr).NotTo(HaveOccurred())
+			}
+
+			skippedIDs, err := repository.GetSkippedLogIDs("other transformer", []int64{eventLogID}, maxAttempts)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(BeEmpty())
+		})
+
+		It("skips logs until their next attempt is due", func() {
+			_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), time.Hour)
+			Expect(recordErr).NotTo(HaveOccurred())
+
+			skippedIDs, err := repository.GetSkippedLogIDs(transformerName, []int64{eventLogID}, maxAttempts)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(ConsistOf(eventLogID))
+		})
+
+		It("skips discarded logs", func() {
+			_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+			Expect(recordErr).NotTo(HaveOccurred())
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			discardErr := repository.DiscardDeadLetterLogs([]int64{deadLetterLogs[0].ID})
+			Expect(discardErr).NotTo(HaveOccurred())
+
+			skippedIDs, err := repository.GetSkippedLogIDs(transformerName, []int64{eventLogID}, maxAttempts)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(ConsistOf(eventLogID))
+		})
+	})
+
+	Describe("RetryDeadLetterLogs", func() {
+		It("clears the failure history so the log is attempted again", func() {
+			for i := 0; i < maxAttempts; i++ {
+				_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+				Expect(recordErr).NotTo(HaveOccurred())
+			}
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+
+			err := repository.RetryDeadLetterLogs([]int64{deadLetterLogs[0].ID})
+
+			Expect(err).NotTo(HaveOccurred())
+			skippedIDs, skippedErr := repository.GetSkippedLogIDs(transformerName, []int64{eventLogID}, maxAttempts)
+			Expect(skippedErr).NotTo(HaveOccurred())
+			Expect(skippedIDs).To(BeEmpty())
+			remaining, remainingErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(remainingErr).NotTo(HaveOccurred())
+			Expect(remaining).To(BeEmpty())
+		})
+	})
+
+	Describe("DiscardDeadLetterLogs", func() {
+		It("marks the dead-lettered log discarded", func() {
+			_, recordErr := repository.RecordFailure(eventLogID, transformerName, fakes.FakeError.Error(), 0)
+			Expect(recordErr).NotTo(HaveOccurred())
+			deadLetterLogs, getErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+
+			err := repository.DiscardDeadLetterLogs([]int64{deadLetterLogs[0].ID})
+
+			Expect(err).NotTo(HaveOccurred())
+			discarded, discardedErr := repository.GetDeadLetterLogs(transformerName)
+			Expect(discardedErr).NotTo(HaveOccurred())
+			Expect(discarded[0].Discarded).To(BeTrue())
+		})
+	})
+})
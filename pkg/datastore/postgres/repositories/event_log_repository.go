@@ -17,8 +17,6 @@
 package repositories
 
 import (
-	"fmt"
-
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/jmoiron/sqlx"
@@ -33,6 +31,20 @@ const insertEventLogQuery = `INSERT INTO public.event_logs
 		(header_id, address, topics, data, block_number, block_hash, tx_index, tx_hash, log_index, raw)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT DO NOTHING`
 
+const getUntransformedEventLogsQuery = `SELECT event_logs.* FROM public.event_logs
+		JOIN public.addresses ON event_logs.address = addresses.id
+	WHERE event_logs.id > $1
+		AND event_logs.transformed = false
+		AND addresses.address = ANY($2)
+		AND event_logs.topics[1] = $3
+		AND ($4::BYTEA IS NULL OR event_logs.topics[2] = $4)
+		AND ($5::BYTEA IS NULL OR event_logs.topics[3] = $5)
+		AND ($6::BYTEA IS NULL OR event_logs.topics[4] = $6)
+		AND NOT EXISTS (SELECT 1 FROM public.transformed_event_logs
+			WHERE transformed_event_logs.event_log_id = event_logs.id AND transformed_event_logs.transformer_name = $7)
+	ORDER BY event_logs.id ASC
+	LIMIT $8`
+
 type EventLogRepository struct {
 	db *postgres.DB
 }
@@ -58,10 +70,17 @@ type rawEventLog struct {
 	Raw         []byte
 }
 
-func (repo EventLogRepository) GetUntransformedEventLogs(minID, limit int) ([]core.EventLog, error) {
+// GetUntransformedEventLogs returns logs matching a transformer's addresses and topics that it hasn't transformed yet.
+// Logs marked transformed in the legacy event_logs.transformed column, before transformations were recorded per
+// transformer, are excluded for every transformer.
+func (repo EventLogRepository) GetUntransformedEventLogs(transformerName string, addresses []string, topics core.Topics, minID, limit int) ([]core.EventLog, error) {
+	checksumAddresses := make([]string, len(addresses))
+	for i, address := range addresses {
+		checksumAddresses[i] = common.HexToAddress(address).Hex()
+	}
 	var rawLogs []rawEventLog
-	query := fmt.Sprintf("SELECT * FROM public.event_logs WHERE transformed = false AND id > %d ORDER BY id ASC LIMIT %d", minID, limit)
-	err := repo.db.Select(&rawLogs, query)
+	err := repo.db.Select(&rawLogs, getUntransformedEventLogsQuery, minID, pq.Array(checksumAddresses),
+		topicBytes(topics[0]), topicBytes(topics[1]), topicBytes(topics[2]), topicBytes(topics[3]), transformerName, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +118,13 @@ func (repo EventLogRepository) GetUntransformedEventLogs(minID, limit int) ([]co
 	return results, nil
 }
 
+// MarkLogsTransformed records that the transformer has transformed the logs, so that they are not delivered to it again
+func (repo EventLogRepository) MarkLogsTransformed(transformerName string, logIDs []int64) error {
+	_, err := repo.db.Exec(`INSERT INTO public.transformed_event_logs (event_log_id, transformer_name)
+		SELECT UNNEST($1::BIGINT[]), $2 ON CONFLICT DO NOTHING`, pq.Array(logIDs), transformerName)
+	return err
+}
+
 func (repo EventLogRepository) CreateEventLogs(headerID int64, logs []types.Log) error {
 	tx, txErr := repo.db.Beginx()
 	if txErr != nil {
@@ -139,3 +165,11 @@ func buildTopics(log types.Log) pq.ByteaArray {
 	}
 	return topics
 }
+
+// topicBytes converts a topic filter to the bytes stored in event_logs.topics, or NULL if the filter is unset
+func topicBytes(topic string) interface{} {
+	if topic == "" {
+		return nil
+	}
+	return common.HexToHash(topic).Bytes()
+}
@@ -17,11 +17,14 @@
 package repositories_test
 
 import (
+	"strings"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/lib/pq"
 	"github.com/makerdao/vulcanizedb/libraries/shared/repository"
 	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
 	"github.com/makerdao/vulcanizedb/pkg/fakes"
@@ -40,7 +43,7 @@ var _ = Describe("Event log repository", func() {
 
 	BeforeEach(func() {
 		test_config.CleanTestDB(db)
-		headerRepository = repositories.NewHeaderRepository(db)
+		headerRepository = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 		var headerErr error
 		headerID, headerErr = headerRepository.CreateOrUpdateHeader(fakes.FakeHeader)
 		Expect(headerErr).NotTo(HaveOccurred())
@@ -157,16 +160,24 @@ var _ = Describe("Event log repository", func() {
 	})
 
 	Describe("GetUntransformedEventLogs", func() {
+		transformerName := "transformer"
+
 		Describe("when there are no logs", func() {
 			It("returns empty collection", func() {
-				result, err := repo.GetUntransformedEventLogs(0, 1)
+				log := test_data.GenericTestLog()
+				result, err := repo.GetUntransformedEventLogs(transformerName, []string{log.Address.Hex()},
+					core.Topics{log.Topics[0].Hex()}, 0, 1)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(result)).To(BeZero())
 			})
 		})
 
 		Describe("when there are logs", func() {
-			var log1, log2 types.Log
+			var (
+				log1, log2 types.Log
+				addresses  []string
+				topics     core.Topics
+			)
 
 			BeforeEach(func() {
 				log1 = test_data.GenericTestLog()
@@ -177,10 +188,12 @@ var _ = Describe("Event log repository", func() {
 				logs := []types.Log{log1, log2}
 				logsErr := repo.CreateEventLogs(headerID, logs)
 				Expect(logsErr).NotTo(HaveOccurred())
+				addresses = []string{log1.Address.Hex(), strings.ToLower(log2.Address.Hex())}
+				topics = core.Topics{log1.Topics[0].Hex()}
 			})
 
 			It("returns persisted logs", func() {
-				result, err := repo.GetUntransformedEventLogs(0, 2)
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(result)).To(Equal(2))
@@ -189,35 +202,77 @@ var _ = Describe("Event log repository", func() {
 				Expect(result[0].Log).NotTo(Equal(result[1].Log))
 			})
 
-			It("excludes logs that have been transformed", func() {
-				_, insertErr := db.Exec(`UPDATE public.event_logs SET transformed = true WHERE tx_hash = $1`, log1.TxHash.Hex())
-				Expect(insertErr).NotTo(HaveOccurred())
+			It("excludes logs from other addresses", func() {
+				result, err := repo.GetUntransformedEventLogs(transformerName, []string{log1.Address.Hex()}, topics, 0, 2)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(result)).To(Equal(1))
+				Expect(result[0].Log).To(Equal(log1))
+			})
+
+			It("excludes logs with other topics", func() {
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses,
+					core.Topics{fakes.FakeHash.Hex()}, 0, 2)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(BeEmpty())
+			})
+
+			It("excludes logs not matching topic filters", func() {
+				filteredTopics := core.Topics{log1.Topics[0].Hex(), log1.Topics[1].Hex()}
 
-				result, err := repo.GetUntransformedEventLogs(0, 2)
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses, filteredTopics, 0, 2)
 
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(result)).To(Equal(1))
-				Expect(result[0].Log).To(Equal(log2))
+				Expect(result[0].Log).To(Equal(log1))
+			})
+
+			It("excludes logs that have been transformed by the transformer", func() {
+				firstResult, firstErr := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
+				Expect(firstErr).NotTo(HaveOccurred())
+				Expect(len(firstResult)).To(Equal(2))
+				markErr := repo.MarkLogsTransformed(transformerName, []int64{firstResult[0].ID})
+				Expect(markErr).NotTo(HaveOccurred())
+
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(result)).To(Equal(1))
+				Expect(result[0].ID).To(Equal(firstResult[1].ID))
 			})
 
-			It("returns empty collection if all logs transformed", func() {
-				_, insertErr := db.Exec(`UPDATE public.event_logs SET transformed = true WHERE header_id = $1`, headerID)
+			It("still returns logs transformed by other transformers", func() {
+				firstResult, firstErr := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
+				Expect(firstErr).NotTo(HaveOccurred())
+				markErr := repo.MarkLogsTransformed("other transformer", []int64{firstResult[0].ID, firstResult[1].ID})
+				Expect(markErr).NotTo(HaveOccurred())
+
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(result)).To(Equal(2))
+			})
+
+			It("excludes logs that were marked transformed for every transformer", func() {
+				_, insertErr := db.Exec(`UPDATE public.event_logs SET transformed = true WHERE tx_hash = $1`, log1.TxHash.Hex())
 				Expect(insertErr).NotTo(HaveOccurred())
 
-				result, err := repo.GetUntransformedEventLogs(0, 2)
+				result, err := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, 2)
 
 				Expect(err).NotTo(HaveOccurred())
-				Expect(len(result)).To(BeZero())
+				Expect(len(result)).To(Equal(1))
+				Expect(result[0].Log).To(Equal(log2))
 			})
 
 			It("enables seeking logs with greater ID", func() {
 				limit := 1
-				resultOne, errOne := repo.GetUntransformedEventLogs(0, limit)
+				resultOne, errOne := repo.GetUntransformedEventLogs(transformerName, addresses, topics, 0, limit)
 				Expect(errOne).NotTo(HaveOccurred())
 				Expect(len(resultOne)).To(Equal(limit))
 
 				nextMinID := int(resultOne[0].ID)
-				resultTwo, errTwo := repo.GetUntransformedEventLogs(nextMinID, limit)
+				resultTwo, errTwo := repo.GetUntransformedEventLogs(transformerName, addresses, topics, nextMinID, limit)
 				Expect(errTwo).NotTo(HaveOccurred())
 				Expect(len(resultTwo)).To(Equal(1))
 
@@ -225,4 +280,26 @@ var _ = Describe("Event log repository", func() {
 			})
 		})
 	})
+
+	Describe("MarkLogsTransformed", func() {
+		It("ignores logs already marked transformed by the transformer", func() {
+			log := test_data.GenericTestLog()
+			test_data.CreateMatchingTx(log, headerID, headerRepository)
+			logsErr := repo.CreateEventLogs(headerID, []types.Log{log})
+			Expect(logsErr).NotTo(HaveOccurred())
+			var logID int64
+			idErr := db.Get(&logID, `SELECT id FROM public.event_logs`)
+			Expect(idErr).NotTo(HaveOccurred())
+			firstErr := repo.MarkLogsTransformed("transformer", []int64{logID})
+			Expect(firstErr).NotTo(HaveOccurred())
+
+			err := repo.MarkLogsTransformed("transformer", []int64{logID})
+
+			Expect(err).NotTo(HaveOccurred())
+			var count int
+			countErr := db.Get(&count, `SELECT COUNT(*) FROM public.transformed_event_logs`)
+			Expect(countErr).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+	})
 })
@@ -17,6 +17,7 @@
 package repositories
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
@@ -25,32 +26,64 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const createOrUpdateHeaderQuery = `SELECT * FROM public.get_or_create_header($1, $2, $3, $4, $5, $6, $7)`
+
+// DefaultHeaderReorgWindow is the reorg window of header repositories that don't configure their own
+const DefaultHeaderReorgWindow int64 = 15
+
 type headerRepository struct {
-	db *postgres.DB
+	db          *postgres.DB
+	reorgWindow int64
 }
 
-func NewHeaderRepository(database *postgres.DB) headerRepository {
-	return headerRepository{db: database}
+// NewHeaderRepository returns a repository where a header with a non-matching hash replaces the existing one if it is
+// within reorgWindow blocks of the most recent header. Older headers are treated as final and left in place.
+func NewHeaderRepository(database *postgres.DB, reorgWindow int64) headerRepository {
+	return headerRepository{db: database, reorgWindow: reorgWindow}
 }
 
 func (repo headerRepository) CreateOrUpdateHeader(header core.Header) (int64, error) {
 	var headerID int64
-	err := repo.db.QueryRowx("SELECT * FROM public.get_or_create_header($1, $2, $3, $4, $5)",
-		header.BlockNumber, header.Hash, header.Raw, header.Timestamp, repo.db.NodeID).Scan(&headerID)
+	err := repo.db.QueryRowx(createOrUpdateHeaderQuery, header.BlockNumber, header.Hash, header.Raw, header.Timestamp, repo.db.NodeID,
+		nullableParentHash(header), repo.reorgWindow).Scan(&headerID)
 	if err != nil {
 		return headerID, fmt.Errorf("error inserting header for block %d: %w", header.BlockNumber, err)
 	}
 	return headerID, nil
 }
 
+// CreateOrUpdateHeaders persists a batch of headers in a single transaction
+func (repo headerRepository) CreateOrUpdateHeaders(headers []core.Header) error {
+	tx, txErr := repo.db.Beginx()
+	if txErr != nil {
+		return fmt.Errorf("error beginning header batch transaction: %w", txErr)
+	}
+	for _, header := range headers {
+		_, execErr := tx.Exec(createOrUpdateHeaderQuery,
+			header.BlockNumber, header.Hash, header.Raw, header.Timestamp, repo.db.NodeID,
+			nullableParentHash(header), repo.reorgWindow)
+		if execErr != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				logrus.Errorf("failed to rollback header batch insert: %s", rollbackErr.Error())
+			}
+			return fmt.Errorf("error inserting header for block %d: %w", header.BlockNumber, execErr)
+		}
+	}
+	return tx.Commit()
+}
+
 func (repo headerRepository) CreateTransactions(headerID int64, transactions []core.TransactionModel) error {
 	for _, transaction := range transactions {
 		_, err := repo.db.Exec(`INSERT INTO public.transactions
-		(header_id, hash, gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value") 
-		VALUES ($1, $2, $3::NUMERIC, $4::NUMERIC, $5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC)
+		(header_id, hash, gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value", tx_type,
+		chain_id, max_fee_per_gas, max_priority_fee_per_gas, access_list, effective_gas_price)
+		VALUES ($1, $2, $3::NUMERIC, NULLIF($4, '')::NUMERIC, $5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC, $12,
+		NULLIF($13, '')::NUMERIC, NULLIF($14, '')::NUMERIC, NULLIF($15, '')::NUMERIC, $16, NULLIF($17, '')::NUMERIC)
 		ON CONFLICT DO NOTHING`, headerID, transaction.Hash, transaction.GasLimit, transaction.GasPrice,
 			transaction.Data, transaction.Nonce, transaction.Raw, transaction.From, transaction.TxIndex, transaction.To,
-			transaction.Value)
+			transaction.Value, transaction.Type, transaction.ChainID, transaction.MaxFeePerGas,
+			transaction.MaxPriorityFeePerGas, transaction.AccessList, transaction.EffectiveGasPrice)
 		if err != nil {
 			return fmt.Errorf("error creating transactions: %w", err)
 		}
@@ -58,17 +91,52 @@ func (repo headerRepository) CreateTransactions(headerID int64, transactions []c
 	return nil
 }
 
+// CreateTransactionsWithReceipts persists transactions along with their embedded receipts in a single transaction
+func (repo headerRepository) CreateTransactionsWithReceipts(headerID int64, transactions []core.TransactionModel) error {
+	tx, txErr := repo.db.Beginx()
+	if txErr != nil {
+		return fmt.Errorf("error beginning transactions and receipts transaction: %w", txErr)
+	}
+	for _, transaction := range transactions {
+		transactionID, createTransactionErr := repo.CreateTransactionInTx(tx, headerID, transaction)
+		if createTransactionErr != nil {
+			rollbackTransactionsWithReceipts(tx)
+			return fmt.Errorf("error creating transaction %s: %w", transaction.Hash, createTransactionErr)
+		}
+		_, createReceiptErr := ReceiptRepository{}.CreateReceiptInTx(headerID, transactionID, transaction.Receipt, tx)
+		if createReceiptErr != nil {
+			rollbackTransactionsWithReceipts(tx)
+			return fmt.Errorf("error creating receipt for transaction %s: %w", transaction.Hash, createReceiptErr)
+		}
+	}
+	return tx.Commit()
+}
+
+func rollbackTransactionsWithReceipts(tx *sqlx.Tx) {
+	rollbackErr := tx.Rollback()
+	if rollbackErr != nil {
+		logrus.Errorf("failed to rollback transactions and receipts insert: %s", rollbackErr.Error())
+	}
+}
+
 func (repo headerRepository) CreateTransactionInTx(tx *sqlx.Tx, headerID int64, transaction core.TransactionModel) (int64, error) {
 	var txId int64
 	err := tx.QueryRowx(`INSERT INTO public.transactions
-		(header_id, hash, gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value")
-		VALUES ($1, $2, $3::NUMERIC, $4::NUMERIC, $5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC)
+		(header_id, hash, gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value", tx_type,
+		chain_id, max_fee_per_gas, max_priority_fee_per_gas, access_list, effective_gas_price)
+		VALUES ($1, $2, $3::NUMERIC, NULLIF($4, '')::NUMERIC, $5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC, $12,
+		NULLIF($13, '')::NUMERIC, NULLIF($14, '')::NUMERIC, NULLIF($15, '')::NUMERIC, $16, NULLIF($17, '')::NUMERIC)
 		ON CONFLICT (hash) DO UPDATE
-		SET (gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value") = ($3::NUMERIC, $4::NUMERIC, $5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC)
+		SET (gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value", tx_type, chain_id,
+		max_fee_per_gas, max_priority_fee_per_gas, access_list, effective_gas_price) = ($3::NUMERIC, NULLIF($4, '')::NUMERIC,
+		$5, $6::NUMERIC, $7, $8, $9::NUMERIC, $10, $11::NUMERIC, $12, NULLIF($13, '')::NUMERIC, NULLIF($14, '')::NUMERIC,
+		NULLIF($15, '')::NUMERIC, $16, NULLIF($17, '')::NUMERIC)
 		RETURNING id`,
 		headerID, transaction.Hash, transaction.GasLimit, transaction.GasPrice,
 		transaction.Data, transaction.Nonce, transaction.Raw, transaction.From,
-		transaction.TxIndex, transaction.To, transaction.Value).Scan(&txId)
+		transaction.TxIndex, transaction.To, transaction.Value, transaction.Type, transaction.ChainID,
+		transaction.MaxFeePerGas, transaction.MaxPriorityFeePerGas, transaction.AccessList,
+		transaction.EffectiveGasPrice).Scan(&txId)
 	if err != nil {
 		logrus.Error("header_repository: error inserting transaction: ", err)
 	}
@@ -78,20 +146,20 @@ func (repo headerRepository) CreateTransactionInTx(tx *sqlx.Tx, headerID int64,
 func (repo headerRepository) GetHeaderByBlockNumber(blockNumber int64) (core.Header, error) {
 	var header core.Header
 	err := repo.db.Get(&header,
-		`SELECT id, block_number, hash, raw, block_timestamp FROM headers WHERE block_number = $1`, blockNumber)
+		`SELECT id, block_number, hash, COALESCE(parent_hash, '') AS parent_hash, raw, block_timestamp FROM headers WHERE block_number = $1`, blockNumber)
 	return header, err
 }
 
 func (repo headerRepository) GetHeaderByID(id int64) (core.Header, error) {
 	var header core.Header
-	headerErr := repo.db.Get(&header, `SELECT id, block_number, hash, raw, block_timestamp FROM headers WHERE id = $1`, id)
+	headerErr := repo.db.Get(&header, `SELECT id, block_number, hash, COALESCE(parent_hash, '') AS parent_hash, raw, block_timestamp FROM headers WHERE id = $1`, id)
 	return header, headerErr
 }
 
 func (repo headerRepository) GetHeadersInRange(startingBlock, endingBlock int64) ([]core.Header, error) {
 	var headers []core.Header
 	err := repo.db.Select(&headers,
-		`SELECT id, block_number, hash, raw, block_timestamp FROM headers WHERE block_number BETWEEN $1 AND $2 ORDER BY block_number ASC`,
+		`SELECT id, block_number, hash, COALESCE(parent_hash, '') AS parent_hash, raw, block_timestamp FROM headers WHERE block_number BETWEEN $1 AND $2 ORDER BY block_number ASC`,
 		startingBlock, endingBlock)
 	return headers, err
 }
@@ -118,3 +186,7 @@ func (repo headerRepository) GetMostRecentHeaderBlockNumber() (int64, error) {
 		`SELECT block_number FROM headers ORDER BY block_number DESC LIMIT 1`)
 	return blockNumber, err
 }
+
+func nullableParentHash(header core.Header) sql.NullString {
+	return sql.NullString{String: header.ParentHash, Valid: header.ParentHash != ""}
+}
@@ -23,6 +23,8 @@ import (
 	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/makerdao/vulcanizedb/libraries/shared/test_data"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore"
 	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
@@ -41,10 +43,50 @@ var _ = Describe("Block header repository", func() {
 
 	BeforeEach(func() {
 		test_config.CleanTestDB(db)
-		repo = repositories.NewHeaderRepository(db)
+		repo = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 		header = fakes.GetFakeHeader(rand.Int63n(50000000))
 	})
 
+	Describe("creating or updating a batch of headers", func() {
+		It("adds every header in the batch", func() {
+			headerTwo := fakes.GetFakeHeader(header.BlockNumber + 1)
+
+			createErr := repo.CreateOrUpdateHeaders([]core.Header{header, headerTwo})
+
+			Expect(createErr).NotTo(HaveOccurred())
+			var dbHashes []string
+			readErr := db.Select(&dbHashes, `SELECT hash FROM public.headers ORDER BY block_number`)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(dbHashes).To(Equal([]string{header.Hash, headerTwo.Hash}))
+		})
+
+		It("does not duplicate headers already persisted", func() {
+			_, createErr := repo.CreateOrUpdateHeader(header)
+			Expect(createErr).NotTo(HaveOccurred())
+
+			createBatchErr := repo.CreateOrUpdateHeaders([]core.Header{header})
+
+			Expect(createBatchErr).NotTo(HaveOccurred())
+			var count int
+			readErr := db.Get(&count, `SELECT COUNT(*) FROM public.headers WHERE block_number = $1`, header.BlockNumber)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("rolls back the whole batch if one header fails", func() {
+			invalidHeader := fakes.GetFakeHeader(header.BlockNumber + 1)
+			invalidHeader.Raw = []byte("not json")
+
+			createErr := repo.CreateOrUpdateHeaders([]core.Header{header, invalidHeader})
+
+			Expect(createErr).To(HaveOccurred())
+			var count int
+			readErr := db.Get(&count, `SELECT COUNT(*) FROM public.headers`)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(count).To(BeZero())
+		})
+	})
+
 	Describe("creating or updating a header", func() {
 		BeforeEach(func() {
 			_, createErr := repo.CreateOrUpdateHeader(header)
@@ -61,6 +103,26 @@ var _ = Describe("Block header repository", func() {
 			Expect(dbHeader.Timestamp).To(Equal(header.Timestamp))
 		})
 
+		It("adds parent hash to header", func() {
+			var parentHash string
+			readErr := db.Get(&parentHash, `SELECT parent_hash FROM public.headers WHERE block_number = $1`, header.BlockNumber)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(parentHash).To(Equal(header.ParentHash))
+		})
+
+		It("records parent hash for a matching header persisted without one", func() {
+			_, clearErr := db.Exec(`UPDATE public.headers SET parent_hash = NULL WHERE block_number = $1`, header.BlockNumber)
+			Expect(clearErr).NotTo(HaveOccurred())
+
+			_, createTwoErr := repo.CreateOrUpdateHeader(header)
+			Expect(createTwoErr).NotTo(HaveOccurred())
+
+			var parentHash string
+			readErr := db.Get(&parentHash, `SELECT parent_hash FROM public.headers WHERE block_number = $1`, header.BlockNumber)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(parentHash).To(Equal(header.ParentHash))
+		})
+
 		It("adds node data to header", func() {
 			var ethNodeId int64
 			readErr := db.Get(&ethNodeId, `SELECT eth_node_id FROM public.headers WHERE block_number = $1`, header.BlockNumber)
@@ -105,6 +167,116 @@ var _ = Describe("Block header repository", func() {
 			Expect(dbHeaderHash).To(Equal(header.Hash))
 		})
 
+		Describe("with a configured reorg window", func() {
+			BeforeEach(func() {
+				repo = repositories.NewHeaderRepository(db, 30)
+			})
+
+			It("replaces header if block number is within the window of the max block number in the db", func() {
+				chainHeadHeader := fakes.GetFakeHeader(header.BlockNumber + 20)
+				_, createHeadErr := repo.CreateOrUpdateHeader(chainHeadHeader)
+				Expect(createHeadErr).NotTo(HaveOccurred())
+
+				conflictingHeader := fakes.GetFakeHeader(header.BlockNumber)
+				_, createConflictErr := repo.CreateOrUpdateHeader(conflictingHeader)
+				Expect(createConflictErr).NotTo(HaveOccurred())
+
+				var dbHeaderHash string
+				readErr := db.Get(&dbHeaderHash, `SELECT hash FROM public.headers WHERE block_number = $1`, header.BlockNumber)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(dbHeaderHash).To(Equal(conflictingHeader.Hash))
+			})
+
+			It("does not replace header if block number is outside the window", func() {
+				chainHeadHeader := fakes.GetFakeHeader(header.BlockNumber + 30)
+				_, createHeadErr := repo.CreateOrUpdateHeader(chainHeadHeader)
+				Expect(createHeadErr).NotTo(HaveOccurred())
+
+				oldConflictingHeader := fakes.GetFakeHeader(header.BlockNumber)
+				_, createConflictErr := repo.CreateOrUpdateHeader(oldConflictingHeader)
+				Expect(createConflictErr).NotTo(HaveOccurred())
+
+				var dbHeaderHash string
+				readErr := db.Get(&dbHeaderHash, `SELECT hash FROM public.headers WHERE block_number = $1`, header.BlockNumber)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(dbHeaderHash).To(Equal(header.Hash))
+			})
+		})
+
+		Describe("when a header is replaced", func() {
+			var (
+				headerID       int64
+				replacement    core.Header
+				replacementErr error
+			)
+
+			BeforeEach(func() {
+				var getIDErr error
+				headerID, getIDErr = repo.CreateOrUpdateHeader(header)
+				Expect(getIDErr).NotTo(HaveOccurred())
+				_, checkErr := db.Exec(`UPDATE public.headers SET check_count = 1 WHERE id = $1`, headerID)
+				Expect(checkErr).NotTo(HaveOccurred())
+				_, checkedErr := db.Exec(`INSERT INTO public.checked_headers (header_id) VALUES ($1)`, headerID)
+				Expect(checkedErr).NotTo(HaveOccurred())
+				logRepo := repositories.NewEventLogRepository(db)
+				createLogErr := logRepo.CreateEventLogs(headerID, []types.Log{test_data.GenericTestLog()})
+				Expect(createLogErr).NotTo(HaveOccurred())
+
+				replacement = fakes.GetFakeHeader(header.BlockNumber)
+			})
+
+			JustBeforeEach(func() {
+				_, replacementErr = repo.CreateOrUpdateHeader(replacement)
+			})
+
+			It("replaces the orphaned header with a new one", func() {
+				Expect(replacementErr).NotTo(HaveOccurred())
+				var dbHeader core.Header
+				readErr := db.Get(&dbHeader, `SELECT id, hash, parent_hash FROM public.headers WHERE block_number = $1`,
+					header.BlockNumber)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(dbHeader.Id).NotTo(Equal(headerID))
+				Expect(dbHeader.Hash).To(Equal(replacement.Hash))
+				Expect(dbHeader.ParentHash).To(Equal(replacement.ParentHash))
+			})
+
+			It("leaves the replacement header unchecked so logs are extracted for it", func() {
+				Expect(replacementErr).NotTo(HaveOccurred())
+				var checkCount int
+				readErr := db.Get(&checkCount, `SELECT check_count FROM public.headers WHERE block_number = $1`,
+					header.BlockNumber)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(checkCount).To(BeZero())
+			})
+
+			It("cascades the deletion to rows referencing the orphaned header", func() {
+				Expect(replacementErr).NotTo(HaveOccurred())
+				var checkedCount int
+				readErr := db.Get(&checkedCount, `SELECT COUNT(*) FROM public.checked_headers WHERE header_id = $1`,
+					headerID)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(checkedCount).To(BeZero())
+			})
+
+			It("removes logs extracted for the orphaned header", func() {
+				Expect(replacementErr).NotTo(HaveOccurred())
+				var logCount int
+				readErr := db.Get(&logCount, `SELECT COUNT(*) FROM public.event_logs WHERE header_id = $1`, headerID)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(logCount).To(BeZero())
+			})
+
+			It("records the orphaned header", func() {
+				Expect(replacementErr).NotTo(HaveOccurred())
+				var orphanedHeader core.OrphanedHeader
+				readErr := db.Get(&orphanedHeader, `SELECT id, header_id, block_number, hash FROM public.orphaned_headers`)
+				Expect(readErr).NotTo(HaveOccurred())
+				Expect(orphanedHeader.HeaderID).To(Equal(headerID))
+				Expect(orphanedHeader.BlockNumber).To(Equal(header.BlockNumber))
+				Expect(orphanedHeader.Hash).To(Equal(header.Hash))
+			})
+		})
+
 		It("does not duplicate headers with different hashes", func() {
 			headerTwo := fakes.GetFakeHeader(header.BlockNumber)
 
@@ -121,7 +293,7 @@ var _ = Describe("Block header repository", func() {
 		It("replaces header if hash is different (even from different node)", func() {
 			dbTwo := test_config.NewTestDB(test_config.NewTestNode())
 
-			repoTwo := repositories.NewHeaderRepository(dbTwo)
+			repoTwo := repositories.NewHeaderRepository(dbTwo, repositories.DefaultHeaderReorgWindow)
 			headerTwo := fakes.GetFakeHeader(header.BlockNumber)
 
 			_, createTwoErr := repoTwo.CreateOrUpdateHeader(headerTwo)
@@ -154,7 +326,7 @@ var _ = Describe("Block header repository", func() {
 				Data:     []byte{},
 				From:     fromAddress.Hex(),
 				GasLimit: 0,
-				GasPrice: 0,
+				GasPrice: "0",
 				Hash:     txHash.Hex(),
 				Nonce:    0,
 				Raw:      []byte{},
@@ -166,7 +338,7 @@ var _ = Describe("Block header repository", func() {
 				Data:     []byte{},
 				From:     fromAddress.Hex(),
 				GasLimit: 1,
-				GasPrice: 1,
+				GasPrice: "1",
 				Hash:     txHashTwo.Hex(),
 				Nonce:    1,
 				Raw:      []byte{},
@@ -213,7 +385,7 @@ var _ = Describe("Block header repository", func() {
 				Data:     []byte{},
 				From:     fromAddress.Hex(),
 				GasLimit: 0,
-				GasPrice: 0,
+				GasPrice: "0",
 				Hash:     txHash.Hex(),
 				Nonce:    0,
 				Raw:      []byte{1, 2, 3},
@@ -237,6 +409,43 @@ var _ = Describe("Block header repository", func() {
 			Expect(dbTransaction).To(Equal(transaction))
 		})
 
+		It("adds the fields of typed transactions", func() {
+			headerID, err := repo.CreateOrUpdateHeader(header)
+			Expect(err).NotTo(HaveOccurred())
+			transaction := core.TransactionModel{
+				AccessList: core.AccessList{{
+					Address:     common.HexToAddress("0x1234").Hex(),
+					StorageKeys: []string{common.HexToHash("0x01").Hex()},
+				}},
+				ChainID:              "1",
+				Data:                 []byte{},
+				EffectiveGasPrice:    "18446744073709551616",
+				From:                 common.HexToAddress("0x1234").Hex(),
+				GasPrice:             "18446744073709551616",
+				Hash:                 common.HexToHash("0x9876").Hex(),
+				MaxFeePerGas:         "18446744073709551617",
+				MaxPriorityFeePerGas: "2",
+				Raw:                  []byte{2, 1},
+				To:                   common.HexToAddress("0x5678").Hex(),
+				Type:                 core.DynamicFeeTxType,
+				Value:                "0",
+			}
+
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			_, insertErr := repo.CreateTransactionInTx(tx, headerID, transaction)
+			Expect(insertErr).NotTo(HaveOccurred())
+			Expect(tx.Commit()).To(Succeed())
+
+			var dbTransaction core.TransactionModel
+			err = db.Get(&dbTransaction,
+				`SELECT hash, gas_limit, gas_price, input_data, nonce, raw, tx_from, tx_index, tx_to, "value", tx_type,
+				chain_id, max_fee_per_gas, max_priority_fee_per_gas, access_list, effective_gas_price
+				FROM public.transactions WHERE header_id = $1`, headerID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbTransaction).To(Equal(transaction))
+		})
+
 		It("silently upserts", func() {
 			headerID, err := repo.CreateOrUpdateHeader(header)
 			Expect(err).NotTo(HaveOccurred())
@@ -248,7 +457,7 @@ var _ = Describe("Block header repository", func() {
 				Data:     []byte{},
 				From:     fromAddress.Hex(),
 				GasLimit: 0,
-				GasPrice: 0,
+				GasPrice: "0",
 				Hash:     txHash.Hex(),
 				Nonce:    0,
 				Raw:      []byte{},
@@ -273,6 +482,75 @@ var _ = Describe("Block header repository", func() {
 		})
 	})
 
+	Describe("creating transactions with receipts", func() {
+		var (
+			headerID    int64
+			transaction core.TransactionModel
+		)
+
+		BeforeEach(func() {
+			var err error
+			headerID, err = repo.CreateOrUpdateHeader(header)
+			Expect(err).NotTo(HaveOccurred())
+			txHash := common.HexToHash("0x9876")
+			transaction = core.TransactionModel{
+				Data:    []byte{},
+				From:    common.HexToAddress("0x1234").Hex(),
+				Hash:    txHash.Hex(),
+				Raw:     []byte{},
+				To:      common.HexToAddress("0x5678").Hex(),
+				TxIndex: 1,
+				Value:   "0",
+				Receipt: core.Receipt{
+					Bloom:             "0x0102",
+					ContractAddress:   common.HexToAddress("0xabcd").Hex(),
+					CumulativeGasUsed: 300,
+					GasUsed:           200,
+					Status:            1,
+					TxHash:            txHash.Hex(),
+					Rlp:               []byte{3, 4},
+				},
+			}
+		})
+
+		It("adds transactions along with their receipts", func() {
+			err := repo.CreateTransactionsWithReceipts(headerID, []core.TransactionModel{transaction})
+			Expect(err).NotTo(HaveOccurred())
+
+			type receiptModel struct {
+				GasUsed   uint64 `db:"gas_used"`
+				Status    int
+				TxHash    string `db:"tx_hash"`
+				LogsBloom []byte `db:"logs_bloom"`
+			}
+			var dbReceipt receiptModel
+			readErr := db.Get(&dbReceipt, `SELECT receipts.gas_used, receipts.status, receipts.tx_hash, receipts.logs_bloom
+				FROM public.receipts JOIN public.transactions ON receipts.transaction_id = transactions.id
+				WHERE transactions.hash = $1`, transaction.Hash)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(dbReceipt).To(Equal(receiptModel{
+				GasUsed:   200,
+				Status:    1,
+				TxHash:    transaction.Hash,
+				LogsBloom: []byte{1, 2},
+			}))
+		})
+
+		It("upserts transactions and receipts that were already persisted", func() {
+			err := repo.CreateTransactionsWithReceipts(headerID, []core.TransactionModel{transaction})
+			Expect(err).NotTo(HaveOccurred())
+			transaction.Receipt.Status = 0
+
+			upsertErr := repo.CreateTransactionsWithReceipts(headerID, []core.TransactionModel{transaction})
+			Expect(upsertErr).NotTo(HaveOccurred())
+
+			var statuses []int
+			readErr := db.Select(&statuses, `SELECT status FROM public.receipts`)
+			Expect(readErr).NotTo(HaveOccurred())
+			Expect(statuses).To(ConsistOf(0))
+		})
+	})
+
 	Describe("Getting a header by block number", func() {
 		It("returns header if it exists", func() {
 			_, createErr := repo.CreateOrUpdateHeader(header)
@@ -284,6 +562,7 @@ var _ = Describe("Block header repository", func() {
 			Expect(dbHeader.Id).NotTo(BeZero())
 			Expect(dbHeader.BlockNumber).To(Equal(header.BlockNumber))
 			Expect(dbHeader.Hash).To(Equal(header.Hash))
+			Expect(dbHeader.ParentHash).To(Equal(header.ParentHash))
 			Expect(dbHeader.Raw).To(MatchJSON(header.Raw))
 			Expect(dbHeader.Timestamp).To(Equal(header.Timestamp))
 		})
@@ -293,7 +572,7 @@ var _ = Describe("Block header repository", func() {
 			Expect(createErr).NotTo(HaveOccurred())
 
 			dbTwo := test_config.NewTestDB(test_config.NewTestNode())
-			repoTwo := repositories.NewHeaderRepository(dbTwo)
+			repoTwo := repositories.NewHeaderRepository(dbTwo, repositories.DefaultHeaderReorgWindow)
 
 			result, readErr := repoTwo.GetHeaderByBlockNumber(header.BlockNumber)
 
@@ -390,7 +669,7 @@ var _ = Describe("Block header repository", func() {
 			Expect(createThreeErr).NotTo(HaveOccurred())
 
 			dbTwo := test_config.NewTestDB(test_config.NewTestNode())
-			repoTwo := repositories.NewHeaderRepository(dbTwo)
+			repoTwo := repositories.NewHeaderRepository(dbTwo, repositories.DefaultHeaderReorgWindow)
 
 			missingBlockNumbers, err := repoTwo.MissingBlockNumbers(1, 5)
 			Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,53 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories
+
+import (
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+type OrphanedHeadersRepository struct {
+	db *postgres.DB
+}
+
+func NewOrphanedHeadersRepository(db *postgres.DB) OrphanedHeadersRepository {
+	return OrphanedHeadersRepository{db: db}
+}
+
+// GetUnhandledOrphanedHeaders returns headers replaced by a reorg whose derived data the named transformer has not
+// yet cleaned up. Headers flagged handled before cleanup was recorded per transformer count as handled by all.
+func (repository OrphanedHeadersRepository) GetUnhandledOrphanedHeaders(transformerName string) ([]core.OrphanedHeader, error) {
+	var orphanedHeaders []core.OrphanedHeader
+	err := repository.db.Select(&orphanedHeaders,
+		`SELECT id, header_id, block_number, hash
+		FROM public.orphaned_headers
+		WHERE handled = false
+		  AND NOT EXISTS(SELECT 1
+		                 FROM public.handled_orphaned_headers
+		                 WHERE handled_orphaned_headers.orphaned_header_id = orphaned_headers.id
+		                   AND handled_orphaned_headers.transformer_name = $1)
+		ORDER BY id ASC`, transformerName)
+	return orphanedHeaders, err
+}
+
+// MarkOrphanedHeaderHandled records that the named transformer has cleaned up derived data for an orphaned header
+func (repository OrphanedHeadersRepository) MarkOrphanedHeaderHandled(id int64, transformerName string) error {
+	_, err := repository.db.Exec(`INSERT INTO public.handled_orphaned_headers (orphaned_header_id, transformer_name)
+		VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, transformerName)
+	return err
+}
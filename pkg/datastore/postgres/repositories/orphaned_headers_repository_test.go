@@ -0,0 +1,120 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories_test
+
+import (
+	"math/rand"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Orphaned headers repository", func() {
+	var (
+		db              *postgres.DB
+		repository      datastore.OrphanedHeadersRepository
+		originalHeader  core.Header
+		headerID        int64
+		transformerName = "transformer"
+	)
+
+	BeforeEach(func() {
+		db = test_config.NewTestDB(test_config.NewTestNode())
+		test_config.CleanTestDB(db)
+		repository = repositories.NewOrphanedHeadersRepository(db)
+
+		headerRepository := repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
+		originalHeader = fakes.GetFakeHeader(rand.Int63n(50000000))
+		var createErr error
+		headerID, createErr = headerRepository.CreateOrUpdateHeader(originalHeader)
+		Expect(createErr).NotTo(HaveOccurred())
+		_, replaceErr := headerRepository.CreateOrUpdateHeader(fakes.GetFakeHeader(originalHeader.BlockNumber))
+		Expect(replaceErr).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		closeErr := db.Close()
+		Expect(closeErr).NotTo(HaveOccurred())
+	})
+
+	Describe("GetUnhandledOrphanedHeaders", func() {
+		It("returns headers replaced by a reorg", func() {
+			orphanedHeaders, err := repository.GetUnhandledOrphanedHeaders(transformerName)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(orphanedHeaders)).To(Equal(1))
+			Expect(orphanedHeaders[0].HeaderID).To(Equal(headerID))
+			Expect(orphanedHeaders[0].BlockNumber).To(Equal(originalHeader.BlockNumber))
+			Expect(orphanedHeaders[0].Hash).To(Equal(originalHeader.Hash))
+		})
+
+		It("excludes headers flagged handled before cleanup was recorded per transformer", func() {
+			db.MustExec(`UPDATE public.orphaned_headers SET handled = true`)
+
+			orphanedHeaders, err := repository.GetUnhandledOrphanedHeaders(transformerName)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphanedHeaders).To(BeEmpty())
+		})
+	})
+
+	Describe("MarkOrphanedHeaderHandled", func() {
+		It("excludes handled orphaned headers from subsequent queries", func() {
+			orphanedHeaders, getErr := repository.GetUnhandledOrphanedHeaders(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(orphanedHeaders)).To(Equal(1))
+
+			err := repository.MarkOrphanedHeaderHandled(orphanedHeaders[0].ID, transformerName)
+
+			Expect(err).NotTo(HaveOccurred())
+			remaining, getRemainingErr := repository.GetUnhandledOrphanedHeaders(transformerName)
+			Expect(getRemainingErr).NotTo(HaveOccurred())
+			Expect(remaining).To(BeEmpty())
+		})
+
+		It("leaves the header unhandled for other transformers", func() {
+			orphanedHeaders, getErr := repository.GetUnhandledOrphanedHeaders(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(orphanedHeaders)).To(Equal(1))
+
+			err := repository.MarkOrphanedHeaderHandled(orphanedHeaders[0].ID, transformerName)
+
+			Expect(err).NotTo(HaveOccurred())
+			remaining, getRemainingErr := repository.GetUnhandledOrphanedHeaders("other-transformer")
+			Expect(getRemainingErr).NotTo(HaveOccurred())
+			Expect(remaining).To(Equal(orphanedHeaders))
+		})
+
+		It("does not error if the header is marked handled twice", func() {
+			orphanedHeaders, getErr := repository.GetUnhandledOrphanedHeaders(transformerName)
+			Expect(getErr).NotTo(HaveOccurred())
+			Expect(len(orphanedHeaders)).To(Equal(1))
+			markErr := repository.MarkOrphanedHeaderHandled(orphanedHeaders[0].ID, transformerName)
+			Expect(markErr).NotTo(HaveOccurred())
+
+			err := repository.MarkOrphanedHeaderHandled(orphanedHeaders[0].ID, transformerName)
+
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
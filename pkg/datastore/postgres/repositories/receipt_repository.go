@@ -17,6 +17,7 @@
 package repositories
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/jmoiron/sqlx"
 	"github.com/makerdao/vulcanizedb/libraries/shared/repository"
@@ -33,12 +34,13 @@ func (ReceiptRepository) CreateReceiptInTx(headerID, transactionID int64, receip
 		return receiptId, getAddressErr
 	}
 	err := tx.QueryRowx(`INSERT INTO public.receipts
-               (header_id, transaction_id, contract_address_id, cumulative_gas_used, gas_used, state_root, status, tx_hash, rlp)
-               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+               (header_id, transaction_id, contract_address_id, cumulative_gas_used, gas_used, state_root, status, tx_hash, rlp, logs_bloom)
+               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 			   ON CONFLICT (header_id, transaction_id) DO UPDATE
-			   SET (contract_address_id, cumulative_gas_used, gas_used, state_root, status, tx_hash, rlp) = ($3, $4::NUMERIC, $5::NUMERIC, $6, $7, $8, $9)
+			   SET (contract_address_id, cumulative_gas_used, gas_used, state_root, status, tx_hash, rlp, logs_bloom) = ($3, $4::NUMERIC, $5::NUMERIC, $6, $7, $8, $9, $10)
                RETURNING id`,
-		headerID, transactionID, addressId, receipt.CumulativeGasUsed, receipt.GasUsed, receipt.StateRoot, receipt.Status, receipt.TxHash, receipt.Rlp).Scan(&receiptId)
+		headerID, transactionID, addressId, receipt.CumulativeGasUsed, receipt.GasUsed, receipt.StateRoot, receipt.Status,
+		receipt.TxHash, receipt.Rlp, common.FromHex(receipt.Bloom)).Scan(&receiptId)
 	if err != nil {
 		log.Error("header_repository: error inserting receipt: ", err)
 		return receiptId, err
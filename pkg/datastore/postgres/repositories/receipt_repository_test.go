@@ -41,7 +41,7 @@ var _ = Describe("Receipt Repository", func() {
 	BeforeEach(func() {
 		test_config.CleanTestDB(db)
 		receiptRepo = repositories.ReceiptRepository{}
-		headerRepo = repositories.NewHeaderRepository(db)
+		headerRepo = repositories.NewHeaderRepository(db, repositories.DefaultHeaderReorgWindow)
 		header = fakes.GetFakeHeader(rand.Int63())
 	})
 
@@ -57,7 +57,7 @@ var _ = Describe("Receipt Repository", func() {
 				Data:     []byte{},
 				From:     fromAddress.Hex(),
 				GasLimit: 0,
-				GasPrice: 0,
+				GasPrice: "0",
 				Hash:     txHash.Hex(),
 				Nonce:    0,
 				Raw:      []byte{},
@@ -0,0 +1,39 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories
+
+import (
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+)
+
+type ReorgRepository struct {
+	db *postgres.DB
+}
+
+func NewReorgRepository(db *postgres.DB) ReorgRepository {
+	return ReorgRepository{db: db}
+}
+
+// CreateReorg records a reorg so that processes other than headerSync can react to it
+func (repository ReorgRepository) CreateReorg(event core.ReorgEvent) error {
+	_, err := repository.db.Exec(`INSERT INTO public.reorgs (common_ancestor_block_number, depth, old_hashes, new_hashes)
+		VALUES ($1, $2, $3, $4)`,
+		event.CommonAncestorBlockNumber, event.Depth, pq.Array(event.OldHashes()), pq.Array(event.NewHashes()))
+	return err
+}
@@ -0,0 +1,68 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package repositories_test
+
+import (
+	"github.com/lib/pq"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres"
+	"github.com/makerdao/vulcanizedb/pkg/datastore/postgres/repositories"
+	"github.com/makerdao/vulcanizedb/test_config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reorg repository", func() {
+	var db *postgres.DB
+
+	BeforeEach(func() {
+		db = test_config.NewTestDB(test_config.NewTestNode())
+		test_config.CleanTestDB(db)
+	})
+
+	AfterEach(func() {
+		closeErr := db.Close()
+		Expect(closeErr).NotTo(HaveOccurred())
+	})
+
+	It("records the reorg", func() {
+		event := core.ReorgEvent{
+			CommonAncestorBlockNumber: 1,
+			Depth:                     2,
+			ReplacedHeaders: []core.ReplacedHeader{
+				{BlockNumber: 2, OldHeaderID: 2, OldHash: "0x2", NewHash: "0x2b"},
+				{BlockNumber: 3, OldHeaderID: 3, OldHash: "0x3", NewHash: "0x3b"},
+			},
+		}
+
+		err := repositories.NewReorgRepository(db).CreateReorg(event)
+
+		Expect(err).NotTo(HaveOccurred())
+		var reorg struct {
+			CommonAncestorBlockNumber int64 `db:"common_ancestor_block_number"`
+			Depth                     int
+			OldHashes                 pq.StringArray `db:"old_hashes"`
+			NewHashes                 pq.StringArray `db:"new_hashes"`
+		}
+		readErr := db.Get(&reorg, `SELECT common_ancestor_block_number, depth, old_hashes, new_hashes FROM public.reorgs`)
+		Expect(readErr).NotTo(HaveOccurred())
+		Expect(reorg.CommonAncestorBlockNumber).To(Equal(int64(1)))
+		Expect(reorg.Depth).To(Equal(2))
+		Expect([]string(reorg.OldHashes)).To(Equal([]string{"0x2", "0x3"}))
+		Expect([]string(reorg.NewHashes)).To(Equal([]string{"0x2b", "0x3b"}))
+	})
+})
@@ -17,6 +17,8 @@
 package datastore
 
 import (
+	"time"
+
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/jmoiron/sqlx"
 	"github.com/makerdao/vulcanizedb/pkg/core"
@@ -26,21 +28,41 @@ type AddressRepository interface {
 	GetOrCreateAddress(address string) (int, error)
 }
 
+type BackFillProgressRepository interface {
+	GetCompletedRanges(watchedLogsHash string) ([]core.BlockRange, error)
+	MarkRangeComplete(watchedLogsHash string, blockRange core.BlockRange) error
+}
+
 type CheckedHeadersRepository interface {
 	MarkHeaderChecked(headerID int64) error
+	MarkHeadersChecked(headerIDs []int64) error
 	MarkSingleHeaderUnchecked(blockNumber int64) error
 	UncheckedHeaders(startingBlockNumber, endingBlockNumber, checkCount int64) ([]core.Header, error)
 }
 
 type CheckedLogsRepository interface {
-	AlreadyWatchingLog(addresses []string, topic0 string) (bool, error)
-	MarkLogWatched(addresses []string, topic0 string) error
+	AlreadyWatchingLog(addresses []string, topics core.Topics) (bool, error)
+	GetLogsToBackFill() ([]core.WatchedLog, error)
+	GetWatchedLogs() ([]core.WatchedLog, error)
+	MarkLogsBackFilledThrough(watchedLogIDs []int64, fromBlock, throughBlock int64) error
+	MarkLogsForBackFill(watchedLogIDs []int64, blockRange core.BlockRange) error
+	MarkLogWatched(addresses []string, topics core.Topics, startingBlock, endingBlock int64) error
+}
+
+type DeadLetterRepository interface {
+	DiscardDeadLetterLogs(ids []int64) error
+	GetDeadLetterLogs(transformerName string) ([]core.DeadLetterLog, error)
+	GetSkippedLogIDs(transformerName string, eventLogIDs []int64, maxAttempts int) ([]int64, error)
+	RecordFailure(eventLogID int64, transformerName, errorMessage string, retryDelay time.Duration) (int, error)
+	RetryDeadLetterLogs(ids []int64) error
 }
 
 type HeaderRepository interface {
 	CreateOrUpdateHeader(header core.Header) (int64, error)
+	CreateOrUpdateHeaders(headers []core.Header) error
 	CreateTransactions(headerID int64, transactions []core.TransactionModel) error
 	CreateTransactionInTx(tx *sqlx.Tx, headerID int64, transaction core.TransactionModel) (int64, error)
+	CreateTransactionsWithReceipts(headerID int64, transactions []core.TransactionModel) error
 	GetHeaderByBlockNumber(blockNumber int64) (core.Header, error)
 	GetHeaderByID(id int64) (core.Header, error)
 	GetHeadersInRange(startingBlock, endingBlock int64) ([]core.Header, error)
@@ -48,7 +70,17 @@ type HeaderRepository interface {
 	GetMostRecentHeaderBlockNumber() (int64, error)
 }
 
+type OrphanedHeadersRepository interface {
+	GetUnhandledOrphanedHeaders(transformerName string) ([]core.OrphanedHeader, error)
+	MarkOrphanedHeaderHandled(id int64, transformerName string) error
+}
+
+type ReorgRepository interface {
+	CreateReorg(event core.ReorgEvent) error
+}
+
 type EventLogRepository interface {
-	GetUntransformedEventLogs(minID, limit int) ([]core.EventLog, error)
+	GetUntransformedEventLogs(transformerName string, addresses []string, topics core.Topics, minID, limit int) ([]core.EventLog, error)
 	CreateEventLogs(headerID int64, logs []types.Log) error
+	MarkLogsTransformed(transformerName string, logIDs []int64) error
 }
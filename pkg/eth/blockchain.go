@@ -18,6 +18,7 @@ package eth
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 	"strconv"
 
@@ -30,7 +31,11 @@ import (
 	"golang.org/x/net/context"
 )
 
-var ErrEmptyHeader = errors.New("empty header returned over RPC")
+var (
+	ErrEmptyHeader = errors.New("empty header returned over RPC")
+	// MaxBatchElementRetries is how many times elements of a batch that the node answered with an error are re-sent
+	MaxBatchElementRetries = 2
+)
 
 const MAX_BATCH_SIZE = 100
 
@@ -88,7 +93,7 @@ func (blockChain *BlockChain) GetTransactions(transactionHashes []common.Hash) (
 		batch = append(batch, batchElem)
 	}
 
-	rpcErr := blockChain.rpcClient.BatchCall(batch)
+	rpcErr := blockChain.batchCall(batch)
 	if rpcErr != nil {
 		return []core.TransactionModel{}, rpcErr
 	}
@@ -96,6 +101,39 @@ func (blockChain *BlockChain) GetTransactions(transactionHashes []common.Hash) (
 	return blockChain.transactionConverter.ConvertRpcTransactionsToModels(transactions)
 }
 
+// GetTransactionReceipts fetches the receipts of the transactions in one batch, in the order of the hashes
+func (blockChain *BlockChain) GetTransactionReceipts(transactionHashes []common.Hash) ([]core.Receipt, error) {
+	var batch []core.BatchElem
+	gethReceipts := make([]*types.Receipt, len(transactionHashes))
+
+	for index, transactionHash := range transactionHashes {
+		batchElem := core.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Result: &gethReceipts[index],
+			Args:   []interface{}{transactionHash},
+		}
+		batch = append(batch, batchElem)
+	}
+
+	rpcErr := blockChain.batchCall(batch)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	receipts := make([]core.Receipt, len(gethReceipts))
+	for index, gethReceipt := range gethReceipts {
+		if gethReceipt == nil {
+			return nil, fmt.Errorf("no receipt found for transaction %s", transactionHashes[index].Hex())
+		}
+		receipt, convertErr := converters.ToCoreReceipt(gethReceipt)
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		receipts[index] = receipt
+	}
+	return receipts, nil
+}
+
 func (blockChain *BlockChain) LastBlock() (*big.Int, error) {
 	block, err := blockChain.ethClient.HeaderByNumber(context.Background(), nil)
 	if err != nil {
@@ -119,7 +157,7 @@ func (blockChain *BlockChain) BatchGetStorageAt(account common.Address, keys []c
 		batch = append(batch, batchElem)
 	}
 
-	rpcErr := blockChain.rpcClient.BatchCall(batch)
+	rpcErr := blockChain.batchCall(batch)
 	if rpcErr != nil {
 		return nil, rpcErr
 	}
@@ -186,7 +224,7 @@ func (blockChain *BlockChain) getPOAHeaders(blockNumbers []int64) (headers []cor
 		batch = append(batch, batchElem)
 	}
 
-	err = blockChain.rpcClient.BatchCall(batch)
+	err = blockChain.batchCall(batch)
 	if err != nil {
 		return headers, err
 	}
@@ -248,7 +286,7 @@ func (blockChain *BlockChain) getPOWHeaders(blockNumbers []int64) (headers []cor
 		batch = append(batch, batchElem)
 	}
 
-	err = blockChain.rpcClient.BatchCall(batch)
+	err = blockChain.batchCall(batch)
 	if err != nil {
 		return headers, err
 	}
@@ -262,3 +300,56 @@ func (blockChain *BlockChain) getPOWHeaders(blockNumbers []int64) (headers []cor
 
 	return headers, err
 }
+
+// batchCall sends the batch, then re-sends only the elements the node answered with an error.
+// Elements that keep failing are reported in a *core.PartialBatchError.
+func (blockChain *BlockChain) batchCall(batch []core.BatchElem) error {
+	err := blockChain.rpcClient.BatchCall(batch)
+	if err != nil {
+		return err
+	}
+	failedIndexes := getFailedIndexes(batch)
+	for attempt := 0; attempt < MaxBatchElementRetries && len(failedIndexes) > 0; attempt++ {
+		retryBatch := make([]core.BatchElem, len(failedIndexes))
+		for i, batchIndex := range failedIndexes {
+			retryBatch[i] = batch[batchIndex]
+			retryBatch[i].Error = nil
+		}
+		retryErr := blockChain.rpcClient.BatchCall(retryBatch)
+		if retryErr != nil {
+			return retryErr
+		}
+		for i, batchIndex := range failedIndexes {
+			batch[batchIndex].Error = retryBatch[i].Error
+		}
+		failedIndexes = getFailedIndexes(batch)
+	}
+	if len(failedIndexes) == 0 {
+		return nil
+	}
+	partialErr := &core.PartialBatchError{Total: len(batch)}
+	for _, batchIndex := range failedIndexes {
+		partialErr.Failed = append(partialErr.Failed, core.FailedBatchElem{
+			Index:  batchIndex,
+			Method: batch[batchIndex].Method,
+			Args:   batch[batchIndex].Args,
+			Err:    batch[batchIndex].Error,
+		})
+	}
+	return partialErr
+}
+
+func getFailedIndexes(batch []core.BatchElem) []int {
+	var failedIndexes []int
+	for index, batchElem := range batch {
+		if batchElem.Error != nil {
+			failedIndexes = append(failedIndexes, index)
+		}
+	}
+	return failedIndexes
+}
+
+// SubscribeNewHeads sends a notification for every new chain head the node announces
+func (blockChain *BlockChain) SubscribeNewHeads(heads chan core.NewHead) (core.Subscription, error) {
+	return blockChain.rpcClient.Subscribe("eth", heads, "newHeads")
+}
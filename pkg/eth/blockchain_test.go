@@ -18,6 +18,7 @@ package eth_test
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"math/rand"
 
@@ -179,6 +180,41 @@ var _ = Describe("Geth blockchain", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mockTransactionConverter.ConvertRpcTransactionsToModelsCalled).To(BeTrue())
 		})
+
+		It("returns an error instead of converting transactions that failed to fetch", func() {
+			mockRpcClient.BatchElemErrs = []map[int]error{{1: fakes.FakeError}}
+			for i := 0; i < eth.MaxBatchElementRetries; i++ {
+				mockRpcClient.BatchElemErrs = append(mockRpcClient.BatchElemErrs, map[int]error{0: fakes.FakeError})
+			}
+
+			_, err := blockChain.GetTransactions([]common.Hash{{}, {}})
+
+			var partialErr *core.PartialBatchError
+			Expect(errors.As(err, &partialErr)).To(BeTrue())
+			Expect(mockTransactionConverter.ConvertRpcTransactionsToModelsCalled).To(BeFalse())
+		})
+	})
+
+	Describe("getting transaction receipts", func() {
+		It("fetches the receipt for each hash", func() {
+			mockRpcClient.ReceiptsToReturn = []*types.Receipt{{Status: 1}, {Status: 0}}
+
+			receipts, err := blockChain.GetTransactionReceipts([]common.Hash{{}, {}})
+
+			Expect(err).NotTo(HaveOccurred())
+			mockRpcClient.AssertBatchCalledWith("eth_getTransactionReceipt", 2)
+			Expect(receipts).To(HaveLen(2))
+			Expect(receipts[0].Status).To(Equal(1))
+			Expect(receipts[1].Status).To(Equal(0))
+		})
+
+		It("returns an error if a transaction has no receipt", func() {
+			mockRpcClient.ReceiptsToReturn = []*types.Receipt{{Status: 1}}
+
+			_, err := blockChain.GetTransactionReceipts([]common.Hash{{}, fakes.FakeHash})
+
+			Expect(err).To(MatchError(ContainSubstring(fakes.FakeHash.Hex())))
+		})
 	})
 
 	Describe("getting the most recent block number", func() {
@@ -217,5 +253,46 @@ var _ = Describe("Geth blockchain", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result).To(Equal(map[common.Hash][]byte{fakeKey: fakeStorageValue}))
 		})
+
+		It("re-sends only the keys that failed", func() {
+			keys := []common.Hash{test_data.FakeHash(), test_data.FakeHash(), test_data.FakeHash()}
+			mockRpcClient.BatchElemErrs = []map[int]error{{1: fakes.FakeError}}
+
+			_, err := blockChain.BatchGetStorageAt(account, keys, blockNumber)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockRpcClient.BatchSizes).To(Equal([]int{3, 1}))
+		})
+
+		It("returns a partial batch error for keys that keep failing", func() {
+			keys := []common.Hash{test_data.FakeHash(), test_data.FakeHash()}
+			for i := 0; i <= eth.MaxBatchElementRetries; i++ {
+				mockRpcClient.BatchElemErrs = append(mockRpcClient.BatchElemErrs, map[int]error{0: fakes.FakeError})
+			}
+			mockRpcClient.BatchElemErrs[0][1] = fakes.FakeError
+
+			result, err := blockChain.BatchGetStorageAt(account, keys, blockNumber)
+
+			Expect(result).To(BeNil())
+			var partialErr *core.PartialBatchError
+			Expect(errors.As(err, &partialErr)).To(BeTrue())
+			Expect(partialErr.Total).To(Equal(2))
+			Expect(partialErr.Failed).To(HaveLen(1))
+			Expect(partialErr.Failed[0].Index).To(Equal(0))
+			Expect(partialErr.Failed[0].Method).To(Equal("eth_getStorageAt"))
+			Expect(errors.Is(err, fakes.FakeError)).To(BeTrue())
+			Expect(mockRpcClient.BatchSizes).To(Equal([]int{2, 2, 1}))
+		})
+	})
+
+	Describe("subscribing to new heads", func() {
+		It("subscribes to newHeads with the rpcClient", func() {
+			heads := make(chan core.NewHead)
+
+			_, err := blockChain.SubscribeNewHeads(heads)
+
+			Expect(err).NotTo(HaveOccurred())
+			mockRpcClient.AssertSubscribeCalledWith("eth", heads, []interface{}{"newHeads"})
+		})
 	})
 })
@@ -0,0 +1,166 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/makerdao/vulcanizedb/pkg/config"
+)
+
+// Dial connects to the endpoint described by the client config over its configured transport
+func Dial(clientConfig config.Client) (*rpc.Client, error) {
+	validateErr := clientConfig.Validate()
+	if validateErr != nil {
+		return nil, validateErr
+	}
+	transport, _ := clientConfig.TransportType()
+	switch transport {
+	case config.HTTPTransport:
+		return dialHTTP(clientConfig)
+	case config.WSTransport:
+		return dialWebsocket(clientConfig)
+	default:
+		return dialIPC(clientConfig)
+	}
+}
+
+// DialEndpoint connects to a single node, throttling requests and splitting batches as configured
+func DialEndpoint(clientConfig config.Client) (Endpoint, error) {
+	rawClient, err := Dial(clientConfig)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	limiter := NewRateLimiter(clientConfig.RequestsPerSecond, clientConfig.MaxBatchSize)
+	return Endpoint{
+		Name:      clientConfig.StableNodeID(),
+		RpcClient: NewRateLimitedRpcClient(NewRpcClient(rawClient, clientConfig), limiter),
+		EthClient: NewRateLimitedEthClient(NewEthClient(ethclient.NewClient(rawClient)), limiter),
+	}, nil
+}
+
+func dialIPC(clientConfig config.Client) (*rpc.Client, error) {
+	ctx, cancel := dialContext(clientConfig)
+	defer cancel()
+	return rpc.DialIPC(ctx, clientConfig.Endpoint())
+}
+
+func dialHTTP(clientConfig config.Client) (*rpc.Client, error) {
+	tlsConfig, tlsErr := buildTLSConfig(clientConfig.TLS)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	endpoint, endpointErr := withBasicAuth(clientConfig)
+	if endpointErr != nil {
+		return nil, endpointErr
+	}
+	baseTransport := http.DefaultTransport.(*http.Transport).Clone()
+	baseTransport.TLSClientConfig = tlsConfig
+	httpClient := &http.Client{
+		Timeout: clientConfig.Timeout,
+		Transport: headerRoundTripper{
+			headers: clientConfig.AuthHeaders(),
+			next:    baseTransport,
+		},
+	}
+	return rpc.DialHTTPWithClient(endpoint, httpClient)
+}
+
+func dialWebsocket(clientConfig config.Client) (*rpc.Client, error) {
+	tlsConfig, tlsErr := buildTLSConfig(clientConfig.TLS)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	// The bundled go-ethereum websocket client only forwards basic auth taken from the URL's user info
+	endpoint, endpointErr := withBasicAuth(clientConfig)
+	if endpointErr != nil {
+		return nil, endpointErr
+	}
+	dialer := websocket.Dialer{
+		HandshakeTimeout: clientConfig.Timeout,
+		TLSClientConfig:  tlsConfig,
+	}
+	ctx, cancel := dialContext(clientConfig)
+	defer cancel()
+	return rpc.DialWebsocketWithDialer(ctx, endpoint, "", dialer)
+}
+
+func dialContext(clientConfig config.Client) (context.Context, context.CancelFunc) {
+	if clientConfig.Timeout > 0 {
+		return context.WithTimeout(context.Background(), clientConfig.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func withBasicAuth(clientConfig config.Client) (string, error) {
+	if clientConfig.BasicAuthUser == "" && clientConfig.BasicAuthPassword == "" {
+		return clientConfig.Endpoint(), nil
+	}
+	endpointURL, err := url.Parse(clientConfig.Endpoint())
+	if err != nil {
+		return "", fmt.Errorf("invalid client url: %w", err)
+	}
+	endpointURL.User = url.UserPassword(clientConfig.BasicAuthUser, clientConfig.BasicAuthPassword)
+	return endpointURL.String(), nil
+}
+
+func buildTLSConfig(tlsOptions config.ClientTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: tlsOptions.InsecureSkipVerify}
+	if tlsOptions.CAFile != "" {
+		caCert, err := ioutil.ReadFile(tlsOptions.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("client CA file contains no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tlsOptions.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsOptions.CertFile, tlsOptions.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// headerRoundTripper sets the configured auth headers on every outgoing request
+type headerRoundTripper struct {
+	headers http.Header
+	next    http.RoundTripper
+}
+
+func (rt headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	for key, values := range rt.headers {
+		outgoing.Header[key] = values
+	}
+	return rt.next.RoundTrip(outgoing)
+}
@@ -0,0 +1,140 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/makerdao/vulcanizedb/pkg/config"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/eth/client"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dial", func() {
+	var (
+		server          *httptest.Server
+		receivedHeaders http.Header
+		responseDelay   time.Duration
+	)
+
+	BeforeEach(func() {
+		responseDelay = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedHeaders = r.Header.Clone()
+			time.Sleep(responseDelay)
+			body, _ := ioutil.ReadAll(r.Body)
+			var request struct {
+				ID json.RawMessage `json:"id"`
+			}
+			_ = json.Unmarshal(body, &request)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":"1"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends configured headers and bearer token with http requests", func() {
+		clientConfig := config.Client{
+			URL:         server.URL,
+			Headers:     map[string]string{"X-Api-Key": "abc"},
+			BearerToken: "token",
+		}
+		rawClient, err := client.Dial(clientConfig)
+		Expect(err).NotTo(HaveOccurred())
+
+		var version string
+		err = client.NewRpcClient(rawClient, clientConfig).CallContext(context.Background(), &version, "net_version")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1"))
+		Expect(receivedHeaders.Get("X-Api-Key")).To(Equal("abc"))
+		Expect(receivedHeaders.Get("Authorization")).To(Equal("Bearer token"))
+	})
+
+	It("sends basic auth credentials", func() {
+		clientConfig := config.Client{URL: server.URL, BasicAuthUser: "user", BasicAuthPassword: "pass"}
+		rawClient, err := client.Dial(clientConfig)
+		Expect(err).NotTo(HaveOccurred())
+
+		var version string
+		err = client.NewRpcClient(rawClient, clientConfig).CallContext(context.Background(), &version, "net_version")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(receivedHeaders.Get("Authorization")).To(Equal("Basic dXNlcjpwYXNz"))
+	})
+
+	It("times out requests that exceed the configured timeout", func() {
+		responseDelay = 200 * time.Millisecond
+		clientConfig := config.Client{URL: server.URL, Timeout: 20 * time.Millisecond}
+		rawClient, err := client.Dial(clientConfig)
+		Expect(err).NotTo(HaveOccurred())
+
+		var version string
+		err = client.NewRpcClient(rawClient, clientConfig).CallContext(context.Background(), &version, "net_version")
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("copies per-element errors back into the batch", func() {
+		batchServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"0x01"},{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"missing trie node"}}]`))
+		}))
+		defer batchServer.Close()
+		clientConfig := config.Client{URL: batchServer.URL}
+		rawClient, err := client.Dial(clientConfig)
+		Expect(err).NotTo(HaveOccurred())
+		var first, second hexutil.Bytes
+		batch := []core.BatchElem{
+			{Method: "eth_getStorageAt", Result: &first},
+			{Method: "eth_getStorageAt", Result: &second},
+		}
+
+		err = client.NewRpcClient(rawClient, clientConfig).BatchCall(batch)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch[0].Error).NotTo(HaveOccurred())
+		Expect(first).To(Equal(hexutil.Bytes{1}))
+		Expect(batch[1].Error).To(MatchError("missing trie node"))
+	})
+
+	It("returns an error for an invalid config", func() {
+		_, err := client.Dial(config.Client{})
+
+		Expect(err).To(MatchError(config.ErrMissingClientEndpoint))
+	})
+
+	It("records the stable node ID and host on the rpc client", func() {
+		clientConfig := config.Client{URL: "https://mainnet.infura.io/v3/key"}
+
+		rpcClient := client.NewRpcClient(nil, clientConfig)
+
+		Expect(rpcClient.NodeID()).To(Equal("mainnet.infura.io"))
+		Expect(rpcClient.Host()).To(Equal("mainnet.infura.io"))
+	})
+})
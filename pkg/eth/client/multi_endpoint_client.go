@@ -0,0 +1,393 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	DefaultHealthCheckInterval = 15 * time.Second
+	// MaxBlockLag is how many blocks an endpoint may trail the best known head before it is only used as a fallback
+	MaxBlockLag uint64 = 5
+	// MaxConsecutiveFailures is how many failed calls in a row take an endpoint out of rotation until its next health check
+	MaxConsecutiveFailures = 3
+	// LatencyResolution groups endpoints with similar latency so that small fluctuations don't flip routing between them
+	LatencyResolution = 10 * time.Millisecond
+
+	ErrNoHealthyEndpoints = errors.New("no healthy rpc endpoints available")
+	ErrNoEndpoints        = errors.New("multi-endpoint client requires at least one endpoint")
+)
+
+// Endpoint pairs the rpc and eth clients that talk to the same node
+type Endpoint struct {
+	Name      string
+	RpcClient core.RpcClient
+	EthClient core.EthClient
+}
+
+type chainIdentity struct {
+	genesisBlock string
+	networkID    string
+}
+
+type endpointState struct {
+	Endpoint
+	index               int
+	identity            *chainIdentity
+	mismatched          bool
+	healthy             bool
+	consecutiveFailures int
+	blockNumber         uint64
+	latency             time.Duration
+}
+
+// MultiEndpointClient implements core.RpcClient and core.EthClient on top of several nodes serving the same chain.
+// Calls go to the healthiest endpoint and are retried on the next one when the node can't be reached.
+// Endpoints reporting a different genesis block or network ID than the first verified endpoint are never used.
+type MultiEndpointClient struct {
+	HealthCheckInterval time.Duration
+	endpoints           []*endpointState
+	identity            *chainIdentity
+	mutex               sync.RWMutex
+	nodeID              string
+	host                string
+}
+
+// NewMultiEndpointClient health-checks every endpoint and returns an error if any reachable endpoint is on a
+// different chain than the others. nodeID identifies the composite node; it defaults to the first endpoint's ID.
+func NewMultiEndpointClient(endpoints []Endpoint, nodeID string) (*MultiEndpointClient, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	client := &MultiEndpointClient{
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		nodeID:              nodeID,
+		host:                endpoints[0].RpcClient.Host(),
+	}
+	if client.nodeID == "" {
+		client.nodeID = endpoints[0].RpcClient.NodeID()
+	}
+	for i, endpoint := range endpoints {
+		client.endpoints = append(client.endpoints, &endpointState{Endpoint: endpoint, index: i})
+	}
+	client.CheckHealth()
+	for _, endpoint := range client.endpoints {
+		if endpoint.mismatched {
+			return nil, fmt.Errorf("rpc endpoint %s is on a different chain (genesis %s, network %s) than %s (network %s)",
+				endpoint.Name, endpoint.identity.genesisBlock, endpoint.identity.networkID,
+				client.identity.genesisBlock, client.identity.networkID)
+		}
+	}
+	return client, nil
+}
+
+// MonitorHealth re-checks every endpoint each HealthCheckInterval until quit is closed
+func (client *MultiEndpointClient) MonitorHealth(quit <-chan bool) {
+	ticker := time.NewTicker(client.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			client.CheckHealth()
+		}
+	}
+}
+
+// CheckHealth probes every endpoint for its head block and, until verified, its chain identity
+func (client *MultiEndpointClient) CheckHealth() {
+	for _, endpoint := range client.endpoints {
+		client.checkEndpoint(endpoint)
+	}
+}
+
+func (client *MultiEndpointClient) checkEndpoint(endpoint *endpointState) {
+	client.mutex.RLock()
+	verified := endpoint.identity != nil
+	client.mutex.RUnlock()
+
+	ctx := context.Background()
+	var identity *chainIdentity
+	if !verified {
+		fetched, err := fetchChainIdentity(ctx, endpoint.RpcClient)
+		if err != nil {
+			logrus.Warnf("rpc endpoint %s failed identity check: %s", endpoint.Name, err.Error())
+			client.markUnhealthy(endpoint)
+			return
+		}
+		identity = fetched
+	}
+
+	start := time.Now()
+	var blockNumber hexutil.Uint64
+	err := endpoint.RpcClient.CallContext(ctx, &blockNumber, "eth_blockNumber")
+	latency := time.Since(start)
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if identity != nil {
+		endpoint.identity = identity
+		if client.identity == nil {
+			client.identity = identity
+		} else if *identity != *client.identity {
+			endpoint.mismatched = true
+			logrus.Errorf("rpc endpoint %s reports genesis %s and network %s, expected genesis %s and network %s; refusing to use it",
+				endpoint.Name, identity.genesisBlock, identity.networkID, client.identity.genesisBlock, client.identity.networkID)
+		}
+	}
+	if err != nil {
+		logrus.Warnf("rpc endpoint %s failed health check: %s", endpoint.Name, err.Error())
+		endpoint.healthy = false
+		return
+	}
+	endpoint.healthy = true
+	endpoint.consecutiveFailures = 0
+	endpoint.blockNumber = uint64(blockNumber)
+	endpoint.latency = latency
+}
+
+func fetchChainIdentity(ctx context.Context, rpcClient core.RpcClient) (*chainIdentity, error) {
+	var networkID string
+	err := rpcClient.CallContext(ctx, &networkID, "net_version")
+	if err != nil {
+		return nil, err
+	}
+	var genesis *types.Header
+	err = rpcClient.CallContext(ctx, &genesis, "eth_getBlockByNumber", "0x0", false)
+	if err != nil {
+		return nil, err
+	}
+	if genesis == nil {
+		return nil, errors.New("endpoint returned no genesis block")
+	}
+	return &chainIdentity{genesisBlock: genesis.Hash().Hex(), networkID: networkID}, nil
+}
+
+func (client *MultiEndpointClient) markUnhealthy(endpoint *endpointState) {
+	client.mutex.Lock()
+	endpoint.healthy = false
+	client.mutex.Unlock()
+}
+
+// candidates orders usable endpoints from healthiest to least healthy: endpoints near the best known head
+// come first, then those with fewer recent failures, then lower latency, then configuration order.
+func (client *MultiEndpointClient) candidates() []*endpointState {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+	var bestBlock uint64
+	var usable []*endpointState
+	for _, endpoint := range client.endpoints {
+		if endpoint.identity == nil || endpoint.mismatched || !endpoint.healthy {
+			continue
+		}
+		usable = append(usable, endpoint)
+		if endpoint.blockNumber > bestBlock {
+			bestBlock = endpoint.blockNumber
+		}
+	}
+	lagging := func(endpoint *endpointState) bool {
+		return bestBlock-endpoint.blockNumber > MaxBlockLag
+	}
+	sort.SliceStable(usable, func(i, j int) bool {
+		a, b := usable[i], usable[j]
+		if lagging(a) != lagging(b) {
+			return !lagging(a)
+		}
+		if a.consecutiveFailures != b.consecutiveFailures {
+			return a.consecutiveFailures < b.consecutiveFailures
+		}
+		if a.latency/LatencyResolution != b.latency/LatencyResolution {
+			return a.latency < b.latency
+		}
+		return a.index < b.index
+	})
+	return usable
+}
+
+func (client *MultiEndpointClient) recordSuccess(endpoint *endpointState) {
+	client.mutex.Lock()
+	endpoint.consecutiveFailures = 0
+	client.mutex.Unlock()
+}
+
+func (client *MultiEndpointClient) recordFailure(endpoint *endpointState, err error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	endpoint.consecutiveFailures++
+	if endpoint.consecutiveFailures >= MaxConsecutiveFailures {
+		endpoint.healthy = false
+	}
+	logrus.Warnf("call to rpc endpoint %s failed, trying next endpoint: %s", endpoint.Name, err.Error())
+}
+
+// withFailover runs call against each usable endpoint in turn until one succeeds. Errors returned by the node
+// itself (JSON-RPC errors) and cancellations of the caller's context are returned without trying other endpoints.
+func (client *MultiEndpointClient) withFailover(ctx context.Context, call func(endpoint *endpointState) error) error {
+	candidates := client.candidates()
+	if len(candidates) == 0 {
+		client.CheckHealth()
+		candidates = client.candidates()
+	}
+	if len(candidates) == 0 {
+		return ErrNoHealthyEndpoints
+	}
+	var lastErr error
+	for _, endpoint := range candidates {
+		err := call(endpoint)
+		if err == nil || !isRetryable(ctx, err) {
+			if err == nil {
+				client.recordSuccess(endpoint)
+			}
+			return err
+		}
+		client.recordFailure(endpoint, err)
+		lastErr = err
+	}
+	return fmt.Errorf("all rpc endpoints failed: %w", lastErr)
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	return !errors.Is(err, ethereum.NotFound)
+}
+
+func (client *MultiEndpointClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return client.withFailover(ctx, func(endpoint *endpointState) error {
+		return endpoint.RpcClient.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (client *MultiEndpointClient) BatchCall(batch []core.BatchElem) error {
+	return client.BatchCallContext(context.Background(), batch)
+}
+
+func (client *MultiEndpointClient) BatchCallContext(ctx context.Context, batch []core.BatchElem) error {
+	return client.withFailover(ctx, func(endpoint *endpointState) error {
+		return endpoint.RpcClient.BatchCallContext(ctx, batch)
+	})
+}
+
+func (client *MultiEndpointClient) NodeID() string {
+	return client.nodeID
+}
+
+func (client *MultiEndpointClient) Host() string {
+	return client.host
+}
+
+func (client *MultiEndpointClient) Subscribe(namespace string, payloadChan interface{}, args ...interface{}) (core.Subscription, error) {
+	var subscription core.Subscription
+	err := client.withFailover(context.Background(), func(endpoint *endpointState) error {
+		var subscribeErr error
+		subscription, subscribeErr = endpoint.RpcClient.Subscribe(namespace, payloadChan, args...)
+		return subscribeErr
+	})
+	return subscription, err
+}
+
+func (client *MultiEndpointClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var block *types.Block
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		block, callErr = endpoint.EthClient.BlockByNumber(ctx, number)
+		return callErr
+	})
+	return block, err
+}
+
+func (client *MultiEndpointClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		result, callErr = endpoint.EthClient.CallContract(ctx, msg, blockNumber)
+		return callErr
+	})
+	return result, err
+}
+
+func (client *MultiEndpointClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		logs, callErr = endpoint.EthClient.FilterLogs(ctx, q)
+		return callErr
+	})
+	return logs, err
+}
+
+func (client *MultiEndpointClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		header, callErr = endpoint.EthClient.HeaderByNumber(ctx, number)
+		return callErr
+	})
+	return header, err
+}
+
+func (client *MultiEndpointClient) SubscribeNewStateChanges(ctx context.Context, q ethereum.FilterQuery, ch chan<- filters.Payload) (ethereum.Subscription, error) {
+	var subscription ethereum.Subscription
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var subscribeErr error
+		subscription, subscribeErr = endpoint.EthClient.SubscribeNewStateChanges(ctx, q, ch)
+		return subscribeErr
+	})
+	return subscription, err
+}
+
+func (client *MultiEndpointClient) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
+	var sender common.Address
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		sender, callErr = endpoint.EthClient.TransactionSender(ctx, tx, block, index)
+		return callErr
+	})
+	return sender, err
+}
+
+func (client *MultiEndpointClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := client.withFailover(ctx, func(endpoint *endpointState) error {
+		var callErr error
+		receipt, callErr = endpoint.EthClient.TransactionReceipt(ctx, txHash)
+		return callErr
+	})
+	return receipt, err
+}
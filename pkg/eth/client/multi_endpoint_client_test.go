@@ -0,0 +1,234 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/eth/client"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type rpcError struct{}
+
+func (rpcError) Error() string  { return "execution reverted" }
+func (rpcError) ErrorCode() int { return -32000 }
+
+var _ = Describe("MultiEndpointClient", func() {
+	var (
+		genesis               *types.Header
+		primaryRpc, backupRpc *fakes.MockRpcClient
+		primaryEth, backupEth *fakes.MockEthClient
+		connectionErr         = errors.New("connection refused")
+	)
+
+	newMockRpcClient := func(nodeID string, blockNumber uint64) *fakes.MockRpcClient {
+		rpcClient := fakes.NewMockRpcClient()
+		rpcClient.SetNodeID(nodeID)
+		rpcClient.NetworkID = "1"
+		rpcClient.GenesisHeader = genesis
+		rpcClient.BlockNumber = blockNumber
+		return rpcClient
+	}
+
+	newClient := func() *client.MultiEndpointClient {
+		multiClient, err := client.NewMultiEndpointClient([]client.Endpoint{
+			{Name: "primary", RpcClient: primaryRpc, EthClient: primaryEth},
+			{Name: "backup", RpcClient: backupRpc, EthClient: backupEth},
+		}, "")
+		Expect(err).NotTo(HaveOccurred())
+		return multiClient
+	}
+
+	BeforeEach(func() {
+		genesis = &types.Header{Number: big.NewInt(0)}
+		primaryRpc = newMockRpcClient("primary", 100)
+		backupRpc = newMockRpcClient("backup", 100)
+		primaryEth = fakes.NewMockEthClient()
+		backupEth = fakes.NewMockEthClient()
+	})
+
+	It("requires at least one endpoint", func() {
+		_, err := client.NewMultiEndpointClient(nil, "")
+
+		Expect(err).To(MatchError(client.ErrNoEndpoints))
+	})
+
+	It("defaults the node ID to the first endpoint's", func() {
+		Expect(newClient().NodeID()).To(Equal("primary"))
+	})
+
+	It("uses a configured node ID", func() {
+		multiClient, err := client.NewMultiEndpointClient([]client.Endpoint{{Name: "primary", RpcClient: primaryRpc, EthClient: primaryEth}}, "archive")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(multiClient.NodeID()).To(Equal("archive"))
+	})
+
+	Describe("chain identity", func() {
+		It("refuses endpoints with a different network ID", func() {
+			backupRpc.NetworkID = "42"
+
+			_, err := client.NewMultiEndpointClient([]client.Endpoint{
+				{Name: "primary", RpcClient: primaryRpc, EthClient: primaryEth},
+				{Name: "backup", RpcClient: backupRpc, EthClient: backupEth},
+			}, "")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("backup"))
+		})
+
+		It("refuses endpoints with a different genesis block", func() {
+			backupRpc.GenesisHeader = &types.Header{Number: big.NewInt(0), Extra: []byte("other chain")}
+
+			_, err := client.NewMultiEndpointClient([]client.Endpoint{
+				{Name: "primary", RpcClient: primaryRpc, EthClient: primaryEth},
+				{Name: "backup", RpcClient: backupRpc, EthClient: backupEth},
+			}, "")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("never routes to an endpoint that was unreachable until its identity is verified", func() {
+			backupRpc.ConnectionErr = connectionErr
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+			backupRpc.ConnectionErr = nil
+			backupRpc.NetworkID = "42"
+
+			err := multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})
+
+			Expect(err).To(HaveOccurred())
+			Expect(backupRpc.BatchCallCount).To(BeZero())
+		})
+	})
+
+	Describe("routing", func() {
+		It("sends calls to the endpoint closest to the head of the chain", func() {
+			primaryRpc.BlockNumber = 80
+			multiClient := newClient()
+
+			err := multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backupRpc.BatchCallCount).To(Equal(1))
+			Expect(primaryRpc.BatchCallCount).To(BeZero())
+		})
+
+		It("retries a failed call on another endpoint", func() {
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+
+			var result string
+			err := multiClient.CallContext(context.Background(), &result, "net_version")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("1"))
+		})
+
+		It("retries eth client calls on another endpoint", func() {
+			multiClient := newClient()
+			primaryEth.SetHeaderByNumberErr(connectionErr)
+			backupEth.SetHeaderByNumberReturnHeader(&types.Header{Number: big.NewInt(123)})
+
+			header, err := multiClient.HeaderByNumber(context.Background(), big.NewInt(123))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(header.Number.Int64()).To(Equal(int64(123)))
+		})
+
+		It("does not retry errors returned by the node itself", func() {
+			multiClient := newClient()
+			primaryEth.SetCallContractErr(rpcError{})
+
+			_, err := multiClient.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+
+			Expect(err).To(MatchError(rpcError{}))
+		})
+
+		It("prefers other endpoints after a failed call", func() {
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+
+			for i := 0; i < 3; i++ {
+				Expect(multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})).To(Succeed())
+			}
+
+			Expect(primaryRpc.BatchCallCount).To(Equal(1))
+			Expect(backupRpc.BatchCallCount).To(Equal(3))
+		})
+
+		It("takes an endpoint out of rotation after repeated failures", func() {
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+			backupRpc.ConnectionErr = connectionErr
+			for i := 0; i < client.MaxConsecutiveFailures; i++ {
+				Expect(multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})).NotTo(Succeed())
+			}
+			backupRpc.ConnectionErr = nil
+			primaryCallsBefore := primaryRpc.BatchCallCount
+
+			Expect(multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})).To(Succeed())
+
+			Expect(primaryRpc.BatchCallCount).To(Equal(primaryCallsBefore))
+		})
+
+		It("puts a recovered endpoint back into rotation after a health check", func() {
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+			for i := 0; i < client.MaxConsecutiveFailures; i++ {
+				Expect(multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})).To(Succeed())
+			}
+			primaryRpc.ConnectionErr = nil
+			backupRpc.BlockNumber = 10
+
+			multiClient.CheckHealth()
+			countBefore := primaryRpc.BatchCallCount
+			Expect(multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})).To(Succeed())
+
+			Expect(primaryRpc.BatchCallCount).To(Equal(countBefore + 1))
+		})
+
+		It("returns an error when every endpoint fails", func() {
+			multiClient := newClient()
+			primaryRpc.ConnectionErr = connectionErr
+			backupRpc.ConnectionErr = connectionErr
+
+			err := multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})
+
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, connectionErr)).To(BeTrue())
+		})
+
+		It("returns an error when no endpoint is healthy", func() {
+			primaryRpc.ConnectionErr = connectionErr
+			backupRpc.ConnectionErr = connectionErr
+			multiClient := newClient()
+
+			err := multiClient.BatchCall([]core.BatchElem{{Method: "eth_getBlockByNumber"}})
+
+			Expect(err).To(MatchError(client.ErrNoHealthyEndpoints))
+		})
+	})
+})
@@ -0,0 +1,317 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	DefaultMaxBatchSize = 100
+	// MaxThrottleRetries is how many times a request rejected for rate limiting is retried before giving up
+	MaxThrottleRetries = 5
+	// ThrottleBackoff is how long to wait before retrying a request rejected for rate limiting; it doubles per retry
+	ThrottleBackoff = time.Second
+	// GrowBatchAfter is how many consecutive successful batches it takes to double a shrunken batch size
+	GrowBatchAfter = 20
+
+	batchTooLargeMessages = []string{"batch too large", "batch size too large", "batch limit", "batch size limit", "request entity too large"}
+	throttledMessages     = []string{"too many requests", "rate limit", "exceeded its requests"}
+)
+
+// RateLimiter throttles requests to a node and tracks the largest batch the node currently accepts.
+// It is shared by the rpc and eth clients for the same endpoint.
+type RateLimiter struct {
+	bucket       *TokenBucket
+	mutex        sync.Mutex
+	maxBatchSize int
+	batchSize    int
+	successes    int
+}
+
+// NewRateLimiter allows requestsPerSecond requests (a batch counts as one request); 0 disables throttling.
+// Batches are split into chunks of at most maxBatchSize elements, or DefaultMaxBatchSize if it is 0.
+func NewRateLimiter(requestsPerSecond float64, maxBatchSize int) *RateLimiter {
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+	limiter := &RateLimiter{maxBatchSize: maxBatchSize, batchSize: maxBatchSize}
+	if requestsPerSecond > 0 {
+		limiter.bucket = NewTokenBucket(requestsPerSecond, int(requestsPerSecond))
+	}
+	return limiter
+}
+
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	if limiter.bucket == nil {
+		return nil
+	}
+	return limiter.bucket.Wait(ctx)
+}
+
+// BatchSize returns the current maximum number of elements sent in one batch
+func (limiter *RateLimiter) BatchSize() int {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.batchSize
+}
+
+// shrink halves the batch size below the size that was rejected, and reports whether it could be reduced
+func (limiter *RateLimiter) shrink(rejectedSize int) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.successes = 0
+	if rejectedSize <= 1 {
+		return false
+	}
+	newSize := rejectedSize / 2
+	if newSize < limiter.batchSize {
+		limiter.batchSize = newSize
+		logrus.Infof("node rejected a batch of %d requests, reducing batch size to %d", rejectedSize, newSize)
+	}
+	return true
+}
+
+func (limiter *RateLimiter) recordSuccess() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if limiter.batchSize == limiter.maxBatchSize {
+		return
+	}
+	limiter.successes++
+	if limiter.successes >= GrowBatchAfter {
+		limiter.successes = 0
+		limiter.batchSize *= 2
+		if limiter.batchSize > limiter.maxBatchSize {
+			limiter.batchSize = limiter.maxBatchSize
+		}
+	}
+}
+
+func isBatchTooLarge(err error) bool {
+	return errorContainsAny(err, batchTooLargeMessages)
+}
+
+func isThrottled(err error) bool {
+	return errorContainsAny(err, throttledMessages)
+}
+
+func errorContainsAny(err error, messages []string) bool {
+	message := strings.ToLower(err.Error())
+	for _, candidate := range messages {
+		if strings.Contains(message, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// withThrottleRetries waits for the rate limiter before each attempt and retries with exponential backoff
+// while the node reports that we are being rate limited
+func (limiter *RateLimiter) withThrottleRetries(ctx context.Context, call func() error) error {
+	backoff := ThrottleBackoff
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := call()
+		if err == nil || !isThrottled(err) || attempt >= MaxThrottleRetries {
+			return err
+		}
+		logrus.Warnf("node is rate limiting requests, retrying in %s: %s", backoff, err.Error())
+		if waitErr := sleepContext(ctx, backoff); waitErr != nil {
+			return waitErr
+		}
+		backoff *= 2
+	}
+}
+
+// sleepContext waits for the duration, returning early with the context's error if it is cancelled
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimitedRpcClient throttles calls to the wrapped client and splits batches into chunks the node accepts,
+// shrinking the chunk size when the node rejects a batch as too large or rate limits it
+type RateLimitedRpcClient struct {
+	core.RpcClient
+	limiter *RateLimiter
+}
+
+func NewRateLimitedRpcClient(rpcClient core.RpcClient, limiter *RateLimiter) RateLimitedRpcClient {
+	return RateLimitedRpcClient{RpcClient: rpcClient, limiter: limiter}
+}
+
+func (client RateLimitedRpcClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return client.limiter.withThrottleRetries(ctx, func() error {
+		return client.RpcClient.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (client RateLimitedRpcClient) BatchCall(batch []core.BatchElem) error {
+	return client.BatchCallContext(context.Background(), batch)
+}
+
+// BatchCallContext sends the batch in chunks; cancelling ctx stops waiting for the rate limiter or a throttle backoff
+func (client RateLimitedRpcClient) BatchCallContext(ctx context.Context, batch []core.BatchElem) error {
+	return client.sendInChunks(ctx, batch, 0)
+}
+
+// sendInChunks sends the batch in chunks of the limiter's current batch size, which may shrink as chunks are sent
+func (client RateLimitedRpcClient) sendInChunks(ctx context.Context, batch []core.BatchElem, throttleRetries int) error {
+	for len(batch) > 0 {
+		size := client.limiter.BatchSize()
+		if size > len(batch) {
+			size = len(batch)
+		}
+		err := client.sendChunk(ctx, batch[:size], throttleRetries)
+		if err != nil {
+			return err
+		}
+		batch = batch[size:]
+	}
+	return nil
+}
+
+// sendChunk sends one chunk. When the node rejects it as too large, the batch size is halved and the chunk is
+// re-sent in smaller pieces; when the node rate limits it, it is retried after a backoff, also in smaller pieces
+// while the batch size can still be reduced.
+func (client RateLimitedRpcClient) sendChunk(ctx context.Context, chunk []core.BatchElem, throttleRetries int) error {
+	if err := client.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	err := client.RpcClient.BatchCallContext(ctx, chunk)
+	if err == nil {
+		client.limiter.recordSuccess()
+		return nil
+	}
+	switch {
+	case isBatchTooLarge(err):
+		if !client.limiter.shrink(len(chunk)) {
+			return err
+		}
+		return client.sendInChunks(ctx, chunk, throttleRetries)
+	case isThrottled(err):
+		if throttleRetries >= MaxThrottleRetries {
+			return err
+		}
+		backoff := ThrottleBackoff << uint(throttleRetries)
+		logrus.Warnf("node is rate limiting batch requests, retrying in %s: %s", backoff, err.Error())
+		if waitErr := sleepContext(ctx, backoff); waitErr != nil {
+			return waitErr
+		}
+		if client.limiter.shrink(len(chunk)) {
+			return client.sendInChunks(ctx, chunk, throttleRetries+1)
+		}
+		return client.sendChunk(ctx, chunk, throttleRetries+1)
+	default:
+		return err
+	}
+}
+
+// RateLimitedEthClient throttles calls to the wrapped client using the same limiter as the endpoint's rpc client
+type RateLimitedEthClient struct {
+	ethClient core.EthClient
+	limiter   *RateLimiter
+}
+
+func NewRateLimitedEthClient(ethClient core.EthClient, limiter *RateLimiter) RateLimitedEthClient {
+	return RateLimitedEthClient{ethClient: ethClient, limiter: limiter}
+}
+
+func (client RateLimitedEthClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var block *types.Block
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		block, callErr = client.ethClient.BlockByNumber(ctx, number)
+		return callErr
+	})
+	return block, err
+}
+
+func (client RateLimitedEthClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		result, callErr = client.ethClient.CallContract(ctx, msg, blockNumber)
+		return callErr
+	})
+	return result, err
+}
+
+func (client RateLimitedEthClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		logs, callErr = client.ethClient.FilterLogs(ctx, q)
+		return callErr
+	})
+	return logs, err
+}
+
+func (client RateLimitedEthClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		header, callErr = client.ethClient.HeaderByNumber(ctx, number)
+		return callErr
+	})
+	return header, err
+}
+
+// SubscribeNewStateChanges is not throttled, since it opens a long-lived subscription rather than polling
+func (client RateLimitedEthClient) SubscribeNewStateChanges(ctx context.Context, q ethereum.FilterQuery, ch chan<- filters.Payload) (ethereum.Subscription, error) {
+	return client.ethClient.SubscribeNewStateChanges(ctx, q, ch)
+}
+
+func (client RateLimitedEthClient) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
+	var sender common.Address
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		sender, callErr = client.ethClient.TransactionSender(ctx, tx, block, index)
+		return callErr
+	})
+	return sender, err
+}
+
+func (client RateLimitedEthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := client.limiter.withThrottleRetries(ctx, func() error {
+		var callErr error
+		receipt, callErr = client.ethClient.TransactionReceipt(ctx, txHash)
+		return callErr
+	})
+	return receipt, err
+}
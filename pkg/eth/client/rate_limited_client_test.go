@@ -0,0 +1,174 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/eth/client"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rate limited rpc client", func() {
+	var (
+		rpcClient          *fakes.MockRpcClient
+		originalBackoff    time.Duration
+		tooManyRequestsErr = errors.New("429 Too Many Requests")
+		makeBatch          func(size int) []core.BatchElem
+	)
+
+	makeBatch = func(size int) []core.BatchElem {
+		batch := make([]core.BatchElem, size)
+		for i := range batch {
+			batch[i] = core.BatchElem{Method: "eth_getStorageAt"}
+		}
+		return batch
+	}
+
+	BeforeEach(func() {
+		rpcClient = fakes.NewMockRpcClient()
+		originalBackoff = client.ThrottleBackoff
+		client.ThrottleBackoff = time.Millisecond
+	})
+
+	AfterEach(func() {
+		client.ThrottleBackoff = originalBackoff
+	})
+
+	It("splits batches larger than the max batch size", func() {
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(0, 100))
+
+		err := limitedClient.BatchCall(makeBatch(250))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rpcClient.BatchSizes).To(Equal([]int{100, 100, 50}))
+	})
+
+	It("shrinks batches the node rejects as too large", func() {
+		rpcClient.MaxBatchSize = 30
+		limiter := client.NewRateLimiter(0, 100)
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, limiter)
+
+		err := limitedClient.BatchCall(makeBatch(100))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limiter.BatchSize()).To(Equal(25))
+		Expect(rpcClient.BatchSizes).To(Equal([]int{100, 50, 25, 25, 25, 25}))
+	})
+
+	It("keeps the reduced batch size for later batches", func() {
+		rpcClient.MaxBatchSize = 30
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(0, 100))
+		Expect(limitedClient.BatchCall(makeBatch(100))).To(Succeed())
+		rpcClient.BatchSizes = nil
+
+		Expect(limitedClient.BatchCall(makeBatch(50))).To(Succeed())
+
+		Expect(rpcClient.BatchSizes).To(Equal([]int{25, 25}))
+	})
+
+	It("grows the batch size back after consecutive successes", func() {
+		rpcClient.BatchCallErrs = []error{errors.New("batch too large")}
+		limiter := client.NewRateLimiter(0, 100)
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, limiter)
+		Expect(limitedClient.BatchCall(makeBatch(100))).To(Succeed())
+		Expect(limiter.BatchSize()).To(Equal(50))
+
+		for i := 0; i < client.GrowBatchAfter; i++ {
+			Expect(limitedClient.BatchCall(makeBatch(1))).To(Succeed())
+		}
+
+		Expect(limiter.BatchSize()).To(Equal(100))
+	})
+
+	It("backs off and shrinks batches when the node rate limits them", func() {
+		rpcClient.BatchCallErrs = []error{tooManyRequestsErr}
+		limiter := client.NewRateLimiter(0, 100)
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, limiter)
+
+		err := limitedClient.BatchCall(makeBatch(40))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rpcClient.BatchSizes).To(Equal([]int{40, 20, 20}))
+	})
+
+	It("gives up after repeated rate limiting", func() {
+		for i := 0; i <= client.MaxThrottleRetries; i++ {
+			rpcClient.BatchCallErrs = append(rpcClient.BatchCallErrs, tooManyRequestsErr)
+		}
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(0, 100))
+
+		err := limitedClient.BatchCall(makeBatch(1))
+
+		Expect(err).To(MatchError(tooManyRequestsErr))
+		Expect(rpcClient.BatchCallCount).To(Equal(client.MaxThrottleRetries + 1))
+	})
+
+	It("stops backing off from rate limiting when the context is cancelled", func() {
+		client.ThrottleBackoff = time.Minute
+		rpcClient.BatchCallErrs = []error{tooManyRequestsErr}
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(0, 100))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := limitedClient.BatchCallContext(ctx, makeBatch(1))
+
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(rpcClient.BatchCallCount).To(Equal(1))
+	})
+
+	It("returns other errors without retrying", func() {
+		fakeErr := errors.New("connection refused")
+		rpcClient.BatchCallErrs = []error{fakeErr}
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(0, 100))
+
+		err := limitedClient.BatchCall(makeBatch(10))
+
+		Expect(err).To(MatchError(fakeErr))
+		Expect(rpcClient.BatchCallCount).To(Equal(1))
+	})
+
+	It("limits the request rate", func() {
+		limitedClient := client.NewRateLimitedRpcClient(rpcClient, client.NewRateLimiter(50, 100))
+		start := time.Now()
+
+		for i := 0; i < 60; i++ {
+			var version string
+			Expect(limitedClient.CallContext(context.Background(), &version, "net_version")).To(Succeed())
+		}
+
+		Expect(time.Since(start)).To(BeNumerically(">=", 150*time.Millisecond))
+	})
+})
+
+var _ = Describe("Token bucket", func() {
+	It("returns when the context is cancelled", func() {
+		bucket := client.NewTokenBucket(0.001, 1)
+		Expect(bucket.Wait(context.Background())).To(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := bucket.Wait(ctx)
+
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -20,20 +20,26 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/makerdao/vulcanizedb/pkg/config"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 )
 
 type RpcClient struct {
 	client  *rpc.Client
-	ipcPath string
+	nodeID  string
+	host    string
+	timeout time.Duration
 }
 
-func NewRpcClient(client *rpc.Client, ipcPath string) RpcClient {
+func NewRpcClient(client *rpc.Client, clientConfig config.Client) RpcClient {
 	return RpcClient{
 		client:  client,
-		ipcPath: ipcPath,
+		nodeID:  clientConfig.StableNodeID(),
+		host:    clientConfig.Host(),
+		timeout: clientConfig.Timeout,
 	}
 }
 
@@ -41,6 +47,8 @@ func (client RpcClient) CallContext(ctx context.Context, result interface{}, met
 	//If an empty interface (or other nil object) is passed to CallContext, when the JSONRPC message is created the params will
 	//be interpreted as [null]. This seems to work fine for most of the ethereum clients (which presumably ignore a null parameter.
 	//Ganache however does not ignore it, and throws an 'Incorrect number of arguments' error.
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	if args == nil {
 		return client.client.CallContext(ctx, result, method)
 	} else {
@@ -48,11 +56,27 @@ func (client RpcClient) CallContext(ctx context.Context, result interface{}, met
 	}
 }
 
-func (client RpcClient) IpcPath() string {
-	return client.ipcPath
+func (client RpcClient) NodeID() string {
+	return client.nodeID
+}
+
+func (client RpcClient) Host() string {
+	return client.host
+}
+
+// withTimeout bounds a request by the configured per-endpoint timeout, unless the caller already set a deadline
+func (client RpcClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline || client.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, client.timeout)
 }
 
 func (client RpcClient) BatchCall(batch []core.BatchElem) error {
+	return client.BatchCallContext(context.Background(), batch)
+}
+
+func (client RpcClient) BatchCallContext(ctx context.Context, batch []core.BatchElem) error {
 	var rpcBatch []rpc.BatchElem
 	for _, batchElem := range batch {
 		var newBatchElem = rpc.BatchElem{
@@ -64,7 +88,16 @@ func (client RpcClient) BatchCall(batch []core.BatchElem) error {
 
 		rpcBatch = append(rpcBatch, newBatchElem)
 	}
-	return client.client.BatchCall(rpcBatch)
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	err := client.client.BatchCallContext(ctx, rpcBatch)
+	if err != nil {
+		return err
+	}
+	for index := range batch {
+		batch[index].Error = rpcBatch[index].Error
+	}
+	return nil
 }
 
 // Subscribe subscribes to an rpc "namespace_subscribe" subscription with the given channel
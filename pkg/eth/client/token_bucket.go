@@ -0,0 +1,78 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket allows bursts of up to burst requests and refills at ratePerSecond
+type TokenBucket struct {
+	mutex         sync.Mutex
+	ratePerSecond float64
+	burst         float64
+	tokens        float64
+	lastRefill    time.Time
+}
+
+func NewTokenBucket(ratePerSecond float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		ratePerSecond: ratePerSecond,
+		burst:         float64(burst),
+		tokens:        float64(burst),
+		lastRefill:    time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (bucket *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		delay := bucket.take()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take consumes a token if one is available, otherwise it returns how long until the next token
+func (bucket *TokenBucket) take() time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	now := time.Now()
+	bucket.tokens += now.Sub(bucket.lastRefill).Seconds() * bucket.ratePerSecond
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.lastRefill = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / bucket.ratePerSecond * float64(time.Second))
+}
@@ -33,6 +33,7 @@ func (converter HeaderConverter) Convert(gethHeader *types.Header, blockHash str
 	}
 	coreHeader := core.Header{
 		Hash:        blockHash,
+		ParentHash:  gethHeader.ParentHash.Hex(),
 		BlockNumber: gethHeader.Number.Int64(),
 		Raw:         rawHeader,
 		Timestamp:   strconv.FormatUint(gethHeader.Time, 10),
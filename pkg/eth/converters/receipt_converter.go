@@ -0,0 +1,77 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package converters
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+)
+
+// ToCoreReceipt converts a receipt fetched from the node. Receipts of pre-Byzantium blocks have a state root
+// rather than a status, in which case Status is left zero.
+func ToCoreReceipt(gethReceipt *types.Receipt) (core.Receipt, error) {
+	receiptRLP, rlpErr := rlp.EncodeToBytes(gethReceipt)
+	if rlpErr != nil {
+		return core.Receipt{}, rlpErr
+	}
+	var contractAddress string
+	if gethReceipt.ContractAddress != (common.Address{}) {
+		contractAddress = gethReceipt.ContractAddress.Hex()
+	}
+	var stateRoot string
+	var status int
+	if len(gethReceipt.PostState) > 0 {
+		stateRoot = hexutil.Encode(gethReceipt.PostState)
+	} else {
+		status = int(gethReceipt.Status)
+	}
+	return core.Receipt{
+		Bloom:             hexutil.Encode(gethReceipt.Bloom.Bytes()),
+		ContractAddress:   contractAddress,
+		CumulativeGasUsed: gethReceipt.CumulativeGasUsed,
+		GasUsed:           gethReceipt.GasUsed,
+		Logs:              toReceiptLogs(gethReceipt.Logs),
+		StateRoot:         stateRoot,
+		Status:            status,
+		TxHash:            gethReceipt.TxHash.Hex(),
+		Rlp:               receiptRLP,
+	}, nil
+}
+
+func toReceiptLogs(gethLogs []*types.Log) []core.ReceiptLog {
+	var logs []core.ReceiptLog
+	for _, gethLog := range gethLogs {
+		var topics core.Topics
+		for i, topic := range gethLog.Topics {
+			if i < len(topics) {
+				topics[i] = topic.Hex()
+			}
+		}
+		logs = append(logs, core.ReceiptLog{
+			BlockNumber: int64(gethLog.BlockNumber),
+			TxHash:      gethLog.TxHash.Hex(),
+			Address:     gethLog.Address.Hex(),
+			Topics:      topics,
+			Index:       int64(gethLog.Index),
+			Data:        hexutil.Encode(gethLog.Data),
+		})
+	}
+	return logs
+}
@@ -0,0 +1,97 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package converters_test
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/eth/converters"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Receipt converter", func() {
+	var gethReceipt *types.Receipt
+
+	BeforeEach(func() {
+		gethLog := &types.Log{
+			Address:     fakes.FakeAddress,
+			Topics:      []common.Hash{fakes.FakeHash},
+			Data:        []byte{1, 2},
+			BlockNumber: 10,
+			TxHash:      fakes.FakeHash,
+			Index:       3,
+		}
+		gethReceipt = &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 300,
+			Logs:              []*types.Log{gethLog},
+			TxHash:            fakes.FakeHash,
+			ContractAddress:   fakes.FakeAddress,
+			GasUsed:           200,
+		}
+		gethReceipt.Bloom = types.CreateBloom(types.Receipts{gethReceipt})
+	})
+
+	It("converts a geth receipt to a core receipt", func() {
+		receipt, err := converters.ToCoreReceipt(gethReceipt)
+
+		Expect(err).NotTo(HaveOccurred())
+		expectedRLP, rlpErr := rlp.EncodeToBytes(gethReceipt)
+		Expect(rlpErr).NotTo(HaveOccurred())
+		Expect(receipt).To(Equal(core.Receipt{
+			Bloom:             hexutil.Encode(gethReceipt.Bloom.Bytes()),
+			ContractAddress:   fakes.FakeAddress.Hex(),
+			CumulativeGasUsed: 300,
+			GasUsed:           200,
+			Logs: []core.ReceiptLog{{
+				BlockNumber: 10,
+				TxHash:      fakes.FakeHash.Hex(),
+				Address:     fakes.FakeAddress.Hex(),
+				Topics:      core.Topics{fakes.FakeHash.Hex()},
+				Index:       3,
+				Data:        "0x0102",
+			}},
+			Status: 1,
+			TxHash: fakes.FakeHash.Hex(),
+			Rlp:    expectedRLP,
+		}))
+	})
+
+	It("leaves the contract address empty if the transaction didn't create a contract", func() {
+		gethReceipt.ContractAddress = common.Address{}
+
+		receipt, err := converters.ToCoreReceipt(gethReceipt)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(receipt.ContractAddress).To(BeEmpty())
+	})
+
+	It("converts the state root of a pre-Byzantium receipt", func() {
+		gethReceipt.PostState = fakes.FakeHash.Bytes()
+
+		receipt, err := converters.ToCoreReceipt(gethReceipt)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(receipt.StateRoot).To(Equal(fakes.FakeHash.Hex()))
+		Expect(receipt.Status).To(BeZero())
+	})
+})
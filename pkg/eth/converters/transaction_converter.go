@@ -18,6 +18,7 @@ package converters
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -48,6 +49,42 @@ type transactionData struct {
 	S            *big.Int
 }
 
+// EIP-2930 transaction payload, encoded after the type byte
+type accessListTransactionData struct {
+	ChainID      *big.Int
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	AccessList   []accessTuple
+	V            *big.Int
+	R            *big.Int
+	S            *big.Int
+}
+
+// EIP-1559 transaction payload, encoded after the type byte
+type dynamicFeeTransactionData struct {
+	ChainID              *big.Int
+	AccountNonce         uint64
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
+	GasLimit             uint64
+	Recipient            *common.Address `rlp:"nil"`
+	Amount               *big.Int
+	Payload              []byte
+	AccessList           []accessTuple
+	V                    *big.Int
+	R                    *big.Int
+	S                    *big.Int
+}
+
+type accessTuple struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
 func NewTransactionConverter(client core.EthClient) TransactionConverter {
 	return &transactionConverter{client: client}
 }
@@ -55,54 +92,119 @@ func NewTransactionConverter(client core.EthClient) TransactionConverter {
 func (converter *transactionConverter) ConvertRpcTransactionsToModels(transactions []core.RpcTransaction) ([]core.TransactionModel, error) {
 	var results []core.TransactionModel
 	for _, transaction := range transactions {
-		txData, convertErr := getTransactionData(transaction)
+		transactionModel, convertErr := convertRpcTransaction(transaction)
 		if convertErr != nil {
-			return nil, convertErr
-		}
-		txRLP, rlpErr := getTransactionRLP(txData)
-		if rlpErr != nil {
-			return nil, rlpErr
-		}
-		txIndex, txIndexErr := hexToBigInt(transaction.TransactionIndex)
-		if txIndexErr != nil {
-			return nil, txIndexErr
-		}
-		transactionModel := core.TransactionModel{
-			Data:     txData.Payload,
-			From:     transaction.From,
-			GasLimit: txData.GasLimit,
-			GasPrice: txData.Price.Int64(),
-			Hash:     transaction.Hash,
-			Nonce:    txData.AccountNonce,
-			Raw:      txRLP,
-			// NOTE: Header Sync transactions don't include receipt; would require separate RPC call
-			To:      transaction.Recipient,
-			TxIndex: txIndex.Int64(),
-			Value:   txData.Amount.String(),
+			return nil, fmt.Errorf("error converting transaction %s: %w", transaction.Hash, convertErr)
 		}
 		results = append(results, transactionModel)
 	}
 	return results, nil
 }
 
+func convertRpcTransaction(transaction core.RpcTransaction) (core.TransactionModel, error) {
+	txType, typeErr := getTransactionType(transaction.Type)
+	if typeErr != nil {
+		return core.TransactionModel{}, typeErr
+	}
+	txData, convertErr := getTransactionData(transaction)
+	if convertErr != nil {
+		return core.TransactionModel{}, convertErr
+	}
+	txIndex, txIndexErr := hexToBigInt(transaction.TransactionIndex)
+	if txIndexErr != nil {
+		return core.TransactionModel{}, txIndexErr
+	}
+	chainID, chainIDErr := getChainID(transaction, txData.V)
+	if chainIDErr != nil {
+		return core.TransactionModel{}, chainIDErr
+	}
+	transactionModel := core.TransactionModel{
+		Data:     txData.Payload,
+		From:     transaction.From,
+		GasLimit: txData.GasLimit,
+		Hash:     transaction.Hash,
+		Nonce:    txData.AccountNonce,
+		// NOTE: Header Sync transactions don't include receipt; would require separate RPC call
+		To:      transaction.Recipient,
+		TxIndex: txIndex.Int64(),
+		Type:    txType,
+		Value:   txData.Amount.String(),
+	}
+	if chainID != nil {
+		transactionModel.ChainID = chainID.String()
+	}
+	if txData.Price != nil {
+		transactionModel.GasPrice = txData.Price.String()
+		transactionModel.EffectiveGasPrice = txData.Price.String()
+	}
+
+	var txRLP []byte
+	var rlpErr error
+	switch txType {
+	case core.LegacyTxType:
+		txRLP, rlpErr = getTransactionRLP(txData)
+	case core.AccessListTxType:
+		txRLP, rlpErr = getAccessListTransactionRLP(transaction, txData, chainID)
+		transactionModel.AccessList = transaction.AccessList
+	case core.DynamicFeeTxType:
+		maxFeePerGas, maxPriorityFeePerGas, feeErr := getFeeCaps(transaction)
+		if feeErr != nil {
+			return core.TransactionModel{}, feeErr
+		}
+		transactionModel.MaxFeePerGas = maxFeePerGas.String()
+		transactionModel.MaxPriorityFeePerGas = maxPriorityFeePerGas.String()
+		transactionModel.AccessList = transaction.AccessList
+		txRLP, rlpErr = getDynamicFeeTransactionRLP(transaction, txData, chainID, maxFeePerGas, maxPriorityFeePerGas)
+	}
+	if rlpErr != nil {
+		return core.TransactionModel{}, rlpErr
+	}
+	transactionModel.Raw = txRLP
+	return transactionModel, nil
+}
+
+func getTransactionType(hex string) (uint8, error) {
+	if hex == "" {
+		return core.LegacyTxType, nil
+	}
+	txType, typeErr := hexutil.DecodeUint64(hex)
+	if typeErr != nil {
+		return 0, fmt.Errorf("invalid transaction type %q: %w", hex, typeErr)
+	}
+	switch txType {
+	case core.LegacyTxType, core.AccessListTxType, core.DynamicFeeTxType:
+		return uint8(txType), nil
+	default:
+		return 0, fmt.Errorf("unsupported transaction type %d", txType)
+	}
+}
+
 func getTransactionData(transaction core.RpcTransaction) (transactionData, error) {
 	nonce, nonceErr := hexToBigInt(transaction.Nonce)
 	if nonceErr != nil {
 		return transactionData{}, nonceErr
 	}
-	gasPrice, gasPriceErr := hexToBigInt(transaction.GasPrice)
-	if gasPriceErr != nil {
-		return transactionData{}, gasPriceErr
+	// dynamic fee transactions only report a gas price once mined, as the price that was paid
+	var gasPrice *big.Int
+	if transaction.GasPrice != "" {
+		var gasPriceErr error
+		gasPrice, gasPriceErr = hexToBigInt(transaction.GasPrice)
+		if gasPriceErr != nil {
+			return transactionData{}, gasPriceErr
+		}
 	}
 	gasLimit, gasLimitErr := hexToBigInt(transaction.GasLimit)
 	if gasLimitErr != nil {
 		return transactionData{}, gasLimitErr
 	}
-	recipient := common.HexToAddress(transaction.Recipient)
 	amount, amountErr := hexToBigInt(transaction.Amount)
 	if amountErr != nil {
 		return transactionData{}, amountErr
 	}
+	payload, payloadErr := hexutil.Decode(transaction.Payload)
+	if payloadErr != nil {
+		return transactionData{}, fmt.Errorf("invalid transaction input %q: %w", transaction.Payload, payloadErr)
+	}
 	v, vErr := hexToBigInt(transaction.V)
 	if vErr != nil {
 		return transactionData{}, vErr
@@ -119,18 +221,133 @@ func getTransactionData(transaction core.RpcTransaction) (transactionData, error
 		AccountNonce: nonce.Uint64(),
 		Price:        gasPrice,
 		GasLimit:     gasLimit.Uint64(),
-		Recipient:    &recipient,
+		Recipient:    getRecipient(transaction.Recipient),
 		Amount:       amount,
-		Payload:      hexutil.MustDecode(transaction.Payload),
+		Payload:      payload,
 		V:            v,
 		R:            r,
 		S:            s,
 	}, nil
 }
 
+func getRecipient(recipient string) *common.Address {
+	if recipient == "" {
+		return nil
+	}
+	address := common.HexToAddress(recipient)
+	return &address
+}
+
+// getChainID uses the chain ID reported by the node, falling back to the one encoded in an EIP-155 signature.
+// It is nil for legacy transactions signed without replay protection.
+func getChainID(transaction core.RpcTransaction, v *big.Int) (*big.Int, error) {
+	if transaction.ChainID != "" {
+		return hexToBigInt(transaction.ChainID)
+	}
+	if transaction.Type != "" && transaction.Type != "0x0" {
+		return nil, errors.New("typed transaction is missing its chain ID")
+	}
+	if v.Cmp(big.NewInt(35)) < 0 {
+		return nil, nil
+	}
+	chainID := new(big.Int).Sub(v, big.NewInt(35))
+	return chainID.Rsh(chainID, 1), nil
+}
+
+func getFeeCaps(transaction core.RpcTransaction) (*big.Int, *big.Int, error) {
+	maxFeePerGas, maxFeeErr := hexToBigInt(transaction.MaxFeePerGas)
+	if maxFeeErr != nil {
+		return nil, nil, fmt.Errorf("invalid maxFeePerGas %q: %w", transaction.MaxFeePerGas, maxFeeErr)
+	}
+	maxPriorityFeePerGas, maxPriorityFeeErr := hexToBigInt(transaction.MaxPriorityFeePerGas)
+	if maxPriorityFeeErr != nil {
+		return nil, nil, fmt.Errorf("invalid maxPriorityFeePerGas %q: %w", transaction.MaxPriorityFeePerGas, maxPriorityFeeErr)
+	}
+	return maxFeePerGas, maxPriorityFeePerGas, nil
+}
+
+func getAccessListTuples(accessList core.AccessList) ([]accessTuple, error) {
+	tuples := make([]accessTuple, 0, len(accessList))
+	for _, tuple := range accessList {
+		if !common.IsHexAddress(tuple.Address) {
+			return nil, fmt.Errorf("invalid access list address %q", tuple.Address)
+		}
+		storageKeys := make([]common.Hash, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			decodedKey, keyErr := hexutil.Decode(key)
+			if keyErr != nil || len(decodedKey) != common.HashLength {
+				return nil, fmt.Errorf("invalid access list storage key %q", key)
+			}
+			storageKeys = append(storageKeys, common.BytesToHash(decodedKey))
+		}
+		tuples = append(tuples, accessTuple{Address: common.HexToAddress(tuple.Address), StorageKeys: storageKeys})
+	}
+	return tuples, nil
+}
+
 func getTransactionRLP(txData transactionData) ([]byte, error) {
+	if txData.Price == nil {
+		return nil, errors.New("legacy transaction is missing its gas price")
+	}
+	return encodeRLP(txData)
+}
+
+func getAccessListTransactionRLP(transaction core.RpcTransaction, txData transactionData, chainID *big.Int) ([]byte, error) {
+	if txData.Price == nil {
+		return nil, errors.New("access list transaction is missing its gas price")
+	}
+	accessList, accessListErr := getAccessListTuples(transaction.AccessList)
+	if accessListErr != nil {
+		return nil, accessListErr
+	}
+	return encodeTypedRLP(core.AccessListTxType, accessListTransactionData{
+		ChainID:      chainID,
+		AccountNonce: txData.AccountNonce,
+		Price:        txData.Price,
+		GasLimit:     txData.GasLimit,
+		Recipient:    txData.Recipient,
+		Amount:       txData.Amount,
+		Payload:      txData.Payload,
+		AccessList:   accessList,
+		V:            txData.V,
+		R:            txData.R,
+		S:            txData.S,
+	})
+}
+
+func getDynamicFeeTransactionRLP(transaction core.RpcTransaction, txData transactionData, chainID, maxFeePerGas, maxPriorityFeePerGas *big.Int) ([]byte, error) {
+	accessList, accessListErr := getAccessListTuples(transaction.AccessList)
+	if accessListErr != nil {
+		return nil, accessListErr
+	}
+	return encodeTypedRLP(core.DynamicFeeTxType, dynamicFeeTransactionData{
+		ChainID:              chainID,
+		AccountNonce:         txData.AccountNonce,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+		MaxFeePerGas:         maxFeePerGas,
+		GasLimit:             txData.GasLimit,
+		Recipient:            txData.Recipient,
+		Amount:               txData.Amount,
+		Payload:              txData.Payload,
+		AccessList:           accessList,
+		V:                    txData.V,
+		R:                    txData.R,
+		S:                    txData.S,
+	})
+}
+
+// encodeTypedRLP produces the EIP-2718 envelope: the transaction type followed by the RLP of its payload
+func encodeTypedRLP(txType byte, payload interface{}) ([]byte, error) {
+	encodedPayload, encodeErr := encodeRLP(payload)
+	if encodeErr != nil {
+		return nil, encodeErr
+	}
+	return append([]byte{txType}, encodedPayload...), nil
+}
+
+func encodeRLP(value interface{}) ([]byte, error) {
 	transactionRlp := bytes.Buffer{}
-	encodeErr := rlp.Encode(&transactionRlp, txData)
+	encodeErr := rlp.Encode(&transactionRlp, value)
 	if encodeErr != nil {
 		return nil, encodeErr
 	}
@@ -17,6 +17,8 @@
 package converters_test
 
 import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/eth/converters"
 	"github.com/makerdao/vulcanizedb/pkg/fakes"
@@ -39,7 +41,8 @@ var _ = Describe("RPC transaction converter", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(len(transactionModels)).To(Equal(1))
 		Expect(transactionModels[0].GasLimit).To(Equal(uint64(1)))
-		Expect(transactionModels[0].GasPrice).To(Equal(int64(1)))
+		Expect(transactionModels[0].GasPrice).To(Equal("1"))
+		Expect(transactionModels[0].EffectiveGasPrice).To(Equal("1"))
 		Expect(transactionModels[0].Nonce).To(Equal(uint64(1)))
 		Expect(transactionModels[0].TxIndex).To(Equal(int64(1)))
 		Expect(transactionModels[0].Value).To(Equal("1"))
@@ -95,6 +98,160 @@ var _ = Describe("RPC transaction converter", func() {
 		Expect(model.Raw).To(Equal(expectedRLP))
 	})
 
+	It("stores big values without truncation", func() {
+		rpcTransaction := getFakeRpcTransaction("0x1")
+		rpcTransaction.GasPrice = "0x10000000000000000"
+
+		transactionModels, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transactionModels[0].GasPrice).To(Equal("18446744073709551616"))
+	})
+
+	It("returns error if the input data is malformed", func() {
+		rpcTransaction := getFakeRpcTransaction("0x1")
+		rpcTransaction.Payload = "0xzz"
+
+		_, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(rpcTransaction.Hash))
+	})
+
+	It("returns error if the transaction type is not supported", func() {
+		rpcTransaction := getFakeRpcTransaction("0x1")
+		rpcTransaction.Type = "0x7f"
+
+		_, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unsupported transaction type 127"))
+	})
+
+	It("derives the chain ID of legacy transactions from an EIP-155 signature", func() {
+		protected := getFakeRpcTransaction("0x1")
+		protected.V = "0x78"
+		unprotected := getFakeRpcTransaction("0x1")
+		unprotected.V = "0x1b"
+
+		transactionModels, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{protected, unprotected})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transactionModels[0].ChainID).To(Equal("42"))
+		Expect(transactionModels[1].ChainID).To(BeEmpty())
+	})
+
+	It("encodes an empty recipient for contract creations", func() {
+		rpcTransaction := getFakeRpcTransaction("0x1")
+		rpcTransaction.Recipient = ""
+
+		transactionModels, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+		Expect(err).NotTo(HaveOccurred())
+		var fields []rlp.RawValue
+		Expect(rlp.DecodeBytes(transactionModels[0].Raw, &fields)).To(Succeed())
+		Expect(len(fields)).To(Equal(9))
+		Expect(fields[3]).To(Equal(rlp.RawValue{0x80}))
+	})
+
+	Describe("typed transactions", func() {
+		var accessList core.AccessList
+
+		BeforeEach(func() {
+			accessList = core.AccessList{{
+				Address:     fakes.FakeAddress.Hex(),
+				StorageKeys: []string{fakes.FakeHash.Hex()},
+			}}
+		})
+
+		It("converts access list transactions", func() {
+			rpcTransaction := getFakeRpcTransaction("0x1")
+			rpcTransaction.Type = "0x1"
+			rpcTransaction.ChainID = "0x1"
+			rpcTransaction.AccessList = accessList
+			rpcTransaction.V = "0x1"
+
+			transactionModels, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+			Expect(err).NotTo(HaveOccurred())
+			model := transactionModels[0]
+			Expect(model.Type).To(Equal(uint8(core.AccessListTxType)))
+			Expect(model.ChainID).To(Equal("1"))
+			Expect(model.GasPrice).To(Equal("1"))
+			Expect(model.AccessList).To(Equal(accessList))
+			Expect(model.MaxFeePerGas).To(BeEmpty())
+			Expect(model.Raw[0]).To(Equal(byte(core.AccessListTxType)))
+			var fields []rlp.RawValue
+			Expect(rlp.DecodeBytes(model.Raw[1:], &fields)).To(Succeed())
+			Expect(len(fields)).To(Equal(11))
+		})
+
+		It("converts dynamic fee transactions", func() {
+			rpcTransaction := getFakeRpcTransaction("0x1")
+			rpcTransaction.Type = "0x2"
+			rpcTransaction.ChainID = "0x1"
+			rpcTransaction.GasPrice = "0x5"
+			rpcTransaction.MaxFeePerGas = "0x10000000000000000"
+			rpcTransaction.MaxPriorityFeePerGas = "0x2"
+			rpcTransaction.AccessList = accessList
+			rpcTransaction.V = "0x1"
+
+			transactionModels, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+			Expect(err).NotTo(HaveOccurred())
+			model := transactionModels[0]
+			Expect(model.Type).To(Equal(uint8(core.DynamicFeeTxType)))
+			Expect(model.ChainID).To(Equal("1"))
+			Expect(model.MaxFeePerGas).To(Equal("18446744073709551616"))
+			Expect(model.MaxPriorityFeePerGas).To(Equal("2"))
+			Expect(model.EffectiveGasPrice).To(Equal("5"))
+			Expect(model.AccessList).To(Equal(accessList))
+			Expect(model.Raw[0]).To(Equal(byte(core.DynamicFeeTxType)))
+			var decoded struct {
+				ChainID              uint64
+				Nonce                uint64
+				MaxPriorityFeePerGas uint64
+				MaxFeePerGas         []byte
+				GasLimit             uint64
+				To                   common.Address
+				Value                uint64
+				Data                 []byte
+				AccessList           []struct {
+					Address     common.Address
+					StorageKeys []common.Hash
+				}
+				V, R, S uint64
+			}
+			Expect(rlp.DecodeBytes(model.Raw[1:], &decoded)).To(Succeed())
+			Expect(decoded.MaxPriorityFeePerGas).To(Equal(uint64(2)))
+			Expect(decoded.To).To(Equal(fakes.FakeAddress))
+			Expect(decoded.AccessList[0].StorageKeys).To(Equal([]common.Hash{fakes.FakeHash}))
+		})
+
+		It("returns error if a dynamic fee transaction is missing its fee caps", func() {
+			rpcTransaction := getFakeRpcTransaction("0x1")
+			rpcTransaction.Type = "0x2"
+			rpcTransaction.ChainID = "0x1"
+
+			_, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("maxFeePerGas"))
+		})
+
+		It("returns error if an access list storage key is malformed", func() {
+			rpcTransaction := getFakeRpcTransaction("0x1")
+			rpcTransaction.Type = "0x1"
+			rpcTransaction.ChainID = "0x1"
+			rpcTransaction.AccessList = core.AccessList{{Address: fakes.FakeAddress.Hex(), StorageKeys: []string{"0x12"}}}
+
+			_, err := converter.ConvertRpcTransactionsToModels([]core.RpcTransaction{rpcTransaction})
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid access list storage key"))
+		})
+	})
+
 	It("does not include transaction receipt", func() {
 		rpcTransaction := getFakeRpcTransaction("0x1")
 
@@ -26,6 +26,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const infuraDomain = "infura.io"
+
 type IPropertiesReader interface {
 	NodeClientName() string
 	NetworkId() float64
@@ -57,7 +59,7 @@ func MakeNode(rpcClient core.RpcClient) core.Node {
 	return core.Node{
 		GenesisBlock: pr.GenesisBlock(),
 		NetworkID:    pr.NetworkId(),
-		ID:           rpcClient.IpcPath(),
+		ID:           rpcClient.NodeID(),
 		ClientName:   pr.NodeClientName(),
 	}
 }
@@ -78,7 +80,7 @@ func makePropertiesReader(client core.RpcClient) IPropertiesReader {
 }
 
 func getNodeType(client core.RpcClient) core.NodeType {
-	if strings.Contains(client.IpcPath(), "infura") {
+	if isInfuraHost(client.Host()) {
 		return core.INFURA
 	}
 	var version string
@@ -95,6 +97,11 @@ func getNodeType(client core.RpcClient) core.NodeType {
 	return core.GANACHE
 }
 
+func isInfuraHost(host string) bool {
+	hostname := strings.Split(host, ":")[0]
+	return hostname == infuraDomain || strings.HasSuffix(hostname, "."+infuraDomain)
+}
+
 func (reader PropertiesReader) NetworkId() float64 {
 	var version string
 	err := reader.client.CallContext(context.Background(), &version, "net_version")
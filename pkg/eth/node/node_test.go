@@ -100,13 +100,13 @@ var _ = Describe("Node Info", func() {
 		Expect(n.NetworkID).To(Equal(expectedNetworkID))
 	})
 
-	It("returns the IpcPath as node ID", func() {
+	It("returns the client's node ID", func() {
 		client := fakes.NewMockRpcClient()
-		client.SetIpcPath("infura/path")
+		client.SetNodeID("mainnet.infura.io")
 
 		n := node.MakeNode(client)
 
-		Expect(n.ID).To(Equal(client.IpcPath()))
+		Expect(n.ID).To(Equal("mainnet.infura.io"))
 	})
 
 	It("returns client name for geth node", func() {
@@ -120,13 +120,23 @@ var _ = Describe("Node Info", func() {
 
 	It("returns client name for infura node", func() {
 		client := fakes.NewMockRpcClient()
-		client.SetIpcPath("infura/path")
+		client.SetHost("mainnet.infura.io:443")
 
 		n := node.MakeNode(client)
 
 		Expect(n.ClientName).To(Equal("infura"))
 	})
 
+	It("does not treat hosts that merely contain infura as infura nodes", func() {
+		client := fakes.NewMockRpcClient()
+		client.SetHost("infura.example.com")
+		client.ClientVersion = "Geth/v1.9.9-omnibus-e320ae4c-20191206/linux-amd64/go1.13.4"
+
+		n := node.MakeNode(client)
+
+		Expect(n.ClientName).To(Equal(client.ClientVersion))
+	})
+
 	It("returns ganache by default", func() {
 		client := fakes.NewMockRpcClient()
 
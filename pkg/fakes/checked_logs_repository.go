@@ -16,24 +16,69 @@
 
 package fakes
 
+import (
+	"sync"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+)
+
 type MockCheckedLogsRepository struct {
-	AlreadyWatchingLogAddresses []string
-	AlreadyWatchingLogError     error
-	AlreadyWatchingLogReturn    bool
-	AlreadyWatchingLogTopicZero string
-	MarkLogWatchedAddresses     []string
-	MarkLogWatchedError         error
-	MarkLogWatchedTopicZero     string
+	AlreadyWatchingLogAddresses    []string
+	AlreadyWatchingLogError        error
+	AlreadyWatchingLogReturn       bool
+	AlreadyWatchingLogTopics       core.Topics
+	GetLogsToBackFillError         error
+	GetWatchedLogsError            error
+	LogsToBackFill                 []core.WatchedLog
+	MarkLogsBackFilledThroughBlock int64
+	MarkLogsBackFilledThroughError error
+	MarkLogsBackFilledThroughFrom  int64
+	MarkLogsBackFilledThroughIDs   []int64
+	MarkLogsForBackFillError       error
+	MarkLogsForBackFillIDs         []int64
+	MarkLogsForBackFillRanges      []core.BlockRange
+	markLogsForBackFillMutex       sync.Mutex
+	MarkLogWatchedAddresses        []string
+	MarkLogWatchedEndingBlock      int64
+	MarkLogWatchedError            error
+	MarkLogWatchedStartingBlock    int64
+	MarkLogWatchedTopics           core.Topics
+	WatchedLogs                    []core.WatchedLog
 }
 
-func (repository *MockCheckedLogsRepository) AlreadyWatchingLog(addresses []string, topic0 string) (bool, error) {
+func (repository *MockCheckedLogsRepository) AlreadyWatchingLog(addresses []string, topics core.Topics) (bool, error) {
 	repository.AlreadyWatchingLogAddresses = addresses
-	repository.AlreadyWatchingLogTopicZero = topic0
+	repository.AlreadyWatchingLogTopics = topics
 	return repository.AlreadyWatchingLogReturn, repository.AlreadyWatchingLogError
 }
 
-func (repository *MockCheckedLogsRepository) MarkLogWatched(addresses []string, topic0 string) error {
+func (repository *MockCheckedLogsRepository) GetLogsToBackFill() ([]core.WatchedLog, error) {
+	return repository.LogsToBackFill, repository.GetLogsToBackFillError
+}
+
+func (repository *MockCheckedLogsRepository) GetWatchedLogs() ([]core.WatchedLog, error) {
+	return repository.WatchedLogs, repository.GetWatchedLogsError
+}
+
+func (repository *MockCheckedLogsRepository) MarkLogsBackFilledThrough(watchedLogIDs []int64, fromBlock, throughBlock int64) error {
+	repository.MarkLogsBackFilledThroughIDs = watchedLogIDs
+	repository.MarkLogsBackFilledThroughFrom = fromBlock
+	repository.MarkLogsBackFilledThroughBlock = throughBlock
+	return repository.MarkLogsBackFilledThroughError
+}
+
+func (repository *MockCheckedLogsRepository) MarkLogsForBackFill(watchedLogIDs []int64, blockRange core.BlockRange) error {
+	repository.markLogsForBackFillMutex.Lock()
+	defer repository.markLogsForBackFillMutex.Unlock()
+	repository.MarkLogsForBackFillIDs = watchedLogIDs
+	repository.MarkLogsForBackFillRanges = append(repository.MarkLogsForBackFillRanges, blockRange)
+	return repository.MarkLogsForBackFillError
+}
+
+func (repository *MockCheckedLogsRepository) MarkLogWatched(addresses []string, topics core.Topics, startingBlock, endingBlock int64) error {
 	repository.MarkLogWatchedAddresses = addresses
-	repository.MarkLogWatchedTopicZero = topic0
+	repository.MarkLogWatchedTopics = topics
+	repository.MarkLogWatchedStartingBlock = startingBlock
+	repository.MarkLogWatchedEndingBlock = endingBlock
 	return repository.MarkLogWatchedError
 }
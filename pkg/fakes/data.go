@@ -51,6 +51,7 @@ func GetFakeHeader(blockNumber int64) core.Header {
 func GetFakeHeaderWithTimestamp(timestamp, blockNumber int64) core.Header {
 	return core.Header{
 		Hash:        "0x" + RandomString(64),
+		ParentHash:  "0x" + RandomString(64),
 		BlockNumber: blockNumber,
 		Raw:         rawFakeHeader,
 		Timestamp:   strconv.FormatInt(timestamp, 10),
@@ -0,0 +1,46 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fakes
+
+import (
+	"sync"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+)
+
+type MockBackFillProgressRepository struct {
+	CompletedRanges               []core.BlockRange
+	GetCompletedRangesPassedHash  string
+	GetCompletedRangesReturnError error
+	MarkRangeCompletePassedHashes []string
+	MarkRangeCompletePassedRanges []core.BlockRange
+	MarkRangeCompleteReturnError  error
+	markRangeCompleteMutex        sync.Mutex
+}
+
+func (repository *MockBackFillProgressRepository) GetCompletedRanges(watchedLogsHash string) ([]core.BlockRange, error) {
+	repository.GetCompletedRangesPassedHash = watchedLogsHash
+	return repository.CompletedRanges, repository.GetCompletedRangesReturnError
+}
+
+func (repository *MockBackFillProgressRepository) MarkRangeComplete(watchedLogsHash string, blockRange core.BlockRange) error {
+	repository.markRangeCompleteMutex.Lock()
+	defer repository.markRangeCompleteMutex.Unlock()
+	repository.MarkRangeCompletePassedHashes = append(repository.MarkRangeCompletePassedHashes, watchedLogsHash)
+	repository.MarkRangeCompletePassedRanges = append(repository.MarkRangeCompletePassedRanges, blockRange)
+	return repository.MarkRangeCompleteReturnError
+}
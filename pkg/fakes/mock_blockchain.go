@@ -18,6 +18,7 @@ package fakes
 
 import (
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -29,11 +30,17 @@ import (
 type MockBlockChain struct {
 	BatchGetStorageAtCalls             []BatchGetStorageAtCall
 	BatchGetStorageAtError             error
+	GetHeaderByNumberPassedNumbers     []int64
+	GetTransactionReceiptsError        error
+	GetTransactionReceiptsPassedHashes []common.Hash
 	GetTransactionsCalled              bool
 	GetTransactionsError               error
 	GetTransactionsPassedHashes        []common.Hash
 	Transactions                       []core.TransactionModel
 	fetchContractDataErr               error
+	getHeaderByNumberErr               error
+	getHeadersByNumbersErr             error
+	headersByNumber                    map[int64]core.Header
 	fetchContractDataPassedAbi         string
 	fetchContractDataPassedAddress     string
 	fetchContractDataPassedBlockNumber int64
@@ -44,13 +51,21 @@ type MockBlockChain struct {
 	lastBlockErr                       error
 	logQuery                           ethereum.FilterQuery
 	logQueryErr                        error
+	logQueryFunc                       func(ethereum.FilterQuery) ([]types.Log, error)
+	LogQueries                         []ethereum.FilterQuery
 	logQueryReturnLogs                 []types.Log
 	node                               core.Node
+	Receipts                           []core.Receipt
 	storageValuesToReturn              map[common.Address]map[int64][]byte
+	subscribeNewHeadsErrs              []error
+	subscribeNewHeadsMutex             sync.Mutex
+	SubscribeNewHeadsPassedChans       []chan core.NewHead // guarded by subscribeNewHeadsMutex
+	NewHeadsSubscription               *MockSubscription
 }
 
 func NewMockBlockChain() *MockBlockChain {
 	return &MockBlockChain{
+		headersByNumber:       make(map[int64]core.Header),
 		node:                  core.Node{GenesisBlock: "GENESIS", NetworkID: 1, ID: "x123", ClientName: "Geth"},
 		storageValuesToReturn: make(map[common.Address]map[int64][]byte),
 	}
@@ -60,6 +75,19 @@ func (blockChain *MockBlockChain) SetFetchContractDataErr(err error) {
 	blockChain.fetchContractDataErr = err
 }
 
+func (blockChain *MockBlockChain) SetGetHeaderByNumberErr(err error) {
+	blockChain.getHeaderByNumberErr = err
+}
+
+// SetHeaderByNumber configures the header returned for its block number by GetHeaderByNumber and GetHeadersByNumbers
+func (blockChain *MockBlockChain) SetHeaderByNumber(header core.Header) {
+	blockChain.headersByNumber[header.BlockNumber] = header
+}
+
+func (blockChain *MockBlockChain) SetGetHeadersByNumbersErr(err error) {
+	blockChain.getHeadersByNumbersErr = err
+}
+
 func (blockChain *MockBlockChain) SetLastBlock(blockNumber *big.Int) {
 	blockChain.lastBlock = blockNumber
 }
@@ -76,6 +104,12 @@ func (blockChain *MockBlockChain) SetGetEthLogsWithCustomQueryReturnLogs(logs []
 	blockChain.logQueryReturnLogs = logs
 }
 
+// SetGetEthLogsWithCustomQueryFunc configures a function computing the result of each log query,
+// overriding the configured logs and error
+func (blockChain *MockBlockChain) SetGetEthLogsWithCustomQueryFunc(queryFunc func(ethereum.FilterQuery) ([]types.Log, error)) {
+	blockChain.logQueryFunc = queryFunc
+}
+
 func (blockChain *MockBlockChain) FetchContractData(abiJSON string, address string, method string, methodArgs []interface{}, result interface{}, blockNumber int64) error {
 	blockChain.fetchContractDataPassedAbi = abiJSON
 	blockChain.fetchContractDataPassedAddress = address
@@ -88,17 +122,31 @@ func (blockChain *MockBlockChain) FetchContractData(abiJSON string, address stri
 
 func (blockChain *MockBlockChain) GetEthLogsWithCustomQuery(query ethereum.FilterQuery) ([]types.Log, error) {
 	blockChain.logQuery = query
+	blockChain.LogQueries = append(blockChain.LogQueries, query)
+	if blockChain.logQueryFunc != nil {
+		return blockChain.logQueryFunc(query)
+	}
 	return blockChain.logQueryReturnLogs, blockChain.logQueryErr
 }
 
 func (blockChain *MockBlockChain) GetHeaderByNumber(blockNumber int64) (core.Header, error) {
-	return core.Header{BlockNumber: blockNumber}, nil
+	blockChain.GetHeaderByNumberPassedNumbers = append(blockChain.GetHeaderByNumberPassedNumbers, blockNumber)
+	if header, ok := blockChain.headersByNumber[blockNumber]; ok {
+		return header, blockChain.getHeaderByNumberErr
+	}
+	return core.Header{BlockNumber: blockNumber}, blockChain.getHeaderByNumberErr
 }
 
 func (blockChain *MockBlockChain) GetHeadersByNumbers(blockNumbers []int64) ([]core.Header, error) {
+	if blockChain.getHeadersByNumbersErr != nil {
+		return nil, blockChain.getHeadersByNumbersErr
+	}
 	var headers []core.Header
 	for _, blockNumber := range blockNumbers {
-		var header = core.Header{BlockNumber: blockNumber}
+		header, ok := blockChain.headersByNumber[blockNumber]
+		if !ok {
+			header = core.Header{BlockNumber: blockNumber}
+		}
 		headers = append(headers, header)
 	}
 	return headers, nil
@@ -110,6 +158,11 @@ func (blockChain *MockBlockChain) GetTransactions(transactionHashes []common.Has
 	return blockChain.Transactions, blockChain.GetTransactionsError
 }
 
+func (blockChain *MockBlockChain) GetTransactionReceipts(transactionHashes []common.Hash) ([]core.Receipt, error) {
+	blockChain.GetTransactionReceiptsPassedHashes = transactionHashes
+	return blockChain.Receipts, blockChain.GetTransactionReceiptsError
+}
+
 func (blockChain *MockBlockChain) CallContract(contractHash string, input []byte, blockNumber *big.Int) ([]byte, error) {
 	return []byte{}, nil
 }
@@ -164,3 +217,37 @@ func (blockChain *MockBlockChain) AssertFetchContractDataCalledWith(abiJSON stri
 func (blockChain *MockBlockChain) AssertGetEthLogsWithCustomQueryCalledWith(query ethereum.FilterQuery) {
 	Expect(blockChain.logQuery).To(Equal(query))
 }
+
+// SetSubscribeNewHeadsErrs configures errors returned by successive calls to SubscribeNewHeads
+func (blockChain *MockBlockChain) SetSubscribeNewHeadsErrs(errs []error) {
+	blockChain.subscribeNewHeadsErrs = errs
+}
+
+func (blockChain *MockBlockChain) SubscribeNewHeads(heads chan core.NewHead) (core.Subscription, error) {
+	blockChain.subscribeNewHeadsMutex.Lock()
+	defer blockChain.subscribeNewHeadsMutex.Unlock()
+	blockChain.SubscribeNewHeadsPassedChans = append(blockChain.SubscribeNewHeadsPassedChans, heads)
+	if len(blockChain.subscribeNewHeadsErrs) > 0 {
+		err := blockChain.subscribeNewHeadsErrs[0]
+		blockChain.subscribeNewHeadsErrs = blockChain.subscribeNewHeadsErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	if blockChain.NewHeadsSubscription == nil {
+		blockChain.NewHeadsSubscription = &MockSubscription{Errs: make(chan error)}
+	}
+	return blockChain.NewHeadsSubscription, nil
+}
+
+func (blockChain *MockBlockChain) SubscribeNewHeadsCallCount() int {
+	blockChain.subscribeNewHeadsMutex.Lock()
+	defer blockChain.subscribeNewHeadsMutex.Unlock()
+	return len(blockChain.SubscribeNewHeadsPassedChans)
+}
+
+func (blockChain *MockBlockChain) LatestNewHeadsChan() chan core.NewHead {
+	blockChain.subscribeNewHeadsMutex.Lock()
+	defer blockChain.subscribeNewHeadsMutex.Unlock()
+	return blockChain.SubscribeNewHeadsPassedChans[len(blockChain.SubscribeNewHeadsPassedChans)-1]
+}
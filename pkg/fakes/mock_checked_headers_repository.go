@@ -17,12 +17,17 @@
 package fakes
 
 import (
+	"sync"
+
 	"github.com/makerdao/vulcanizedb/pkg/core"
 )
 
 type MockCheckedHeadersRepository struct {
 	MarkHeaderCheckedHeaderID           int64
 	MarkHeaderCheckedReturnError        error
+	MarkHeadersCheckedHeaderIDs         []int64
+	MarkHeadersCheckedReturnError       error
+	markHeadersCheckedMutex             sync.Mutex
 	UncheckedHeadersCheckCount          int64
 	UncheckedHeadersEndingBlockNumber   int64
 	UncheckedHeadersReturnError         error
@@ -39,6 +44,13 @@ func (repository *MockCheckedHeadersRepository) MarkHeaderChecked(headerID int64
 	return repository.MarkHeaderCheckedReturnError
 }
 
+func (repository *MockCheckedHeadersRepository) MarkHeadersChecked(headerIDs []int64) error {
+	repository.markHeadersCheckedMutex.Lock()
+	defer repository.markHeadersCheckedMutex.Unlock()
+	repository.MarkHeadersCheckedHeaderIDs = append(repository.MarkHeadersCheckedHeaderIDs, headerIDs...)
+	return repository.MarkHeadersCheckedReturnError
+}
+
 func (repository *MockCheckedHeadersRepository) UncheckedHeaders(startingBlockNumber, endingBlockNumber, checkCount int64) ([]core.Header, error) {
 	repository.UncheckedHeadersStartingBlockNumber = startingBlockNumber
 	repository.UncheckedHeadersEndingBlockNumber = endingBlockNumber
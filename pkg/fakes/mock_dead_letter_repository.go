@@ -0,0 +1,80 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fakes
+
+import (
+	"time"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+)
+
+type MockDeadLetterRepository struct {
+	DeadLetterLogs                 []core.DeadLetterLog
+	DiscardedIDs                   []int64
+	DiscardError                   error
+	GetDeadLetterLogsPassedName    string
+	GetDeadLetterLogsError         error
+	GetSkippedLogIDsPassedIDs      [][]int64
+	GetSkippedLogIDsPassedAttempts int
+	GetSkippedLogIDsError          error
+	RecordFailureError             error
+	RecordFailurePassedRetryDelay  time.Duration
+	RecordedFailures               []core.DeadLetterLog
+	RetriedIDs                     []int64
+	RetryError                     error
+	SkippedLogIDs                  map[string][]int64
+	attempts                       map[string]map[int64]int
+}
+
+func (repository *MockDeadLetterRepository) DiscardDeadLetterLogs(ids []int64) error {
+	repository.DiscardedIDs = append(repository.DiscardedIDs, ids...)
+	return repository.DiscardError
+}
+
+func (repository *MockDeadLetterRepository) GetDeadLetterLogs(transformerName string) ([]core.DeadLetterLog, error) {
+	repository.GetDeadLetterLogsPassedName = transformerName
+	return repository.DeadLetterLogs, repository.GetDeadLetterLogsError
+}
+
+func (repository *MockDeadLetterRepository) GetSkippedLogIDs(transformerName string, eventLogIDs []int64, maxAttempts int) ([]int64, error) {
+	repository.GetSkippedLogIDsPassedIDs = append(repository.GetSkippedLogIDsPassedIDs, eventLogIDs)
+	repository.GetSkippedLogIDsPassedAttempts = maxAttempts
+	return repository.SkippedLogIDs[transformerName], repository.GetSkippedLogIDsError
+}
+
+// RecordFailure returns how many failures have been recorded for the log and transformer, including this one
+func (repository *MockDeadLetterRepository) RecordFailure(eventLogID int64, transformerName, errorMessage string, retryDelay time.Duration) (int, error) {
+	repository.RecordFailurePassedRetryDelay = retryDelay
+	repository.RecordedFailures = append(repository.RecordedFailures, core.DeadLetterLog{
+		EventLogID:      eventLogID,
+		TransformerName: transformerName,
+		Error:           errorMessage,
+	})
+	if repository.attempts == nil {
+		repository.attempts = make(map[string]map[int64]int)
+	}
+	if repository.attempts[transformerName] == nil {
+		repository.attempts[transformerName] = make(map[int64]int)
+	}
+	repository.attempts[transformerName][eventLogID]++
+	return repository.attempts[transformerName][eventLogID], repository.RecordFailureError
+}
+
+func (repository *MockDeadLetterRepository) RetryDeadLetterLogs(ids []int64) error {
+	repository.RetriedIDs = append(repository.RetriedIDs, ids...)
+	return repository.RetryError
+}
@@ -22,35 +22,62 @@ import (
 )
 
 type MockEventLogRepository struct {
-	CreateError    error
-	GetCalled      bool
-	GetError       error
-	PassedMinIDs   []int
-	PassedLimits   []int
-	PassedHeaderID int64
-	PassedLogs     []types.Log
-	ReturnLogs     []core.EventLog
+	CreateError             error
+	GetCalled               bool
+	GetError                error
+	MarkTransformedError    error
+	MarkedTransformedLogIDs map[string][]int64
+	PassedAddresses         [][]string
+	PassedMinIDs            []int
+	PassedLimits            []int
+	PassedHeaderID          int64
+	PassedLogs              []types.Log
+	PassedTopics            []core.Topics
+	PassedTransformerNames  []string
+	ReturnLogs              []core.EventLog
+	remainingLogs           map[string][]core.EventLog
 }
 
-func (repository *MockEventLogRepository) GetUntransformedEventLogs(minID, limit int) ([]core.EventLog, error) {
+// GetUntransformedEventLogs pages through ReturnLogs separately for each transformer
+func (repository *MockEventLogRepository) GetUntransformedEventLogs(transformerName string, addresses []string, topics core.Topics, minID, limit int) ([]core.EventLog, error) {
 	repository.GetCalled = true
+	repository.PassedTransformerNames = append(repository.PassedTransformerNames, transformerName)
+	repository.PassedAddresses = append(repository.PassedAddresses, addresses)
+	repository.PassedTopics = append(repository.PassedTopics, topics)
 	repository.PassedMinIDs = append(repository.PassedMinIDs, minID)
 	repository.PassedLimits = append(repository.PassedLimits, limit)
 
+	if repository.remainingLogs == nil {
+		repository.remainingLogs = make(map[string][]core.EventLog)
+	}
+	remaining, started := repository.remainingLogs[transformerName]
+	if !started {
+		remaining = repository.ReturnLogs
+	}
+
 	var returnLogs []core.EventLog
-	if limit >= len(repository.ReturnLogs) {
-		returnLogs = repository.ReturnLogs
-		repository.ReturnLogs = []core.EventLog{}
+	if limit >= len(remaining) {
+		returnLogs = remaining
+		remaining = []core.EventLog{}
 	} else {
 		for i := 0; i < limit; i++ {
-			returnLogs = append(returnLogs, repository.ReturnLogs[i])
+			returnLogs = append(returnLogs, remaining[i])
 		}
-		repository.ReturnLogs = repository.ReturnLogs[limit:]
+		remaining = remaining[limit:]
 	}
+	repository.remainingLogs[transformerName] = remaining
 
 	return returnLogs, repository.GetError
 }
 
+func (repository *MockEventLogRepository) MarkLogsTransformed(transformerName string, logIDs []int64) error {
+	if repository.MarkedTransformedLogIDs == nil {
+		repository.MarkedTransformedLogIDs = make(map[string][]int64)
+	}
+	repository.MarkedTransformedLogIDs[transformerName] = append(repository.MarkedTransformedLogIDs[transformerName], logIDs...)
+	return repository.MarkTransformedError
+}
+
 func (repository *MockEventLogRepository) CreateEventLogs(headerID int64, logs []types.Log) error {
 	repository.PassedHeaderID = headerID
 	repository.PassedLogs = logs
@@ -17,6 +17,8 @@
 package fakes
 
 import (
+	"sync"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	. "github.com/onsi/gomega"
@@ -26,6 +28,8 @@ type MockHeaderRepository struct {
 	AllHeaders                             []core.Header
 	CreateTransactionsCalled               bool
 	CreateTransactionsError                error
+	CreateTransactionsWithReceiptsError    error
+	CreateTransactionsWithReceiptsPassed   []core.TransactionModel
 	GetHeaderByBlockNumberError            error
 	GetHeaderByBlockNumberReturnHash       string
 	GetHeaderByBlockNumberReturnID         int64
@@ -35,14 +39,19 @@ type MockHeaderRepository struct {
 	GetHeadersInRangeEndingBlocks          []int64
 	GetHeadersInRangeError                 error
 	GetHeadersInRangeStartingBlocks        []int64
+	MissingBlockNumbersRelease             chan bool // if set, MissingBlockNumbers blocks until it's closed
 	MostRecentHeaderBlockNumber            int64
 	MostRecentHeaderBlockNumberErr         error
 	createOrUpdateHeaderCallCount          int
 	createOrUpdateHeaderErr                error
+	createOrUpdateHeadersBatchSizes        []int
+	createOrUpdateHeadersErr               error
+	createOrUpdateHeadersPassedHeaders     [][]core.Header
 	createOrUpdateHeaderPassedBlockNumbers []int64
 	createOrUpdateHeaderReturnID           int64
 	headerExists                           bool
 	missingBlockNumbers                    []int64
+	mutex                                  sync.Mutex
 }
 
 func NewMockHeaderRepository() *MockHeaderRepository {
@@ -62,16 +71,42 @@ func (mock *MockHeaderRepository) SetMissingBlockNumbers(blockNumbers []int64) {
 }
 
 func (mock *MockHeaderRepository) CreateOrUpdateHeader(header core.Header) (int64, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.createOrUpdateHeaderCallCount++
 	mock.createOrUpdateHeaderPassedBlockNumbers = append(mock.createOrUpdateHeaderPassedBlockNumbers, header.BlockNumber)
 	return mock.createOrUpdateHeaderReturnID, mock.createOrUpdateHeaderErr
 }
 
+func (mock *MockHeaderRepository) SetCreateOrUpdateHeadersReturnErr(err error) {
+	mock.createOrUpdateHeadersErr = err
+}
+
+func (mock *MockHeaderRepository) CreateOrUpdateHeaders(headers []core.Header) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.createOrUpdateHeadersBatchSizes = append(mock.createOrUpdateHeadersBatchSizes, len(headers))
+	mock.createOrUpdateHeadersPassedHeaders = append(mock.createOrUpdateHeadersPassedHeaders, headers)
+	if mock.createOrUpdateHeadersErr != nil {
+		return mock.createOrUpdateHeadersErr
+	}
+	for _, header := range headers {
+		mock.createOrUpdateHeaderCallCount++
+		mock.createOrUpdateHeaderPassedBlockNumbers = append(mock.createOrUpdateHeaderPassedBlockNumbers, header.BlockNumber)
+	}
+	return nil
+}
+
 func (mock *MockHeaderRepository) CreateTransactions(headerID int64, transactions []core.TransactionModel) error {
 	mock.CreateTransactionsCalled = true
 	return mock.CreateTransactionsError
 }
 
+func (mock *MockHeaderRepository) CreateTransactionsWithReceipts(headerID int64, transactions []core.TransactionModel) error {
+	mock.CreateTransactionsWithReceiptsPassed = transactions
+	return mock.CreateTransactionsWithReceiptsError
+}
+
 func (mock *MockHeaderRepository) CreateTransactionInTx(tx *sqlx.Tx, headerID int64, transaction core.TransactionModel) (int64, error) {
 	panic("implement me")
 }
@@ -90,12 +125,17 @@ func (mock *MockHeaderRepository) GetHeaderByID(id int64) (core.Header, error) {
 }
 
 func (mock *MockHeaderRepository) GetHeadersInRange(startingBlock, endingBlock int64) ([]core.Header, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
 	mock.GetHeadersInRangeStartingBlocks = append(mock.GetHeadersInRangeStartingBlocks, startingBlock)
 	mock.GetHeadersInRangeEndingBlocks = append(mock.GetHeadersInRangeEndingBlocks, endingBlock)
 	return mock.AllHeaders, mock.GetHeadersInRangeError
 }
 
 func (mock *MockHeaderRepository) MissingBlockNumbers(startingBlockNumber, endingBlockNumber int64) ([]int64, error) {
+	if mock.MissingBlockNumbersRelease != nil {
+		<-mock.MissingBlockNumbersRelease
+	}
 	return mock.missingBlockNumbers, nil
 }
 
@@ -107,3 +147,26 @@ func (mock *MockHeaderRepository) AssertCreateOrUpdateHeaderCallCountAndPassedBl
 	Expect(mock.createOrUpdateHeaderCallCount).To(Equal(times))
 	Expect(mock.createOrUpdateHeaderPassedBlockNumbers).To(Equal(blockNumbers))
 }
+
+func (mock *MockHeaderRepository) AssertCreateOrUpdateHeadersBatchSizes(batchSizes []int) {
+	Expect(mock.createOrUpdateHeadersBatchSizes).To(ConsistOf(batchSizes))
+}
+
+func (mock *MockHeaderRepository) AssertCreateOrUpdateHeaderPassedBlockNumbersConsistOf(blockNumbers []int64) {
+	Expect(mock.createOrUpdateHeaderPassedBlockNumbers).To(ConsistOf(blockNumbers))
+}
+
+func (mock *MockHeaderRepository) AssertCreateOrUpdateHeadersCalledWith(headers []core.Header) {
+	Expect(mock.createOrUpdateHeadersPassedHeaders).To(ContainElement(headers))
+}
+
+func (mock *MockHeaderRepository) AssertCreateOrUpdateHeadersNotCalled() {
+	Expect(mock.createOrUpdateHeadersPassedHeaders).To(BeEmpty())
+}
+
+// CreateOrUpdateHeaderPassedBlockNumbers returns the block numbers of every header persisted so far
+func (mock *MockHeaderRepository) CreateOrUpdateHeaderPassedBlockNumbers() []int64 {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	return append([]int64{}, mock.createOrUpdateHeaderPassedBlockNumbers...)
+}
@@ -0,0 +1,39 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fakes
+
+import "github.com/makerdao/vulcanizedb/pkg/core"
+
+type MockOrphanedHeadersRepository struct {
+	GetError                      error
+	GetPassedTransformerNames     []string
+	MarkHandledError              error
+	MarkedHandledIDs              []int64
+	MarkedHandledTransformerNames []string
+	ReturnOrphanHeaders           []core.OrphanedHeader
+}
+
+func (repository *MockOrphanedHeadersRepository) GetUnhandledOrphanedHeaders(transformerName string) ([]core.OrphanedHeader, error) {
+	repository.GetPassedTransformerNames = append(repository.GetPassedTransformerNames, transformerName)
+	return repository.ReturnOrphanHeaders, repository.GetError
+}
+
+func (repository *MockOrphanedHeadersRepository) MarkOrphanedHeaderHandled(id int64, transformerName string) error {
+	repository.MarkedHandledIDs = append(repository.MarkedHandledIDs, id)
+	repository.MarkedHandledTransformerNames = append(repository.MarkedHandledTransformerNames, transformerName)
+	return repository.MarkHandledError
+}
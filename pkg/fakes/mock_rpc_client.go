@@ -23,7 +23,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/makerdao/vulcanizedb/pkg/core"
@@ -32,22 +31,33 @@ import (
 )
 
 type MockRpcClient struct {
+	BatchCallCount       int
+	BatchCallErrs        []error
+	BatchElemErrs        []map[int]error
+	BatchSizes           []int
+	BlockNumber          uint64
 	callContextErr       error
+	CallContextCallCount int
 	ClientVersion        string
+	ConnectionErr        error
+	GenesisHeader        *types.Header
 	GethNodeInfo         p2p.NodeInfo
-	ipcPath              string
+	host                 string
 	NetworkID            string
+	nodeID               string
 	nodeType             core.NodeType
 	ParityEnode          string
 	ParityNodeInfo       core.ParityNodeInfo
+	MaxBatchSize         int
 	passedContext        context.Context
 	passedMethod         string
 	passedResult         interface{}
 	passedBatch          []core.BatchElem
 	passedNamespace      string
-	passedPayloadChan    chan filters.Payload
+	passedPayloadChan    interface{}
 	passedSubscribeArgs  []interface{}
 	lengthOfBatch        int
+	ReceiptsToReturn     []*types.Receipt
 	returnPOAHeader      core.POAHeader
 	returnPOAHeaders     []core.POAHeader
 	returnPOWHeaders     []*types.Header
@@ -61,11 +71,7 @@ func NewMockRpcClient() *MockRpcClient {
 func (c *MockRpcClient) Subscribe(namespace string, payloadChan interface{}, args ...interface{}) (core.Subscription, error) {
 	c.passedNamespace = namespace
 
-	passedPayloadChan, ok := payloadChan.(chan filters.Payload)
-	if !ok {
-		return nil, errors.New("passed in channel is not of the correct type")
-	}
-	c.passedPayloadChan = passedPayloadChan
+	c.passedPayloadChan = payloadChan
 
 	for _, arg := range args {
 		c.passedSubscribeArgs = append(c.passedSubscribeArgs, arg)
@@ -75,22 +81,49 @@ func (c *MockRpcClient) Subscribe(namespace string, payloadChan interface{}, arg
 	return client.Subscription{RpcSubscription: &subscription}, nil
 }
 
-func (c *MockRpcClient) AssertSubscribeCalledWith(namespace string, payloadChan chan filters.Payload, args []interface{}) {
+func (c *MockRpcClient) AssertSubscribeCalledWith(namespace string, payloadChan interface{}, args []interface{}) {
 	Expect(c.passedNamespace).To(Equal(namespace))
 	Expect(c.passedPayloadChan).To(Equal(payloadChan))
 	Expect(c.passedSubscribeArgs).To(Equal(args))
 }
 
-func (c *MockRpcClient) SetIpcPath(ipcPath string) {
-	c.ipcPath = ipcPath
+func (c *MockRpcClient) SetNodeID(nodeID string) {
+	c.nodeID = nodeID
+}
+
+func (c *MockRpcClient) SetHost(host string) {
+	c.host = host
+}
+
+func (c *MockRpcClient) BatchCallContext(_ context.Context, batch []core.BatchElem) error {
+	return c.BatchCall(batch)
 }
 
 func (c *MockRpcClient) BatchCall(batch []core.BatchElem) error {
+	c.BatchCallCount++
+	c.BatchSizes = append(c.BatchSizes, len(batch))
+	if c.ConnectionErr != nil {
+		return c.ConnectionErr
+	}
+	if len(c.BatchCallErrs) > 0 {
+		err := c.BatchCallErrs[0]
+		c.BatchCallErrs = c.BatchCallErrs[1:]
+		return err
+	}
+	if c.MaxBatchSize > 0 && len(batch) > c.MaxBatchSize {
+		return errors.New("batch too large")
+	}
+	if len(c.BatchElemErrs) > 0 {
+		for index, err := range c.BatchElemErrs[0] {
+			batch[index].Error = err
+		}
+		c.BatchElemErrs = c.BatchElemErrs[1:]
+	}
 	c.passedBatch = batch
 	c.passedMethod = batch[0].Method
 	c.lengthOfBatch = len(batch)
 
-	for _, batchElem := range batch {
+	for index, batchElem := range batch {
 		c.passedContext = context.Background()
 		c.passedResult = &batchElem.Result
 		c.passedMethod = batchElem.Method
@@ -103,20 +136,34 @@ func (c *MockRpcClient) BatchCall(batch []core.BatchElem) error {
 		if p, ok := batchElem.Result.(*hexutil.Bytes); ok {
 			*p = c.StorageValueToReturn
 		}
+		if p, ok := batchElem.Result.(**types.Receipt); ok && index < len(c.ReceiptsToReturn) {
+			*p = c.ReceiptsToReturn[index]
+		}
 	}
 
 	return nil
 }
 
 func (c *MockRpcClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.CallContextCallCount++
 	c.passedContext = ctx
 	c.passedResult = result
 	c.passedMethod = method
+	if c.ConnectionErr != nil {
+		return c.ConnectionErr
+	}
 	switch method {
+	case "eth_blockNumber":
+		if p, ok := result.(*hexutil.Uint64); ok {
+			*p = hexutil.Uint64(c.BlockNumber)
+		}
 	case "eth_getBlockByNumber":
 		if p, ok := result.(*types.Header); ok {
 			*p = types.Header{Number: big.NewInt(100)}
 		}
+		if p, ok := result.(**types.Header); ok && c.GenesisHeader != nil && len(args) > 0 && args[0] == "0x0" {
+			*p = c.GenesisHeader
+		}
 		if p, ok := result.(*core.POAHeader); ok {
 
 			*p = c.returnPOAHeader
@@ -144,8 +191,12 @@ func (c *MockRpcClient) CallContext(ctx context.Context, result interface{}, met
 	return nil
 }
 
-func (c *MockRpcClient) IpcPath() string {
-	return c.ipcPath
+func (c *MockRpcClient) NodeID() string {
+	return c.nodeID
+}
+
+func (c *MockRpcClient) Host() string {
+	return c.host
 }
 
 func (c *MockRpcClient) SetCallContextErr(err error) {
@@ -0,0 +1,211 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/makerdao/vulcanizedb/pkg/eth"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultHeaderBatchSize = eth.MAX_BATCH_SIZE
+	DefaultHeaderWorkers   = 4
+)
+
+var (
+	DefaultProgressReportInterval = 30 * time.Second
+	ErrInvalidBatchSize           = errors.New("header backfill batch size must be greater than zero")
+	ErrInvalidWorkerCount         = errors.New("header backfill worker count must be greater than zero")
+)
+
+// HeaderBackFiller fetches headers for a set of block numbers in batches, spreading the batches across a pool of
+// workers that each issue their own RPC batch request and persist the results.
+type HeaderBackFiller struct {
+	blockChain             core.BlockChain
+	headerRepository       datastore.HeaderRepository
+	BatchSize              int
+	Workers                int
+	ProgressReportInterval time.Duration
+}
+
+func NewHeaderBackFiller(blockChain core.BlockChain, headerRepository datastore.HeaderRepository, workers, batchSize int) HeaderBackFiller {
+	return HeaderBackFiller{
+		blockChain:             blockChain,
+		headerRepository:       headerRepository,
+		BatchSize:              batchSize,
+		Workers:                workers,
+		ProgressReportInterval: DefaultProgressReportInterval,
+	}
+}
+
+// BackFillHeaders fetches and persists headers for every given block number, returning the number of headers written
+func (backFiller HeaderBackFiller) BackFillHeaders(blockNumbers []int64) (int, error) {
+	if backFiller.BatchSize < 1 {
+		return 0, ErrInvalidBatchSize
+	}
+	if backFiller.Workers < 1 {
+		return 0, ErrInvalidWorkerCount
+	}
+	if len(blockNumbers) == 0 {
+		return 0, nil
+	}
+
+	batches := ChunkBlockNumbers(blockNumbers, backFiller.BatchSize)
+	batchesChan := make(chan []int64)
+	quitChan := make(chan bool)
+	progress := newBackFillProgress(len(blockNumbers))
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(quitChan)
+		})
+	}
+
+	for i := 0; i < backFiller.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batchesChan {
+				written, err := backFiller.backFillBatch(batch)
+				if err != nil {
+					fail(err)
+					return
+				}
+				progress.add(written)
+			}
+		}()
+	}
+
+	reportDone := make(chan bool)
+	go progress.report(backFiller.ProgressReportInterval, reportDone)
+
+dispatch:
+	for _, batch := range batches {
+		select {
+		case batchesChan <- batch:
+		case <-quitChan:
+			break dispatch
+		}
+	}
+	close(batchesChan)
+	wg.Wait()
+	close(reportDone)
+
+	written := progress.completed()
+	if firstErr != nil {
+		return written, firstErr
+	}
+	if len(batches) > 1 {
+		progress.log()
+	}
+	return written, nil
+}
+
+func (backFiller HeaderBackFiller) backFillBatch(blockNumbers []int64) (int, error) {
+	headers, getErr := backFiller.blockChain.GetHeadersByNumbers(blockNumbers)
+	if getErr != nil {
+		return 0, fmt.Errorf("error fetching headers for blocks %d-%d: %w",
+			blockNumbers[0], blockNumbers[len(blockNumbers)-1], getErr)
+	}
+	if len(headers) == 0 {
+		return 0, nil
+	}
+	createErr := backFiller.headerRepository.CreateOrUpdateHeaders(headers)
+	if createErr != nil {
+		return 0, fmt.Errorf("error persisting headers for blocks %d-%d: %w",
+			blockNumbers[0], blockNumbers[len(blockNumbers)-1], createErr)
+	}
+	return len(headers), nil
+}
+
+// ChunkBlockNumbers splits block numbers into consecutive slices of at most batchSize elements
+func ChunkBlockNumbers(blockNumbers []int64, batchSize int) [][]int64 {
+	var chunks [][]int64
+	for start := 0; start < len(blockNumbers); start += batchSize {
+		end := start + batchSize
+		if end > len(blockNumbers) {
+			end = len(blockNumbers)
+		}
+		chunks = append(chunks, blockNumbers[start:end])
+	}
+	return chunks
+}
+
+type backFillProgress struct {
+	total     int
+	done      int64
+	startTime time.Time
+}
+
+func newBackFillProgress(total int) *backFillProgress {
+	return &backFillProgress{total: total, startTime: time.Now()}
+}
+
+func (progress *backFillProgress) add(n int) {
+	atomic.AddInt64(&progress.done, int64(n))
+}
+
+func (progress *backFillProgress) completed() int {
+	return int(atomic.LoadInt64(&progress.done))
+}
+
+func (progress *backFillProgress) report(interval time.Duration, done chan bool) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			progress.log()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (progress *backFillProgress) log() {
+	completed := progress.completed()
+	elapsed := time.Since(progress.startTime)
+	blocksPerSecond := float64(completed) / elapsed.Seconds()
+	eta := "unknown"
+	if blocksPerSecond > 0 {
+		remaining := progress.total - completed
+		eta = time.Duration(float64(remaining) / blocksPerSecond * float64(time.Second)).Round(time.Second).String()
+	}
+	logrus.WithFields(logrus.Fields{
+		"completed":       completed,
+		"total":           progress.total,
+		"blocksPerSecond": fmt.Sprintf("%.2f", blocksPerSecond),
+		"eta":             eta,
+	}).Info("header backfill progress")
+}
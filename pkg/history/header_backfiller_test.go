@@ -0,0 +1,114 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history_test
+
+import (
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/pkg/history"
+)
+
+var _ = Describe("Header back-filler", func() {
+	var (
+		blockChain       *fakes.MockBlockChain
+		headerRepository *fakes.MockHeaderRepository
+	)
+
+	BeforeEach(func() {
+		blockChain = fakes.NewMockBlockChain()
+		headerRepository = fakes.NewMockHeaderRepository()
+	})
+
+	It("persists headers for every block number across batches", func() {
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 3, 2)
+
+		written, err := backFiller.BackFillHeaders([]int64{1, 2, 3, 4, 5})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(Equal(5))
+		headerRepository.AssertCreateOrUpdateHeadersBatchSizes([]int{2, 2, 1})
+		headerRepository.AssertCreateOrUpdateHeaderPassedBlockNumbersConsistOf([]int64{1, 2, 3, 4, 5})
+	})
+
+	It("returns without fetching when there are no block numbers", func() {
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 1, 1)
+
+		written, err := backFiller.BackFillHeaders([]int64{})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(written).To(BeZero())
+		headerRepository.AssertCreateOrUpdateHeadersBatchSizes([]int{})
+	})
+
+	It("returns an error if the batch size is invalid", func() {
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 1, 0)
+
+		_, err := backFiller.BackFillHeaders([]int64{1})
+
+		Expect(err).To(MatchError(history.ErrInvalidBatchSize))
+	})
+
+	It("returns an error if the worker count is invalid", func() {
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 0, 1)
+
+		_, err := backFiller.BackFillHeaders([]int64{1})
+
+		Expect(err).To(MatchError(history.ErrInvalidWorkerCount))
+	})
+
+	It("returns an error if fetching headers fails", func() {
+		blockChain.SetGetHeadersByNumbersErr(fakes.FakeError)
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 2, 1)
+
+		_, err := backFiller.BackFillHeaders([]int64{1, 2, 3})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError(ContainSubstring(fakes.FakeError.Error())))
+	})
+
+	It("returns an error if persisting headers fails", func() {
+		headerRepository.SetCreateOrUpdateHeadersReturnErr(fakes.FakeError)
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 2, 1)
+
+		_, err := backFiller.BackFillHeaders([]int64{1, 2, 3})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError(ContainSubstring(fakes.FakeError.Error())))
+	})
+
+	It("populates headers missing up to the head of the chain", func() {
+		blockChain.SetLastBlock(big.NewInt(5))
+		headerRepository.SetMissingBlockNumbers([]int64{2, 4, 5})
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 2, 2)
+
+		populated, err := backFiller.PopulateMissingHeaders(1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(populated).To(Equal(3))
+		headerRepository.AssertCreateOrUpdateHeaderPassedBlockNumbersConsistOf([]int64{2, 4, 5})
+	})
+
+	It("chunks block numbers into batches", func() {
+		chunks := history.ChunkBlockNumbers([]int64{1, 2, 3, 4, 5}, 2)
+
+		Expect(chunks).To(Equal([][]int64{{1, 2}, {3, 4}, {5}}))
+	})
+})
@@ -0,0 +1,168 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	DefaultResubscribeInterval    = 7 * time.Second
+	ErrNewHeadsSubscriptionClosed = errors.New("newHeads subscription closed")
+	ErrNewHeadOrphaned            = errors.New("announced head is no longer canonical")
+)
+
+// HeaderSubscriber persists headers as soon as the node announces them through a newHeads subscription. While the
+// subscription is unavailable it falls back to polling for missing headers.
+type HeaderSubscriber struct {
+	blockChain          core.BlockChain
+	headerRepository    datastore.HeaderRepository
+	backFiller          HeaderBackFiller
+	ResubscribeInterval time.Duration
+}
+
+func NewHeaderSubscriber(blockChain core.BlockChain, headerRepository datastore.HeaderRepository, backFiller HeaderBackFiller) HeaderSubscriber {
+	return HeaderSubscriber{
+		blockChain:          blockChain,
+		headerRepository:    headerRepository,
+		backFiller:          backFiller,
+		ResubscribeInterval: DefaultResubscribeInterval,
+	}
+}
+
+// SyncHeaders follows the chain head from startingBlockNumber until quit is closed. Each time a subscription is
+// (re)established, headers missed while it was down are filled in from MissingBlockNumbers.
+func (subscriber HeaderSubscriber) SyncHeaders(startingBlockNumber int64, quit <-chan bool) {
+	for {
+		err := subscriber.followNewHeads(startingBlockNumber, quit)
+		if err == nil {
+			return
+		}
+		logrus.Warnf("header subscription unavailable, polling for headers: %s", err.Error())
+		subscriber.pollMissingHeaders(startingBlockNumber)
+
+		select {
+		case <-quit:
+			return
+		case <-time.After(subscriber.ResubscribeInterval):
+		}
+	}
+}
+
+// followNewHeads persists announced heads until the subscription fails or quit is closed. Missing headers are polled
+// for in the background once subscribed; heads announced meanwhile are still received, so the subscription isn't left
+// blocked, and are persisted once the poll finishes.
+func (subscriber HeaderSubscriber) followNewHeads(startingBlockNumber int64, quit <-chan bool) error {
+	heads := make(chan core.NewHead)
+	subscription, subscribeErr := subscriber.blockChain.SubscribeNewHeads(heads)
+	if subscribeErr != nil {
+		return fmt.Errorf("error subscribing to newHeads: %w", subscribeErr)
+	}
+	defer subscription.Unsubscribe()
+	logrus.Info("subscribed to newHeads")
+
+	pollDone := make(chan struct{})
+	go func() {
+		defer close(pollDone)
+		subscriber.pollMissingHeaders(startingBlockNumber)
+	}()
+	defer func() { <-pollDone }()
+
+	var (
+		lastHead     int64
+		polling      = pollDone
+		pendingHeads []core.NewHead
+	)
+	for {
+		select {
+		case <-quit:
+			return nil
+		case subscriptionErr, ok := <-subscription.Err():
+			if !ok || subscriptionErr == nil {
+				return ErrNewHeadsSubscriptionClosed
+			}
+			return fmt.Errorf("newHeads subscription failed: %w", subscriptionErr)
+		case <-polling:
+			polling = nil
+			for _, head := range pendingHeads {
+				lastHead = subscriber.handleHead(lastHead, head)
+			}
+			pendingHeads = nil
+		case head := <-heads:
+			if polling != nil {
+				pendingHeads = append(pendingHeads, head)
+				continue
+			}
+			lastHead = subscriber.handleHead(lastHead, head)
+		}
+	}
+}
+
+// handleHead persists an announced head and returns the latest head persisted
+func (subscriber HeaderSubscriber) handleHead(lastHead int64, head core.NewHead) int64 {
+	if head.Number == nil {
+		return lastHead
+	}
+	blockNumber := head.Number.ToInt().Int64()
+	persistErr := subscriber.persistHead(lastHead, blockNumber, head.Hash.Hex())
+	if persistErr != nil {
+		logrus.WithField("blockNumber", blockNumber).Errorf("error persisting new head: %s", persistErr.Error())
+		return lastHead
+	}
+	return blockNumber
+}
+
+// persistHead writes the announced header, first back-filling any block numbers skipped since the previous head. If
+// the node no longer has the announced block at that height, nothing is written: the head that replaced it will be
+// announced as well.
+func (subscriber HeaderSubscriber) persistHead(lastHead, blockNumber int64, hash string) error {
+	if lastHead != 0 && blockNumber > lastHead+1 {
+		_, backFillErr := subscriber.backFiller.BackFillHeaders(MakeRange(lastHead+1, blockNumber-1))
+		if backFillErr != nil {
+			return fmt.Errorf("error back-filling headers skipped since block %d: %w", lastHead, backFillErr)
+		}
+	}
+	header, getErr := subscriber.blockChain.GetHeaderByNumber(blockNumber)
+	if getErr != nil {
+		return fmt.Errorf("error fetching header: %w", getErr)
+	}
+	if header.Hash != hash {
+		return fmt.Errorf("%w: announced %s, node has %s", ErrNewHeadOrphaned, hash, header.Hash)
+	}
+	_, createErr := subscriber.headerRepository.CreateOrUpdateHeader(header)
+	if createErr != nil {
+		return fmt.Errorf("error persisting header: %w", createErr)
+	}
+	return nil
+}
+
+func (subscriber HeaderSubscriber) pollMissingHeaders(startingBlockNumber int64) {
+	populated, err := subscriber.backFiller.PopulateMissingHeaders(startingBlockNumber)
+	if err != nil {
+		logrus.Errorf("error populating missing headers: %s", err.Error())
+		return
+	}
+	if populated > 0 {
+		logrus.Infof("populated %d missing headers", populated)
+	}
+}
@@ -0,0 +1,148 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history_test
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/pkg/history"
+)
+
+var _ = Describe("Header subscriber", func() {
+	var (
+		blockChain       *fakes.MockBlockChain
+		headerRepository *fakes.MockHeaderRepository
+		subscription     *fakes.MockSubscription
+		subscriber       history.HeaderSubscriber
+		quit             chan bool
+	)
+
+	newHead := func(blockNumber int64) core.NewHead {
+		hash := common.BigToHash(big.NewInt(blockNumber))
+		blockChain.SetHeaderByNumber(core.Header{BlockNumber: blockNumber, Hash: hash.Hex()})
+		return core.NewHead{Number: (*hexutil.Big)(big.NewInt(blockNumber)), Hash: hash}
+	}
+
+	BeforeEach(func() {
+		blockChain = fakes.NewMockBlockChain()
+		blockChain.SetLastBlock(big.NewInt(10))
+		subscription = &fakes.MockSubscription{Errs: make(chan error)}
+		blockChain.NewHeadsSubscription = subscription
+		headerRepository = fakes.NewMockHeaderRepository()
+		backFiller := history.NewHeaderBackFiller(blockChain, headerRepository, 1, 10)
+		subscriber = history.NewHeaderSubscriber(blockChain, headerRepository, backFiller)
+		subscriber.ResubscribeInterval = time.Millisecond
+		quit = make(chan bool)
+	})
+
+	AfterEach(func() {
+		close(quit)
+	})
+
+	It("persists headers announced by the subscription", func() {
+		head := newHead(11)
+		go subscriber.SyncHeaders(1, quit)
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(1))
+
+		blockChain.LatestNewHeadsChan() <- head
+
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(ContainElement(int64(11)))
+	})
+
+	It("doesn't persist an announced head the node no longer has", func() {
+		orphanedHead := newHead(11)
+		blockChain.SetHeaderByNumber(core.Header{BlockNumber: 11, Hash: common.BigToHash(big.NewInt(111)).Hex()})
+		nextHead := newHead(12)
+		go subscriber.SyncHeaders(1, quit)
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(1))
+
+		blockChain.LatestNewHeadsChan() <- orphanedHead
+		blockChain.LatestNewHeadsChan() <- nextHead
+
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(ContainElement(int64(12)))
+		Expect(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers()).NotTo(ContainElement(int64(11)))
+	})
+
+	It("fills missing headers once subscribed", func() {
+		headerRepository.SetMissingBlockNumbers([]int64{3, 4})
+
+		go subscriber.SyncHeaders(1, quit)
+
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(ContainElements(int64(3), int64(4)))
+	})
+
+	It("keeps receiving announced heads while polling for missing headers", func() {
+		release := make(chan bool)
+		headerRepository.MissingBlockNumbersRelease = release
+		headerRepository.SetMissingBlockNumbers([]int64{3})
+		firstHead, nextHead := newHead(11), newHead(12)
+		go subscriber.SyncHeaders(1, quit)
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(1))
+
+		received := make(chan bool)
+		go func() {
+			blockChain.LatestNewHeadsChan() <- firstHead
+			blockChain.LatestNewHeadsChan() <- nextHead
+			close(received)
+		}()
+
+		Eventually(received).Should(BeClosed())
+		Expect(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers()).To(BeEmpty())
+		close(release)
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(
+			ContainElements(int64(3), int64(11), int64(12)))
+	})
+
+	It("back-fills block numbers skipped between announced heads", func() {
+		firstHead, nextHead := newHead(11), newHead(14)
+		go subscriber.SyncHeaders(1, quit)
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(1))
+
+		blockChain.LatestNewHeadsChan() <- firstHead
+		blockChain.LatestNewHeadsChan() <- nextHead
+
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(
+			ContainElements(int64(11), int64(12), int64(13), int64(14)))
+	})
+
+	It("polls for missing headers and retries if subscribing fails", func() {
+		blockChain.SetSubscribeNewHeadsErrs([]error{fakes.FakeError})
+		headerRepository.SetMissingBlockNumbers([]int64{5})
+
+		go subscriber.SyncHeaders(1, quit)
+
+		Eventually(headerRepository.CreateOrUpdateHeaderPassedBlockNumbers).Should(ContainElement(int64(5)))
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(2))
+	})
+
+	It("resubscribes if the subscription drops", func() {
+		go subscriber.SyncHeaders(1, quit)
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(Equal(1))
+
+		subscription.Errs <- fakes.FakeError
+
+		Eventually(blockChain.SubscribeNewHeadsCallCount).Should(BeNumerically(">=", 2))
+	})
+})
@@ -17,16 +17,25 @@
 package history
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/datastore"
+	"github.com/sirupsen/logrus"
 )
 
+var ErrReorgDeeperThanWindow = errors.New("reorg extends beyond the validation window")
+
+type ReorgHandler interface {
+	HandleReorg(event core.ReorgEvent) error
+}
+
 type HeaderValidator struct {
 	blockChain       core.BlockChain
 	headerRepository datastore.HeaderRepository
 	windowSize       int
+	reorgHandlers    []ReorgHandler
 }
 
 func NewHeaderValidator(blockChain core.BlockChain, repository datastore.HeaderRepository, windowSize int) HeaderValidator {
@@ -37,15 +46,124 @@ func NewHeaderValidator(blockChain core.BlockChain, repository datastore.HeaderR
 	}
 }
 
+// AddReorgHandler registers a handler to be notified whenever an orphaned branch of headers is replaced
+func (validator *HeaderValidator) AddReorgHandler(handler ReorgHandler) {
+	validator.reorgHandlers = append(validator.reorgHandlers, handler)
+}
+
+// ValidateHeaders walks back from the most recent stored header in the validation window, following parent hashes
+// until it reaches a header shared with the canonical chain. Any stored headers on an orphaned branch are replaced.
 func (validator HeaderValidator) ValidateHeaders() (ValidationWindow, error) {
 	window, err := MakeValidationWindow(validator.blockChain, validator.windowSize)
 	if err != nil {
 		return ValidationWindow{}, fmt.Errorf("error creating validation window: %s", err.Error())
 	}
-	blockNumbers := MakeRange(window.LowerBound, window.UpperBound)
-	_, err = RetrieveAndUpdateHeaders(validator.blockChain, validator.headerRepository, blockNumbers)
+	storedHeaders, err := validator.headerRepository.GetHeadersInRange(window.LowerBound, window.UpperBound)
 	if err != nil {
-		return ValidationWindow{}, fmt.Errorf("error getting/updating headers: %s", err.Error())
+		return ValidationWindow{}, fmt.Errorf("error getting headers in validation window: %s", err.Error())
+	}
+	if len(storedHeaders) == 0 {
+		return window, nil
+	}
+
+	event, canonicalHeaders, detectErr := validator.detectReorg(window, storedHeaders)
+	if detectErr != nil {
+		return ValidationWindow{}, detectErr
+	}
+	if len(canonicalHeaders) == 0 {
+		return window, nil
+	}
+
+	replaceErr := validator.headerRepository.CreateOrUpdateHeaders(canonicalHeaders)
+	if replaceErr != nil {
+		return ValidationWindow{}, fmt.Errorf("error replacing orphaned headers: %s", replaceErr.Error())
 	}
+	validator.emitReorg(event)
 	return window, nil
 }
+
+// detectReorg returns the orphaned branch of stored headers and the canonical headers replacing it. If the header at
+// the lower bound of the window is orphaned, the common ancestor can't be confirmed, so nothing is returned for
+// replacement and ErrReorgDeeperThanWindow is returned instead.
+func (validator HeaderValidator) detectReorg(window ValidationWindow, storedHeaders []core.Header) (core.ReorgEvent, []core.Header, error) {
+	storedByNumber := make(map[int64]core.Header, len(storedHeaders))
+	head := storedHeaders[0].BlockNumber
+	for _, header := range storedHeaders {
+		storedByNumber[header.BlockNumber] = header
+		if header.BlockNumber > head {
+			head = header.BlockNumber
+		}
+	}
+
+	canonical, err := validator.blockChain.GetHeaderByNumber(head)
+	if err != nil {
+		return core.ReorgEvent{}, nil, fmt.Errorf("error fetching header for block %d: %w", head, err)
+	}
+	expectedHash := canonical.Hash
+
+	var (
+		replaced         []core.ReplacedHeader
+		canonicalHeaders []core.Header
+		lowerBoundOrphan bool
+	)
+	for blockNumber := head; blockNumber >= window.LowerBound; blockNumber-- {
+		stored, ok := storedByNumber[blockNumber]
+		if !ok {
+			// gaps are filled from the canonical chain by the back-filler
+			break
+		}
+		if stored.Hash == expectedHash {
+			if stored.ParentHash == "" {
+				// headers persisted before parent hashes were recorded can't be walked any further
+				break
+			}
+			expectedHash = stored.ParentHash
+			continue
+		}
+
+		if canonical.BlockNumber != blockNumber {
+			canonical, err = validator.blockChain.GetHeaderByNumber(blockNumber)
+			if err != nil {
+				return core.ReorgEvent{}, nil, fmt.Errorf("error fetching header for block %d: %w", blockNumber, err)
+			}
+		}
+		replaced = append([]core.ReplacedHeader{{
+			BlockNumber: blockNumber,
+			OldHeaderID: stored.Id,
+			OldHash:     stored.Hash,
+			NewHash:     canonical.Hash,
+		}}, replaced...)
+		canonicalHeaders = append([]core.Header{canonical}, canonicalHeaders...)
+		expectedHash = canonical.ParentHash
+		lowerBoundOrphan = blockNumber == window.LowerBound
+	}
+
+	if len(replaced) == 0 {
+		return core.ReorgEvent{}, nil, nil
+	}
+	if lowerBoundOrphan {
+		return core.ReorgEvent{}, nil, fmt.Errorf("no common ancestor found at or above block %d, %d orphaned headers left in place: %w",
+			window.LowerBound, len(replaced), ErrReorgDeeperThanWindow)
+	}
+	event := core.ReorgEvent{
+		CommonAncestorBlockNumber: replaced[0].BlockNumber - 1,
+		Depth:                     int(head - replaced[0].BlockNumber + 1),
+		ReplacedHeaders:           replaced,
+	}
+	return event, canonicalHeaders, nil
+}
+
+func (validator HeaderValidator) emitReorg(event core.ReorgEvent) {
+	logrus.WithFields(logrus.Fields{
+		"commonAncestor": event.CommonAncestorBlockNumber,
+		"depth":          event.Depth,
+		"oldHashes":      event.OldHashes(),
+		"newHashes":      event.NewHashes(),
+	}).Warn("reorg detected, replaced orphaned headers")
+	for _, handler := range validator.reorgHandlers {
+		handleErr := handler.HandleReorg(event)
+		if handleErr != nil {
+			logrus.Errorf("error handling reorg at block %d: %s", event.CommonAncestorBlockNumber, handleErr.Error())
+		}
+	}
+}
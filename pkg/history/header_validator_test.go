@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/makerdao/vulcanizedb/pkg/core"
 	"github.com/makerdao/vulcanizedb/pkg/fakes"
 	"github.com/makerdao/vulcanizedb/pkg/history"
 )
@@ -31,31 +32,161 @@ var _ = Describe("Header validator", func() {
 	var (
 		headerRepository *fakes.MockHeaderRepository
 		blockChain       *fakes.MockBlockChain
+		reorgHandler     *fakes.MockReorgHandler
+		validator        history.HeaderValidator
+		storedOne        = core.Header{Id: 1, BlockNumber: 1, Hash: "0x1", ParentHash: "0x0"}
+		storedTwo        = core.Header{Id: 2, BlockNumber: 2, Hash: "0x2", ParentHash: "0x1"}
+		storedThree      = core.Header{Id: 3, BlockNumber: 3, Hash: "0x3", ParentHash: "0x2"}
 	)
 
 	BeforeEach(func() {
 		headerRepository = fakes.NewMockHeaderRepository()
 		blockChain = fakes.NewMockBlockChain()
+		blockChain.SetLastBlock(big.NewInt(3))
+		reorgHandler = &fakes.MockReorgHandler{}
+		validator = history.NewHeaderValidator(blockChain, headerRepository, 2)
+		validator.AddReorgHandler(reorgHandler)
 	})
 
-	It("attempts to create every header in the validation window", func() {
-		headerRepository.SetMissingBlockNumbers([]int64{})
-		blockChain.SetLastBlock(big.NewInt(3))
-		validator := history.NewHeaderValidator(blockChain, headerRepository, 2)
+	It("gets stored headers in the validation window", func() {
+		_, err := validator.ValidateHeaders()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(headerRepository.GetHeadersInRangeStartingBlocks).To(ConsistOf(int64(1)))
+		Expect(headerRepository.GetHeadersInRangeEndingBlocks).To(ConsistOf(int64(3)))
+	})
+
+	It("propagates error getting stored headers", func() {
+		headerRepository.GetHeadersInRangeError = fakes.FakeError
 
 		_, err := validator.ValidateHeaders()
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+	})
+
+	It("only fetches the chain head when the stored chain is canonical", func() {
+		headerRepository.AllHeaders = []core.Header{storedOne, storedTwo, storedThree}
+		blockChain.SetHeaderByNumber(storedThree)
+
+		_, err := validator.ValidateHeaders()
+
 		Expect(err).NotTo(HaveOccurred())
+		Expect(blockChain.GetHeaderByNumberPassedNumbers).To(Equal([]int64{3}))
+		headerRepository.AssertCreateOrUpdateHeadersNotCalled()
+		Expect(reorgHandler.HandledEvents).To(BeEmpty())
+	})
+
+	It("propagates error fetching the chain head", func() {
+		headerRepository.AllHeaders = []core.Header{storedOne, storedTwo, storedThree}
+		blockChain.SetGetHeaderByNumberErr(fakes.FakeError)
+
+		_, err := validator.ValidateHeaders()
 
-		headerRepository.AssertCreateOrUpdateHeaderCallCountAndPassedBlockNumbers(3, []int64{1, 2, 3})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
 	})
 
-	It("propagates header repository errors", func() {
-		blockChain.SetLastBlock(big.NewInt(3))
-		headerRepositoryError := errors.New("CreateOrUpdate")
-		headerRepository.SetCreateOrUpdateHeaderReturnErr(headerRepositoryError)
-		validator := history.NewHeaderValidator(blockChain, headerRepository, 2)
+	Describe("when the stored chain has been orphaned", func() {
+		var (
+			newTwo   = core.Header{BlockNumber: 2, Hash: "0x2b", ParentHash: "0x1"}
+			newThree = core.Header{BlockNumber: 3, Hash: "0x3b", ParentHash: "0x2b"}
+		)
+
+		BeforeEach(func() {
+			headerRepository.AllHeaders = []core.Header{storedOne, storedTwo, storedThree}
+			blockChain.SetHeaderByNumber(newTwo)
+			blockChain.SetHeaderByNumber(newThree)
+		})
+
+		It("replaces the orphaned branch back to the common ancestor", func() {
+			_, err := validator.ValidateHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+			headerRepository.AssertCreateOrUpdateHeadersCalledWith([]core.Header{newTwo, newThree})
+		})
+
+		It("emits a reorg event describing the replaced headers", func() {
+			_, err := validator.ValidateHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reorgHandler.HandledEvents).To(ConsistOf(core.ReorgEvent{
+				CommonAncestorBlockNumber: 1,
+				Depth:                     2,
+				ReplacedHeaders: []core.ReplacedHeader{
+					{BlockNumber: 2, OldHeaderID: 2, OldHash: "0x2", NewHash: "0x2b"},
+					{BlockNumber: 3, OldHeaderID: 3, OldHash: "0x3", NewHash: "0x3b"},
+				},
+			}))
+		})
+
+		It("propagates error replacing headers without emitting an event", func() {
+			headerRepository.SetCreateOrUpdateHeadersReturnErr(fakes.FakeError)
+
+			_, err := validator.ValidateHeaders()
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+			Expect(reorgHandler.HandledEvents).To(BeEmpty())
+		})
+
+		It("does not return an error if a reorg handler fails", func() {
+			reorgHandler.HandleErr = fakes.FakeError
+
+			_, err := validator.ValidateHeaders()
+
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	It("replaces a stored header that doesn't match its child's parent hash", func() {
+		staleTwo := core.Header{Id: 2, BlockNumber: 2, Hash: "0x2a", ParentHash: "0x1"}
+		headerRepository.AllHeaders = []core.Header{storedOne, staleTwo, storedThree}
+		blockChain.SetHeaderByNumber(storedTwo)
+		blockChain.SetHeaderByNumber(storedThree)
+
+		_, err := validator.ValidateHeaders()
+
+		Expect(err).NotTo(HaveOccurred())
+		headerRepository.AssertCreateOrUpdateHeadersCalledWith([]core.Header{storedTwo})
+	})
+
+	It("returns an error when the reorg is deeper than the validation window", func() {
+		newTwo := core.Header{BlockNumber: 2, Hash: "0x2b", ParentHash: "0x1b"}
+		newThree := core.Header{BlockNumber: 3, Hash: "0x3b", ParentHash: "0x2b"}
+		headerRepository.AllHeaders = []core.Header{storedOne, storedTwo, storedThree}
+		blockChain.SetHeaderByNumber(newTwo)
+		blockChain.SetHeaderByNumber(newThree)
 
 		_, err := validator.ValidateHeaders()
-		Expect(err.Error()).To(ContainSubstring(headerRepositoryError.Error()))
+
+		Expect(errors.Is(err, history.ErrReorgDeeperThanWindow)).To(BeTrue())
+		headerRepository.AssertCreateOrUpdateHeadersNotCalled()
+		Expect(reorgHandler.HandledEvents).To(BeEmpty())
+	})
+
+	It("compares the header at the lower bound of the validation window", func() {
+		staleOne := core.Header{Id: 1, BlockNumber: 1, Hash: "0x1a", ParentHash: "0x0"}
+		headerRepository.AllHeaders = []core.Header{staleOne, storedTwo, storedThree}
+		blockChain.SetHeaderByNumber(storedOne)
+		blockChain.SetHeaderByNumber(storedThree)
+
+		_, err := validator.ValidateHeaders()
+
+		Expect(errors.Is(err, history.ErrReorgDeeperThanWindow)).To(BeTrue())
+		Expect(blockChain.GetHeaderByNumberPassedNumbers).To(Equal([]int64{3, 1}))
+		headerRepository.AssertCreateOrUpdateHeadersNotCalled()
+	})
+
+	It("stops walking back at headers without a recorded parent hash", func() {
+		legacyThree := core.Header{Id: 3, BlockNumber: 3, Hash: "0x3"}
+		headerRepository.AllHeaders = []core.Header{storedOne, storedTwo, legacyThree}
+		blockChain.SetHeaderByNumber(storedThree)
+
+		_, err := validator.ValidateHeaders()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockChain.GetHeaderByNumberPassedNumbers).To(Equal([]int64{3}))
+		headerRepository.AssertCreateOrUpdateHeadersNotCalled()
 	})
 })
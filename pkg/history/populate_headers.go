@@ -25,12 +25,18 @@ import (
 )
 
 func PopulateMissingHeaders(blockChain core.BlockChain, headerRepository datastore.HeaderRepository, startingBlockNumber int64) (int, error) {
-	lastBlock, err := blockChain.LastBlock()
+	backFiller := NewHeaderBackFiller(blockChain, headerRepository, DefaultHeaderWorkers, DefaultHeaderBatchSize)
+	return backFiller.PopulateMissingHeaders(startingBlockNumber)
+}
+
+// PopulateMissingHeaders back-fills every header missing between the starting block and the head of the chain
+func (backFiller HeaderBackFiller) PopulateMissingHeaders(startingBlockNumber int64) (int, error) {
+	lastBlock, err := backFiller.blockChain.LastBlock()
 	if err != nil {
 		return 0, fmt.Errorf("error getting last block: %w", err)
 	}
 
-	blockNumbers, err := headerRepository.MissingBlockNumbers(startingBlockNumber, lastBlock.Int64())
+	blockNumbers, err := backFiller.headerRepository.MissingBlockNumbers(startingBlockNumber, lastBlock.Int64())
 	if err != nil {
 		return 0, fmt.Errorf("error getting missing block numbers: %s", err.Error())
 	} else if len(blockNumbers) == 0 {
@@ -38,15 +44,18 @@ func PopulateMissingHeaders(blockChain core.BlockChain, headerRepository datasto
 	}
 
 	logrus.Debug(getBlockRangeString(blockNumbers))
-	_, err = RetrieveAndUpdateHeaders(blockChain, headerRepository, blockNumbers)
+	populated, err := backFiller.BackFillHeaders(blockNumbers)
 	if err != nil {
-		return 0, fmt.Errorf("error getting/updating headers: %s", err.Error())
+		return populated, fmt.Errorf("error getting/updating headers: %s", err.Error())
 	}
-	return len(blockNumbers), nil
+	return populated, nil
 }
 
 func RetrieveAndUpdateHeaders(blockChain core.BlockChain, headerRepository datastore.HeaderRepository, blockNumbers []int64) (int, error) {
 	headers, err := blockChain.GetHeadersByNumbers(blockNumbers)
+	if err != nil {
+		return 0, err
+	}
 	for _, header := range headers {
 		_, err = headerRepository.CreateOrUpdateHeader(header)
 		if err != nil {
@@ -0,0 +1,42 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history
+
+import (
+	"fmt"
+
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/datastore"
+)
+
+// ReorgRecorder is a ReorgHandler that persists reorg events, so that transformers running in other processes can
+// find the headers that were replaced
+type ReorgRecorder struct {
+	repository datastore.ReorgRepository
+}
+
+func NewReorgRecorder(repository datastore.ReorgRepository) ReorgRecorder {
+	return ReorgRecorder{repository: repository}
+}
+
+func (recorder ReorgRecorder) HandleReorg(event core.ReorgEvent) error {
+	createErr := recorder.repository.CreateReorg(event)
+	if createErr != nil {
+		return fmt.Errorf("error recording reorg: %w", createErr)
+	}
+	return nil
+}
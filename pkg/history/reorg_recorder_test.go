@@ -0,0 +1,51 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package history_test
+
+import (
+	"github.com/makerdao/vulcanizedb/pkg/core"
+	"github.com/makerdao/vulcanizedb/pkg/fakes"
+	"github.com/makerdao/vulcanizedb/pkg/history"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reorg recorder", func() {
+	event := core.ReorgEvent{
+		CommonAncestorBlockNumber: 1,
+		Depth:                     1,
+		ReplacedHeaders:           []core.ReplacedHeader{{BlockNumber: 2, OldHeaderID: 2, OldHash: "0x2", NewHash: "0x2b"}},
+	}
+
+	It("persists the reorg event", func() {
+		repository := &fakes.MockReorgRepository{}
+
+		err := history.NewReorgRecorder(repository).HandleReorg(event)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repository.PassedReorgEvents).To(ConsistOf(event))
+	})
+
+	It("returns an error if persisting the event fails", func() {
+		repository := &fakes.MockReorgRepository{CreateError: fakes.FakeError}
+
+		err := history.NewReorgRecorder(repository).HandleReorg(event)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(fakes.FakeError.Error()))
+	})
+})
@@ -22,7 +22,9 @@ import (
 
 	. "github.com/dave/jennifer/jen"
 
+	"github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
 	"github.com/makerdao/vulcanizedb/pkg/config"
+	"github.com/makerdao/vulcanizedb/pkg/eth"
 	"github.com/makerdao/vulcanizedb/pkg/plugin/helpers"
 )
 
@@ -61,6 +63,9 @@ func (w *writer) WritePlugin() error {
 	f.ImportAlias("github.com/makerdao/vulcanizedb/libraries/shared/factories/event", "event")
 	f.ImportAlias("github.com/makerdao/vulcanizedb/libraries/shared/factories/storage", "storage")
 	for name, transformer := range w.GenConfig.Transformers {
+		if transformer.IsABIEvent() {
+			continue
+		}
 		f.ImportAlias(transformer.RepositoryPath+"/"+transformer.Path, name)
 	}
 
@@ -99,7 +104,15 @@ func (w *writer) WritePlugin() error {
 // Collect code for various types of initializers
 func (w *writer) collectTransformers() (map[config.TransformerType][]Code, error) {
 	code := make(map[config.TransformerType][]Code)
-	for _, transformer := range w.GenConfig.Transformers {
+	for name, transformer := range w.GenConfig.Transformers {
+		if transformer.IsABIEvent() {
+			initializer, err := abiEventInitializer(name, transformer.ABIEvent)
+			if err != nil {
+				return nil, err
+			}
+			code[config.EthEvent] = append(code[config.EthEvent], initializer)
+			continue
+		}
 		path := transformer.RepositoryPath + "/" + transformer.Path
 		switch transformer.Type {
 		case config.EthEvent:
@@ -116,6 +129,42 @@ func (w *writer) collectTransformers() (map[config.TransformerType][]Code, error
 	return code, nil
 }
 
+// Generates the initializer of a transformer built from an event of a contract ABI, embedding the ABI in the plugin.
+// The event is looked up here so that a misconfigured transformer fails the plugin's generation rather than its loading.
+func abiEventInitializer(name string, abiEvent config.ABIEvent) (Code, error) {
+	abiPath, pathErr := helpers.CleanPath(abiEvent.AbiPath)
+	if pathErr != nil {
+		return nil, pathErr
+	}
+	contractAbi, abiErr := eth.ReadAbiFile(abiPath)
+	if abiErr != nil {
+		return nil, fmt.Errorf("failed to read abi for transformer %s: %w", name, abiErr)
+	}
+	_, transformerErr := event.NewABITransformer(contractAbi, abiEvent.EventName, event.SchemaName(abiEvent.SchemaName),
+		event.TableName(abiEvent.TableName))
+	if transformerErr != nil {
+		return nil, fmt.Errorf("invalid abi event config for transformer %s: %w", name, transformerErr)
+	}
+
+	addresses := make([]Code, len(abiEvent.ContractAddresses))
+	for i, address := range abiEvent.ContractAddresses {
+		addresses[i] = Lit(address)
+	}
+	eventPkg := "github.com/makerdao/vulcanizedb/libraries/shared/factories/event"
+	return Qual(eventPkg, "MustNewABITransformerInitializer").Call(
+		Qual(eventPkg, "TransformerConfig").Values(Dict{
+			Id("TransformerName"):     Lit(name),
+			Id("ContractAddresses"):   Index().String().Values(addresses...),
+			Id("ContractAbi"):         Lit(contractAbi),
+			Id("StartingBlockNumber"): Lit(abiEvent.StartingBlock),
+			Id("EndingBlockNumber"):   Lit(abiEvent.EndingBlock),
+		}),
+		Lit(abiEvent.EventName),
+		Lit(abiEvent.SchemaName),
+		Lit(abiEvent.TableName),
+	), nil
+}
+
 // Setup the .go, clear old ones if present
 func (w *writer) setupFilePath() (string, error) {
 	goFile, soFile, err := w.GenConfig.GetPluginPaths()
@@ -48,14 +48,15 @@ func setTestConfig() {
 		logrus.Fatal(err)
 	}
 	ipc := TestConfig.GetString("client.ipcPath")
+	url := TestConfig.GetString("client.url")
 
 	// If we don't have an ipc path in the config file, check the env variable
-	if ipc == "" {
+	if ipc == "" && url == "" {
 		TestConfig.BindEnv("url", "CLIENT_IPCPATH")
 		ipc = TestConfig.GetString("url")
 	}
-	if ipc == "" {
-		logrus.Fatal(errors.New("testing.toml IPC path or $CLIENT_IPCPATH env variable need to be set"))
+	if ipc == "" && url == "" {
+		logrus.Fatal(errors.New("testing.toml IPC path or url, or $CLIENT_IPCPATH env variable need to be set"))
 	}
 
 	hn := TestConfig.GetString("database.hostname")
@@ -69,6 +70,7 @@ func setTestConfig() {
 	}
 	TestClient = config.Client{
 		IPCPath: ipc,
+		URL:     url,
 	}
 }
 
@@ -87,14 +89,22 @@ func NewTestDB(node core.Node) *postgres.DB {
 
 func CleanTestDB(db *postgres.DB) {
 	db.MustExec("DELETE FROM public.addresses")
+	db.MustExec("DELETE FROM public.backfill_progress")
 	db.MustExec("DELETE FROM public.checked_headers")
+	db.MustExec("DELETE FROM public.dead_letter_logs")
 	// can't delete from eth_nodes since this function is called after the required eth_node is persisted
 	db.MustExec("DELETE FROM public.goose_db_version")
+	db.MustExec("DELETE FROM public.handled_orphaned_headers")
 	db.MustExec("DELETE FROM public.event_logs")
 	db.MustExec("DELETE FROM public.receipts")
+	db.MustExec("DELETE FROM public.reorgs")
 	db.MustExec("DELETE FROM public.transactions")
+	db.MustExec("DELETE FROM public.transformed_event_logs")
+	db.MustExec("DELETE FROM public.orphaned_headers")
 	db.MustExec("DELETE FROM public.headers")
 	db.MustExec("DELETE FROM public.storage_diff")
+	db.MustExec("DELETE FROM public.storage_preimage")
+	db.MustExec("DELETE FROM public.storage_preimage_cursor")
 	db.MustExec("DELETE FROM public.watched_logs")
 }
 